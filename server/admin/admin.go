@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"chess/auth"
+	"chess/model"
+)
+
+// endpoints for site admins, every route checks the user is an admin
+type AdminServer struct {
+	ServeMux      *http.ServeMux
+	db            *model.Queries
+	authServer    auth.AuthStrategy
+	exportLimiter *rateLimiter
+	backupLimiter *rateLimiter
+	backup        Backuper
+}
+
+func NewAdminServer(db *model.Queries, authServer auth.AuthStrategy) *AdminServer {
+	server := &AdminServer{
+		ServeMux:      http.NewServeMux(),
+		db:            db,
+		authServer:    authServer,
+		exportLimiter: newRateLimiter(exportInterval),
+		backupLimiter: newRateLimiter(backupInterval),
+	}
+
+	server.ServeMux.HandleFunc("/export", server.ExportHandler)
+	server.ServeMux.HandleFunc("/backup", server.BackupHandler)
+	server.ServeMux.HandleFunc("/verify", server.VerifyHandler)
+	server.ServeMux.HandleFunc("/api-keys", server.ApiKeysHandler)
+
+	return server
+}
+
+func (server *AdminServer) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	isAdmin, err := server.authServer.IsAdmin(ctx, writer, req)
+	if err != nil {
+		return
+	}
+	if !isAdmin {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+	server.ServeMux.ServeHTTP(writer, req)
+}
+
+func logError(ctx context.Context, err error) {
+	slog.ErrorContext(ctx, "error", slog.Any("error", err))
+}
@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"chess/auth"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+// an organisation's key as listed to admins, the key itself is never shown
+// after it's created
+type ApiKeyResponse struct {
+	Id           string       `json:"id"`
+	Organization string       `json:"organization"`
+	Scopes       []auth.Scope `json:"scopes"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	RevokedAt    *time.Time   `json:"revokedAt,omitempty"`
+	// only set in the response to creating the key
+	Key string `json:"key,omitempty"`
+}
+
+type CreateApiKeyRequest struct {
+	Organization string   `json:"organization"`
+	Scopes       []string `json:"scopes"`
+}
+
+func apiKeyResponse(key model.ApiKey) ApiKeyResponse {
+	scopes := make([]auth.Scope, 0)
+	for _, scope := range strings.Fields(key.Scopes) {
+		scopes = append(scopes, auth.Scope(scope))
+	}
+	response := ApiKeyResponse{
+		Id:           key.ID.String(),
+		Organization: key.Organization,
+		Scopes:       scopes,
+		CreatedAt:    key.CreatedAt.UTC(),
+	}
+	if key.RevokedAt.Valid {
+		revokedAt := key.RevokedAt.Time.UTC()
+		response.RevokedAt = &revokedAt
+	}
+	return response
+}
+
+// GET lists every key, POST creates one and DELETE ?id= revokes one
+func (server *AdminServer) ApiKeysHandler(writer http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		server.listApiKeys(writer, req)
+	case http.MethodPost:
+		server.createApiKey(writer, req)
+	case http.MethodDelete:
+		server.revokeApiKey(writer, req)
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (server *AdminServer) listApiKeys(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	keys, err := server.db.ListApiKeys(ctx)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+
+	response := make([]ApiKeyResponse, len(keys))
+	for i, key := range keys {
+		response[i] = apiKeyResponse(key)
+	}
+	writeJson(writer, http.StatusOK, response)
+}
+
+func (server *AdminServer) createApiKey(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	body := CreateApiKeyRequest{}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Organization == "" || len(body.Scopes) == 0 {
+		http.Error(writer, "organization and scopes are required", http.StatusBadRequest)
+		return
+	}
+	scopes, err := auth.ParseScopes(body.Scopes)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, hash, err := auth.GenerateApiKey()
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+	params := model.CreateApiKeyParams{
+		ID:           uuid.New(),
+		Organization: body.Organization,
+		KeyHash:      hash,
+		Scopes:       auth.JoinScopes(scopes),
+	}
+	err = server.db.CreateApiKey(ctx, params)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+
+	writeJson(writer, http.StatusCreated, ApiKeyResponse{
+		Id:           params.ID.String(),
+		Organization: params.Organization,
+		Scopes:       scopes,
+		CreatedAt:    time.Now().UTC(),
+		Key:          key,
+	})
+}
+
+func (server *AdminServer) revokeApiKey(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	id, err := uuid.Parse(req.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(writer, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := server.db.RevokeApiKey(ctx, id)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+	if revoked == 0 {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func writeJson(writer http.ResponseWriter, status int, body any) {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Add("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	writer.Write(bytes)
+}
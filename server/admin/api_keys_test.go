@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chess/auth"
+	"chess/env"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+func apiKeysRequest(t *testing.T, server *AdminServer, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: auth.CookieKeySession, Value: testSessionId})
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestApiKeys(t *testing.T) {
+	db := newTestDb(t)
+	server := NewAdminServer(db, &auth.MockAuthServer{})
+	authServer := auth.NewAuthServer(db, &env.Env{}, "")
+	results := authServer.RequireScope(auth.ScopeReadResults,
+		http.HandlerFunc(server.OrganizationExportHandler))
+	server.exportLimiter = newRateLimiter(0)
+
+	resultsRequest := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/org/results", nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		recorder := httptest.NewRecorder()
+		results.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	recorder := apiKeysRequest(t, server, http.MethodPost, "/api-keys",
+		`{"organization": "club", "scopes": ["results:read"]}`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", recorder.Code)
+	}
+	resultsKey := ApiKeyResponse{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &resultsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keys can't be made without a scope through the api
+	unscopedKey, hash, err := auth.GenerateApiKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.CreateApiKey(context.Background(), model.CreateApiKeyParams{
+		ID:           uuid.New(),
+		Organization: "club",
+		KeyHash:      hash,
+		Scopes:       "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder = apiKeysRequest(t, server, http.MethodPost, "/api-keys",
+		`{"organization": "club", "scopes": ["games:relay"]}`)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a scope which guards nothing to be rejected, got %d", recorder.Code)
+	}
+
+	if code := resultsRequest(""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a key, got %d", code)
+	}
+	if code := resultsRequest(resultsKey.Key); code != http.StatusOK {
+		t.Errorf("Expected 200 with the results scope, got %d", code)
+	}
+	if code := resultsRequest(unscopedKey); code != http.StatusForbidden {
+		t.Errorf("Expected 403 without the results scope, got %d", code)
+	}
+
+	recorder = apiKeysRequest(t, server, http.MethodGet, "/api-keys", "")
+	listed := []ApiKeyResponse{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &listed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 || listed[0].Key != "" {
+		t.Errorf("Expected both keys to be listed without the key itself, got %+v", listed)
+	}
+
+	recorder = apiKeysRequest(t, server, http.MethodDelete, "/api-keys?id="+resultsKey.Id, "")
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", recorder.Code)
+	}
+	if code := resultsRequest(resultsKey.Key); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a revoked key, got %d", code)
+	}
+	recorder = apiKeysRequest(t, server, http.MethodDelete, "/api-keys?id="+resultsKey.Id, "")
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected revoking twice to 404, got %d", recorder.Code)
+	}
+}
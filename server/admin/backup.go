@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const backupInterval = time.Minute
+
+type Backuper interface {
+	Run(ctx context.Context) (string, error)
+}
+
+type BackupResponse struct {
+	Path string `json:"path"`
+}
+
+// backups are disabled until this is called
+func (server *AdminServer) SetBackup(backup Backuper) {
+	server.backup = backup
+}
+
+// POST /backup takes a backup straight away, e.g. before a risky migration
+func (server *AdminServer) BackupHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if req.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if server.backup == nil {
+		http.Error(writer, "backups are not configured", http.StatusNotImplemented)
+		return
+	}
+
+	// one at a time across all admins
+	wait := server.backupLimiter.allow("")
+	if wait > 0 {
+		writer.Header().Add("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		writer.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	path, err := server.backup.Run(ctx)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(BackupResponse{Path: path})
+	if err != nil {
+		logError(ctx, err)
+	}
+}
@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chess/auth"
+)
+
+type fakeBackup struct {
+	runs int
+}
+
+func (backup *fakeBackup) Run(ctx context.Context) (string, error) {
+	backup.runs++
+	return "backup.ndjson", nil
+}
+
+func backupRequest(server *AdminServer, method string) int {
+	req := httptest.NewRequest(method, "/backup", nil)
+	req.AddCookie(&http.Cookie{Name: auth.CookieKeySession, Value: testSessionId})
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestBackupHandler(t *testing.T) {
+	server := NewAdminServer(newTestDb(t), &auth.MockAuthServer{})
+	if code := backupRequest(server, http.MethodPost); code != http.StatusNotImplemented {
+		t.Fatalf("Expected backups to be disabled, got %d", code)
+	}
+
+	backup := &fakeBackup{}
+	server.SetBackup(backup)
+	if code := backupRequest(server, http.MethodGet); code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status 405, got %d", code)
+	}
+	if code := backupRequest(server, http.MethodPost); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if code := backupRequest(server, http.MethodPost); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected backup to be rate limited, got %d", code)
+	}
+	if backup.runs != 1 {
+		t.Errorf("Expected 1 backup, got %d", backup.runs)
+	}
+}
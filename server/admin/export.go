@@ -0,0 +1,231 @@
+package admin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chess/auth"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+const (
+	exportInterval  = 10 * time.Second
+	exportPageSize  = 500
+	maxExportLimit  = 100_000
+	exportDateQuery = "2006-01-02"
+)
+
+// one line of the export, games are ordered by end time then id
+type ExportedGame struct {
+	Id         string    `json:"id"`
+	White      string    `json:"white"`
+	Black      string    `json:"black"`
+	GameLength int64     `json:"gameLength"` // milliseconds
+	Increment  int64     `json:"increment"`  // milliseconds
+	Result     string    `json:"result"`
+	Condition  string    `json:"condition"`
+	Moves      []string  `json:"moves"`
+	CreatedAt  time.Time `json:"createdAt"`
+	EndedAt    time.Time `json:"endedAt"`
+	Variant    string    `json:"variant"`
+	// why the game ended e.g. "checkmate" or "time forfeit"
+	Termination string `json:"termination"`
+	// empty for games stored before the final position was kept
+	FinalFen string `json:"finalFen"`
+	// pass as ?cursor= to resume the export after this game
+	Cursor string `json:"cursor"`
+}
+
+type cursor struct {
+	endedAt time.Time
+	id      uuid.UUID
+}
+
+func (cur cursor) String() string {
+	str := cur.endedAt.UTC().Format(time.RFC3339Nano) + "|" + cur.id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(str))
+}
+
+func parseCursor(str string) (cursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return cursor{}, err
+	}
+	endedAtStr, idStr, found := strings.Cut(string(bytes), "|")
+	if !found {
+		return cursor{}, errors.New("malformed cursor")
+	}
+	endedAt, err := time.Parse(time.RFC3339Nano, endedAtStr)
+	if err != nil {
+		return cursor{}, err
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return cursor{}, err
+	}
+	return cursor{endedAt: endedAt, id: id}, nil
+}
+
+func parseDate(str string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339, str)
+	if err == nil {
+		return date.UTC(), nil
+	}
+	return time.Parse(exportDateQuery, str)
+}
+
+type exportParams struct {
+	start cursor
+	to    time.Time
+	limit int
+}
+
+// ?from= and ?to= take a date or rfc3339 time, to is exclusive. ?cursor=
+// overrides from, ?limit= caps the number of games
+func getExportParams(req *http.Request) (exportParams, error) {
+	query := req.URL.Query()
+	params := exportParams{
+		to:    time.Now().UTC(),
+		limit: maxExportLimit,
+	}
+
+	if from := query.Get("from"); from != "" {
+		date, err := parseDate(from)
+		if err != nil {
+			return params, fmt.Errorf("invalid from: %w", err)
+		}
+		params.start.endedAt = date
+	}
+	if to := query.Get("to"); to != "" {
+		date, err := parseDate(to)
+		if err != nil {
+			return params, fmt.Errorf("invalid to: %w", err)
+		}
+		params.to = date
+	}
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cur, err := parseCursor(cursorStr)
+		if err != nil {
+			return params, fmt.Errorf("invalid cursor: %w", err)
+		}
+		params.start = cur
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return params, errors.New("invalid limit")
+		}
+		params.limit = min(limit, maxExportLimit)
+	}
+	return params, nil
+}
+
+func exportGame(game model.Game) ExportedGame {
+	moves := strings.Fields(game.Moves)
+	return ExportedGame{
+		Id:          game.ID.String(),
+		White:       game.WhiteID.String(),
+		Black:       game.BlackID.String(),
+		GameLength:  game.GameLength,
+		Increment:   game.Increment,
+		Result:      game.Result,
+		Condition:   game.Condition,
+		Moves:       moves,
+		CreatedAt:   game.CreatedAt.UTC(),
+		EndedAt:     game.EndedAt.UTC(),
+		Variant:     game.Variant,
+		Termination: game.Termination,
+		FinalFen:    game.FinalFen,
+		Cursor:      cursor{endedAt: game.EndedAt, id: game.ID}.String(),
+	}
+}
+
+// Streams finished games as newline delimited json. If the stream is cut
+// short the export can be resumed from the cursor of the last game received
+func (server *AdminServer) ExportHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if req.Method != http.MethodGet {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	if err != nil {
+		return
+	}
+	server.export(writer, req, userSession.UserID.String())
+}
+
+// The same export for organisations with the results:read scope, it's mounted
+// outside the admin routes behind the api key check
+func (server *AdminServer) OrganizationExportHandler(writer http.ResponseWriter, req *http.Request) {
+	key, found := auth.ApiKeyFromContext(req.Context())
+	if !found {
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	server.export(writer, req, "org:"+key.ID.String())
+}
+
+// requests are rate limited by limiterKey
+func (server *AdminServer) export(writer http.ResponseWriter, req *http.Request, limiterKey string) {
+	ctx := req.Context()
+	params, err := getExportParams(req)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wait := server.exportLimiter.allow(limiterKey)
+	if wait > 0 {
+		writer.Header().Add("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		writer.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(writer)
+	flusher, canFlush := writer.(http.Flusher)
+
+	start := params.start
+	exported := 0
+	for exported < params.limit {
+		games, err := server.db.ListGamesEndedBetween(ctx, model.ListGamesEndedBetweenParams{
+			EndedBefore:   params.to,
+			CursorEndedAt: start.endedAt.UTC(),
+			CursorID:      start.id,
+			Limit:         int64(min(exportPageSize, params.limit-exported)),
+		})
+		if err != nil {
+			// the status has already been sent so the stream just ends early
+			logError(ctx, err)
+			return
+		}
+
+		for _, game := range games {
+			err = encoder.Encode(exportGame(game))
+			if err != nil {
+				logError(ctx, err)
+				return
+			}
+		}
+		if canFlush {
+			flusher.Flush()
+		}
+
+		exported += len(games)
+		if len(games) < exportPageSize {
+			return
+		}
+		last := games[len(games)-1]
+		start = cursor{endedAt: last.EndedAt, id: last.ID}
+	}
+}
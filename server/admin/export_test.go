@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/model"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDb(t *testing.T) *model.Queries {
+	t.Helper()
+	ddl, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(string(ddl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.New(db)
+}
+
+var testSessionId = uuid.NewString()
+
+func exportRequest(t *testing.T, server *AdminServer, query string) (int, []ExportedGame) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/export?"+query, nil)
+	req.AddCookie(&http.Cookie{Name: auth.CookieKeySession, Value: testSessionId})
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+
+	games := make([]ExportedGame, 0)
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		game := ExportedGame{}
+		err := json.Unmarshal(scanner.Bytes(), &game)
+		if err != nil {
+			t.Fatal(err)
+		}
+		games = append(games, game)
+	}
+	return recorder.Code, games
+}
+
+func TestExport(t *testing.T) {
+	db := newTestDb(t)
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		err := db.CreateGame(context.Background(), model.CreateGameParams{
+			ID:         uuid.New(),
+			WhiteID:    uuid.New(),
+			BlackID:    uuid.New(),
+			GameLength: 300_000,
+			Result:     "1-0",
+			Condition:  "White win",
+			Moves:      "D1:C2 E8:F7",
+			CreatedAt:  start,
+			EndedAt:    start.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := NewAdminServer(db, &auth.MockAuthServer{})
+
+	code, games := exportRequest(t, server, "from=2025-03-01&to=2025-03-02&limit=2")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(games) != 2 {
+		t.Fatalf("Expected 2 games, got %d", len(games))
+	}
+	if len(games[0].Moves) != 2 || !games[0].EndedAt.Equal(start) {
+		t.Errorf("Unexpected first game %+v", games[0])
+	}
+
+	code, _ = exportRequest(t, server, "from=2025-03-01")
+	if code != http.StatusTooManyRequests {
+		t.Fatalf("Expected export to be rate limited, got %d", code)
+	}
+
+	server.exportLimiter = newRateLimiter(0)
+	_, rest := exportRequest(t, server, "to=2025-03-02&cursor="+games[1].Cursor)
+	if len(rest) != 2 {
+		t.Fatalf("Expected the remaining 2 games, got %d", len(rest))
+	}
+	if !rest[0].EndedAt.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("Expected export to resume after the cursor, got %s", rest[0].EndedAt)
+	}
+
+	_, none := exportRequest(t, server, "from=2025-03-01&to=2025-03-01T13:00:00Z")
+	if len(none) != 1 {
+		t.Errorf("Expected games ending after to to be excluded, got %d", len(none))
+	}
+}
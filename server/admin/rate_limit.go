@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"sync"
+	"time"
+)
+
+// allows each key one request per interval
+type rateLimiter struct {
+	lock     sync.Mutex
+	interval time.Duration
+	last     map[string]time.Time
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		interval: interval,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// how long to wait before trying again, zero when the request is allowed
+func (limiter *rateLimiter) allow(key string) time.Duration {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	now := time.Now()
+	if last, found := limiter.last[key]; found {
+		wait := limiter.interval - now.Sub(last)
+		if wait > 0 {
+			return wait
+		}
+	}
+	limiter.last[key] = now
+	return 0
+}
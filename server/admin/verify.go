@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"chess/engine"
+	"chess/model"
+	"chess/verify"
+)
+
+// Replays stored games and streams the reports of the ones which fail as
+// newline delimited json, takes the same parameters as /export
+func (server *AdminServer) VerifyHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if req.Method != http.MethodGet {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	params, err := getExportParams(req)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(writer)
+
+	start := params.start
+	checked := 0
+	for checked < params.limit {
+		games, err := server.db.ListGamesEndedBetween(ctx, model.ListGamesEndedBetweenParams{
+			EndedBefore:   params.to,
+			CursorEndedAt: start.endedAt.UTC(),
+			CursorID:      start.id,
+			Limit:         int64(min(exportPageSize, params.limit-checked)),
+		})
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		for _, game := range games {
+			report := verify.Game(game, engine.InsufficientMaterial{})
+			if report.Verified {
+				continue
+			}
+			err = encoder.Encode(report)
+			if err != nil {
+				logError(ctx, err)
+				return
+			}
+		}
+
+		checked += len(games)
+		if len(games) < exportPageSize {
+			return
+		}
+		last := games[len(games)-1]
+		start = cursor{endedAt: last.EndedAt, id: last.ID}
+	}
+}
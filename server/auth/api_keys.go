@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"chess/model"
+)
+
+// What an organisation's api key is allowed to do, personal sessions aren't
+// scoped
+type Scope string
+
+// Scopes for creating tournaments and relaying games get added along with
+// the routes they guard, the game relay is public for now
+const ScopeReadResults Scope = "results:read"
+
+var Scopes = []Scope{ScopeReadResults}
+
+func ParseScopes(strs []string) ([]Scope, error) {
+	scopes := make([]Scope, 0, len(strs))
+	for _, str := range strs {
+		scope := Scope(str)
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", str)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+func JoinScopes(scopes []Scope) string {
+	strs := make([]string, len(scopes))
+	for i, scope := range scopes {
+		strs[i] = string(scope)
+	}
+	return strings.Join(strs, " ")
+}
+
+func KeyHasScope(key model.ApiKey, scope Scope) bool {
+	return slices.Contains(strings.Fields(key.Scopes), string(scope))
+}
+
+// keys are easy to tell apart from other tokens
+const apiKeyPrefix = "org_"
+
+// The key is only ever handed out once, only its hash is stored
+func GenerateApiKey() (key string, hash string, err error) {
+	bytes := make([]byte, 32)
+	_, err = rand.Read(bytes)
+	if err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(bytes)
+	return key, HashApiKey(key), nil
+}
+
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// keys are sent as Authorization: Bearer <key>
+func bearerApiKey(req *http.Request) (string, bool) {
+	token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !found || !strings.HasPrefix(token, apiKeyPrefix) {
+		return "", false
+	}
+	return token, true
+}
+
+type apiKeyContextKey struct{}
+
+// The key the request was made with, set for handlers behind RequireScope
+func ApiKeyFromContext(ctx context.Context) (model.ApiKey, bool) {
+	key, found := ctx.Value(apiKeyContextKey{}).(model.ApiKey)
+	return key, found
+}
+
+func (server *AuthServer) RequireScope(scope Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		token, found := bearerApiKey(req)
+		if !found {
+			http.Error(writer, "api key required", http.StatusUnauthorized)
+			return
+		}
+
+		key, err := server.db.GetApiKeyByHash(ctx, HashApiKey(token))
+		if err == sql.ErrNoRows {
+			http.Error(writer, "invalid api key", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			slog.Error("error retrieving api key", slog.Any("error", err))
+			http.Error(writer, "Failed querying db", http.StatusInternalServerError)
+			return
+		}
+
+		if !KeyHasScope(key, scope) {
+			http.Error(writer, "api key is missing the "+string(scope)+" scope",
+				http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(writer, req.WithContext(context.WithValue(ctx, apiKeyContextKey{}, key)))
+	})
+}
@@ -12,6 +12,7 @@ import (
 
 	"chess/env"
 	"chess/model"
+	"chess/utility"
 
 	"github.com/google/uuid"
 	"golang.org/x/oauth2"
@@ -31,6 +32,14 @@ type AuthStrategy interface {
 		writer http.ResponseWriter,
 		req *http.Request,
 	) (*model.GetSessionByIdAndUserRow, error)
+	IsAdmin(
+		ctx context.Context,
+		writer http.ResponseWriter,
+		req *http.Request,
+	) (bool, error)
+	// only lets through requests made with an organisation api key which
+	// has the scope
+	RequireScope(scope Scope, next http.Handler) http.Handler
 }
 
 type MockAuthServer struct {
@@ -50,18 +59,19 @@ func (server *MockAuthServer) GetUserSession(
 	writer http.ResponseWriter,
 	req *http.Request,
 ) (*model.GetSessionByIdAndUserRow, error) {
-	_, err := getSessionId(writer, req)
+	sessionId, err := getSessionId(writer, req)
 	if err != nil {
 		return nil, err
 	}
 
+	// the same session cookie always maps to the same mock user
 	return &model.GetSessionByIdAndUserRow{
-		UserID:                uuid.New(),
+		UserID:                uuid.NewSHA1(uuid.NameSpaceOID, sessionId[:]),
 		UserUsername:          nullString("user"),
 		UserEmail:             "user@gmail.com",
 		UserCreatedAt:         time.Now(),
 		UserUpdatedAt:         time.Now(),
-		SessionID:             uuid.New(),
+		SessionID:             sessionId,
 		SessionAccessToken:    "access_token",
 		SessionRefreshToken:   nullString("refresh_token"),
 		SessionExpiresAt:      time.Now().Add(time.Hour),
@@ -70,11 +80,34 @@ func (server *MockAuthServer) GetUserSession(
 	}, nil
 }
 
+// every mock user is an admin
+func (server *MockAuthServer) IsAdmin(
+	ctx context.Context,
+	writer http.ResponseWriter,
+	req *http.Request,
+) (bool, error) {
+	return true, nil
+}
+
+// every request is treated as if it came from a key with every scope
+func (server *MockAuthServer) RequireScope(scope Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		key := model.ApiKey{
+			ID:           uuid.NewSHA1(uuid.NameSpaceOID, []byte("mock")),
+			Organization: "mock",
+			Scopes:       JoinScopes(Scopes),
+		}
+		ctx := context.WithValue(req.Context(), apiKeyContextKey{}, key)
+		next.ServeHTTP(writer, req.WithContext(ctx))
+	})
+}
+
 type AuthServer struct {
 	ServeMux     *http.ServeMux
 	oAuth2Config *oauth2.Config
 	stateStore   StateStoreMap
 	db           *model.Queries
+	admins       utility.Set[uuid.UUID]
 }
 
 func NewAuthServer(db *model.Queries, environment *env.Env, path string) *AuthServer {
@@ -92,12 +125,24 @@ func NewAuthServer(db *model.Queries, environment *env.Env, path string) *AuthSe
 		},
 		stateStore: make(StateStoreMap),
 		db:         db,
+		admins:     utility.NewSet[uuid.UUID](),
+	}
+
+	for _, id := range environment.AdminIds {
+		adminId, err := uuid.Parse(id)
+		if err != nil {
+			slog.Error("invalid admin id", slog.String("id", id))
+			continue
+		}
+		server.admins.Add(adminId)
 	}
 
 	server.ServeMux.HandleFunc("/login", server.LoginHandler)
 	server.ServeMux.HandleFunc("/logout", server.LogoutHandler)
 	server.ServeMux.HandleFunc("/callback", server.CallbackHandler)
 	server.ServeMux.HandleFunc("/user", server.UserHandler)
+	server.ServeMux.HandleFunc("/notification-preferences",
+		server.NotificationPreferencesHandler)
 
 	return server
 }
@@ -544,3 +589,13 @@ func (server *AuthServer) IsAuthenticated(
 
 	return true, nil
 }
+
+func (server *AuthServer) IsAdmin(
+	ctx context.Context, writer http.ResponseWriter, req *http.Request,
+) (bool, error) {
+	userSession, err := server.GetUserSession(ctx, writer, req)
+	if err != nil {
+		return false, err
+	}
+	return server.admins.Has(userSession.UserID), nil
+}
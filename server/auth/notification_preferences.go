@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"chess/notification"
+)
+
+// GET returns the user's notification preferences, PATCH takes the same
+// shape with only the choices being changed and returns the result
+func (server *AuthServer) NotificationPreferencesHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	ctx := req.Context()
+	userSession, err := server.GetUserSession(ctx, writer, req)
+	if err != nil {
+		return
+	}
+
+	var preferences notification.Preferences
+	switch req.Method {
+	case http.MethodGet:
+		preferences, err = notification.Load(ctx, server.db, userSession.UserID)
+	case http.MethodPatch:
+		patch := notification.Preferences{}
+		err = json.NewDecoder(req.Body).Decode(&patch)
+		if err == nil {
+			err = patch.Validate()
+		}
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		preferences, err = notification.Update(ctx, server.db, userSession.UserID, patch)
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		slog.Error("error updating notification preferences", slog.Any("error", err))
+		http.Error(writer, "Failed querying db", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(preferences)
+	if err != nil {
+		slog.Error("error encoding notification preferences", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,166 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	Interval         = 24 * time.Hour
+	DefaultRetention = 7
+	filePrefix       = "backup-"
+	fileSuffix       = ".ndjson"
+	fileTimeFormat   = "20060102T150405.000Z"
+)
+
+// sessions are left out, they're short lived and can be recreated by logging in
+var tables = []string{"users", "games", "notification_preferences"}
+
+// one line of a backup file
+type Row struct {
+	Table  string         `json:"table"`
+	Values map[string]any `json:"values"`
+}
+
+// Exports the critical tables to ndjson files in a directory, works the same
+// against local sqlite and remote libsql where file snapshots aren't possible
+type Backup struct {
+	db        *sql.DB
+	dir       string
+	retention int
+}
+
+// retention is how many backups to keep, older ones are removed after each run
+func New(db *sql.DB, dir string, retention int) *Backup {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Backup{db: db, dir: dir, retention: retention}
+}
+
+// Writes a new backup and returns its path
+func (backup *Backup) Run(ctx context.Context) (string, error) {
+	err := os.MkdirAll(backup.dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+
+	name := filePrefix + time.Now().UTC().Format(fileTimeFormat) + fileSuffix
+	path := filepath.Join(backup.dir, name)
+	// written to a temp file first so a failed run never looks like a backup
+	tmp, err := os.CreateTemp(backup.dir, name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, table := range tables {
+		err = exportTable(ctx, backup.db, table, encoder)
+		if err != nil {
+			return "", fmt.Errorf("failed to export %s: %w", table, err)
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return "", err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return "", err
+	}
+
+	return path, backup.prune()
+}
+
+// scheduler compatible version of Run
+func (backup *Backup) Job(ctx context.Context) error {
+	_, err := backup.Run(ctx)
+	return err
+}
+
+func exportTable(ctx context.Context, db *sql.DB, table string, encoder *json.Encoder) error {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return err
+		}
+		row := Row{Table: table, Values: make(map[string]any, len(columns))}
+		for i, column := range columns {
+			// text columns can come back as bytes depending on the driver
+			if bytes, ok := values[i].([]byte); ok {
+				row.Values[column] = string(bytes)
+			} else {
+				row.Values[column] = values[i]
+			}
+		}
+		err = encoder.Encode(row)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Paths of the existing backups, oldest first
+func (backup *Backup) List() ([]string, error) {
+	entries, err := os.ReadDir(backup.dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			paths = append(paths, filepath.Join(backup.dir, name))
+		}
+	}
+	// the timestamps sort lexically
+	slices.Sort(paths)
+	return paths, nil
+}
+
+func (backup *Backup) prune() error {
+	paths, err := backup.List()
+	if err != nil {
+		return err
+	}
+	for len(paths) > backup.retention {
+		err = os.Remove(paths[0])
+		if err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
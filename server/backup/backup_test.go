@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDb(t *testing.T) *sql.DB {
+	t.Helper()
+	ddl, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would get its own in memory db
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(string(ddl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestBackup(t *testing.T) {
+	db := newTestDb(t)
+	_, err := db.Exec(`INSERT INTO users (id, username, email) VALUES ('a', 'alice', 'a@example.com')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO sessions (id, user_id, access_token, expires_at)
+		VALUES ('s', 'a', 'token', CURRENT_TIMESTAMP)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup := New(db, t.TempDir(), 2)
+	path, err := backup.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows := make([]Row, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		row := Row{}
+		err = json.Unmarshal(scanner.Bytes(), &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) != 1 || rows[0].Table != "users" || rows[0].Values["email"] != "a@example.com" {
+		t.Errorf("Unexpected backup rows %+v", rows)
+	}
+}
+
+func TestBackupRetention(t *testing.T) {
+	db := newTestDb(t)
+	backup := New(db, t.TempDir(), 2)
+
+	paths := make([]string, 0)
+	for range 3 {
+		path, err := backup.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	kept, err := backup.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0] != paths[1] || kept[1] != paths[2] {
+		t.Errorf("Expected the newest two backups to be kept, got %v", kept)
+	}
+}
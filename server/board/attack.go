@@ -0,0 +1,117 @@
+package board
+
+// queries which work out attacks from the pieces directly rather than the
+// attacked flags, which are only set for the side not to move
+
+func (board *BoardState) pieceAt(pos Position, vec Vector) (Piece, Position) {
+	moved, inBounds := pos.AddInBounds(vec)
+	if !inBounds {
+		return Clear, moved
+	}
+	return board.GetSquare(moved), moved
+}
+
+// calls found for every piece attacking the square until it returns false
+func (board *BoardState) forEachAttacker(pos Position, found func(Piece, Position) bool) {
+	for _, vec := range knightDirectionArray {
+		piece, from := board.pieceAt(pos, vec)
+		if piece.Is(Knight) && !found(piece, from) {
+			return
+		}
+	}
+
+	// a pawn attacks the square from the opposite of its capture direction
+	for _, pawn := range [...]Piece{WPawn, BPawn} {
+		for _, dir := range board.Variant().Pawns(pawn.Colour()).Captures {
+			piece, from := board.pieceAt(pos, directionToVec(reverseDirection(dir)))
+			if piece.IsPieceAndColour(pawn) && !found(piece, from) {
+				return
+			}
+		}
+	}
+
+	for _, vec := range nonKnightDirectionArray {
+		piece, from := board.pieceAt(pos, vec)
+		if piece.Is(King) && !found(piece, from) {
+			return
+		}
+	}
+
+	for _, vec := range diagonalDirectionArray {
+		piece, from := board.FindInDirection(vec, &pos)
+		if piece.IsDiagonalAttacker() && !found(piece, from) {
+			return
+		}
+	}
+	for _, vec := range straightDirectionArray {
+		piece, from := board.FindInDirection(vec, &pos)
+		if piece.IsStraightLongAttacker() && !found(piece, from) {
+			return
+		}
+	}
+}
+
+// Positions of every piece of either colour attacking the square, pins are
+// ignored and pieces behind other attackers are not included
+func (board *BoardState) Attackers(pos Position) []Position {
+	ret := make([]Position, 0)
+	board.forEachAttacker(pos, func(_ Piece, from Position) bool {
+		ret = append(ret, from)
+		return true
+	})
+	return ret
+}
+
+func (board *BoardState) IsSquareAttacked(pos Position, byColour Colour) bool {
+	attacked := false
+	board.forEachAttacker(pos, func(piece Piece, _ Position) bool {
+		attacked = piece.Colour() == byColour
+		return !attacked
+	})
+	return attacked
+}
+
+// false when the colour has no king, e.g. in a hand built position
+func (board *BoardState) kingPosition(colour Colour) (Position, bool) {
+	for index, piece := range board.State {
+		if piece.Is(King) && piece.Colour() == colour {
+			return IndexToPosition(index), true
+		}
+	}
+	return Position{}, false
+}
+
+// Positions of the opponent's pieces checking the colour's king, unlike
+// CheckState this works for either side whoever is to move
+func (board *BoardState) CheckingPieces(colour Colour) []Position {
+	ret := make([]Position, 0)
+	king, found := board.kingPosition(colour)
+	if !found {
+		return ret
+	}
+
+	board.forEachAttacker(king, func(piece Piece, from Position) bool {
+		if piece.Colour() != colour {
+			ret = append(ret, from)
+		}
+		return true
+	})
+	return ret
+}
+
+func (board *BoardState) InCheck(colour Colour) bool {
+	king, found := board.kingPosition(colour)
+	return found && board.IsSquareAttacked(king, OppositeColour(colour))
+}
+
+// The colour's pieces pinned to their king and the line each is pinned along.
+// Read from the pin flags so it's only up to date after UpdateCheckState
+func (board *BoardState) PinnedPieces(colour Colour) map[Position]PinDirection {
+	ret := make(map[Position]PinDirection)
+	for index, piece := range board.State {
+		if piece.IsPinned() && piece.Colour() == colour {
+			ret[IndexToPosition(index)] = piece.GetPin()
+		}
+	}
+	return ret
+}
@@ -0,0 +1,157 @@
+package board
+
+// How many pieces of each colour attack every square, kept between moves so
+// a move only has to look again at the pieces whose attacks it could have
+// changed rather than every piece on the board. Sliding pieces stop at the
+// first piece in the way, the x-ray through the king to move is added when
+// the attacked flags are set
+type attackMap [2][64]uint8
+
+func colourIndex(colour Colour) int {
+	return int(colour) - 1
+}
+
+// calls attack for every square the piece on pos attacks
+func forEachAttackedSquare(state *[64]Piece, variant Variant, pos Position, attack func(Position)) {
+	piece := state[positionToIndex(pos)]
+	step := func(vec Vector) {
+		if moved, inBounds := pos.AddInBounds(vec); inBounds {
+			attack(moved)
+		}
+	}
+	slide := func(vec Vector) {
+		for start := pos; ; {
+			var inBounds bool
+			start, inBounds = start.AddInBounds(vec)
+			if !inBounds {
+				return
+			}
+			attack(start)
+			if !state[positionToIndex(start)].IsClear() {
+				return
+			}
+		}
+	}
+
+	switch {
+	case piece.IsClear():
+	case piece.Is(Knight):
+		for _, vec := range knightDirectionArray {
+			step(vec)
+		}
+	case piece.Is(Pawn):
+		for _, dir := range variant.Pawns(piece.Colour()).Captures {
+			step(directionToVec(dir))
+		}
+	case piece.Is(King):
+		for _, vec := range nonKnightDirectionArray {
+			step(vec)
+		}
+	default:
+		if piece.IsDiagonalAttacker() {
+			for _, vec := range diagonalDirectionArray {
+				slide(vec)
+			}
+		}
+		if piece.IsStraightLongAttacker() {
+			for _, vec := range straightDirectionArray {
+				slide(vec)
+			}
+		}
+	}
+}
+
+func (attacks *attackMap) add(state *[64]Piece, variant Variant, pos Position) {
+	counts := &attacks[colourIndex(state[positionToIndex(pos)].Colour())]
+	forEachAttackedSquare(state, variant, pos, func(to Position) {
+		counts[positionToIndex(to)]++
+	})
+}
+
+func (attacks *attackMap) remove(state *[64]Piece, variant Variant, pos Position) {
+	counts := &attacks[colourIndex(state[positionToIndex(pos)].Colour())]
+	forEachAttackedSquare(state, variant, pos, func(to Position) {
+		counts[positionToIndex(to)]--
+	})
+}
+
+func newAttackMap(state *[64]Piece, variant Variant) attackMap {
+	var attacks attackMap
+	for index, piece := range state {
+		if !piece.IsClear() {
+			attacks.add(state, variant, IndexToPosition(index))
+		}
+	}
+	return attacks
+}
+
+// Pieces whose attacks depend on the changed squares: the pieces on them
+// and the sliding pieces which can see them. Knights, pawns and kings
+// attack the same squares wherever the other pieces are
+func affectedPieces(state *[64]Piece, changed []Position) [64]bool {
+	var affected [64]bool
+	for _, pos := range changed {
+		if !state[positionToIndex(pos)].IsClear() {
+			affected[positionToIndex(pos)] = true
+		}
+		for dir, vec := range nonKnightDirectionArray {
+			for start := pos; ; {
+				var inBounds bool
+				start, inBounds = start.AddInBounds(vec)
+				if !inBounds {
+					break
+				}
+				piece := state[positionToIndex(start)]
+				if piece.IsClear() {
+					continue
+				}
+				diagonal := Direction(dir) < Up
+				if diagonal && piece.IsDiagonalAttacker() ||
+					!diagonal && piece.IsStraightLongAttacker() {
+					affected[positionToIndex(start)] = true
+				}
+				break
+			}
+		}
+	}
+	return affected
+}
+
+// The squares whose piece or colour differs between the two states
+func changedSquares(before, after *[64]Piece) []Position {
+	changed := make([]Position, 0, 4)
+	for index := range before {
+		if !samePiece(before[index], after[index]) {
+			changed = append(changed, IndexToPosition(index))
+		}
+	}
+	return changed
+}
+
+// Takes the attacks of the affected pieces as they were before the move off
+// and adds them back as they are now
+func (board *BoardState) updateAttackMap(before *[64]Piece) {
+	changed := changedSquares(before, &board.State)
+	if len(changed) == 0 {
+		return
+	}
+	variant := board.Variant()
+
+	affected := affectedPieces(before, changed)
+	for index, isAffected := range affected {
+		if isAffected {
+			board.attacks.remove(before, variant, IndexToPosition(index))
+		}
+	}
+	affected = affectedPieces(&board.State, changed)
+	for index, isAffected := range affected {
+		if isAffected {
+			board.attacks.add(&board.State, variant, IndexToPosition(index))
+		}
+	}
+}
+
+// Number of pieces of the colour attacking the square, as of the last move
+func (board *BoardState) AttackCount(colour Colour, pos Position) int {
+	return int(board.attacks[colourIndex(colour)][positionToIndex(pos)])
+}
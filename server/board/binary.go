@@ -0,0 +1,153 @@
+package board
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Fixed size encoding of a position for storing live games and snapshots,
+// everything else is worked out again by Init when it's decoded
+//
+//	0      format version
+//	1      variant, an index into binaryVariants
+//	2-3    chess960 setup, 0xffff when there isn't one
+//	4-35   a nibble per square, high nibble first, 0 for empty
+//	36-43  bit per square set for pieces which have moved
+//	44-45  move counter
+//	46-47  capture move counter
+//	48     win state
+//	49-50  the last move's from and to squares, 0xff when there isn't one
+//
+// The move history is not kept, only the last move so en passant can still
+// be taken after decoding
+const (
+	binaryVersion    = 1
+	BinaryBoardSize  = 51
+	binaryNoSetup    = 0xffff
+	binaryNoLastMove = 0xff
+)
+
+// the order is part of the format, variants are only ever added to the end
+var binaryVariants = [...]Variant{Diagonal, Standard, Chess960}
+
+func binaryVariantIndex(variant Variant) (int, bool) {
+	for index, other := range binaryVariants {
+		if other.Name() == variant.Name() {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// the inverse of arrIndex
+func pieceFromArrIndex(index int) Piece {
+	if index == 0 {
+		return Clear
+	}
+	index -= 1
+	return newPiece(PieceType(index%6), Colour(index/6+1))
+}
+
+func (board *BoardState) MarshalBinary() ([]byte, error) {
+	variant := board.Variant()
+	variantIndex, found := binaryVariantIndex(variant)
+	if !found {
+		return nil, fmt.Errorf("variant %q can't be encoded", variant.Name())
+	}
+
+	data := make([]byte, BinaryBoardSize)
+	data[0] = binaryVersion
+	data[1] = byte(variantIndex)
+	setup := uint16(binaryNoSetup)
+	if shuffled, ok := variant.(Shuffled); ok && shuffled.Setup() != -1 {
+		setup = uint16(shuffled.Setup())
+	}
+	binary.BigEndian.PutUint16(data[2:4], setup)
+
+	var moved uint64
+	for index, piece := range board.State {
+		nibble := byte(piece.arrIndex())
+		if index%2 == 0 {
+			data[4+index/2] = nibble << 4
+		} else {
+			data[4+index/2] |= nibble
+		}
+		if !piece.IsClear() && piece.IsMoved() {
+			moved |= 1 << index
+		}
+	}
+	binary.BigEndian.PutUint64(data[36:44], moved)
+
+	binary.BigEndian.PutUint16(data[44:46], board.MoveCounter)
+	binary.BigEndian.PutUint16(data[46:48], board.CaptureMoveCounter)
+	data[48] = board.WinState
+
+	data[49], data[50] = binaryNoLastMove, binaryNoLastMove
+	if len(board.MoveHistory) > 0 {
+		last := board.MoveHistory[len(board.MoveHistory)-1]
+		data[49] = byte(positionToIndex(last.From))
+		data[50] = byte(positionToIndex(last.To))
+	}
+	return data, nil
+}
+
+// Replaces the board with the encoded position, the legal moves and piece
+// flags are worked out again
+func (board *BoardState) UnmarshalBinary(data []byte) error {
+	if len(data) != BinaryBoardSize {
+		return fmt.Errorf("encoded board must be %d bytes, got %d", BinaryBoardSize, len(data))
+	}
+	if data[0] != binaryVersion {
+		return fmt.Errorf("unknown encoded board version %d", data[0])
+	}
+	if int(data[1]) >= len(binaryVariants) {
+		return fmt.Errorf("unknown encoded variant %d", data[1])
+	}
+
+	variant := binaryVariants[data[1]]
+	if setup := binary.BigEndian.Uint16(data[2:4]); setup != binaryNoSetup {
+		var err error
+		variant, err = Chess960Setup(int(setup))
+		if err != nil {
+			return err
+		}
+	}
+
+	var state [64]Piece
+	moved := binary.BigEndian.Uint64(data[36:44])
+	for index := range state {
+		nibble := data[4+index/2]
+		if index%2 == 0 {
+			nibble >>= 4
+		}
+		nibble &= 0x0f
+		if int(nibble) >= len(pieceToFenArr) {
+			return fmt.Errorf("invalid piece %d on square %d", nibble, index)
+		}
+		state[index] = pieceFromArrIndex(int(nibble))
+		if moved&(1<<index) != 0 {
+			state[index] = state[index].Moved()
+		}
+	}
+
+	history := make([]MoveRecord, 0, 1)
+	if from, to := data[49], data[50]; from != binaryNoLastMove {
+		if from >= 64 || to >= 64 {
+			return errors.New("invalid encoded last move")
+		}
+		last := Move{From: IndexToPosition(int(from)), To: IndexToPosition(int(to))}
+		history = append(history, MoveRecord{Move: last, Piece: state[to]})
+	}
+
+	*board = BoardState{
+		State:              state,
+		Check:              defaultCheckState(),
+		CaptureMoveCounter: binary.BigEndian.Uint16(data[46:48]),
+		MoveHistory:        history,
+		MoveCounter:        binary.BigEndian.Uint16(data[44:46]),
+		WinState:           data[48],
+		variant:            variant,
+	}
+	return board.Init()
+}
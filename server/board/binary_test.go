@@ -0,0 +1,86 @@
+package board_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"chess/board"
+)
+
+func roundTrip(test *testing.T, boardState *board.BoardState) *board.BoardState {
+	test.Helper()
+	data, err := boardState.MarshalBinary()
+	assertSuccess(test, err)
+	assertNumEq(test, board.BinaryBoardSize, len(data))
+
+	decoded := &board.BoardState{}
+	err = decoded.UnmarshalBinary(data)
+	assertSuccess(test, err)
+	return decoded
+}
+
+func Test_binary(test *testing.T) {
+	test.Run("test round trips random games", func(test *testing.T) {
+		test.Parallel()
+		chess960, err := board.Chess960Setup(518)
+		assertSuccess(test, err)
+		for _, variant := range []board.Variant{board.Diagonal, board.Standard, chess960} {
+			for range 10 {
+				boardState := board.NewVariantBoard(variant)
+				err := boardState.Init()
+				assertSuccess(test, err)
+
+				for boardState.HasWinner() == board.NoWin {
+					decoded := roundTrip(test, boardState)
+					assertStrEquality(test, board.VariantId(variant),
+						board.VariantId(decoded.Variant()))
+					assertStrEquality(test, boardState.Fen(), decoded.Fen())
+					assertNumEq(test, int(boardState.CaptureMoveCounter),
+						int(decoded.CaptureMoveCounter))
+					assertStrEquality(test, board.MoveListToString(boardState.LegalMoves),
+						board.MoveListToString(decoded.LegalMoves))
+					if decoded.State != boardState.State {
+						test.Fatalf("piece flags differ after decoding\n%s", boardState.String())
+					}
+
+					moves := boardState.LegalMoves
+					err := boardState.MakeMove(moves[rand.IntN(len(moves))])
+					assertSuccess(test, err)
+				}
+			}
+		}
+	})
+
+	test.Run("test keeps en passant", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		playStandard(test, boardState, "E2:E4", "A7:A6", "E4:E5", "D7:D5")
+		decoded := roundTrip(test, boardState)
+		if !hasMove(decoded, "E5:D6") {
+			test.Fatal("expected en passant to be legal after decoding")
+		}
+	})
+
+	test.Run("test rejects bad data", func(test *testing.T) {
+		test.Parallel()
+		boardState := board.NewBoard()
+		assertSuccess(test, boardState.Init())
+		data, err := boardState.MarshalBinary()
+		assertSuccess(test, err)
+
+		decoded := &board.BoardState{}
+		assertFailure(test, decoded.UnmarshalBinary(data[:10]))
+
+		bad := append([]byte(nil), data...)
+		bad[0] = 0
+		assertFailure(test, decoded.UnmarshalBinary(bad))
+
+		bad = append([]byte(nil), data...)
+		bad[1] = 100
+		assertFailure(test, decoded.UnmarshalBinary(bad))
+
+		bad = append([]byte(nil), data...)
+		bad[4] = 0xff
+		assertFailure(test, decoded.UnmarshalBinary(bad))
+	})
+}
@@ -1,7 +1,6 @@
 package board
 
 import (
-	"errors"
 	"fmt"
 	"slices"
 	"strconv"
@@ -57,12 +56,13 @@ func (state *CheckState) String() string {
 		CheckToString(state.Check), state.From.String())
 }
 
+// Only the side to move can be in check in the state kept on the board, use
+// BoardState.InCheck to ask about either side
 func (state *CheckState) InCheck(colour Colour) bool {
 	if colour == White {
-		return debug || state.Check == NoCheck || state.Check == BlackCheck
-	} else {
-		return debug || state.Check == NoCheck || state.Check == WhiteCheck
+		return checkIsWhite(state.Check)
 	}
+	return checkIsBlack(state.Check)
 }
 
 func (state *CheckState) Promote(colour Colour) error {
@@ -84,7 +84,8 @@ func (state *CheckState) Promote(colour Colour) error {
 	}
 
 	if state.Check == WhiteDoubleCheck || state.Check > BlackDoubleCheck {
-		return errors.New("a third piece checked an already double checked king")
+		return fmt.Errorf("%w: a third piece checked an already double checked king",
+			ErrInconsistentPosition)
 	}
 
 	return nil
@@ -94,31 +95,68 @@ type BoardState struct {
 	State              [64]Piece
 	Check              CheckState
 	CaptureMoveCounter uint16
-	MoveHistory        []Move
+	MoveHistory        []MoveRecord
 	MoveCounter        uint16
 	LegalMoves         []Move
 	WinState           WinState
+
+	variant Variant
+	attacks attackMap
+	// hashes of every position since the last capture or pawn move, the
+	// current position last. Earlier positions can't come up again
+	positions []uint64
+	undoStack []undoRecord
+}
+
+// everything MakeMove overwrites, the whole piece array is kept so the
+// moved, pin and attack flags come back exactly as they were
+type undoRecord struct {
+	state              [64]Piece
+	check              CheckState
+	captureMoveCounter uint16
+	legalMoves         []Move
+	winState           WinState
+	attacks            attackMap
+	positions          []uint64
 }
 
 func NewBoard() *BoardState {
-	state := [64]Piece{
-		WKing, WRook, WBishop, WPawn, WPawn, Clear, Clear, Clear,
-		WRook, WQueen, WKnight, WPawn, Clear, Clear, Clear, Clear,
-		WKnight, WBishop, WPawn, Clear, Clear, Clear, Clear, Clear,
-		WPawn, WPawn, Clear, Clear, Clear, Clear, Clear, BPawn,
-		WPawn, Clear, Clear, Clear, Clear, Clear, BPawn, BPawn,
-		Clear, Clear, Clear, Clear, Clear, BPawn, BBishop, BKnight,
-		Clear, Clear, Clear, Clear, BPawn, BKnight, BQueen, BRook,
-		Clear, Clear, Clear, BPawn, BPawn, BBishop, BRook, BKing,
-	}
+	return NewVariantBoard(DefaultVariant)
+}
+
+func NewVariantBoard(variant Variant) *BoardState {
 	return &BoardState{
-		State:              state,
+		State:              variant.StartingPosition(),
 		Check:              defaultCheckState(),
 		CaptureMoveCounter: 0,
-		MoveHistory:        make([]Move, 0),
+		MoveHistory:        make([]MoveRecord, 0),
 		MoveCounter:        0,
 		LegalMoves:         nil,
+		variant:            variant,
+	}
+}
+
+// boards made without a variant, e.g. as struct literals, use the default
+func (board *BoardState) Variant() Variant {
+	if board.variant == nil {
+		return DefaultVariant
 	}
+	return board.variant
+}
+
+// Deep copy, moves made on the clone don't affect the original
+func (board *BoardState) Clone() *BoardState {
+	clone := *board
+	clone.MoveHistory = slices.Clone(board.MoveHistory)
+	clone.LegalMoves = slices.Clone(board.LegalMoves)
+	clone.positions = slices.Clone(board.positions)
+	clone.undoStack = slices.Clone(board.undoStack)
+	// unmaking on the clone then moving appends to these, they can't share
+	// the original's arrays
+	for i := range clone.undoStack {
+		clone.undoStack[i].positions = slices.Clone(clone.undoStack[i].positions)
+	}
+	return &clone
 }
 
 // loop
@@ -127,34 +165,171 @@ func (board *BoardState) Init() error {
 	if err != nil {
 		return err
 	}
+	// positions before the board was set up aren't known
+	if len(board.positions) == 0 {
+		board.positions = []uint64{board.Hash()}
+	}
 
 	return board.UpdateLegalMoves()
 }
 
+func (board *BoardState) IsLegal(move Move) bool {
+	return slices.Contains(board.LegalMoves, move)
+}
+
+// The position after the move is made, the receiver is left untouched
+func (board *BoardState) Peek(move Move) (*BoardState, error) {
+	if !board.IsLegal(move) {
+		return nil, board.illegalMoveError(move)
+	}
+
+	next := board.Clone()
+	err := next.MakeMove(move)
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// ErrWrongTurn when the piece on the from square is the other player's,
+// ErrIllegalMove for any other move which isn't legal
+func (board *BoardState) illegalMoveError(move Move) error {
+	if move.From.InBounds() {
+		piece := board.GetSquare(move.From)
+		if !piece.IsClear() && piece.Colour() != board.WhoseMove() {
+			return fmt.Errorf("%w: %s", ErrWrongTurn, move.Serialise())
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrIllegalMove, move.Serialise())
+}
+
 func (board *BoardState) MakeMove(move Move) error {
-	if !slices.Contains(board.LegalMoves, move) {
-		return errors.New("move is not in legal moves")
+	if !board.IsLegal(move) {
+		return board.illegalMoveError(move)
 	}
 
-	captured, err := board.Move(move.From, move.To)
+	record := undoRecord{
+		state:              board.State,
+		check:              board.Check,
+		captureMoveCounter: board.CaptureMoveCounter,
+		legalMoves:         board.LegalMoves,
+		winState:           board.WinState,
+		attacks:            board.attacks,
+		positions:          board.positions,
+	}
+
+	moveRecord := board.recordMove(move)
+
+	var captured bool
+	var err error
+	if specialMover, ok := board.Variant().(SpecialMover); ok {
+		captured, err = specialMover.MakeMove(board, move)
+	} else {
+		captured, err = board.Move(move.From, move.To)
+	}
 	if err != nil {
 		return err
 	}
+	if move.Promotion != NoPromotion {
+		promoted := promotionToPieceType[move.Promotion]
+		board.SetSquare(move.To, newPiece(promoted, board.WhoseMove()).Moved())
+	}
+
+	board.MoveHistory = append(board.MoveHistory, moveRecord)
+	board.undoStack = append(board.undoStack, record)
+	// attacked squares are worked out for the side which is now to move
+	// so the counter has to be updated first
+	board.MoveCounter += 1
 
-	err = board.UpdateBoardState()
+	board.updateAttackMap(&record.state)
+	err = board.updatePieceStates()
 	if err != nil {
 		return err
 	}
 
-	board.MoveHistory = append(board.MoveHistory, move)
-	board.MoveCounter += 1
 	if captured {
 		board.CaptureMoveCounter = 0
 	} else {
 		board.CaptureMoveCounter += 1
 	}
+	// a new slice as the undo stack still holds the old one
+	if captured || moveRecord.Piece.Is(Pawn) {
+		board.positions = []uint64{board.Hash()}
+	} else {
+		board.positions = append(board.positions, board.Hash())
+	}
 
-	return board.UpdateLegalMoves()
+	err = board.UpdateLegalMoves()
+	if err != nil {
+		return err
+	}
+
+	// only the side to move can be in check
+	last := &board.MoveHistory[len(board.MoveHistory)-1]
+	last.Check = board.Check.Check != NoCheck
+	last.Checkmate = last.Check && len(board.LegalMoves) == 0
+	return nil
+}
+
+// What a legal move is about to do, worked out before it's made. Castling
+// is the only way a king moves two squares or onto its own rook and a pawn
+// moving to an empty capture square has to be taking en passant
+func (board *BoardState) recordMove(move Move) MoveRecord {
+	piece := board.GetSquare(move.From).Reset()
+	target := board.GetSquare(move.To).Reset()
+	record := MoveRecord{Move: move, Piece: piece, Kind: NormalMove}
+
+	switch {
+	case piece.Is(King) &&
+		(abs(move.To.X-move.From.X) == 2 ||
+			(target.Is(Rook) && target.Colour() == piece.Colour())):
+		// the kingside is towards the H file
+		record.Kind = QueensideCastle
+		if move.To.X < move.From.X {
+			record.Kind = KingsideCastle
+		}
+		return record
+	case piece.Is(Pawn) && target.IsClear() && board.pawnAttacks(piece, move.From, move.To):
+		record.Kind = EnPassant
+		record.Captured = board.GetSquare(Position{X: move.To.X, Y: move.From.Y}).Reset()
+		return record
+	case piece.Is(Pawn):
+		push := directionToVec(board.Variant().Pawns(piece.Colour()).Push)
+		if move.To.Diff(move.From) == push.Mult(2) {
+			record.Kind = DoublePawnPush
+		}
+	}
+
+	record.Captured = target
+	return record
+}
+
+// The moves played so far without the details of what they did
+func (board *BoardState) PlayedMoves() []Move {
+	return RecordMoves(board.MoveHistory)
+}
+
+// Reverts the last move made with MakeMove
+func (board *BoardState) UnmakeMove() error {
+	if len(board.undoStack) == 0 || len(board.MoveHistory) == 0 {
+		return ErrNoMoveToUnmake
+	}
+
+	last := len(board.undoStack) - 1
+	record := board.undoStack[last]
+	board.undoStack = board.undoStack[:last]
+
+	board.State = record.state
+	board.Check = record.check
+	board.CaptureMoveCounter = record.captureMoveCounter
+	board.LegalMoves = record.legalMoves
+	board.WinState = record.winState
+	board.attacks = record.attacks
+	board.positions = record.positions
+	board.MoveHistory = board.MoveHistory[:len(board.MoveHistory)-1]
+	board.MoveCounter -= 1
+
+	return nil
 }
 
 type WinState = uint8
@@ -166,8 +341,59 @@ const (
 
 	Stalemate
 	MoveRuleDraw
+	// decided by a tablebase before the game reached a draw by the rules
+	AdjudicatedDraw
+	// offered by one player and accepted by the other
+	AgreedDraw
+	// a player ran out of time but their opponent couldn't have mated them
+	TimeoutDraw
+	// nobody can ever mate, see IsDeadPosition
+	DeadPosition
 )
 
+// Why a game ended. Unlike WinState this tells a checkmate apart from a
+// player losing on time or leaving, it's stored with each finished game
+type Termination string
+
+const (
+	TerminationNone         Termination = ""
+	TerminationCheckmate    Termination = "checkmate"
+	TerminationStalemate    Termination = "stalemate"
+	TerminationMoveRule     Termination = "move rule"
+	TerminationDeadPosition Termination = "dead position"
+	TerminationAdjudicated  Termination = "adjudication"
+	TerminationAgreement    Termination = "agreement"
+	TerminationTimeForfeit  Termination = "time forfeit"
+	// flagged against a lone king or similar, scored as a draw
+	TerminationTimeoutDraw Termination = "timeout vs insufficient material"
+	TerminationAbandonment Termination = "abandonment"
+	TerminationResignation Termination = "resignation"
+	// moving out of turn forfeits the game
+	TerminationRulesInfraction Termination = "rules infraction"
+)
+
+// The termination for games which ended on the board
+func BoardTermination(winState WinState) Termination {
+	switch winState {
+	case WhiteWin, BlackWin:
+		return TerminationCheckmate
+	case Stalemate:
+		return TerminationStalemate
+	case MoveRuleDraw:
+		return TerminationMoveRule
+	case AdjudicatedDraw:
+		return TerminationAdjudicated
+	case AgreedDraw:
+		return TerminationAgreement
+	case TimeoutDraw:
+		return TerminationTimeoutDraw
+	case DeadPosition:
+		return TerminationDeadPosition
+	default:
+		return TerminationNone
+	}
+}
+
 func ColourToWinState(colour Colour) WinState {
 	return WinState(colour)
 }
@@ -182,6 +408,14 @@ func WinStateToString(winState WinState) string {
 		return "Stalemate"
 	case MoveRuleDraw:
 		return "Move rule draw"
+	case AdjudicatedDraw:
+		return "Adjudicated draw"
+	case AgreedDraw:
+		return "Agreed draw"
+	case TimeoutDraw:
+		return "Timeout draw"
+	case DeadPosition:
+		return "Dead position"
 	default:
 		return "No win"
 	}
@@ -197,13 +431,19 @@ func (board *BoardState) HasWinner() WinState {
 	return winState
 }
 func (board *BoardState) HasWinnerImpl() WinState {
-	if board.CaptureMoveCounter == 50 {
+	return board.Variant().Winner(board)
+}
+
+// Checkmate, stalemate, a dead position or a draw once captureMoveLimit moves
+// have been played without a capture, the win conditions most variants share
+func (board *BoardState) mateOrDraw(captureMoveLimit uint16) WinState {
+	if board.CaptureMoveCounter == captureMoveLimit {
 		return MoveRuleDraw
 	}
 
 	if len(board.LegalMoves) == 0 {
 		whoseMove := board.WhoseMove()
-		if board.Check.InCheck(whoseMove) {
+		if board.InCheck(whoseMove) {
 			if whoseMove == Black {
 				return WhiteWin
 			} else {
@@ -214,9 +454,46 @@ func (board *BoardState) HasWinnerImpl() WinState {
 		}
 	}
 
+	if board.IsDeadPosition() {
+		return DeadPosition
+	}
+
 	return NoWin
 }
 
+// Number of pieces on the board including kings
+func (board *BoardState) PieceCount() int {
+	count := 0
+	for _, piece := range board.State {
+		if !piece.IsClear() {
+			count++
+		}
+	}
+	return count
+}
+
+// Whether colour could ever checkmate given help from their opponent. A lone
+// king never can, a single bishop or knight only when the other side has
+// pieces of its own for their king to be boxed in by
+func (board *BoardState) HasMatingMaterial(colour Colour) bool {
+	minorPieces := 0
+	opponentPieces := 0
+	for _, piece := range board.State {
+		if piece.IsClear() || piece.Is(King) {
+			continue
+		}
+		if piece.Colour() != colour {
+			opponentPieces += 1
+			continue
+		}
+		if !piece.Is(Bishop) && !piece.Is(Knight) {
+			return true
+		}
+		minorPieces += 1
+	}
+	return minorPieces > 1 || (minorPieces == 1 && opponentPieces > 0)
+}
+
 // utility
 func (board *BoardState) WhoseMove() Colour {
 	if board.MoveCounter%2 == 0 {
@@ -225,13 +502,26 @@ func (board *BoardState) WhoseMove() Colour {
 	return Black
 }
 
+// The board as white sees it
 func (board *BoardState) String() string {
-	str := " H G F E D C B A  \n\n "
-	for i, piece := range board.State {
-		str += piece.String() + " "
-		if i%8 == 7 {
-			str += fmt.Sprintf(" %d\n ", i/8+1)
+	return board.StringFrom(WhiteOrientation)
+}
+
+// The board drawn from one player's side, files along the top and ranks
+// down the right
+func (board *BoardState) StringFrom(orientation Orientation) string {
+	str := " "
+	for col := range 8 {
+		pos := orientation.Square(col, 0)
+		str += string(pos.FileByte()) + " "
+	}
+	str += " \n\n "
+	for row := range 8 {
+		for col := range 8 {
+			str += board.GetSquare(orientation.Square(col, row)).String() + " "
 		}
+		pos := orientation.Square(0, row)
+		str += " " + string(pos.RankByte()) + "\n "
 	}
 	str += "\n"
 	return str
@@ -387,11 +677,15 @@ func getPiece(char rune) (Piece, error) {
 	case '/':
 		return Clear, nil
 	default:
-		return 0, errors.New("the charcter is an invalid")
+		return 0, fmt.Errorf("%w: invalid character %q", ErrInvalidFen, char)
 	}
 }
 
 func ParseFen(fen string) (*BoardState, error) {
+	return ParseVariantFen(fen, DefaultVariant)
+}
+
+func ParseVariantFen(fen string, variant Variant) (*BoardState, error) {
 	state := [64]Piece{}
 	stateIndex := 0
 	rowIndex := 0
@@ -402,8 +696,7 @@ func ParseFen(fen string) (*BoardState, error) {
 	for strIndex, char := range fen {
 		if stateIndex == 64 {
 			if char != ' ' {
-				errorStr := fmt.Sprintf("space not found at end of pieces")
-				return nil, errors.New(errorStr)
+				return nil, fmt.Errorf("%w: space not found at end of pieces", ErrInvalidFen)
 			}
 
 			boardStrLen = strIndex + 1
@@ -420,49 +713,31 @@ func ParseFen(fen string) (*BoardState, error) {
 
 		piece, err := getPiece(char)
 		if err != nil {
-			return nil, fmt.Errorf("unexpected character found: %s ", string(char))
+			return nil, fmt.Errorf("%w: unexpected character found: %s", ErrInvalidFen, string(char))
 		}
 
 		if piece.IsPieceAndColour(Clear) {
 			if stateIndex%8 != 0 || rowIndex != 8 {
-				errorStr := fmt.Sprintf("/ found in wrong place stateIndex: %d, rowIndex: %d",
-					stateIndex, rowIndex)
-				return nil, errors.New(errorStr)
+				return nil, fmt.Errorf("%w: / found in wrong place stateIndex: %d, rowIndex: %d",
+					ErrInvalidFen, stateIndex, rowIndex)
 			}
 
 			rowIndex = 0
 			continue
 		} else {
 			if piece.IsPieceAndColour(WKing) && wKing {
-				return nil, errors.New("multiple white kings")
+				return nil, fmt.Errorf("%w: multiple white kings", ErrInvalidFen)
 			}
 			wKing = wKing || piece.IsPieceAndColour(WKing)
 
 			if piece.IsPieceAndColour(BKing) && bKing {
-				return nil, errors.New("multiple black kings")
+				return nil, fmt.Errorf("%w: multiple black kings", ErrInvalidFen)
 			}
 			bKing = bKing || piece.IsPieceAndColour(BKing)
 
-			if piece.IsPieceAndColour(WPawn) {
-				if !(stateIndex == 3 ||
-					stateIndex == 4 ||
-					stateIndex == 11 ||
-					stateIndex == 18 ||
-					stateIndex == 24 ||
-					stateIndex == 25 ||
-					stateIndex == 32) {
-					piece |= MovedMask
-				}
-			} else if piece.IsPieceAndColour(BPawn) {
-				if !(stateIndex == 31 ||
-					stateIndex == 38 ||
-					stateIndex == 39 ||
-					stateIndex == 45 ||
-					stateIndex == 52 ||
-					stateIndex == 59 ||
-					stateIndex == 60) {
-					piece |= MovedMask
-				}
+			if piece.Is(Pawn) &&
+				!variant.IsPawnStart(piece.Colour(), IndexToPosition(stateIndex)) {
+				piece |= MovedMask
 			}
 			state[stateIndex] = piece
 		}
@@ -471,13 +746,12 @@ func ParseFen(fen string) (*BoardState, error) {
 		rowIndex += 1
 
 		if rowIndex > 8 {
-			errorStr := fmt.Sprintf("row index too large: %d", rowIndex)
-			return nil, errors.New(errorStr)
+			return nil, fmt.Errorf("%w: row index too large: %d", ErrInvalidFen, rowIndex)
 		}
 	}
 
 	if !wKing || !bKing {
-		return nil, errors.New("need both black and white king on the board")
+		return nil, fmt.Errorf("%w: need both black and white king on the board", ErrInvalidFen)
 	}
 
 	var colour Colour
@@ -486,22 +760,20 @@ func ParseFen(fen string) (*BoardState, error) {
 	} else if fen[boardStrLen] == 'b' {
 		colour = Black
 	} else {
-		errorStr := fmt.Sprintf("unexpected character, should be w or b: %s",
-			string(fen[boardStrLen]))
-		return nil, errors.New(errorStr)
+		return nil, fmt.Errorf("%w: unexpected character, should be w or b: %s",
+			ErrInvalidFen, string(fen[boardStrLen]))
 	}
 
 	boardStrLen += 1
 	if fen[boardStrLen] != ' ' {
-		errorStr := fmt.Sprintf("unexpected character, should be space: %s",
-			string(fen[boardStrLen]))
-		return nil, errors.New(errorStr)
+		return nil, fmt.Errorf("%w: unexpected character, should be space: %s",
+			ErrInvalidFen, string(fen[boardStrLen]))
 	}
 
 	boardStrLen += 1
 	moveCounter, err := strconv.ParseUint(fen[boardStrLen:], 10, 0)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFen, err)
 	}
 
 	moveCounter = moveCounter * 2
@@ -509,7 +781,12 @@ func ParseFen(fen string) (*BoardState, error) {
 		moveCounter += 1
 	}
 
-	return &BoardState{State: state, Check: CheckState{}, MoveCounter: uint16(moveCounter)}, nil
+	return &BoardState{
+		State:       state,
+		Check:       CheckState{},
+		MoveCounter: uint16(moveCounter),
+		variant:     variant,
+	}, nil
 }
 
 // check stuff
@@ -527,18 +804,16 @@ func (board *BoardState) GetKingPositions() (wKing, bKing *Position, err error)
 	}
 
 	if wKing == nil && bKing == nil {
-		return nil, nil, errors.New("neither king was found")
+		return nil, nil, fmt.Errorf("%w: neither king was found", ErrInconsistentPosition)
 	} else if wKing == nil {
-		return nil, nil, errors.New("no white king was found")
+		return nil, nil, fmt.Errorf("%w: no white king was found", ErrInconsistentPosition)
 	} else if bKing == nil {
-		return nil, nil, errors.New("no black king was found")
+		return nil, nil, fmt.Errorf("%w: no black king was found", ErrInconsistentPosition)
 	}
 
 	return wKing, bKing, nil
 }
 
-const debug = true
-
 func (board *BoardState) CheckKnightChecks(
 	wKing, bKing *Position,
 ) (*CheckState, error) {
@@ -550,25 +825,27 @@ func (board *BoardState) CheckKnightChecks(
 		pos, inBounds := wKing.AddInBounds(vec)
 		if inBounds && board.GetSquare(pos).IsPieceAndColour(BKnight) {
 			if check != NoCheck {
-				err := fmt.Errorf("weird board state reached, check: %s\n\n%s",
-					CheckToString(check), board.String())
+				err := fmt.Errorf("%w: weird board state reached, check: %s\n\n%s",
+					ErrInconsistentPosition, CheckToString(check), board.String())
 				return nil, err
 			}
 
 			check = WhiteCheck
 			from = pos
+			board.SetSquare(pos, board.GetSquare(pos).CheckSquare())
 		}
 
 		pos, inBounds = bKing.AddInBounds(vec)
 		if inBounds && board.GetSquare(pos).IsPieceAndColour(WKnight) {
 			if check != NoCheck {
-				err := fmt.Errorf("weird board state reached, check: %s\n\n%s",
-					CheckToString(check), board.String())
+				err := fmt.Errorf("%w: weird board state reached, check: %s\n\n%s",
+					ErrInconsistentPosition, CheckToString(check), board.String())
 				return nil, err
 			}
 
 			check = BlackCheck
 			from = pos
+			board.SetSquare(pos, board.GetSquare(pos).CheckSquare())
 		}
 	}
 
@@ -584,14 +861,26 @@ func (board *BoardState) FindInDirection(vec Vector, pos *Position) (Piece, Posi
 			return Clear, posCopy
 		}
 
+		// empty squares can still carry attack and check flags
 		piece := board.GetSquare(posCopy)
-		if piece != Clear {
+		if !piece.IsClear() {
 			return piece, posCopy
 		}
 	}
 }
 
-func AmBeingAttacked(
+// whether a pawn on from attacks the square
+func (board *BoardState) pawnAttacks(pawn Piece, from, to Position) bool {
+	diff := to.Diff(from)
+	for _, dir := range board.Variant().Pawns(pawn.Colour()).Captures {
+		if diff == directionToVec(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (board *BoardState) AmBeingAttacked(
 	king *Position, piece Piece, colour Colour,
 	piecePosition Position, diagonal bool,
 ) bool {
@@ -602,11 +891,8 @@ func AmBeingAttacked(
 		return false
 	}
 
-	diff := king.Diff(piecePosition)
-	if piece.IsPieceAndColour(BPawn) {
-		return colour == White && (diff == UpVec || diff == LeftVec)
-	} else if piece.IsPieceAndColour(WPawn) {
-		return colour == Black && (diff == DownVec || diff == RightVec)
+	if piece.Is(Pawn) {
+		return board.pawnAttacks(piece, piecePosition, *king)
 	} else if diagonal {
 		return piece.IsDiagonalAttacker()
 	} else {
@@ -658,11 +944,11 @@ func (board *BoardState) otherPieceChecksImpl(
 		return check, nil
 	}
 
-	if AmBeingAttacked(king, piece, colour, piecePosition, diagonal) {
+	if board.AmBeingAttacked(king, piece, colour, piecePosition, diagonal) {
 		if colour == White && checkIsBlack(check.Check) ||
 			colour == Black && checkIsWhite(check.Check) {
-			return nil,
-				errors.New("both white and black kings are being attacked simultaneously")
+			return nil, fmt.Errorf("%w: both white and black kings are being attacked simultaneously",
+				ErrInconsistentPosition)
 		}
 
 		err := check.Promote(colour)
@@ -678,7 +964,7 @@ func (board *BoardState) otherPieceChecksImpl(
 		board.addCheckSquares(king, &piecePosition, dir)
 	} else if piece.Colour() == colour {
 		pinningPiece, pinningPiecePosition := board.FindInDirection(vec, &piecePosition)
-		if AmBeingAttacked(
+		if board.AmBeingAttacked(
 			king,
 			pinningPiece,
 			colour,
@@ -734,29 +1020,18 @@ func (board *BoardState) UpdateCheckState() error {
 	return nil
 }
 
-func (board *BoardState) attackSquare(start, vec Position) {
-	moved, bounds := start.AddInBounds(vec)
-	if !bounds {
-		return
-	}
-	piece := board.GetSquare(moved)
-	board.SetSquare(moved, piece.Attacked())
-}
-
-func (board *BoardState) attackDirection(otherKing Piece, start, vec Position) {
-	for {
+// marks the squares behind the king to move which a slider checking it
+// would reach if the king stepped away along the line
+func (board *BoardState) attackBehindKing(king Position, vec Vector) {
+	for start := king; ; {
 		var inBounds bool
 		start, inBounds = start.AddInBounds(vec)
 		if !inBounds {
 			return
 		}
-
 		piece := board.GetSquare(start)
 		board.SetSquare(start, piece.Attacked())
-
-		if piece.IsClear() || piece.IsPieceAndColour(otherKing) {
-			continue
-		} else {
+		if !piece.IsClear() {
 			return
 		}
 	}
@@ -768,65 +1043,41 @@ func (board *BoardState) ResetPieceStates() {
 	}
 }
 
+// Sets the attacked flags from the attack map for the side not to move
 func (board *BoardState) UpdateAttackedSquares() {
 	whoseMove := board.WhoseMove()
-
-	var king Piece
-	if whoseMove == White {
-		king = WKing
-	} else {
-		king = BKing
-	}
-
+	attackers := &board.attacks[colourIndex(OppositeColour(whoseMove))]
 	for index, piece := range board.State {
-		pos := IndexToPosition(index)
-		if piece.IsClear() || piece.Colour() == whoseMove {
-			continue
-		}
-
-		if piece.Is(Knight) {
-			for _, move := range knightDirectionArray {
-				board.attackSquare(pos, move)
-			}
-			continue
-		}
-
-		if piece.IsPieceAndColour(WPawn) {
-			board.attackSquare(pos, DownVec)
-			board.attackSquare(pos, RightVec)
-			continue
-		}
-		if piece.IsPieceAndColour(BPawn) {
-			board.attackSquare(pos, UpVec)
-			board.attackSquare(pos, LeftVec)
-			continue
+		if attackers[index] > 0 {
+			board.State[index] = piece.Attacked()
 		}
+	}
 
-		if piece.Is(King) {
-			for _, move := range diagonalDirectionArray {
-				board.attackSquare(pos, move)
-			}
-			for _, move := range straightDirectionArray {
-				board.attackSquare(pos, move)
-			}
+	king, found := board.kingPosition(whoseMove)
+	if !found {
+		return
+	}
+	for dir, vec := range nonKnightDirectionArray {
+		piece, _ := board.FindInDirection(vec, &king)
+		if piece.IsClear() || piece.Colour() == whoseMove {
 			continue
 		}
-
-		if piece.IsDiagonalAttacker() {
-			for _, move := range diagonalDirectionArray {
-				board.attackDirection(king, pos, move)
-			}
-		}
-
-		if piece.IsStraightLongAttacker() {
-			for _, move := range straightDirectionArray {
-				board.attackDirection(king, pos, move)
-			}
+		diagonal := Direction(dir) < Up
+		if diagonal && piece.IsDiagonalAttacker() ||
+			!diagonal && piece.IsStraightLongAttacker() {
+			board.attackBehindKing(king, vec.Mult(-1))
 		}
 	}
 }
 
+// Works out the attack map from scratch, after a move only the changes are
+// applied to it
 func (board *BoardState) UpdateBoardState() error {
+	board.attacks = newAttackMap(&board.State, board.Variant())
+	return board.updatePieceStates()
+}
+
+func (board *BoardState) updatePieceStates() error {
 	board.ResetPieceStates()
 
 	err := board.UpdateCheckState()
@@ -841,10 +1092,10 @@ func (board *BoardState) UpdateBoardState() error {
 
 func (board *BoardState) Move(start, end Position) (bool, error) {
 	if start == end {
-		return false, errors.New("positions are same")
+		return false, fmt.Errorf("%w: positions are same", ErrInvalidMove)
 	}
 	if start.X >= 8 || end.Y >= 8 {
-		return false, errors.New("move out of bounds")
+		return false, fmt.Errorf("%w: move out of bounds", ErrInvalidSquare)
 	}
 
 	endPiece := board.GetSquare(end)
@@ -870,10 +1121,10 @@ func (board *BoardState) MoveStr(start, end string) error {
 	return err
 }
 
+// LegalMoves is kept by the undo stack so each move gets a new slice
 func (board *BoardState) UpdateLegalMoves() error {
 	board.LegalMoves = nil
-	moveMaker := newLegalMoveCreator(board)
-	legalMoves, err := moveMaker.getLegalMoves()
+	legalMoves, err := board.generateLegalMoves(nil)
 	if err != nil {
 		return err
 	}
@@ -881,6 +1132,57 @@ func (board *BoardState) UpdateLegalMoves() error {
 	return nil
 }
 
+// The legal moves in the current position written into dst, which is
+// overwritten from its start and grown if it's too small. Passing the same
+// buffer back in each time avoids allocating, unlike LegalMoves the result
+// is only valid until the buffer is next used. Positions which fail to Init
+// have no moves
+func (board *BoardState) GenerateLegalMoves(dst []Move) []Move {
+	legalMoves, err := board.generateLegalMoves(dst)
+	if err != nil {
+		return dst[:0]
+	}
+	return legalMoves
+}
+
+func (board *BoardState) generateLegalMoves(dst []Move) ([]Move, error) {
+	moveMaker := newLegalMoveCreator(board, dst)
+	legalMoves, err := moveMaker.getLegalMoves()
+	if err != nil {
+		return nil, err
+	}
+	if specialMover, ok := board.Variant().(SpecialMover); ok {
+		legalMoves = append(legalMoves, specialMover.SpecialMoves(board)...)
+	}
+	return board.expandPromotions(legalMoves), nil
+}
+
+var promotionToPieceType = [...]PieceType{
+	QueenPromotion:  Queen,
+	RookPromotion:   Rook,
+	BishopPromotion: Bishop,
+	KnightPromotion: Knight,
+}
+
+// pawn moves onto a promotion square become one move per promotion piece
+func (board *BoardState) expandPromotions(moves []Move) []Move {
+	variant := board.Variant()
+	colour := board.WhoseMove()
+	for i, count := 0, len(moves); i < count; i++ {
+		move := moves[i]
+		if !board.GetSquare(move.From).Is(Pawn) ||
+			!variant.IsPromotionSquare(colour, move.To) {
+			continue
+		}
+		moves[i].Promotion = QueenPromotion
+		for _, promotion := range [...]Promotion{RookPromotion, BishopPromotion, KnightPromotion} {
+			move.Promotion = promotion
+			moves = append(moves, move)
+		}
+	}
+	return moves
+}
+
 type ColourLessCheck = uint8
 
 const (
@@ -1,8 +1,13 @@
 package board_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"math/rand/v2"
+	"slices"
+	"strings"
 	"testing"
 
 	"chess/board"
@@ -106,24 +111,10 @@ func Test_check(test *testing.T) {
 			check := &boardState.Check
 			assertCheckEquality(test, endingCheck, check)
 
-			for i := range 64 {
-				pos := board.IndexToPosition(i)
-				expectedPin, found := pinnedPieces[pos]
-				piece := boardState.GetSquare(pos)
-				receivedPin := piece.GetPin()
-				if found {
-					if receivedPin != expectedPin {
-						test.Errorf(
-							"The %s at %s was expected to be pinned %s but was pinned %s",
-							piece.StringDebug(), pos.String(),
-							board.PinToString(expectedPin), board.PinToString(receivedPin))
-					}
-				} else {
-					if piece.IsPinned() {
-						test.Errorf("The %s at %s was expected to not be pinned but was not",
-							piece.StringDebug(), pos.String())
-					}
-				}
+			received := boardState.PinnedPieces(board.White)
+			maps.Copy(received, boardState.PinnedPieces(board.Black))
+			if !maps.Equal(received, pinnedPieces) {
+				test.Errorf("expected pins %v\nreceived: %v", pinnedPieces, received)
 			}
 		}
 
@@ -267,7 +258,7 @@ func helperFromBoardInner(
 			board.MoveListToString(moveSet.DiffArr(&expectedMoveSet)),
 		)
 	}
-	for move := range expectedMoveSet.Iter() {
+	for move := range expectedMoveSet.Keys() {
 		found := moveSet.Has(move)
 		if !found {
 			test.Log(boardState.String())
@@ -316,6 +307,22 @@ then move list: %s
 %s
 %s`
 
+func Test_pinned_pieces(test *testing.T) {
+	test.Parallel()
+	// the black queen is pinned to its king by the white bishop
+	boardState, err := board.ParseFen("k7/1PP5/8/8/4b3/8/6Q1/7K w 0")
+	assertSuccess(test, err)
+	err = boardState.Init()
+	assertSuccess(test, err)
+
+	assertNumEq(test, 0, len(boardState.PinnedPieces(board.White)))
+	pins := boardState.PinnedPieces(board.Black)
+	assertNumEq(test, 1, len(pins))
+	if pins[board.Position{X: 6, Y: 6}] != board.DownRightPin {
+		test.Fatalf("expected the queen to be pinned, received %v", pins)
+	}
+}
+
 func Test_legal_moves(test *testing.T) {
 	test.Run("test legal moves", func(test *testing.T) {
 		test.Parallel()
@@ -388,7 +395,7 @@ func Test_legal_moves(test *testing.T) {
 		legalMovesHelper(
 			test,
 			"1rb5/5N2/1Q1P2p1/ppk4P/p2R1n2/1P5n/2B1PN1R/4P2K w 92",
-			[]string{"F4:G3"},
+			[]string{"F4:G3", "G1:G3"},
 		)
 
 		//     . ♔ .          1
@@ -476,7 +483,7 @@ func Test_legal_moves(test *testing.T) {
 				"A5:B4",
 				"A5:C3",
 				"A4:B3",
-				"A4:C2",
+				// A4:C2 is blocked by the pawn which just moved there
 				// knight moves
 				"C7:E6",
 				"C7:D5",
@@ -575,6 +582,616 @@ func Test_legal_moves(test *testing.T) {
 	})
 }
 
+func Test_generate_legal_moves(test *testing.T) {
+	test.Run("test matches legal moves", func(test *testing.T) {
+		test.Parallel()
+		buffer := make([]board.Move, 0)
+		for _, variant := range []board.Variant{board.Diagonal, board.Standard} {
+			for range 20 {
+				boardState := board.NewVariantBoard(variant)
+				err := boardState.Init()
+				assertSuccess(test, err)
+
+				for boardState.HasWinner() == board.NoWin {
+					buffer = boardState.GenerateLegalMoves(buffer)
+					assertStrEquality(test, board.MoveListToString(boardState.LegalMoves),
+						board.MoveListToString(buffer))
+
+					moves := boardState.LegalMoves
+					err := boardState.MakeMove(moves[rand.IntN(len(moves))])
+					assertSuccess(test, err)
+				}
+			}
+		}
+	})
+
+	// not parallel, other tests would show up in the allocation count
+	test.Run("test reusing the buffer doesn't allocate", func(test *testing.T) {
+		boardState := board.NewBoard()
+		err := boardState.Init()
+		assertSuccess(test, err)
+
+		buffer := make([]board.Move, 0, 64)
+		allocs := testing.AllocsPerRun(100, func() {
+			buffer = boardState.GenerateLegalMoves(buffer)
+		})
+		assertNumEq(test, 0, int(allocs))
+		assertNumEq(test, len(boardState.LegalMoves), len(buffer))
+	})
+}
+
+func Test_unmake_move(test *testing.T) {
+	test.Run("test unmake restores random games", func(test *testing.T) {
+		test.Parallel()
+		for range 50 {
+			boardState := board.NewBoard()
+			err := boardState.Init()
+			assertSuccess(test, err)
+
+			type snapshot struct {
+				fen        string
+				state      [64]board.Piece
+				check      board.CheckState
+				counter    uint16
+				legalMoves string
+			}
+			snapshots := make([]snapshot, 0)
+			for boardState.HasWinner() == board.NoWin {
+				snapshots = append(snapshots, snapshot{
+					boardState.Fen(),
+					boardState.State,
+					boardState.Check,
+					boardState.CaptureMoveCounter,
+					board.MoveListToString(boardState.LegalMoves),
+				})
+				moves := boardState.LegalMoves
+				err := boardState.MakeMove(moves[rand.IntN(len(moves))])
+				assertSuccess(test, err)
+			}
+
+			for i := len(snapshots) - 1; i >= 0; i-- {
+				err := boardState.UnmakeMove()
+				assertSuccess(test, err)
+
+				expected := snapshots[i]
+				assertStrEquality(test, expected.fen, boardState.Fen())
+				assertStrEquality(test, expected.legalMoves,
+					board.MoveListToString(boardState.LegalMoves))
+				assertCheckEquality(test, &expected.check, &boardState.Check)
+				assertNumEq(test, int(expected.counter), int(boardState.CaptureMoveCounter))
+				if expected.state != boardState.State {
+					test.Fatalf("piece flags were not restored\nexpected:\n%v\nreceived:\n%v",
+						expected.state, boardState.State)
+				}
+			}
+
+			assertNumEq(test, 0, len(boardState.MoveHistory))
+			err = boardState.UnmakeMove()
+			assertFailure(test, err)
+		}
+	})
+}
+
+func Test_clone(test *testing.T) {
+	test.Run("test moves on a clone leave the original untouched", func(test *testing.T) {
+		test.Parallel()
+		original := board.NewBoard()
+		err := original.Init()
+		assertSuccess(test, err)
+		err = original.MakeMove(original.LegalMoves[0])
+		assertSuccess(test, err)
+
+		fen := original.Fen()
+		legalMoves := board.MoveListToString(original.LegalMoves)
+		history := board.MoveListToString(original.PlayedMoves())
+
+		clone := original.Clone()
+		assertStrEquality(test, fen, clone.Fen())
+		for range 4 {
+			err = clone.MakeMove(clone.LegalMoves[len(clone.LegalMoves)-1])
+			assertSuccess(test, err)
+		}
+		clone.LegalMoves[0] = board.Move{}
+
+		assertStrEquality(test, fen, original.Fen())
+		assertStrEquality(test, legalMoves, board.MoveListToString(original.LegalMoves))
+		assertStrEquality(test, history, board.MoveListToString(original.PlayedMoves()))
+
+		// the undo stack is copied too
+		for range 4 {
+			err = clone.UnmakeMove()
+			assertSuccess(test, err)
+		}
+		assertStrEquality(test, fen, clone.Fen())
+		err = original.UnmakeMove()
+		assertSuccess(test, err)
+		err = original.UnmakeMove()
+		assertFailure(test, err)
+	})
+}
+
+func Test_peek(test *testing.T) {
+	test.Run("test peek and is legal leave the board untouched", func(test *testing.T) {
+		test.Parallel()
+		boardState := board.NewBoard()
+		err := boardState.Init()
+		assertSuccess(test, err)
+		fen := boardState.Fen()
+
+		legal, err := board.DeserialiseMove("D1:C2")
+		assertSuccess(test, err)
+		illegal, err := board.DeserialiseMove("D1:D2")
+		assertSuccess(test, err)
+
+		assertBoolEq(test, true, boardState.IsLegal(legal))
+		assertBoolEq(test, false, boardState.IsLegal(illegal))
+
+		next, err := boardState.Peek(legal)
+		assertSuccess(test, err)
+		assertNumEq(test, 1, int(next.MoveCounter))
+		assertStrEquality(test, fen, boardState.Fen())
+		assertNumEq(test, 0, int(boardState.MoveCounter))
+
+		_, err = boardState.Peek(illegal)
+		assertFailure(test, err)
+	})
+}
+
+func Test_attackers(test *testing.T) {
+	test.Run("test attackers of a square", func(test *testing.T) {
+		test.Parallel()
+		boardState, err := board.ParseFen("k7/8/8/3r4/8/1N6/2p5/7K w 0")
+		assertSuccess(test, err)
+		err = boardState.Init()
+		assertSuccess(test, err)
+
+		pos, err := board.StringToPosition("E7")
+		assertSuccess(test, err)
+		attackers := boardState.Attackers(pos)
+		strs := make([]string, len(attackers))
+		for i, from := range attackers {
+			strs[i] = from.CoordsString()
+		}
+		slices.Sort(strs)
+		assertStrEquality(test, "E4 F7 G6", strings.Join(strs, " "))
+		assertBoolEq(test, true, boardState.IsSquareAttacked(pos, board.White))
+		assertBoolEq(test, true, boardState.IsSquareAttacked(pos, board.Black))
+	})
+
+	test.Run("test agrees with attacked flags", func(test *testing.T) {
+		test.Parallel()
+		for range 20 {
+			boardState := board.NewBoard()
+			err := boardState.Init()
+			assertSuccess(test, err)
+
+			for boardState.HasWinner() == board.NoWin {
+				// when in check the flags x-ray through the king
+				if boardState.Check.Check == board.NoCheck {
+					attacker := board.OppositeColour(boardState.WhoseMove())
+					for i, piece := range boardState.State {
+						pos := board.IndexToPosition(i)
+						if piece.IsAttacked() != boardState.IsSquareAttacked(pos, attacker) {
+							test.Fatalf("attack mismatch at %s\n%s",
+								pos.CoordsString(), boardState.String())
+						}
+					}
+				}
+				moves := boardState.LegalMoves
+				err := boardState.MakeMove(moves[rand.IntN(len(moves))])
+				assertSuccess(test, err)
+			}
+		}
+	})
+
+	test.Run("test incremental attacks match a full update", func(test *testing.T) {
+		test.Parallel()
+		for _, variant := range []board.Variant{board.Diagonal, board.Standard} {
+			for range 20 {
+				boardState := board.NewVariantBoard(variant)
+				err := boardState.Init()
+				assertSuccess(test, err)
+
+				for boardState.HasWinner() == board.NoWin {
+					moves := boardState.LegalMoves
+					err := boardState.MakeMove(moves[rand.IntN(len(moves))])
+					assertSuccess(test, err)
+
+					rebuilt := boardState.Clone()
+					err = rebuilt.UpdateBoardState()
+					assertSuccess(test, err)
+					if rebuilt.State != boardState.State {
+						test.Fatalf("piece flags differ from a full update\n%s", boardState.String())
+					}
+					for i := range boardState.State {
+						pos := board.IndexToPosition(i)
+						for _, colour := range [...]board.Colour{board.White, board.Black} {
+							assertNumEq(test, rebuilt.AttackCount(colour, pos),
+								boardState.AttackCount(colour, pos))
+						}
+					}
+				}
+			}
+		}
+	})
+}
+
+func Test_in_check(test *testing.T) {
+	positionStrs := func(positions []board.Position) string {
+		strs := make([]string, len(positions))
+		for i, pos := range positions {
+			strs[i] = pos.CoordsString()
+		}
+		slices.Sort(strs)
+		return strings.Join(strs, " ")
+	}
+
+	tests := []struct {
+		name          string
+		fen           string
+		whiteCheckers string
+		blackCheckers string
+	}{
+		{"no check", "k7/8/8/8/8/8/8/7K w 10", "", ""},
+		{"rook check", "k4R2/8/8/8/8/8/8/7K w 10", "C1", ""},
+		{"double check", "k4R2/2N5/8/8/8/8/8/7K w 10", "C1 F2", ""},
+		// black is in check with white to move, CheckState can't show this
+		{"side not to move", "k7/8/8/8/8/8/8/6rK w 10", "", "B8"},
+	}
+
+	for _, tt := range tests {
+		test.Run(tt.name, func(test *testing.T) {
+			test.Parallel()
+			boardState, err := board.ParseFen(tt.fen)
+			assertSuccess(test, err)
+
+			assertStrEquality(test, tt.whiteCheckers,
+				positionStrs(boardState.CheckingPieces(board.White)))
+			assertStrEquality(test, tt.blackCheckers,
+				positionStrs(boardState.CheckingPieces(board.Black)))
+			assertBoolEq(test, tt.whiteCheckers != "", boardState.InCheck(board.White))
+			assertBoolEq(test, tt.blackCheckers != "", boardState.InCheck(board.Black))
+		})
+	}
+
+	test.Run("stalemate is not mate", func(test *testing.T) {
+		test.Parallel()
+		boardState, err := board.ParseFen("k7/8/1Q6/8/8/8/8/7K w 10")
+		assertSuccess(test, err)
+		err = boardState.Init()
+		assertSuccess(test, err)
+
+		assertBoolEq(test, false, boardState.InCheck(board.White))
+		assertNumEq(test, 0, len(boardState.LegalMoves))
+		assertStrEquality(test, board.WinStateToString(board.Stalemate),
+			board.WinStateToString(boardState.HasWinner()))
+	})
+
+	test.Run("agrees with check state", func(test *testing.T) {
+		test.Parallel()
+		for range 20 {
+			boardState := board.NewBoard()
+			err := boardState.Init()
+			assertSuccess(test, err)
+
+			for boardState.HasWinner() == board.NoWin {
+				whoseMove := boardState.WhoseMove()
+				inCheck := boardState.InCheck(whoseMove)
+				if inCheck != (boardState.Check.Check != board.NoCheck) ||
+					inCheck != boardState.Check.InCheck(whoseMove) {
+					test.Fatalf("check mismatch, %s\n%s",
+						boardState.Check.String(), boardState.String())
+				}
+				assertBoolEq(test, false, boardState.InCheck(board.OppositeColour(whoseMove)))
+
+				moves := boardState.LegalMoves
+				err := boardState.MakeMove(moves[rand.IntN(len(moves))])
+				assertSuccess(test, err)
+			}
+		}
+	})
+}
+
+func Test_hash(test *testing.T) {
+	test.Run("test transpositions hash equally", func(test *testing.T) {
+		test.Parallel()
+		play := func(moves []string) *board.BoardState {
+			boardState := board.NewBoard()
+			err := boardState.Init()
+			assertSuccess(test, err)
+			for _, str := range moves {
+				move, err := board.DeserialiseMove(str)
+				assertSuccess(test, err)
+				err = boardState.MakeMove(move)
+				assertSuccess(test, err)
+			}
+			return boardState
+		}
+
+		first := play([]string{"D1:C2", "E8:F7", "F2:E4", "C7:E6"})
+		second := play([]string{"F2:E4", "C7:E6", "D1:C2", "E8:F7"})
+		if first.Hash() != second.Hash() {
+			test.Fatal("expected transposed positions to hash equally")
+		}
+
+		start := play(nil)
+		hash := start.Hash()
+		err := start.MakeMove(start.LegalMoves[0])
+		assertSuccess(test, err)
+		if start.Hash() == hash {
+			test.Fatal("expected hash to change after a move")
+		}
+		err = start.UnmakeMove()
+		assertSuccess(test, err)
+		if start.Hash() != hash {
+			test.Fatal("expected hash to be restored after unmaking a move")
+		}
+	})
+}
+
+func Test_repetition_count(test *testing.T) {
+	test.Run("test knights shuffling repeat the position", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		assertNumEq(test, 1, boardState.RepetitionCount())
+
+		shuffle := []string{"G1:F3", "G8:F6", "F3:G1", "F6:G8"}
+		playStandard(test, boardState, shuffle...)
+		assertNumEq(test, 2, boardState.RepetitionCount())
+		playStandard(test, boardState, shuffle[:2]...)
+		assertNumEq(test, 2, boardState.RepetitionCount())
+		playStandard(test, boardState, shuffle[2:]...)
+		assertNumEq(test, 3, boardState.RepetitionCount())
+
+		assertSuccess(test, boardState.UnmakeMove())
+		assertNumEq(test, 2, boardState.RepetitionCount())
+		playStandard(test, boardState, "F6:G8")
+		assertNumEq(test, 3, boardState.RepetitionCount())
+
+		// a pawn move means nothing before it can come up again
+		playStandard(test, boardState, "E2:E4", "G8:F6", "G1:F3", "F6:G8", "F3:G1")
+		assertNumEq(test, 2, boardState.RepetitionCount())
+		clone := boardState.Clone()
+		playStandard(test, clone, "G8:F6")
+		assertNumEq(test, 2, boardState.RepetitionCount())
+	})
+
+	test.Run("test taking moves back on a clone leaves the original's history", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		shuffle := []string{"G1:F3", "G8:F6", "F3:G1", "F6:G8"}
+		playStandard(test, boardState, shuffle...)
+		playStandard(test, boardState, shuffle...)
+		assertNumEq(test, 3, boardState.RepetitionCount())
+
+		// back to the start and somewhere new
+		clone := boardState.Clone()
+		for range 4 {
+			assertSuccess(test, clone.UnmakeMove())
+		}
+		playStandard(test, clone, "B1:C3")
+		assertNumEq(test, 1, clone.RepetitionCount())
+
+		// back to the knight on F3 for the second time
+		for range 3 {
+			assertSuccess(test, boardState.UnmakeMove())
+		}
+		assertNumEq(test, 2, boardState.RepetitionCount())
+	})
+}
+
+func Test_error_kinds(test *testing.T) {
+	test.Run("test move errors can be told apart", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+
+		move, err := board.DeserialiseMove("E2:E5")
+		assertSuccess(test, err)
+		err = boardState.MakeMove(move)
+		assertBoolEq(test, true, errors.Is(err, board.ErrIllegalMove))
+		_, err = boardState.Peek(move)
+		assertBoolEq(test, true, errors.Is(err, board.ErrIllegalMove))
+
+		move, err = board.DeserialiseMove("E7:E5")
+		assertSuccess(test, err)
+		err = boardState.MakeMove(move)
+		assertBoolEq(test, true, errors.Is(err, board.ErrWrongTurn))
+		assertBoolEq(test, false, errors.Is(err, board.ErrIllegalMove))
+
+		err = boardState.UnmakeMove()
+		assertBoolEq(test, true, errors.Is(err, board.ErrNoMoveToUnmake))
+
+		_, err = board.DeserialiseMove("E2E4")
+		assertBoolEq(test, true, errors.Is(err, board.ErrInvalidMove))
+		_, err = board.DeserialiseMove("E2:J4")
+		assertBoolEq(test, true, errors.Is(err, board.ErrInvalidSquare))
+		_, err = board.DeserialiseUciMove("e2e4k")
+		assertBoolEq(test, true, errors.Is(err, board.ErrInvalidMove))
+		_, err = boardState.SanToMove("Ke2")
+		assertBoolEq(test, true, errors.Is(err, board.ErrIllegalMove))
+	})
+
+	test.Run("test invalid fens", func(test *testing.T) {
+		test.Parallel()
+		fens := []string{
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x 0",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQQBNR w 0",
+			"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w 0",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w x",
+		}
+		for _, fen := range fens {
+			_, err := board.ParseFen(fen)
+			if !errors.Is(err, board.ErrInvalidFen) {
+				test.Errorf("Expected invalid fen error for %q, got %v", fen, err)
+			}
+		}
+	})
+}
+
+func Test_coordinates(test *testing.T) {
+	test.Run("test squares convert the same way everywhere", func(test *testing.T) {
+		test.Parallel()
+		for index := range 64 {
+			pos := board.IndexToPosition(index)
+			str := pos.CoordsString()
+			parsed, err := board.StringToPosition(str)
+			assertSuccess(test, err)
+			assertBoolEq(test, true, parsed == pos)
+			assertBoolEq(test, true, board.PositionFromFileRank(pos.File(), pos.Rank()) == pos)
+			assertStrEquality(test, str, string([]byte{'A' + byte(pos.File()), '1' + byte(pos.Rank())}))
+
+			move := board.Move{From: pos, To: pos}
+			assertStrEquality(test, strings.ToLower(str+str), move.UciString())
+		}
+		h1 := board.IndexToPosition(0)
+		assertStrEquality(test, "H1", h1.CoordsString())
+	})
+
+	test.Run("test orientations", func(test *testing.T) {
+		test.Parallel()
+		corners := []struct {
+			orientation board.Orientation
+			topLeft     string
+			bottomLeft  string
+		}{
+			{board.WhiteOrientation, "A8", "A1"},
+			{board.BlackOrientation, "H1", "H8"},
+		}
+		for _, corner := range corners {
+			topLeft := corner.orientation.Square(0, 0)
+			bottomLeft := corner.orientation.Square(0, 7)
+			assertStrEquality(test, corner.topLeft, topLeft.CoordsString())
+			assertStrEquality(test, corner.bottomLeft, bottomLeft.CoordsString())
+		}
+
+		boardState := newStandard(test, "")
+		white := strings.Split(boardState.StringFrom(board.WhiteOrientation), "\n")
+		black := strings.Split(boardState.StringFrom(board.BlackOrientation), "\n")
+		assertStrEquality(test, " A B C D E F G H  ", white[0])
+		assertStrEquality(test, " H G F E D C B A  ", black[0])
+		assertBoolEq(test, true, strings.HasSuffix(white[2], "8"))
+		assertBoolEq(test, true, strings.HasSuffix(black[2], "1"))
+		assertStrEquality(test, boardState.String(), boardState.StringFrom(board.WhiteOrientation))
+	})
+}
+
+func Test_lenient_parsing(test *testing.T) {
+	test.Run("test every way of writing a move parses the same", func(test *testing.T) {
+		test.Parallel()
+		expected, err := board.DeserialiseMove("E2:E4")
+		assertSuccess(test, err)
+		for _, str := range []string{"E2:E4", "e2:e4", "e2e4", "E2E4", "e2-e4", " e2e4\n"} {
+			move, err := board.ParseMove(str)
+			assertSuccess(test, err)
+			assertStrEquality(test, expected.Serialise(), move.Serialise())
+		}
+
+		promotion, err := board.DeserialiseMove("B7:B8q")
+		assertSuccess(test, err)
+		for _, str := range []string{"B7:B8q", "b7b8q", "B7B8Q", "b7:b8Q"} {
+			move, err := board.ParseMove(str)
+			assertSuccess(test, err)
+			assertStrEquality(test, promotion.Serialise(), move.Serialise())
+		}
+
+		for _, str := range []string{"", "e2", "e2:e9", "e2::e4", "e2e4e", "i2i4", "e7e8k"} {
+			_, err := board.ParseMove(str)
+			assertFailure(test, err)
+		}
+	})
+
+	test.Run("test positions in either case", func(test *testing.T) {
+		test.Parallel()
+		upper, err := board.ParsePosition("C3")
+		assertSuccess(test, err)
+		lower, err := board.ParsePosition(" c3")
+		assertSuccess(test, err)
+		assertStrEquality(test, "C3", lower.CoordsString())
+		assertBoolEq(test, true, upper == lower)
+		_, err = board.ParsePosition("c9")
+		assertFailure(test, err)
+	})
+}
+
+func Test_compact_move_list(test *testing.T) {
+	test.Run("test compact move lists round trip", func(test *testing.T) {
+		test.Parallel()
+		for _, variant := range []board.Variant{board.Diagonal, board.Standard} {
+			boardState := board.NewVariantBoard(variant)
+			assertSuccess(test, boardState.Init())
+			records, err := boardState.Playouts(4, 80, 7)
+			assertSuccess(test, err)
+			for _, record := range records {
+				replay := board.NewVariantBoard(variant)
+				assertSuccess(test, replay.Init())
+				for _, move := range record.Moves {
+					compact := board.SerialiseMoveListCompact(replay.LegalMoves)
+					parsed, err := board.DeserialiseMoveListCompact(compact)
+					assertSuccess(test, err)
+					assertNumEq(test, len(replay.LegalMoves), len(parsed))
+					for _, legal := range replay.LegalMoves {
+						assertBoolEq(test, true, slices.Contains(parsed, legal))
+					}
+					assertSuccess(test, replay.MakeMove(move))
+				}
+			}
+		}
+	})
+
+	test.Run("test promotions and grouping", func(test *testing.T) {
+		test.Parallel()
+		moves := make([]board.Move, 0)
+		for _, str := range []string{"B7:B8q", "E2:E3", "B7:B8b", "E2:E4", "B7:A8n"} {
+			move, err := board.DeserialiseMove(str)
+			assertSuccess(test, err)
+			moves = append(moves, move)
+		}
+		compact := board.SerialiseMoveListCompact(moves)
+		assertStrEquality(test, "E2E3E4 B7B8qB8bA8n", compact)
+		parsed, err := board.DeserialiseMoveListCompact(compact)
+		assertSuccess(test, err)
+		assertNumEq(test, len(moves), len(parsed))
+		for _, move := range moves {
+			assertBoolEq(test, true, slices.Contains(parsed, move))
+		}
+
+		assertStrEquality(test, "", board.SerialiseMoveListCompact(nil))
+		for _, str := range []string{"E2", "E2E", "E2E3k", "E2E9"} {
+			_, err := board.DeserialiseMoveListCompact(str)
+			assertFailure(test, err)
+		}
+	})
+}
+
+func Test_uci_moves(test *testing.T) {
+	test.Run("test uci serialisation", func(test *testing.T) {
+		test.Parallel()
+		move, err := board.DeserialiseMove("H5:H6")
+		assertSuccess(test, err)
+		assertStrEquality(test, "h5h6", move.UciString())
+
+		parsed, err := board.DeserialiseUciMove("h5h6")
+		assertSuccess(test, err)
+		if parsed != move {
+			test.Fatalf("expected %s\nreceived: %s", move.String(), parsed.String())
+		}
+
+		promotion, err := board.DeserialiseUciMove("a7a8q")
+		assertSuccess(test, err)
+		if promotion.Promotion != board.QueenPromotion {
+			test.Fatalf("expected queen promotion\nreceived: %d", promotion.Promotion)
+		}
+		assertStrEquality(test, "a7a8q", promotion.UciString())
+
+		_, err = board.DeserialiseUciMove("H5H6")
+		assertFailure(test, err)
+		_, err = board.DeserialiseUciMove("a7a8k")
+		assertFailure(test, err)
+		_, err = board.DeserialiseUciMove("a7a")
+		assertFailure(test, err)
+	})
+}
+
 func assertEq(test *testing.T, expected, received fmt.Stringer) {
 	test.Helper()
 	if expected != received {
@@ -670,3 +1287,117 @@ func assertBoolEq(test *testing.T, expected, received bool) {
 			expected, received)
 	}
 }
+
+func Test_mating_material(test *testing.T) {
+	tests := []struct {
+		fen   string
+		white bool
+		black bool
+	}{
+		{"k7/8/8/8/8/8/8/7K w 0", false, false},
+		{"k7/8/8/8/8/8/1r6/7K w 0", true, false},
+		{"k7/8/8/8/8/8/1p6/7K w 0", true, false},
+		// a lone minor piece can only mate with the other king boxed in
+		{"k7/8/8/8/8/8/1n6/7K w 0", false, false},
+		{"k7/1P6/8/8/8/8/1b6/7K w 0", true, true},
+		{"k7/8/8/8/8/8/1nb5/7K w 0", true, false},
+	}
+
+	for _, tt := range tests {
+		test.Run(tt.fen, func(test *testing.T) {
+			test.Parallel()
+			boardState, err := board.ParseFen(tt.fen)
+			if err != nil {
+				test.Fatal(err)
+			}
+			assertBoolEq(test, tt.white, boardState.HasMatingMaterial(board.White))
+			assertBoolEq(test, tt.black, boardState.HasMatingMaterial(board.Black))
+		})
+	}
+}
+
+func Test_diff(test *testing.T) {
+	squares := func(changes []board.SquareChange) string {
+		strs := make([]string, len(changes))
+		for i, change := range changes {
+			strs[i] = change.Square.CoordsString()
+		}
+		slices.Sort(strs)
+		return strings.Join(strs, " ")
+	}
+
+	test.Run("test diff after moves", func(test *testing.T) {
+		test.Parallel()
+		before := newStandard(test, "")
+		assertNumEq(test, 0, len(before.Diff(before)))
+
+		after, err := before.Peek(board.Move{
+			From: board.Position{X: 3, Y: 1},
+			To:   board.Position{X: 3, Y: 3},
+		})
+		assertSuccess(test, err)
+		changes := before.Diff(after)
+		assertStrEquality(test, "E2 E4", squares(changes))
+		for _, change := range changes {
+			if change.Square.CoordsString() == "E2" {
+				assertBoolEq(test, true, change.Before == board.WPawn)
+				assertBoolEq(test, true, change.After.IsClear())
+			}
+		}
+
+		castling := newStandard(test, "")
+		playStandard(test, castling, "E2:E4", "E7:E5", "G1:F3", "B8:C6", "F1:C4", "G8:F6")
+		castled := castling.Clone()
+		playStandard(test, castled, "E1:G1")
+		assertStrEquality(test, "E1 F1 G1 H1", squares(castling.Diff(castled)))
+
+		passant := newStandard(test, "")
+		playStandard(test, passant, "E2:E4", "A7:A6", "E4:E5", "D7:D5")
+		taken := passant.Clone()
+		playStandard(test, taken, "E5:D6")
+		assertStrEquality(test, "D5 D6 E5", squares(passant.Diff(taken)))
+	})
+
+	test.Run("test diff json", func(test *testing.T) {
+		test.Parallel()
+		change := board.SquareChange{
+			Square: board.Position{X: 3, Y: 1},
+			Before: board.WPawn,
+			After:  board.Clear,
+		}
+		bytes, err := json.Marshal(change)
+		assertSuccess(test, err)
+		assertStrEquality(test, `{"square":"E2","before":"p","after":""}`, string(bytes))
+	})
+}
+
+func Test_game_result(test *testing.T) {
+	test.Run("test results map to win states", func(test *testing.T) {
+		test.Parallel()
+		results := []struct {
+			result board.GameResult
+			win    board.WinState
+		}{
+			{board.GameResult{}, board.NoWin},
+			{board.BoardResult(board.BlackWin), board.BlackWin},
+			{board.BoardResult(board.Stalemate), board.Stalemate},
+			{board.BoardResult(board.DeadPosition), board.DeadPosition},
+			{board.WinResult(board.White, board.TerminationTimeForfeit), board.WhiteWin},
+			{board.DrawResult(board.TerminationTimeoutDraw), board.TimeoutDraw},
+			{board.DrawResult(board.TerminationAgreement), board.AgreedDraw},
+			{board.DrawResult(board.TerminationAdjudicated), board.AdjudicatedDraw},
+		}
+		for _, tt := range results {
+			assertStrEquality(test, board.WinStateToString(tt.win),
+				board.WinStateToString(tt.result.WinState()))
+		}
+
+		checkmate := board.BoardResult(board.WhiteWin)
+		assertBoolEq(test, true, checkmate.Winner == board.White)
+		assertBoolEq(test, true, checkmate.Reason == board.TerminationCheckmate)
+		assertBoolEq(test, false, checkmate.IsDraw())
+		assertStrEquality(test, "White wins by checkmate", checkmate.String())
+		assertBoolEq(test, true, board.DrawResult(board.TerminationStalemate).IsDraw())
+		assertBoolEq(test, false, board.GameResult{}.IsOver())
+	})
+}
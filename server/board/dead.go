@@ -0,0 +1,99 @@
+package board
+
+// A position neither side can ever mate from, either nobody has the material
+// for it or only kings and pawns are left, every pawn is stuck for good and
+// neither king can get to a pawn it could take
+func (board *BoardState) IsDeadPosition() bool {
+	if !board.HasMatingMaterial(White) && !board.HasMatingMaterial(Black) {
+		return true
+	}
+	return board.isBlockade()
+}
+
+func (board *BoardState) isBlockade() bool {
+	for index, piece := range board.State {
+		if piece.IsClear() || piece.Is(King) {
+			continue
+		}
+		if !piece.Is(Pawn) || !board.isPawnStuck(piece, IndexToPosition(index)) {
+			return false
+		}
+	}
+
+	for _, colour := range [...]Colour{White, Black} {
+		if board.kingCanTakePawn(colour) {
+			return false
+		}
+	}
+	return true
+}
+
+// A pawn blocked by another pawn, or pushing off the edge as pawns in the
+// diagonal variant's far corner do, with nothing to capture. The enemy king
+// can never step onto the capture squares so only pawns moving would free it
+func (board *BoardState) isPawnStuck(pawn Piece, from Position) bool {
+	rules := board.Variant().Pawns(pawn.Colour())
+	to, inBounds := from.AddInBounds(directionToVec(rules.Push))
+	if inBounds && !board.GetSquare(to).Is(Pawn) {
+		return false
+	}
+
+	for _, dir := range rules.Captures {
+		piece, _ := board.pieceAt(from, directionToVec(dir))
+		if !piece.IsClear() && piece.Colour() != pawn.Colour() {
+			return false
+		}
+	}
+	return true
+}
+
+// whether a pawn of the colour attacks the square
+func (board *BoardState) pawnGuards(pos Position, colour Colour) bool {
+	for _, dir := range board.Variant().Pawns(colour).Captures {
+		piece, _ := board.pieceAt(pos, directionToVec(reverseDirection(dir)))
+		if piece.Is(Pawn) && piece.Colour() == colour {
+			return true
+		}
+	}
+	return false
+}
+
+// Searches every square the king can walk to without stepping onto a square
+// an enemy pawn attacks, the enemy king is ignored so this can only
+// overestimate how far the king gets
+func (board *BoardState) kingCanTakePawn(colour Colour) bool {
+	opponent := OppositeColour(colour)
+	start, found := board.kingPosition(colour)
+	if !found {
+		return false
+	}
+
+	visited := [64]bool{}
+	visited[positionToIndex(start)] = true
+	queue := []Position{start}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+
+		for _, vec := range nonKnightDirectionArray {
+			to, inBounds := pos.AddInBounds(vec)
+			if !inBounds || visited[positionToIndex(to)] {
+				continue
+			}
+			visited[positionToIndex(to)] = true
+			if board.pawnGuards(to, opponent) {
+				continue
+			}
+
+			piece := board.GetSquare(to)
+			if piece.Is(Pawn) && piece.Colour() == opponent {
+				return true
+			}
+			if piece.Is(Pawn) {
+				continue
+			}
+			queue = append(queue, to)
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package board_test
+
+import (
+	"testing"
+
+	"chess/board"
+)
+
+// white pawns run along one anti-diagonal and black pawns along the next,
+// every pawn is blocked and the squares between them are guarded by both
+// sides so neither king can cross
+const diagonalBlockade = "k5p1/5p1P/4p1P1/3p1P2/2p1P3/1p1P4/p1P5/1P5K w 20"
+
+func Test_dead_position(test *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		dead bool
+	}{
+		{"bare kings", "k7/8/8/8/8/8/8/7K w 0", true},
+		{"lone knight", "k7/8/8/8/8/8/1n6/7K w 0", true},
+		{"rook", "k7/8/8/8/8/8/1r6/7K w 0", false},
+		{"blockade", diagonalBlockade, true},
+		// the white pawn on E4 can push into the gap
+		{"blockade with a gap", "k5p1/5p1P/4p1P1/3p1P2/2p5/1p1P4/p1P5/1P5K w 20", false},
+		// pawns in the far corner can't move but the king can take them
+		{"corner pawn", "k7/8/8/8/8/8/8/6Kp w 20", false},
+		{"opening", "", false},
+	}
+
+	for _, tt := range tests {
+		test.Run(tt.name, func(test *testing.T) {
+			test.Parallel()
+			var boardState *board.BoardState
+			var err error
+			if tt.fen == "" {
+				boardState = board.NewBoard()
+			} else {
+				boardState, err = board.ParseFen(tt.fen)
+				if err != nil {
+					test.Fatal(err)
+				}
+			}
+			err = boardState.Init()
+			if err != nil {
+				test.Fatal(err)
+			}
+
+			assertBoolEq(test, tt.dead, boardState.IsDeadPosition())
+			if tt.dead && boardState.HasWinner() != board.DeadPosition {
+				test.Fatalf("expected a dead position draw\nreceived: %s",
+					board.WinStateToString(boardState.HasWinner()))
+			}
+		})
+	}
+}
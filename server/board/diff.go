@@ -0,0 +1,54 @@
+package board
+
+import "encoding/json"
+
+// A square whose piece differs between two positions, the pieces don't
+// carry any flags and are Clear for an empty square
+type SquareChange struct {
+	Square Position
+	Before Piece
+	After  Piece
+}
+
+func samePiece(a, b Piece) bool {
+	return a&pieceAndColourMask == b&pieceAndColourMask
+}
+
+// The squares which differ going from the board to other, in index order.
+// Only pieces are compared, a move changes two squares, castling and en
+// passant three or four
+func (board *BoardState) Diff(other *BoardState) []SquareChange {
+	changes := make([]SquareChange, 0, 4)
+	for index, before := range board.State {
+		after := other.State[index]
+		if samePiece(before, after) {
+			continue
+		}
+		changes = append(changes, SquareChange{
+			Square: IndexToPosition(index),
+			Before: before & pieceAndColourMask,
+			After:  after & pieceAndColourMask,
+		})
+	}
+	return changes
+}
+
+func pieceFenOrEmpty(piece Piece) string {
+	if piece.IsClear() {
+		return ""
+	}
+	return piece.FenString()
+}
+
+// e.g. {"square":"E2","before":"p","after":""} using fen letters
+func (change SquareChange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Square string `json:"square"`
+		Before string `json:"before"`
+		After  string `json:"after"`
+	}{
+		Square: change.Square.CoordsString(),
+		Before: pieceFenOrEmpty(change.Before),
+		After:  pieceFenOrEmpty(change.After),
+	})
+}
@@ -0,0 +1,23 @@
+package board
+
+import "errors"
+
+// Errors returned by the board package wrap one of these so callers can tell
+// a bad move from a client apart from a board that has gone wrong, check
+// with errors.Is
+var (
+	// the move is well formed but not one of the legal moves
+	ErrIllegalMove = errors.New("move is not in legal moves")
+	// the piece being moved belongs to the player not on move
+	ErrWrongTurn = errors.New("not player to move")
+	// a move string that couldn't be parsed
+	ErrInvalidMove = errors.New("invalid move")
+	// a square string that couldn't be parsed
+	ErrInvalidSquare = errors.New("invalid square")
+	ErrInvalidFen    = errors.New("invalid fen")
+	// UnmakeMove was called with nothing played since the position was set up
+	ErrNoMoveToUnmake = errors.New("no move to unmake")
+	// the board reached a state the rules don't allow, e.g. a missing king,
+	// which means there's a bug rather than a bad request
+	ErrInconsistentPosition = errors.New("inconsistent position")
+)
@@ -0,0 +1,225 @@
+package board
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Structured json for positions and moves, for REST responses and debugging
+// where a fen string isn't enough to see what's going on
+
+type moveJson struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Promotion string `json:"promotion,omitempty"`
+}
+
+// e.g. {"from":"B7","to":"B8","promotion":"q"}
+func (move Move) MarshalJSON() ([]byte, error) {
+	ret := moveJson{From: move.From.CoordsString(), To: move.To.CoordsString()}
+	if move.Promotion != NoPromotion {
+		ret.Promotion = string(promotionToUciArr[move.Promotion])
+	}
+	return json.Marshal(ret)
+}
+
+func (move *Move) UnmarshalJSON(data []byte) error {
+	parsed := moveJson{}
+	err := json.Unmarshal(data, &parsed)
+	if err != nil {
+		return err
+	}
+
+	from, err := StringToPosition(parsed.From)
+	if err != nil {
+		return err
+	}
+	to, err := StringToPosition(parsed.To)
+	if err != nil {
+		return err
+	}
+	promotion := NoPromotion
+	if len(parsed.Promotion) == 1 {
+		promotion, err = uciByteToPromotion(parsed.Promotion[0])
+		if err != nil {
+			return err
+		}
+	} else if parsed.Promotion != "" {
+		return fmt.Errorf("invalid promotion %q", parsed.Promotion)
+	}
+
+	*move = Move{From: from, To: to, Promotion: promotion}
+	return nil
+}
+
+type checkStateJson struct {
+	Check string `json:"check"`
+	// the checking piece, left out when there's no check
+	From string `json:"from,omitempty"`
+}
+
+func (state CheckState) MarshalJSON() ([]byte, error) {
+	ret := checkStateJson{Check: CheckToString(state.Check)}
+	if state.Check != NoCheck {
+		ret.From = state.From.CoordsString()
+	}
+	return json.Marshal(ret)
+}
+
+func (state *CheckState) UnmarshalJSON(data []byte) error {
+	parsed := checkStateJson{}
+	err := json.Unmarshal(data, &parsed)
+	if err != nil {
+		return err
+	}
+
+	ret := defaultCheckState()
+	found := false
+	for check := NoCheck; check <= BlackDoubleCheck; check++ {
+		if CheckToString(check) == parsed.Check {
+			ret.Check = check
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("invalid check %q", parsed.Check)
+	}
+	if parsed.From != "" {
+		ret.From, err = StringToPosition(parsed.From)
+		if err != nil {
+			return err
+		}
+	}
+
+	*state = ret
+	return nil
+}
+
+type pieceJson struct {
+	Square string `json:"square"`
+	Colour string `json:"colour"`
+	Type   string `json:"type"`
+	Moved  bool   `json:"moved"`
+}
+
+type boardStateJson struct {
+	Variant            string      `json:"variant"`
+	Fen                string      `json:"fen"`
+	WhoseMove          string      `json:"whoseMove"`
+	MoveCounter        uint16      `json:"moveCounter"`
+	CaptureMoveCounter uint16      `json:"captureMoveCounter"`
+	Pieces             []pieceJson `json:"pieces"`
+	Check              CheckState  `json:"check"`
+	WinState           string      `json:"winState"`
+	LegalMoves         []Move      `json:"legalMoves"`
+	MoveHistory        []Move      `json:"moveHistory"`
+}
+
+func (board *BoardState) MarshalJSON() ([]byte, error) {
+	ret := boardStateJson{
+		Variant:            VariantId(board.Variant()),
+		Fen:                board.Fen(),
+		WhoseMove:          ColourString(board.WhoseMove()),
+		MoveCounter:        board.MoveCounter,
+		CaptureMoveCounter: board.CaptureMoveCounter,
+		Pieces:             make([]pieceJson, 0, 32),
+		Check:              board.Check,
+		WinState:           WinStateToString(board.WinState),
+		LegalMoves:         board.LegalMoves,
+		MoveHistory:        board.PlayedMoves(),
+	}
+	if ret.LegalMoves == nil {
+		ret.LegalMoves = make([]Move, 0)
+	}
+	for index, piece := range board.State {
+		if piece.IsClear() {
+			continue
+		}
+		pos := IndexToPosition(index)
+		ret.Pieces = append(ret.Pieces, pieceJson{
+			Square: pos.CoordsString(),
+			Colour: ColourString(piece.Colour()),
+			Type:   pieceTypeToString[piece.PieceType()],
+			Moved:  piece.IsMoved(),
+		})
+	}
+	return json.Marshal(ret)
+}
+
+// The variant, pieces, counters and move history are read, everything else
+// is worked out again by Init. Only the last move of the history is checked
+// against the pieces, it's needed for en passant
+func (board *BoardState) UnmarshalJSON(data []byte) error {
+	parsed := boardStateJson{}
+	err := json.Unmarshal(data, &parsed)
+	if err != nil {
+		return err
+	}
+
+	variant, err := VariantFromName(parsed.Variant)
+	if err != nil {
+		return err
+	}
+
+	var state [64]Piece
+	for _, pieceStr := range parsed.Pieces {
+		pos, err := StringToPosition(pieceStr.Square)
+		if err != nil {
+			return err
+		}
+		var colour Colour
+		switch pieceStr.Colour {
+		case "white":
+			colour = White
+		case "black":
+			colour = Black
+		default:
+			return fmt.Errorf("invalid colour %q", pieceStr.Colour)
+		}
+		pieceType := slices.Index(pieceTypeToString[:], pieceStr.Type)
+		if pieceType == -1 {
+			return fmt.Errorf("invalid piece type %q", pieceStr.Type)
+		}
+
+		piece := newPiece(PieceType(pieceType), colour)
+		if pieceStr.Moved {
+			piece = piece.Moved()
+		}
+		state[positionToIndex(pos)] = piece
+	}
+
+	winState := NoWin
+	for ; winState <= DeadPosition; winState++ {
+		if WinStateToString(winState) == parsed.WinState {
+			break
+		}
+	}
+	if winState > DeadPosition {
+		return fmt.Errorf("invalid win state %q", parsed.WinState)
+	}
+
+	history := make([]MoveRecord, len(parsed.MoveHistory))
+	for i, move := range parsed.MoveHistory {
+		history[i] = MoveRecord{Move: move}
+	}
+	if len(history) > 0 {
+		last := &history[len(history)-1]
+		last.Piece = state[positionToIndex(last.To)]
+		if last.Piece.IsClear() {
+			return fmt.Errorf("no piece on the last move's square %s", last.To.CoordsString())
+		}
+	}
+
+	*board = BoardState{
+		State:              state,
+		Check:              defaultCheckState(),
+		CaptureMoveCounter: parsed.CaptureMoveCounter,
+		MoveHistory:        history,
+		MoveCounter:        parsed.MoveCounter,
+		WinState:           winState,
+		variant:            variant,
+	}
+	return board.Init()
+}
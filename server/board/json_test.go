@@ -0,0 +1,102 @@
+package board_test
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"testing"
+
+	"chess/board"
+)
+
+func Test_json(test *testing.T) {
+	test.Run("test move json", func(test *testing.T) {
+		test.Parallel()
+		move := board.Move{
+			From:      board.Position{X: 6, Y: 6},
+			To:        board.Position{X: 6, Y: 7},
+			Promotion: board.QueenPromotion,
+		}
+		bytes, err := json.Marshal(move)
+		assertSuccess(test, err)
+		assertStrEquality(test, `{"from":"B7","to":"B8","promotion":"q"}`, string(bytes))
+
+		decoded := board.Move{}
+		assertSuccess(test, json.Unmarshal(bytes, &decoded))
+		assertStrEquality(test, move.Serialise(), decoded.Serialise())
+
+		bytes, err = json.Marshal([]board.Move{{From: move.From, To: move.To}})
+		assertSuccess(test, err)
+		assertStrEquality(test, `[{"from":"B7","to":"B8"}]`, string(bytes))
+
+		assertFailure(test, json.Unmarshal([]byte(`{"from":"Z9","to":"B8"}`), &decoded))
+		assertFailure(test, json.Unmarshal([]byte(`{"from":"B7","to":"B8","promotion":"k"}`), &decoded))
+	})
+
+	test.Run("test check state json", func(test *testing.T) {
+		test.Parallel()
+		state := board.CheckState{Check: board.BlackCheck, From: board.Position{X: 3, Y: 3}}
+		bytes, err := json.Marshal(state)
+		assertSuccess(test, err)
+		assertStrEquality(test, `{"check":"black check","from":"E4"}`, string(bytes))
+
+		decoded := board.CheckState{}
+		assertSuccess(test, json.Unmarshal(bytes, &decoded))
+		assertCheckEquality(test, &state, &decoded)
+
+		bytes, err = json.Marshal(board.CheckState{})
+		assertSuccess(test, err)
+		assertStrEquality(test, `{"check":"no check"}`, string(bytes))
+		assertFailure(test, json.Unmarshal([]byte(`{"check":"checkish"}`), &decoded))
+	})
+
+	test.Run("test board state round trips random games", func(test *testing.T) {
+		test.Parallel()
+		for _, variant := range []board.Variant{board.Diagonal, board.Standard} {
+			for range 5 {
+				boardState := board.NewVariantBoard(variant)
+				err := boardState.Init()
+				assertSuccess(test, err)
+
+				for boardState.HasWinner() == board.NoWin {
+					bytes, err := json.Marshal(boardState)
+					assertSuccess(test, err)
+					decoded := &board.BoardState{}
+					err = json.Unmarshal(bytes, decoded)
+					assertSuccess(test, err)
+
+					assertStrEquality(test, boardState.Fen(), decoded.Fen())
+					assertStrEquality(test, board.MoveListToString(boardState.PlayedMoves()),
+						board.MoveListToString(decoded.PlayedMoves()))
+					assertStrEquality(test, board.MoveListToString(boardState.LegalMoves),
+						board.MoveListToString(decoded.LegalMoves))
+					if decoded.State != boardState.State {
+						test.Fatalf("piece flags differ after decoding\n%s", boardState.String())
+					}
+
+					moves := boardState.LegalMoves
+					err = boardState.MakeMove(moves[rand.IntN(len(moves))])
+					assertSuccess(test, err)
+				}
+			}
+		}
+	})
+
+	test.Run("test board state fields", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		playStandard(test, boardState, "E2:E4")
+		bytes, err := json.Marshal(boardState)
+		assertSuccess(test, err)
+
+		parsed := map[string]any{}
+		assertSuccess(test, json.Unmarshal(bytes, &parsed))
+		assertStrEquality(test, "standard", parsed["variant"].(string))
+		assertStrEquality(test, "black", parsed["whoseMove"].(string))
+		assertNumEq(test, 32, len(parsed["pieces"].([]any)))
+		assertNumEq(test, 20, len(parsed["legalMoves"].([]any)))
+		assertNumEq(test, 1, len(parsed["moveHistory"].([]any)))
+
+		decoded := &board.BoardState{}
+		assertFailure(test, json.Unmarshal([]byte(`{"variant":"nope"}`), decoded))
+	})
+}
@@ -1,14 +1,66 @@
 package board
 
 import (
-	"errors"
 	"fmt"
+	"slices"
 	"strings"
 )
 
+type Promotion uint8
+
+const (
+	NoPromotion Promotion = iota
+	QueenPromotion
+	RookPromotion
+	BishopPromotion
+	KnightPromotion
+)
+
 type Move struct {
-	From Position
-	To   Position
+	From      Position
+	To        Position
+	Promotion Promotion
+}
+
+type MoveKind uint8
+
+const (
+	NormalMove MoveKind = iota
+	DoublePawnPush
+	KingsideCastle
+	QueensideCastle
+	EnPassant
+)
+
+// A move as it was played along with what it did, so the history can be
+// shown or undone without replaying the game
+type MoveRecord struct {
+	Move
+	// the piece which moved, before any promotion
+	Piece Piece
+	// Clear when nothing was taken, for en passant it's the pawn taken
+	// from beside the moving pawn
+	Captured  Piece
+	Kind      MoveKind
+	Check     bool
+	Checkmate bool
+}
+
+func (record *MoveRecord) IsCapture() bool {
+	return !record.Captured.IsClear()
+}
+
+func (record *MoveRecord) IsCastle() bool {
+	return record.Kind == KingsideCastle || record.Kind == QueensideCastle
+}
+
+// Strips the records down to the moves
+func RecordMoves(records []MoveRecord) []Move {
+	ret := make([]Move, len(records))
+	for i, record := range records {
+		ret[i] = record.Move
+	}
+	return ret
 }
 
 func (move *Move) String() string {
@@ -27,27 +79,161 @@ func MoveListToString(moveList []Move) string {
 	return ret + "]"
 }
 
+// promotions add the piece after the destination, e.g. B7:B8q
 func (move *Move) Serialise() string {
-	return fmt.Sprintf("%s:%s", move.From.CoordsString(), move.To.CoordsString())
+	bytes := make([]byte, 5, 6)
+	bytes[0], bytes[1] = move.From.FileByte(), move.From.RankByte()
+	bytes[2] = ':'
+	bytes[3], bytes[4] = move.To.FileByte(), move.To.RankByte()
+	if move.Promotion != NoPromotion {
+		bytes = append(bytes, promotionToUciArr[move.Promotion])
+	}
+	return string(bytes)
 }
 
 func DeserialiseMove(str string) (Move, error) {
-	// TODO don't do this like a js andy
-	parts := strings.Split(str, ":")
-	if len(parts) != 2 {
-		return Move{}, errors.New("failed deserialising moves")
+	fromStr, toStr, found := strings.Cut(str, ":")
+	if !found || strings.Contains(toStr, ":") {
+		return Move{}, fmt.Errorf("%w: %q", ErrInvalidMove, str)
+	}
+
+	from, err := StringToPosition(fromStr)
+	if err != nil {
+		return Move{}, err
+	}
+	promotion := NoPromotion
+	if len(toStr) == 3 {
+		promotion, err = uciByteToPromotion(toStr[2])
+		if err != nil {
+			return Move{}, err
+		}
+		toStr = toStr[:2]
+	}
+	to, err := StringToPosition(toStr)
+	if err != nil {
+		return Move{}, err
 	}
 
-	from, err := StringToPosition(parts[0])
+	return Move{From: from, To: to, Promotion: promotion}, nil
+}
+
+// long algebraic notation as used by uci, e.g. h5h6 or h7h8q
+// files use the same orientation as CoordsString
+
+var promotionToUciArr = [...]byte{0, 'q', 'r', 'b', 'n'}
+
+func uciByteToPromotion(char byte) (Promotion, error) {
+	switch char {
+	case 'q':
+		return QueenPromotion, nil
+	case 'r':
+		return RookPromotion, nil
+	case 'b':
+		return BishopPromotion, nil
+	case 'n':
+		return KnightPromotion, nil
+	default:
+		return NoPromotion, fmt.Errorf("%w: invalid promotion piece: %s", ErrInvalidMove, string(char))
+	}
+}
+
+func (move *Move) UciString() string {
+	bytes := []byte{
+		fileByte(move.From), move.From.RankByte(),
+		fileByte(move.To), move.To.RankByte(),
+	}
+	if move.Promotion != NoPromotion {
+		bytes = append(bytes, promotionToUciArr[move.Promotion])
+	}
+	return string(bytes)
+}
+
+func uciToPosition(file, rank byte) (Position, error) {
+	return parseFileRank(file, rank, 'a')
+}
+
+func DeserialiseUciMove(str string) (Move, error) {
+	if len(str) != 4 && len(str) != 5 {
+		return Move{}, fmt.Errorf("%w: uci move must be of length 4 or 5", ErrInvalidMove)
+	}
+
+	from, err := uciToPosition(str[0], str[1])
 	if err != nil {
 		return Move{}, err
 	}
-	to, err := StringToPosition(parts[1])
+	to, err := uciToPosition(str[2], str[3])
 	if err != nil {
 		return Move{}, err
 	}
 
-	return Move{From: from, To: to}, nil
+	promotion := NoPromotion
+	if len(str) == 5 {
+		promotion, err = uciByteToPromotion(str[4])
+		if err != nil {
+			return Move{}, err
+		}
+	}
+
+	return Move{From: from, To: to, Promotion: promotion}, nil
+}
+
+type MoveFormat uint8
+
+const (
+	CoordsFormat MoveFormat = iota
+	UciFormat
+)
+
+func ParseMoveFormat(str string) (MoveFormat, error) {
+	switch str {
+	case "", "coords":
+		return CoordsFormat, nil
+	case "uci":
+		return UciFormat, nil
+	default:
+		return CoordsFormat, fmt.Errorf("unknown move format: %s", str)
+	}
+}
+
+// the name ParseMoveFormat takes
+func (format MoveFormat) String() string {
+	if format == UciFormat {
+		return "uci"
+	}
+	return "coords"
+}
+
+func (move *Move) SerialiseFormat(format MoveFormat) string {
+	if format == UciFormat {
+		return move.UciString()
+	}
+	return move.Serialise()
+}
+
+func DeserialiseMoveFormat(str string, format MoveFormat) (Move, error) {
+	if format == UciFormat {
+		return DeserialiseUciMove(str)
+	}
+	return DeserialiseMove(str)
+}
+
+// Accepts moves in either format in any case and with or without a
+// separator, e.g. E2:E4, e2e4, e2-e4 or E7:E8Q, for clients which don't stick
+// to one format. The move is the same whichever way it was written
+func ParseMove(str string) (Move, error) {
+	normalised := strings.ToLower(strings.TrimSpace(str))
+	if len(normalised) > 2 && (normalised[2] == ':' || normalised[2] == '-') {
+		normalised = normalised[:2] + normalised[3:]
+	}
+	return DeserialiseUciMove(normalised)
+}
+
+func SerialiseMoveListFormat(moveList []Move, format MoveFormat) []string {
+	ret := make([]string, len(moveList))
+	for i, move := range moveList {
+		ret[i] = move.SerialiseFormat(format)
+	}
+	return ret
 }
 
 // todo don't use json arrays
@@ -60,7 +246,69 @@ func SerialiseMoveList(moveList []Move) []string {
 	return ret
 }
 
-func CanPieceDoMove(
+// Moves grouped by the square they're from, each group is the from square
+// followed by every square it goes to, with the promotion piece after the
+// square when there is one, and groups are separated by spaces e.g.
+// "E2E3E4 G1F3H3 B7B8qB8n". Squares are always upper case so a promotion to
+// a bishop can't be mistaken for the B file. Around a third of the size of
+// the json array of the same moves
+func SerialiseMoveListCompact(moveList []Move) string {
+	sorted := slices.Clone(moveList)
+	slices.SortStableFunc(sorted, func(a, b Move) int {
+		return int(positionToIndex(a.From)) - int(positionToIndex(b.From))
+	})
+
+	bytes := make([]byte, 0, len(sorted)*3+len(sorted)/2)
+	for i, move := range sorted {
+		if i == 0 || move.From != sorted[i-1].From {
+			if i > 0 {
+				bytes = append(bytes, ' ')
+			}
+			bytes = append(bytes, move.From.FileByte(), move.From.RankByte())
+		}
+		bytes = append(bytes, move.To.FileByte(), move.To.RankByte())
+		if move.Promotion != NoPromotion {
+			bytes = append(bytes, promotionToUciArr[move.Promotion])
+		}
+	}
+	return string(bytes)
+}
+
+func DeserialiseMoveListCompact(str string) ([]Move, error) {
+	moves := make([]Move, 0, len(str)/3)
+	for group := range strings.FieldsSeq(str) {
+		if len(group) < 4 {
+			return nil, fmt.Errorf("%w: move group %q too short", ErrInvalidMove, group)
+		}
+		from, err := StringToPosition(group[:2])
+		if err != nil {
+			return nil, err
+		}
+		for rest := group[2:]; rest != ""; {
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("%w: move group %q", ErrInvalidMove, group)
+			}
+			to, err := StringToPosition(rest[:2])
+			if err != nil {
+				return nil, err
+			}
+			rest = rest[2:]
+
+			promotion := NoPromotion
+			if rest != "" && rest[0] >= 'a' && rest[0] <= 'z' {
+				promotion, err = uciByteToPromotion(rest[0])
+				if err != nil {
+					return nil, err
+				}
+				rest = rest[1:]
+			}
+			moves = append(moves, Move{From: from, To: to, Promotion: promotion})
+		}
+	}
+	return moves, nil
+}
+
+func (board *BoardState) CanPieceDoMove(
 	from, to Position,
 	fromPiece, toPiece Piece,
 	dir Direction,
@@ -70,25 +318,14 @@ func CanPieceDoMove(
 		return false
 	}
 
-	toPieceColour := toPiece.Colour()
-	if fromPiece.IsPieceAndColour(BPawn) {
-		diff := to.Diff(from)
-		if toPieceColour == White {
-			return diff == DownVec || diff == RightVec
-		} else if toPiece.IsClear() {
-			return diff == DownRightVec || (!fromPiece.IsMoved() && diff == DownRightVec.Mult(2))
-		} else {
-			return false
+	if fromPiece.Is(Pawn) {
+		if !toPiece.IsClear() {
+			return toPiece.Colour() != fromPiece.Colour() &&
+				board.pawnAttacks(fromPiece, from, to)
 		}
-	} else if fromPiece.IsPieceAndColour(WPawn) {
 		diff := to.Diff(from)
-		if toPieceColour == Black {
-			return diff == DownVec || diff == RightVec
-		} else if toPiece.IsClear() {
-			return diff == DownRightVec || (!fromPiece.IsMoved() && diff == DownRightVec.Mult(2))
-		} else {
-			return false
-		}
+		push := directionToVec(board.Variant().Pawns(fromPiece.Colour()).Push)
+		return diff == push || (!fromPiece.IsMoved() && diff == push.Mult(2))
 	} else if diagonal {
 		return fromPiece.IsDiagonalAttacker()
 	} else {
@@ -104,13 +341,13 @@ type LegalMoveCreator struct {
 	checkSquares []Position
 }
 
-func newLegalMoveCreator(board *BoardState) *LegalMoveCreator {
+// moves are appended to dst, which is reused from its start
+func newLegalMoveCreator(board *BoardState, dst []Move) LegalMoveCreator {
 	colour := board.WhoseMove()
 	colourLessCheck := checkToColourlessCheck(board.Check.Check)
-	moves := make([]Move, 0)
 
-	return &LegalMoveCreator{
-		moves,
+	return LegalMoveCreator{
+		dst[:0],
 		colour,
 		colourLessCheck,
 		board,
@@ -119,7 +356,7 @@ func newLegalMoveCreator(board *BoardState) *LegalMoveCreator {
 }
 
 func (moveMaker *LegalMoveCreator) addMove(from, to Position) {
-	moveMaker.moves = append(moveMaker.moves, Move{from, to})
+	moveMaker.moves = append(moveMaker.moves, Move{From: from, To: to})
 }
 
 func (moveMaker *LegalMoveCreator) addKnightMoves(piece Piece, from Position) error {
@@ -140,7 +377,7 @@ func (moveMaker *LegalMoveCreator) addKnightMoves(piece Piece, from Position) er
 		}
 		if toPiece.Colour() != moveMaker.colour {
 			if toPiece.Is(King) {
-				return errors.New("move to king found")
+				return fmt.Errorf("%w: move to king found", ErrInconsistentPosition)
 			}
 			moveMaker.addMove(from, to)
 		}
@@ -148,7 +385,7 @@ func (moveMaker *LegalMoveCreator) addKnightMoves(piece Piece, from Position) er
 	return nil
 }
 
-func (moveMaker *LegalMoveCreator) addPawnMoveLong(piece Piece, from Position, dir Direction) {
+func (moveMaker *LegalMoveCreator) addPawnPush(piece Piece, from Position, dir Direction) {
 	pin := piece.GetPin()
 	if !ableToMoveDirection(pin, dir) {
 		return
@@ -175,12 +412,13 @@ func (moveMaker *LegalMoveCreator) addPawnMoveLong(piece Piece, from Position, d
 		return
 	}
 
+	toPiece = moveMaker.state.GetSquare(to)
 	if toPiece.IsClear() {
 		moveMaker.addMove(from, to)
 	}
 }
 
-func (moveMaker *LegalMoveCreator) addPawnMoveStraight(piece Piece, from Position, dir Direction) error {
+func (moveMaker *LegalMoveCreator) addPawnCapture(piece Piece, from Position, dir Direction) error {
 	pin := piece.GetPin()
 	if !ableToMoveDirection(pin, dir) {
 		return nil
@@ -194,7 +432,7 @@ func (moveMaker *LegalMoveCreator) addPawnMoveStraight(piece Piece, from Positio
 	toPiece := moveMaker.state.GetSquare(to)
 	if !toPiece.IsClear() && toPiece.Colour() != moveMaker.colour {
 		if toPiece.Is(King) {
-			return errors.New("move to king found")
+			return fmt.Errorf("%w: move to king found", ErrInconsistentPosition)
 		}
 		moveMaker.addMove(from, to)
 	}
@@ -202,27 +440,14 @@ func (moveMaker *LegalMoveCreator) addPawnMoveStraight(piece Piece, from Positio
 }
 
 func (moveMaker *LegalMoveCreator) addPawnMoves(piece Piece, from Position) error {
-	if piece.Colour() == White {
-		err := moveMaker.addPawnMoveStraight(piece, from, Down)
-		if err != nil {
-			return err
-		}
-		err = moveMaker.addPawnMoveStraight(piece, from, Right)
-		if err != nil {
-			return err
-		}
-		moveMaker.addPawnMoveLong(piece, from, DownRight)
-	} else {
-		err := moveMaker.addPawnMoveStraight(piece, from, Up)
-		if err != nil {
-			return err
-		}
-		err = moveMaker.addPawnMoveStraight(piece, from, Left)
+	rules := moveMaker.state.Variant().Pawns(piece.Colour())
+	for _, dir := range rules.Captures {
+		err := moveMaker.addPawnCapture(piece, from, dir)
 		if err != nil {
 			return err
 		}
-		moveMaker.addPawnMoveLong(piece, from, UpLeft)
 	}
+	moveMaker.addPawnPush(piece, from, rules.Push)
 	return nil
 }
 
@@ -233,7 +458,7 @@ func (moveMaker *LegalMoveCreator) addMoveKing(from Position, dir Direction) err
 	}
 	toPiece := moveMaker.state.GetSquare(to)
 	if toPiece.Is(King) {
-		return errors.New("move to king found")
+		return fmt.Errorf("%w: move to king found", ErrInconsistentPosition)
 	}
 	if (toPiece.IsClear() || toPiece.Colour() != moveMaker.colour) && !toPiece.IsAttacked() {
 		moveMaker.addMove(from, to)
@@ -272,7 +497,7 @@ func (moveMaker *LegalMoveCreator) addMovesInDirection(piece Piece, from Positio
 		} else {
 			if toPiece.Colour() != moveMaker.colour {
 				if toPiece.Is(King) {
-					return errors.New("move to king found")
+					return fmt.Errorf("%w: move to king found", ErrInconsistentPosition)
 				}
 				moveMaker.addMove(from, to)
 			}
@@ -346,14 +571,14 @@ func (moveMaker *LegalMoveCreator) getLegalMovesCheckImpl(to Position, toPiece P
 		return
 	}
 
-	if CanPieceDoMove(
+	if moveMaker.state.CanPieceDoMove(
 		from,
 		to,
 		fromPiece,
 		toPiece,
 		reverseDirection(dir),
 	) {
-		moveMaker.moves = append(moveMaker.moves, Move{from, to})
+		moveMaker.moves = append(moveMaker.moves, Move{From: from, To: to})
 	}
 }
 
@@ -379,12 +604,12 @@ func (moveMaker *LegalMoveCreator) getLegalMovesCheck() {
 
 			otherPiece := moveMaker.state.GetSquare(otherSquare)
 			if otherPiece.Colour() != moveMaker.colour ||
-				!piece.Is(Knight) ||
-				piece.IsPinned() {
+				!otherPiece.Is(Knight) ||
+				otherPiece.IsPinned() {
 				continue
 			}
 
-			moveMaker.moves = append(moveMaker.moves, Move{otherSquare, to})
+			moveMaker.moves = append(moveMaker.moves, Move{From: otherSquare, To: to})
 			continue
 		}
 	}
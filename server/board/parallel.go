@@ -0,0 +1,136 @@
+package board
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+)
+
+// Calls work for every index below count spread over GOMAXPROCS goroutines.
+// Every index is run even if one fails, the error of the lowest failing
+// index is returned so the result doesn't depend on scheduling
+func parallelFor(count int, work func(index int) error) error {
+	errs := make([]error, count)
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for range min(runtime.GOMAXPROCS(0), count) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				errs[index] = work(index)
+			}
+		}()
+	}
+	for index := range count {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Divide with each root move searched on its own copy of the board in
+// parallel, the receiver isn't changed
+func (board *BoardState) ParallelDivide(depth int) (map[Move]uint64, error) {
+	ret := make(map[Move]uint64, len(board.LegalMoves))
+	if depth == 0 {
+		return ret, nil
+	}
+
+	moves := board.LegalMoves
+	counts := make([]uint64, len(moves))
+	err := parallelFor(len(moves), func(index int) error {
+		clone := board.Clone()
+		err := clone.MakeMove(moves[index])
+		if err != nil {
+			return err
+		}
+		counts[index], err = clone.Perft(depth - 1)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for index, move := range moves {
+		ret[move] = counts[index]
+	}
+	return ret, nil
+}
+
+// Perft split over all cores by root move, for the deeper correctness runs
+func (board *BoardState) ParallelPerft(depth int) (uint64, error) {
+	if depth <= 1 {
+		return board.Perft(depth)
+	}
+
+	divided, err := board.ParallelDivide(depth)
+	if err != nil {
+		return 0, err
+	}
+	nodes := uint64(0)
+	for _, count := range divided {
+		nodes += count
+	}
+	return nodes, nil
+}
+
+// A game of random legal moves played from a position
+type PlayoutRecord struct {
+	// the position the moves were played from
+	StartingFen string
+	Moves       []Move
+	// not over when the playout hit its move limit first
+	Result GameResult
+}
+
+// Plays random legal moves on the board until the game ends or maxMoves half
+// moves have been played
+func (board *BoardState) randomPlayout(rng *rand.Rand, maxMoves int) (PlayoutRecord, error) {
+	record := PlayoutRecord{StartingFen: board.Fen(), Moves: make([]Move, 0, maxMoves)}
+	for len(record.Moves) < maxMoves &&
+		board.HasWinner() == NoWin && len(board.LegalMoves) > 0 {
+		move := board.LegalMoves[rng.IntN(len(board.LegalMoves))]
+		err := board.MakeMove(move)
+		if err != nil {
+			return PlayoutRecord{}, err
+		}
+		record.Moves = append(record.Moves, move)
+	}
+	record.Result = BoardResult(board.WinState)
+	return record, nil
+}
+
+func (board *BoardState) seededPlayout(seed, stream uint64, maxMoves int) (PlayoutRecord, error) {
+	rng := rand.New(rand.NewPCG(seed, stream))
+	return board.Clone().randomPlayout(rng, maxMoves)
+}
+
+// Plays random legal moves from the position, the same seed always gives
+// the same game. The receiver isn't changed
+func (board *BoardState) Playout(seed uint64, maxMoves int) (PlayoutRecord, error) {
+	return board.seededPlayout(seed, 0, maxMoves)
+}
+
+// Plays count random games from the position over all cores. Playout i is
+// seeded with seed and i so the same arguments always give the same games,
+// the first being the one Playout gives for the seed
+func (board *BoardState) Playouts(count, maxMoves int, seed uint64) ([]PlayoutRecord, error) {
+	records := make([]PlayoutRecord, count)
+	err := parallelFor(count, func(index int) error {
+		var err error
+		records[index], err = board.seededPlayout(seed, uint64(index), maxMoves)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
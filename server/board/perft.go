@@ -0,0 +1,61 @@
+package board
+
+// Counts the leaf nodes of the move tree to the given depth, used to
+// validate move generation against known node counts
+func (board *BoardState) Perft(depth int) (uint64, error) {
+	if depth == 0 {
+		return 1, nil
+	}
+	if depth == 1 {
+		return uint64(len(board.LegalMoves)), nil
+	}
+
+	nodes := uint64(0)
+	moves := board.LegalMoves
+	for _, move := range moves {
+		err := board.MakeMove(move)
+		if err != nil {
+			return 0, err
+		}
+
+		count, err := board.Perft(depth - 1)
+		if err != nil {
+			return 0, err
+		}
+		nodes += count
+
+		err = board.UnmakeMove()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return nodes, nil
+}
+
+// Perft split by root move, makes it easy to find which subtree diverges
+func (board *BoardState) Divide(depth int) (map[Move]uint64, error) {
+	ret := make(map[Move]uint64, len(board.LegalMoves))
+	if depth == 0 {
+		return ret, nil
+	}
+
+	moves := board.LegalMoves
+	for _, move := range moves {
+		err := board.MakeMove(move)
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := board.Perft(depth - 1)
+		if err != nil {
+			return nil, err
+		}
+		ret[move] = count
+
+		err = board.UnmakeMove()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
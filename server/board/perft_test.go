@@ -0,0 +1,189 @@
+package board_test
+
+import (
+	"slices"
+	"testing"
+
+	"chess/board"
+)
+
+// node counts were cross checked against a naive generator which plays
+// every pseudo legal move and rejects the ones leaving the king attacked
+func Test_perft(test *testing.T) {
+	helper := func(test *testing.T, fen string, expected []uint64) {
+		test.Helper()
+		var boardState *board.BoardState
+		if fen == "" {
+			boardState = board.NewBoard()
+		} else {
+			var err error
+			boardState, err = board.ParseFen(fen)
+			assertSuccess(test, err)
+		}
+		err := boardState.Init()
+		assertSuccess(test, err)
+
+		before := boardState.Fen()
+		for i, expectedNodes := range expected {
+			depth := i + 1
+			nodes, err := boardState.Perft(depth)
+			assertSuccess(test, err)
+			if nodes != expectedNodes {
+				test.Errorf("fen: %s\ndepth %d expected %d nodes\nreceived: %d",
+					fen, depth, expectedNodes, nodes)
+			}
+		}
+		assertStrEquality(test, before, boardState.Fen())
+	}
+
+	test.Run("test perft from start position", func(test *testing.T) {
+		test.Parallel()
+		helper(test, "", []uint64{22, 470, 10739, 240902})
+	})
+
+	test.Run("test perft kings and pawn", func(test *testing.T) {
+		test.Parallel()
+		helper(test, "k7/1p6/8/8/8/8/8/7K w 0", []uint64{3, 9, 42, 252})
+	})
+
+	test.Run("test perft x-ray check", func(test *testing.T) {
+		test.Parallel()
+		helper(test, "1rb5/5N2/1Q1P2p1/ppk4P/p2R1n2/1P5n/2B1PN1R/4P2K w 92",
+			[]uint64{2, 64, 1487})
+	})
+
+	test.Run("test perft pins", func(test *testing.T) {
+		test.Parallel()
+		helper(test, "kq3R2/r2p4/1rR2P2/p1n5/3bp1B1/2p2P2/2p1P3/3P3K w 84",
+			[]uint64{31, 690, 20376})
+	})
+
+	test.Run("test divide sums to perft", func(test *testing.T) {
+		test.Parallel()
+		boardState := board.NewBoard()
+		err := boardState.Init()
+		assertSuccess(test, err)
+
+		divided, err := boardState.Divide(3)
+		assertSuccess(test, err)
+		assertNumEq(test, len(boardState.LegalMoves), len(divided))
+
+		sum := uint64(0)
+		for _, nodes := range divided {
+			sum += nodes
+		}
+		if sum != 10739 {
+			test.Fatalf("expected divide to sum to %d\nreceived: %d", 10739, sum)
+		}
+	})
+}
+
+func Test_parallel_perft(test *testing.T) {
+	test.Run("test parallel perft matches perft", func(test *testing.T) {
+		test.Parallel()
+		boardState := board.NewBoard()
+		assertSuccess(test, boardState.Init())
+		before := boardState.Fen()
+
+		for depth := range 5 {
+			expected, err := boardState.Perft(depth)
+			assertSuccess(test, err)
+			nodes, err := boardState.ParallelPerft(depth)
+			assertSuccess(test, err)
+			if nodes != expected {
+				test.Errorf("depth %d expected %d nodes\nreceived: %d", depth, expected, nodes)
+			}
+		}
+
+		divided, err := boardState.Divide(3)
+		assertSuccess(test, err)
+		parallelDivided, err := boardState.ParallelDivide(3)
+		assertSuccess(test, err)
+		assertNumEq(test, len(divided), len(parallelDivided))
+		for move, nodes := range divided {
+			if parallelDivided[move] != nodes {
+				test.Errorf("move %s expected %d nodes\nreceived: %d",
+					move.Serialise(), nodes, parallelDivided[move])
+			}
+		}
+		assertStrEquality(test, before, boardState.Fen())
+	})
+
+	test.Run("test standard perft at depth 5", func(test *testing.T) {
+		test.Parallel()
+		if testing.Short() {
+			test.Skip("deep perft skipped in short mode")
+		}
+		nodes, err := newStandard(test, "").ParallelPerft(5)
+		assertSuccess(test, err)
+		if nodes != 4865609 {
+			test.Errorf("expected %d nodes\nreceived: %d", 4865609, nodes)
+		}
+	})
+}
+
+func Test_playouts(test *testing.T) {
+	test.Run("test playouts are legal and repeatable", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		records, err := boardState.Playouts(8, 60, 42)
+		assertSuccess(test, err)
+		assertNumEq(test, 8, len(records))
+		again, err := boardState.Playouts(8, 60, 42)
+		assertSuccess(test, err)
+		assertNumEq(test, 0, len(boardState.MoveHistory))
+
+		for i, record := range records {
+			assertBoolEq(test, true, len(record.Moves) <= 60)
+			assertBoolEq(test, true, slices.Equal(record.Moves, again[i].Moves))
+
+			replay := newStandard(test, "")
+			for _, move := range record.Moves {
+				assertSuccess(test, replay.MakeMove(move))
+			}
+			if len(record.Moves) < 60 {
+				assertBoolEq(test, true, record.Result.IsOver())
+			}
+		}
+	})
+}
+
+func Test_playout(test *testing.T) {
+	test.Run("test playouts replay from their seed", func(test *testing.T) {
+		test.Parallel()
+		for _, variant := range []board.Variant{board.Diagonal, board.Standard} {
+			boardState := board.NewVariantBoard(variant)
+			assertSuccess(test, boardState.Init())
+			before := boardState.Fen()
+
+			for seed := range uint64(20) {
+				record, err := boardState.Playout(seed, 200)
+				assertSuccess(test, err)
+				again, err := boardState.Playout(seed, 200)
+				assertSuccess(test, err)
+				assertBoolEq(test, true, slices.Equal(record.Moves, again.Moves))
+				assertBoolEq(test, true, record.Result == again.Result)
+				assertStrEquality(test, before, record.StartingFen)
+
+				replay := board.NewVariantBoard(variant)
+				assertSuccess(test, replay.Init())
+				for _, move := range record.Moves {
+					assertSuccess(test, replay.MakeMove(move))
+				}
+				assertBoolEq(test, true, board.BoardResult(replay.HasWinner()) == record.Result)
+			}
+			assertStrEquality(test, before, boardState.Fen())
+			assertNumEq(test, 0, len(boardState.MoveHistory))
+		}
+	})
+
+	test.Run("test the first of a batch matches a single playout", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		record, err := boardState.Playout(99, 40)
+		assertSuccess(test, err)
+		records, err := boardState.Playouts(3, 40, 99)
+		assertSuccess(test, err)
+		assertBoolEq(test, true, slices.Equal(record.Moves, records[0].Moves))
+	})
+}
@@ -232,3 +232,7 @@ func directionToPinDirection(dir Direction) PinDirection {
 		panic("can not pin with a knight")
 	}
 }
+
+func newPiece(pieceType PieceType, colour Colour) Piece {
+	return Piece(pieceType)<<PieceShift | Piece(colour)
+}
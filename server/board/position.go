@@ -1,8 +1,8 @@
 package board
 
 import (
-	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -20,11 +20,71 @@ func (pos *Position) Print() {
 	fmt.Print(pos.String() + "\n")
 }
 
+// Positions count X from the H file, so X 0 is H and X 7 is A, and Y from
+// rank 1. Files and ranks here count from A and from rank 1, anything naming
+// squares should convert through these rather than working it out itself
+func PositionFromFileRank(file, rank int8) Position {
+	return Position{X: 7 - file, Y: rank}
+}
+
+// 0 for the A file up to 7 for the H file
+func (pos *Position) File() int8 {
+	return 7 - pos.X
+}
+
+// 0 for rank 1 up to 7 for rank 8
+func (pos *Position) Rank() int8 {
+	return pos.Y
+}
+
+func (pos *Position) InBounds() bool {
+	return pos.X >= 0 && pos.X < 8 && pos.Y >= 0 && pos.Y < 8
+}
+
+// 'A' to 'H'
+func (pos *Position) FileByte() byte {
+	return byte('A' + pos.File())
+}
+
+// '1' to '8'
+func (pos *Position) RankByte() byte {
+	return byte('1' + pos.Rank())
+}
+
 func (pos *Position) CoordsString() string {
-	bytes := []byte{byte('H' - pos.X), byte('1' + pos.Y)}
+	bytes := []byte{pos.FileByte(), pos.RankByte()}
 	return string(bytes)
 }
 
+// Which player's side of the board is drawn at the bottom
+type Orientation uint8
+
+const (
+	// A1 in the bottom left
+	WhiteOrientation Orientation = iota
+	// H8 in the bottom left, the board's index order read from the top
+	BlackOrientation
+)
+
+// The square drawn at column col from the left and row row from the top
+func (orientation Orientation) Square(col, row int) Position {
+	if orientation == BlackOrientation {
+		return PositionFromFileRank(int8(7-col), int8(row))
+	}
+	return PositionFromFileRank(int8(col), int8(7-row))
+}
+
+// Parses a file letter starting from firstFile, 'A' or 'a', and a rank digit
+func parseFileRank(file, rank, firstFile byte) (Position, error) {
+	if file < firstFile || file > firstFile+7 {
+		return Position{}, fmt.Errorf("%w: file out of bounds", ErrInvalidSquare)
+	}
+	if rank < '1' || rank > '8' {
+		return Position{}, fmt.Errorf("%w: rank out of bounds", ErrInvalidSquare)
+	}
+	return PositionFromFileRank(int8(file-firstFile), int8(rank-'1')), nil
+}
+
 func (pos *Position) Add(other Position) Position {
 	return Position{pos.X + other.X, pos.Y + other.Y}
 }
@@ -53,21 +113,14 @@ func IndexToPosition(index int) Position {
 
 func StringToPosition(str string) (Position, error) {
 	if len(str) != 2 {
-		return Position{}, errors.New("string must be of length 2")
-	}
-
-	file := str[0]
-	rank := str[1]
-	if file < 'A' || file > 'H' {
-		return Position{}, errors.New("rank out of bounds")
-	}
-	if rank < '1' || rank > '8' {
-		return Position{}, errors.New("rank out of bounds")
+		return Position{}, fmt.Errorf("%w: string must be of length 2", ErrInvalidSquare)
 	}
+	return parseFileRank(str[0], str[1], 'A')
+}
 
-	parsedFile := int8('H' - file)
-	parsedRank := int8(rank - '1')
-	return Position{X: parsedFile, Y: parsedRank}, nil
+// StringToPosition which also accepts lower case and surrounding spaces
+func ParsePosition(str string) (Position, error) {
+	return StringToPosition(strings.ToUpper(strings.TrimSpace(str)))
 }
 
 func (pos *Position) AddInBounds(other Position) (Position, bool) {
@@ -0,0 +1,65 @@
+package board
+
+// How a game ended, shared by games ending on the board and the ones the
+// server ends, e.g. on time or when a player leaves
+type GameResult struct {
+	// None for a draw or a game which hasn't ended
+	Winner Colour
+	Reason Termination
+}
+
+// The result of a game which ended on the board or was adjudicated
+func BoardResult(win WinState) GameResult {
+	result := GameResult{Winner: None, Reason: BoardTermination(win)}
+	if win == WhiteWin || win == BlackWin {
+		result.Winner = Colour(win)
+	}
+	return result
+}
+
+func WinResult(winner Colour, reason Termination) GameResult {
+	return GameResult{Winner: winner, Reason: reason}
+}
+
+func DrawResult(reason Termination) GameResult {
+	return GameResult{Winner: None, Reason: reason}
+}
+
+func (result GameResult) IsOver() bool {
+	return result.Reason != TerminationNone
+}
+
+func (result GameResult) IsDraw() bool {
+	return result.IsOver() && result.Winner == None
+}
+
+// The WinState stored on the board, draws are told apart by their reason
+func (result GameResult) WinState() WinState {
+	if !result.IsOver() {
+		return NoWin
+	}
+	if result.Winner != None {
+		return ColourToWinState(result.Winner)
+	}
+	switch result.Reason {
+	case TerminationStalemate:
+		return Stalemate
+	case TerminationMoveRule:
+		return MoveRuleDraw
+	case TerminationAgreement:
+		return AgreedDraw
+	case TerminationTimeoutDraw:
+		return TimeoutDraw
+	case TerminationDeadPosition:
+		return DeadPosition
+	default:
+		return AdjudicatedDraw
+	}
+}
+
+func (result GameResult) String() string {
+	if !result.IsOver() {
+		return WinStateToString(NoWin)
+	}
+	return WinStateToString(result.WinState()) + " by " + string(result.Reason)
+}
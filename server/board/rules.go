@@ -0,0 +1,128 @@
+package board
+
+import (
+	"slices"
+	"strings"
+)
+
+// A direction on the board, x is towards the A file and y towards rank 8
+type RuleVector struct {
+	X int8 `json:"x"`
+	Y int8 `json:"y"`
+}
+
+type PawnMovement struct {
+	Push RuleVector `json:"push"`
+	// unmoved pawns can always push two squares if both are empty
+	DoublePush       bool         `json:"doublePush"`
+	Captures         []RuleVector `json:"captures"`
+	PromotionSquares []string     `json:"promotionSquares"`
+	PromotionPieces  []string     `json:"promotionPieces"`
+}
+
+// Everything about a variant's rules which clients need to show them, worked
+// out from the variant itself so it can't drift from how games are played
+type VariantRules struct {
+	Name        string       `json:"name"`
+	StartingFen string       `json:"startingFen"`
+	WhitePawns  PawnMovement `json:"whitePawns"`
+	BlackPawns  PawnMovement `json:"blackPawns"`
+	// e.g. castling or en passant
+	SpecialMoves  []string      `json:"specialMoves"`
+	WinConditions []Termination `json:"winConditions"`
+	DrawRules     []Termination `json:"drawRules"`
+	// half moves without a capture before the game is drawn
+	MoveRuleLimit uint16 `json:"moveRuleLimit"`
+	// each game gets its own starting position, startingFen is one of them
+	Shuffled bool `json:"shuffled"`
+}
+
+var moveKindToString = [...]string{
+	NormalMove:      "normal",
+	DoublePawnPush:  "double pawn push",
+	KingsideCastle:  "kingside castle",
+	QueensideCastle: "queenside castle",
+	EnPassant:       "en passant",
+}
+
+var pieceTypeToString = [...]string{
+	King:   "king",
+	Queen:  "queen",
+	Bishop: "bishop",
+	Knight: "knight",
+	Pawn:   "pawn",
+	Rook:   "rook",
+}
+
+func ruleVector(dir Direction) RuleVector {
+	vec := directionToVec(dir)
+	return RuleVector{X: vec.X, Y: vec.Y}
+}
+
+func pawnMovement(variant Variant, colour Colour) PawnMovement {
+	rules := variant.Pawns(colour)
+	movement := PawnMovement{
+		Push:             ruleVector(rules.Push),
+		DoublePush:       true,
+		Captures:         make([]RuleVector, 0, len(rules.Captures)),
+		PromotionSquares: make([]string, 0),
+		PromotionPieces:  make([]string, 0),
+	}
+	for _, dir := range rules.Captures {
+		movement.Captures = append(movement.Captures, ruleVector(dir))
+	}
+
+	for index := range 64 {
+		pos := IndexToPosition(index)
+		if variant.IsPromotionSquare(colour, pos) {
+			movement.PromotionSquares = append(movement.PromotionSquares, pos.CoordsString())
+		}
+	}
+	slices.Sort(movement.PromotionSquares)
+	if len(movement.PromotionSquares) > 0 {
+		for _, pieceType := range promotionToPieceType[QueenPromotion:] {
+			movement.PromotionPieces = append(movement.PromotionPieces, pieceTypeToString[pieceType])
+		}
+	}
+	return movement
+}
+
+func Rules(variant Variant) VariantRules {
+	start := BoardState{State: variant.StartingPosition(), variant: variant}
+	rules := VariantRules{
+		Name:         variant.Name(),
+		StartingFen:  start.Fen(),
+		WhitePawns:   pawnMovement(variant, White),
+		BlackPawns:   pawnMovement(variant, Black),
+		SpecialMoves: make([]string, 0),
+		// the conditions mateOrDraw checks
+		WinConditions: []Termination{TerminationCheckmate},
+		DrawRules: []Termination{
+			TerminationStalemate,
+			TerminationDeadPosition,
+			TerminationMoveRule,
+		},
+		MoveRuleLimit: variant.MoveRuleLimit(),
+	}
+	if specialMover, ok := variant.(SpecialMover); ok {
+		for _, kind := range specialMover.SpecialMoveKinds() {
+			rules.SpecialMoves = append(rules.SpecialMoves, moveKindToString[kind])
+		}
+	}
+	if shuffled, ok := variant.(Shuffled); ok {
+		rules.Shuffled = shuffled.Setup() == -1
+	}
+	return rules
+}
+
+// Every variant games can be played under, sorted by name
+func Variants() []Variant {
+	ret := make([]Variant, 0, len(variants))
+	for _, variant := range variants {
+		ret = append(ret, variant)
+	}
+	slices.SortFunc(ret, func(a, b Variant) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return ret
+}
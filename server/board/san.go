@@ -0,0 +1,173 @@
+package board
+
+import (
+	"fmt"
+	"strings"
+)
+
+// standard algebraic notation, files and ranks follow the same
+// orientation as UciString
+
+var pieceTypeToSanArr = [...]byte{
+	'K',
+	'Q',
+	'B',
+	'N',
+	0,
+	'R',
+}
+
+var promotionToSanArr = [...]byte{0, 'Q', 'R', 'B', 'N'}
+
+// lower case as san and uci use
+func fileByte(pos Position) byte {
+	return pos.FileByte() - 'A' + 'a'
+}
+
+func rankByte(pos Position) byte {
+	return pos.RankByte()
+}
+
+func sanSquare(pos Position) string {
+	return string([]byte{fileByte(pos), rankByte(pos)})
+}
+
+func (board *BoardState) sanDisambiguation(move Move, piece Piece) string {
+	sameFile := false
+	sameRank := false
+	ambiguous := false
+	for _, other := range board.LegalMoves {
+		if other.To != move.To || other.From == move.From {
+			continue
+		}
+		if board.GetSquare(other.From).PieceType() != piece.PieceType() {
+			continue
+		}
+
+		ambiguous = true
+		if other.From.X == move.From.X {
+			sameFile = true
+		}
+		if other.From.Y == move.From.Y {
+			sameRank = true
+		}
+	}
+
+	if !ambiguous {
+		return ""
+	}
+	if !sameFile {
+		return string(fileByte(move.From))
+	}
+	if !sameRank {
+		return string(rankByte(move.From))
+	}
+	return sanSquare(move.From)
+}
+
+// Must be called before the move is made, the move must be legal
+func (board *BoardState) MoveToSan(move Move) (string, error) {
+	piece := board.GetSquare(move.From)
+	if piece.IsClear() {
+		return "", fmt.Errorf("no piece to move at %s", move.From.CoordsString())
+	}
+
+	next, err := board.Peek(move)
+	if err != nil {
+		return "", err
+	}
+
+	// en passant captures land on an empty square
+	capture := !board.GetSquare(move.To).IsClear() ||
+		(piece.Is(Pawn) && board.pawnAttacks(piece, move.From, move.To))
+
+	san := ""
+	kingside, castles := board.castlingSide(move)
+	if castles {
+		if kingside {
+			san = "O-O"
+		} else {
+			san = "O-O-O"
+		}
+	} else if piece.Is(Pawn) {
+		// pawns move diagonally so a single and a double step can
+		// both reach the same square, their files always differ though
+		if capture {
+			san += string(fileByte(move.From))
+		} else {
+			san += board.sanDisambiguation(move, piece)
+		}
+	} else {
+		san += string(pieceTypeToSanArr[piece.PieceType()])
+		san += board.sanDisambiguation(move, piece)
+	}
+
+	if !castles {
+		if capture {
+			san += "x"
+		}
+		san += sanSquare(move.To)
+	}
+
+	if move.Promotion != NoPromotion {
+		san += "=" + string(promotionToSanArr[move.Promotion])
+	}
+
+	if next.Check.Check != NoCheck {
+		if len(next.LegalMoves) == 0 {
+			san += "#"
+		} else {
+			san += "+"
+		}
+	}
+
+	return san, nil
+}
+
+// Converts a list of moves played from the variant's starting position into SAN
+func MoveHistoryToSan(variant Variant, moves []Move) ([]string, error) {
+	board := NewVariantBoard(variant)
+	err := board.Init()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]string, len(moves))
+	for i, move := range moves {
+		san, err := board.MoveToSan(move)
+		if err != nil {
+			return nil, fmt.Errorf("move %d (%s): %w", i+1, move.Serialise(), err)
+		}
+		ret[i] = san
+
+		err = board.MakeMove(move)
+		if err != nil {
+			return nil, fmt.Errorf("move %d (%s): %w", i+1, move.Serialise(), err)
+		}
+	}
+	return ret, nil
+}
+
+func stripSanSuffix(san string) string {
+	return strings.TrimRight(san, "+#!?")
+}
+
+// Finds the legal move matching the san string in the current position
+func (board *BoardState) SanToMove(san string) (Move, error) {
+	stripped := stripSanSuffix(san)
+	if stripped == "" {
+		return Move{}, fmt.Errorf("%w: empty san string", ErrInvalidMove)
+	}
+
+	for _, move := range board.LegalMoves {
+		legalSan, err := board.MoveToSan(move)
+		if err != nil {
+			return Move{}, err
+		}
+		if stripSanSuffix(legalSan) == stripped {
+			return move, nil
+		}
+	}
+
+	return Move{}, fmt.Errorf("%w: %s", ErrIllegalMove, san)
+}
@@ -0,0 +1,396 @@
+package board
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Orthodox chess and chess960 share everything apart from the back rank.
+// Files run from H at X = 0 so kingside is towards X = 0. The zobrist hash
+// doesn't include castling or en passant rights
+type orthodoxVariant struct {
+	name string
+	// the back rank from the A file to the H file
+	backRank [8]PieceType
+	// chess960 setup number, -1 for standard chess
+	setup int
+	// picks a setup for each game, played as the standard setup otherwise
+	random bool
+}
+
+var Standard Variant = orthodoxVariant{
+	name:     "standard",
+	backRank: [8]PieceType{Rook, Knight, Bishop, Queen, King, Bishop, Knight, Rook},
+	setup:    -1,
+}
+
+const (
+	chess960Setups   = 960
+	chess960Standard = 518
+)
+
+// Chess960 before a setup is picked, games played under it get a random
+// setup through Shuffle
+var Chess960 Variant = orthodoxVariant{
+	name:     "chess960",
+	backRank: Standard.(orthodoxVariant).backRank,
+	setup:    chess960Standard,
+	random:   true,
+}
+
+func (variant orthodoxVariant) Name() string {
+	return variant.name
+}
+
+func (variant orthodoxVariant) StartingPosition() [64]Piece {
+	state := [64]Piece{}
+	for x := range int8(8) {
+		pieceType := variant.backRank[7-x]
+		state[positionToIndex(Position{X: x, Y: 0})] = newPiece(pieceType, White)
+		state[positionToIndex(Position{X: x, Y: 1})] = WPawn
+		state[positionToIndex(Position{X: x, Y: 6})] = BPawn
+		state[positionToIndex(Position{X: x, Y: 7})] = newPiece(pieceType, Black)
+	}
+	return state
+}
+
+var (
+	orthodoxWhitePawns = PawnRules{Push: Down, Captures: [2]Direction{DownLeft, DownRight}}
+	orthodoxBlackPawns = PawnRules{Push: Up, Captures: [2]Direction{UpLeft, UpRight}}
+)
+
+func (orthodoxVariant) Pawns(colour Colour) PawnRules {
+	if colour == White {
+		return orthodoxWhitePawns
+	}
+	return orthodoxBlackPawns
+}
+
+func (orthodoxVariant) IsPawnStart(colour Colour, pos Position) bool {
+	if colour == White {
+		return pos.Y == 1
+	}
+	return pos.Y == 6
+}
+
+func (orthodoxVariant) PawnAdvance(colour Colour, pos Position) int {
+	if colour == White {
+		return int(pos.Y) - 1
+	}
+	return 6 - int(pos.Y)
+}
+
+func (orthodoxVariant) IsPromotionSquare(colour Colour, pos Position) bool {
+	if colour == White {
+		return pos.Y == 7
+	}
+	return pos.Y == 0
+}
+
+func (variant orthodoxVariant) Winner(board *BoardState) WinState {
+	return board.mateOrDraw(variant.MoveRuleLimit())
+}
+
+func (orthodoxVariant) MoveRuleLimit() uint16 {
+	return 100
+}
+
+func (variant orthodoxVariant) Setup() int {
+	if variant.random {
+		return -1
+	}
+	return variant.setup
+}
+
+func (variant orthodoxVariant) Shuffle() Variant {
+	if !variant.random {
+		return variant
+	}
+	return mustChess960(rand.IntN(chess960Setups))
+}
+
+// Chess960 using the setup's Scharnagl number, 518 is the standard setup
+func Chess960Setup(setup int) (Variant, error) {
+	if setup < 0 || setup >= chess960Setups {
+		return nil, fmt.Errorf("chess960 setup %d out of range", setup)
+	}
+
+	backRank := [8]PieceType{}
+	filled := [8]bool{}
+	place := func(file int, pieceType PieceType) {
+		backRank[file] = pieceType
+		filled[file] = true
+	}
+	// the nth empty file
+	empty := func(n int) int {
+		for file, isFilled := range filled {
+			if isFilled {
+				continue
+			}
+			if n == 0 {
+				return file
+			}
+			n--
+		}
+		panic("no empty file")
+	}
+
+	n := setup
+	place(2*(n%4)+1, Bishop)
+	n /= 4
+	place(2*(n%4), Bishop)
+	n /= 4
+	place(empty(n%6), Queen)
+	n /= 6
+	knights := [...][2]int{
+		{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+		{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+	}[n]
+	// the second knight's index shifts once the first is placed
+	place(empty(knights[0]), Knight)
+	place(empty(knights[1]-1), Knight)
+	place(empty(0), Rook)
+	place(empty(0), King)
+	place(empty(0), Rook)
+
+	return orthodoxVariant{name: "chess960", backRank: backRank, setup: setup}, nil
+}
+
+func mustChess960(setup int) Variant {
+	variant, err := Chess960Setup(setup)
+	if err != nil {
+		panic(err)
+	}
+	return variant
+}
+
+func homeRank(colour Colour) int8 {
+	if colour == White {
+		return 0
+	}
+	return 7
+}
+
+func (variant orthodoxVariant) kingFile() int8 {
+	for file, pieceType := range variant.backRank {
+		if pieceType == King {
+			return PositionFromFileRank(int8(file), 0).X
+		}
+	}
+	panic("no king in back rank")
+}
+
+// the kingside rook is towards the H file
+func (variant orthodoxVariant) rookFiles() (kingside, queenside int8) {
+	kingFile := variant.kingFile()
+	for file, pieceType := range variant.backRank {
+		x := PositionFromFileRank(int8(file), 0).X
+		if pieceType != Rook {
+			continue
+		}
+		if x < kingFile {
+			kingside = x
+		} else {
+			queenside = x
+		}
+	}
+	return kingside, queenside
+}
+
+type castle struct {
+	kingFrom, kingTo Position
+	rookFrom, rookTo Position
+}
+
+// where the king and rook end up is the same as in standard chess
+func (variant orthodoxVariant) castle(colour Colour, kingside bool) castle {
+	rank := homeRank(colour)
+	kingsideRook, queensideRook := variant.rookFiles()
+	ret := castle{kingFrom: Position{X: variant.kingFile(), Y: rank}}
+	if kingside {
+		ret.kingTo = Position{X: 1, Y: rank}
+		ret.rookFrom = Position{X: kingsideRook, Y: rank}
+		ret.rookTo = Position{X: 2, Y: rank}
+	} else {
+		ret.kingTo = Position{X: 5, Y: rank}
+		ret.rookFrom = Position{X: queensideRook, Y: rank}
+		ret.rookTo = Position{X: 4, Y: rank}
+	}
+	return ret
+}
+
+// standard chess writes castling as the king moving two squares, e.g.
+// E1:G1. In chess960 the king can move one square or not at all so it's
+// written as the king taking its own rook
+func (variant orthodoxVariant) castleMove(castle castle) Move {
+	if variant.setup < 0 {
+		return Move{From: castle.kingFrom, To: castle.kingTo}
+	}
+	return Move{From: castle.kingFrom, To: castle.rookFrom}
+}
+
+func (orthodoxVariant) SpecialMoveKinds() []MoveKind {
+	return []MoveKind{KingsideCastle, QueensideCastle, EnPassant}
+}
+
+func (variant orthodoxVariant) SpecialMoves(board *BoardState) []Move {
+	moves := variant.castlingMoves(board)
+	return append(moves, variant.enPassantMoves(board)...)
+}
+
+func (variant orthodoxVariant) castlingMoves(board *BoardState) []Move {
+	// only the side to move can be in check
+	if board.Check.Check != NoCheck {
+		return nil
+	}
+
+	colour := board.WhoseMove()
+	moves := make([]Move, 0, 2)
+	for _, kingside := range [...]bool{true, false} {
+		castle := variant.castle(colour, kingside)
+		if variant.canCastle(board, colour, castle) {
+			moves = append(moves, variant.castleMove(castle))
+		}
+	}
+	return moves
+}
+
+func (variant orthodoxVariant) canCastle(board *BoardState, colour Colour, castle castle) bool {
+	kingPiece := board.GetSquare(castle.kingFrom)
+	rookPiece := board.GetSquare(castle.rookFrom)
+	if !kingPiece.Is(King) || kingPiece.Colour() != colour || kingPiece.IsMoved() ||
+		!rookPiece.Is(Rook) || rookPiece.Colour() != colour || rookPiece.IsMoved() {
+		return false
+	}
+
+	// every square either piece crosses has to be empty apart from the
+	// castling pieces themselves
+	rank := castle.kingFrom.Y
+	low := min(castle.kingFrom.X, castle.kingTo.X, castle.rookFrom.X, castle.rookTo.X)
+	high := max(castle.kingFrom.X, castle.kingTo.X, castle.rookFrom.X, castle.rookTo.X)
+	for x := low; x <= high; x++ {
+		pos := Position{X: x, Y: rank}
+		if pos != castle.kingFrom && pos != castle.rookFrom && !board.GetSquare(pos).IsClear() {
+			return false
+		}
+	}
+
+	// the rook can be shielding the king's path so attacks are worked
+	// out with both pieces lifted
+	lifted := board.Clone()
+	lifted.SetSquare(castle.kingFrom, Clear)
+	lifted.SetSquare(castle.rookFrom, Clear)
+	step := int8(1)
+	if castle.kingTo.X < castle.kingFrom.X {
+		step = -1
+	}
+	opponent := OppositeColour(colour)
+	for x := castle.kingFrom.X; ; x += step {
+		if lifted.IsSquareAttacked(Position{X: x, Y: rank}, opponent) {
+			return false
+		}
+		if x == castle.kingTo.X {
+			return true
+		}
+	}
+}
+
+func (orthodoxVariant) enPassantMoves(board *BoardState) []Move {
+	if len(board.MoveHistory) == 0 {
+		return nil
+	}
+	last := board.MoveHistory[len(board.MoveHistory)-1]
+	if !board.GetSquare(last.To).Is(Pawn) || abs(last.To.Y-last.From.Y) != 2 {
+		return nil
+	}
+
+	colour := board.WhoseMove()
+	to := Position{X: last.To.X, Y: (last.From.Y + last.To.Y) / 2}
+	moves := make([]Move, 0, 2)
+	for _, dx := range [...]int8{-1, 1} {
+		from, inBounds := last.To.AddInBounds(Position{X: dx, Y: 0})
+		if !inBounds {
+			continue
+		}
+		piece := board.GetSquare(from)
+		if !piece.Is(Pawn) || piece.Colour() != colour {
+			continue
+		}
+
+		// pins don't cover two pieces leaving the same rank so the
+		// resulting position is checked directly
+		after := board.Clone()
+		after.SetSquare(last.To, Clear)
+		after.SetSquare(to, piece)
+		after.SetSquare(from, Clear)
+		king, found := after.findKing(colour)
+		if !found || after.IsSquareAttacked(king, OppositeColour(colour)) {
+			continue
+		}
+		moves = append(moves, Move{From: from, To: to})
+	}
+	return moves
+}
+
+// the castle the move makes, if it is one. Only a castling move can take
+// the king two squares or onto its own rook so the move has to be legal
+func (variant orthodoxVariant) castleFor(board *BoardState, move Move) (castle, bool) {
+	piece := board.GetSquare(move.From)
+	if !piece.Is(King) {
+		return castle{}, false
+	}
+	for _, kingside := range [...]bool{true, false} {
+		castle := variant.castle(piece.Colour(), kingside)
+		if variant.castleMove(castle) == move {
+			return castle, true
+		}
+	}
+	return castle{}, false
+}
+
+func (variant orthodoxVariant) MakeMove(board *BoardState, move Move) (bool, error) {
+	piece := board.GetSquare(move.From)
+	if castle, ok := variant.castleFor(board, move); ok {
+		rook := board.GetSquare(castle.rookFrom)
+		board.SetSquare(castle.kingFrom, Clear)
+		board.SetSquare(castle.rookFrom, Clear)
+		board.SetSquare(castle.kingTo, piece.Moved())
+		board.SetSquare(castle.rookTo, rook.Moved())
+		return false, nil
+	}
+
+	if piece.Is(Pawn) && move.From.X != move.To.X && board.GetSquare(move.To).IsClear() {
+		board.SetSquare(Position{X: move.To.X, Y: move.From.Y}, Clear)
+	}
+	captured, err := board.Move(move.From, move.To)
+	return captured || piece.Is(Pawn), err
+}
+
+// whether the move castles and which side, used for SAN
+func (board *BoardState) castlingSide(move Move) (kingside bool, ok bool) {
+	variant, isOrthodox := board.Variant().(orthodoxVariant)
+	if !isOrthodox {
+		return false, false
+	}
+	castle, ok := variant.castleFor(board, move)
+	if !ok {
+		return false, false
+	}
+	return castle.kingTo.X == 1, true
+}
+
+func abs(n int8) int8 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (board *BoardState) findKing(colour Colour) (Position, bool) {
+	for index, piece := range board.State {
+		if piece.Is(King) && piece.Colour() == colour {
+			return IndexToPosition(index), true
+		}
+	}
+	return Position{}, false
+}
@@ -0,0 +1,355 @@
+package board_test
+
+import (
+	"slices"
+	"testing"
+
+	"chess/board"
+)
+
+func newStandard(test *testing.T, fen string) *board.BoardState {
+	test.Helper()
+	var boardState *board.BoardState
+	if fen == "" {
+		boardState = board.NewVariantBoard(board.Standard)
+	} else {
+		var err error
+		boardState, err = board.ParseVariantFen(fen, board.Standard)
+		assertSuccess(test, err)
+	}
+	assertSuccess(test, boardState.Init())
+	return boardState
+}
+
+func playStandard(test *testing.T, boardState *board.BoardState, moves ...string) {
+	test.Helper()
+	for _, str := range moves {
+		move, err := board.DeserialiseMove(str)
+		assertSuccess(test, err)
+		err = boardState.MakeMove(move)
+		if err != nil {
+			test.Fatalf("%s: %s", str, err)
+		}
+	}
+}
+
+func hasMove(boardState *board.BoardState, str string) bool {
+	move, err := board.DeserialiseMove(str)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(boardState.LegalMoves, move)
+}
+
+// fens are the usual perft positions flipped into this board's orientation
+func Test_standard_perft(test *testing.T) {
+	helper := func(test *testing.T, fen string, expected []uint64) {
+		test.Helper()
+		boardState := newStandard(test, fen)
+		before := boardState.Fen()
+		for i, expectedNodes := range expected {
+			nodes, err := boardState.Perft(i + 1)
+			assertSuccess(test, err)
+			if nodes != expectedNodes {
+				test.Errorf("fen: %s\ndepth %d expected %d nodes\nreceived: %d",
+					fen, i+1, expectedNodes, nodes)
+			}
+		}
+		assertStrEquality(test, before, boardState.Fen())
+	}
+
+	test.Run("test perft from start position", func(test *testing.T) {
+		test.Parallel()
+		helper(test, "", []uint64{20, 400, 8902, 197281})
+	})
+
+	test.Run("test perft castling, en passant and promotions", func(test *testing.T) {
+		test.Parallel()
+		helper(test, "r2k3r/pppbbppp/P1q2n2/3p2P1/3np3/1PNP2NB/1BPQPP1P/R2K3R w 1",
+			[]uint64{48, 2039, 97862})
+	})
+
+	test.Run("test perft en passant pins", func(test *testing.T) {
+		test.Parallel()
+		helper(test, "8/1p1p4/8/K1P3r1/R5pk/4P3/5P2/8 w 1",
+			[]uint64{14, 191, 2812, 43238})
+	})
+
+	test.Run("test perft promotions out of check", func(test *testing.T) {
+		test.Parallel()
+		helper(test, "1kr1q2r/pp2p1Pp/2n4Q/3p1pbb/6pN/nBN3B1/PPP1PPPp/R2K3R w 1",
+			[]uint64{6, 264, 9467})
+	})
+}
+
+func Test_standard(test *testing.T) {
+	test.Run("test castling", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		playStandard(test, boardState, "E2:E4", "E7:E5", "G1:F3", "B8:C6", "F1:C4", "G8:F6")
+		if !hasMove(boardState, "E1:G1") {
+			test.Fatal("expected kingside castling to be legal")
+		}
+		if hasMove(boardState, "E1:C1") {
+			test.Fatal("expected queenside castling to be blocked")
+		}
+
+		san, err := boardState.MoveToSan(board.Move{
+			From: board.Position{X: 3, Y: 0},
+			To:   board.Position{X: 1, Y: 0},
+		})
+		assertSuccess(test, err)
+		assertStrEquality(test, "O-O", san)
+
+		playStandard(test, boardState, "E1:G1")
+		assertBoolEq(test, true, boardState.GetSquare(board.Position{X: 1, Y: 0}).IsPieceAndColour(board.WKing))
+		assertBoolEq(test, true, boardState.GetSquare(board.Position{X: 2, Y: 0}).IsPieceAndColour(board.WRook))
+		if !boardState.GetSquare(board.Position{X: 0, Y: 0}).IsClear() {
+			test.Fatal("expected the rook to have left its corner")
+		}
+
+		assertSuccess(test, boardState.UnmakeMove())
+		assertBoolEq(test, true, boardState.GetSquare(board.Position{X: 0, Y: 0}).IsPieceAndColour(board.WRook))
+	})
+
+	test.Run("test no castling through an attacked square", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "r2k3r/8/8/8/8/8/8/3KQ3 w 1")
+		if hasMove(boardState, "E1:C1") {
+			test.Fatal("expected castling through check to be illegal")
+		}
+		if !hasMove(boardState, "E1:G1") {
+			test.Fatal("expected kingside castling to be legal")
+		}
+	})
+
+	test.Run("test castling rights are lost once the king moves", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "r2k3r/8/8/8/8/8/8/3K4 w 1")
+		playStandard(test, boardState, "E1:E2", "E8:D8", "E2:E1", "D8:E8")
+		if hasMove(boardState, "E1:G1") || hasMove(boardState, "E1:C1") {
+			test.Fatal("expected no castling after the king has moved")
+		}
+	})
+
+	test.Run("test en passant", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		playStandard(test, boardState, "E2:E4", "A7:A6", "E4:E5", "D7:D5")
+		if !hasMove(boardState, "E5:D6") {
+			test.Fatal("expected en passant to be legal")
+		}
+
+		move, err := board.DeserialiseMove("E5:D6")
+		assertSuccess(test, err)
+		san, err := boardState.MoveToSan(move)
+		assertSuccess(test, err)
+		assertStrEquality(test, "exd6", san)
+
+		counter := boardState.CaptureMoveCounter
+		playStandard(test, boardState, "E5:D6")
+		if !boardState.GetSquare(board.Position{X: 4, Y: 4}).IsClear() {
+			test.Fatal("expected the captured pawn to be removed")
+		}
+		if boardState.CaptureMoveCounter != 0 || counter != 0 {
+			test.Fatal("expected pawn moves to reset the move rule counter")
+		}
+
+		boardState = newStandard(test, "")
+		playStandard(test, boardState, "E2:E4", "A7:A6", "E4:E5", "D7:D5", "H2:H3", "A6:A5")
+		if hasMove(boardState, "E5:D6") {
+			test.Fatal("expected en passant to only be available straight after the double push")
+		}
+	})
+
+	test.Run("test move records", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		playStandard(test, boardState, "E2:E4", "E7:E5", "G1:F3", "B8:C6", "F1:C4", "G8:F6", "E1:G1")
+		first := boardState.MoveHistory[0]
+		assertBoolEq(test, true, first.Kind == board.DoublePawnPush)
+		assertBoolEq(test, true, first.Piece.IsPieceAndColour(board.WPawn))
+		castle := boardState.MoveHistory[len(boardState.MoveHistory)-1]
+		assertBoolEq(test, true, castle.Kind == board.KingsideCastle)
+		assertBoolEq(test, false, castle.IsCapture())
+
+		boardState = newStandard(test, "")
+		playStandard(test, boardState, "E2:E4", "A7:A6", "E4:E5", "D7:D5", "E5:D6", "A6:A5", "D6:C7")
+		enPassant := boardState.MoveHistory[4]
+		assertBoolEq(test, true, enPassant.Kind == board.EnPassant)
+		assertBoolEq(test, true, enPassant.Captured.IsPieceAndColour(board.BPawn))
+		capture := boardState.MoveHistory[6]
+		assertBoolEq(test, true, capture.Kind == board.NormalMove)
+		assertBoolEq(test, true, capture.Captured.IsPieceAndColour(board.BPawn))
+		assertBoolEq(test, false, capture.Check)
+
+		boardState = newStandard(test, "")
+		playStandard(test, boardState, "F2:F3", "E7:E5", "G2:G4", "D8:H4")
+		mate := boardState.MoveHistory[3]
+		assertBoolEq(test, true, mate.Piece.IsPieceAndColour(board.BQueen))
+		assertBoolEq(test, true, mate.Check && mate.Checkmate)
+
+		assertSuccess(test, boardState.UnmakeMove())
+		assertNumEq(test, 3, len(boardState.MoveHistory))
+	})
+
+	test.Run("test promotion", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "3k4/8/8/8/8/8/7p/3K4 w 1")
+		for _, str := range []string{"A7:A8q", "A7:A8r", "A7:A8b", "A7:A8n"} {
+			if !hasMove(boardState, str) {
+				test.Fatalf("expected %s to be legal", str)
+			}
+		}
+		if hasMove(boardState, "A7:A8") {
+			test.Fatal("expected pawns to have to promote")
+		}
+
+		move, err := board.DeserialiseMove("A7:A8n")
+		assertSuccess(test, err)
+		assertStrEquality(test, "A7:A8n", move.Serialise())
+		san, err := boardState.MoveToSan(move)
+		assertSuccess(test, err)
+		assertStrEquality(test, "a8=N", san)
+
+		playStandard(test, boardState, "A7:A8n")
+		assertBoolEq(test, true, boardState.GetSquare(board.Position{X: 7, Y: 7}).IsPieceAndColour(board.WKnight))
+	})
+
+	test.Run("test fools mate", func(test *testing.T) {
+		test.Parallel()
+		boardState := newStandard(test, "")
+		playStandard(test, boardState, "F2:F3", "E7:E5", "G2:G4", "D8:H4")
+		assertBoolEq(test, true, boardState.HasWinner() == board.BlackWin)
+	})
+
+	test.Run("test standard is registered", func(test *testing.T) {
+		test.Parallel()
+		variant, err := board.VariantFromName("standard")
+		assertSuccess(test, err)
+		assertStrEquality(test, board.Standard.Name(), variant.Name())
+	})
+}
+
+var sanToPieceType = map[byte]board.Piece{
+	'K': board.WKing, 'Q': board.WQueen, 'R': board.WRook,
+	'B': board.WBishop, 'N': board.WKnight,
+}
+
+// finds the chess960 variant with the back rank, given from A to H
+func chess960WithBackRank(test *testing.T, backRank string) board.Variant {
+	test.Helper()
+	for setup := range 960 {
+		variant, err := board.Chess960Setup(setup)
+		assertSuccess(test, err)
+		state := variant.StartingPosition()
+		matches := true
+		for x := range 8 {
+			if !state[x].IsPieceAndColour(sanToPieceType[backRank[7-x]]) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return variant
+		}
+	}
+	test.Fatalf("no setup with back rank %s", backRank)
+	return nil
+}
+
+func Test_chess960(test *testing.T) {
+	test.Run("test setups", func(test *testing.T) {
+		test.Parallel()
+		seen := make(map[[64]board.Piece]bool)
+		for setup := range 960 {
+			variant, err := board.Chess960Setup(setup)
+			assertSuccess(test, err)
+			seen[variant.StartingPosition()] = true
+		}
+		assertNumEq(test, 960, len(seen))
+
+		variant, err := board.Chess960Setup(518)
+		assertSuccess(test, err)
+		if variant.StartingPosition() != board.Standard.StartingPosition() {
+			test.Fatal("expected setup 518 to be the standard setup")
+		}
+
+		_, err = board.Chess960Setup(960)
+		assertFailure(test, err)
+	})
+
+	test.Run("test variant id", func(test *testing.T) {
+		test.Parallel()
+		variant, err := board.VariantFromName("chess960:123")
+		assertSuccess(test, err)
+		assertStrEquality(test, "chess960:123", board.VariantId(variant))
+		assertStrEquality(test, "standard", board.VariantId(board.Standard))
+
+		_, err = board.VariantFromName("standard:123")
+		assertFailure(test, err)
+		_, err = board.VariantFromName("chess960:abc")
+		assertFailure(test, err)
+	})
+
+	test.Run("test perft", func(test *testing.T) {
+		test.Parallel()
+		helper := func(backRank, fen string, expected []uint64) {
+			variant := chess960WithBackRank(test, backRank)
+			boardState, err := board.ParseVariantFen(fen, variant)
+			assertSuccess(test, err)
+			assertSuccess(test, boardState.Init())
+			for i, expectedNodes := range expected {
+				nodes, err := boardState.Perft(i + 1)
+				assertSuccess(test, err)
+				if nodes != expectedNodes {
+					test.Errorf("fen: %s\ndepth %d expected %d nodes\nreceived: %d",
+						fen, i+1, expectedNodes, nodes)
+				}
+			}
+		}
+		helper("BQNBNRKR", "rkrnb1qb/pp1p1ppn/4p2p/2p5/5P2/2NPP3/PPP3PP/RKR1BNQB w 1",
+			[]uint64{21, 528, 12189})
+		helper("BQNNRBKR", "rkbrnnqb/2p2ppp/3pp3/pp6/4BPP1/8/PPPPPQ1P/RKBRNN2 w 1",
+			[]uint64{21, 807, 18002})
+	})
+
+	test.Run("test castling onto the rook", func(test *testing.T) {
+		test.Parallel()
+		// king on G1 with the rook next to it, castling only moves the rook
+		variant := chess960WithBackRank(test, "BQNBNRKR")
+		boardState, err := board.ParseVariantFen("rk6/8/8/8/8/8/8/1K6 w 1", variant)
+		assertSuccess(test, err)
+		assertSuccess(test, boardState.Init())
+
+		move := board.Move{From: board.Position{X: 1, Y: 0}, To: board.Position{X: 0, Y: 0}}
+		if !boardState.IsLegal(move) {
+			test.Fatal("expected castling to be legal")
+		}
+		san, err := boardState.MoveToSan(move)
+		assertSuccess(test, err)
+		assertStrEquality(test, "O-O", san)
+
+		assertSuccess(test, boardState.MakeMove(move))
+		assertBoolEq(test, true, boardState.GetSquare(board.Position{X: 1, Y: 0}).IsPieceAndColour(board.WKing))
+		assertBoolEq(test, true, boardState.GetSquare(board.Position{X: 2, Y: 0}).IsPieceAndColour(board.WRook))
+		assertBoolEq(test, true, boardState.GetSquare(board.Position{X: 0, Y: 0}).IsClear())
+	})
+
+	test.Run("test shuffle", func(test *testing.T) {
+		test.Parallel()
+		shuffled, ok := board.Chess960.(board.Shuffled)
+		assertBoolEq(test, true, ok)
+		assertStrEquality(test, "chess960", board.VariantId(board.Chess960))
+		variant := shuffled.Shuffle()
+		assertStrEquality(test, "chess960", variant.Name())
+		if variant.(board.Shuffled).Setup() == -1 {
+			test.Fatal("expected a setup to be picked")
+		}
+		assertStrEquality(test, board.VariantId(variant), board.VariantId(variant.(board.Shuffled).Shuffle()))
+
+		standard, ok := board.Standard.(board.Shuffled)
+		assertBoolEq(test, true, ok)
+		assertStrEquality(test, "standard", board.VariantId(standard.Shuffle()))
+	})
+}
@@ -0,0 +1,159 @@
+package board
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// how pawns of one colour move, directions are from the pawn's square
+type PawnRules struct {
+	Push     Direction
+	Captures [2]Direction
+}
+
+// A ruleset the board can be played under, everything which differs
+// between variants goes through here so they can share move generation
+type Variant interface {
+	Name() string
+	StartingPosition() [64]Piece
+	Pawns(colour Colour) PawnRules
+	// whether a pawn of the colour on the square hasn't moved yet, used to
+	// restore the moved flag when reading a fen
+	IsPawnStart(colour Colour, pos Position) bool
+	// how far a pawn has come from where it started
+	PawnAdvance(colour Colour, pos Position) int
+	// pawns moving here have to promote
+	IsPromotionSquare(colour Colour, pos Position) bool
+	// called once the legal moves for the side to move are known
+	Winner(board *BoardState) WinState
+	// half moves without a capture before the game is drawn
+	MoveRuleLimit() uint16
+}
+
+// Optional, for variants with moves the normal generator doesn't know about
+// like castling and en passant
+type SpecialMover interface {
+	// extra legal moves for the side to move
+	SpecialMoves(board *BoardState) []Move
+	// makes the move along with any other piece it affects, returns
+	// whether the move resets the move rule counter
+	MakeMove(board *BoardState, move Move) (resetsCounter bool, err error)
+	// the kinds of move SpecialMoves can return
+	SpecialMoveKinds() []MoveKind
+}
+
+// Variants with more than one starting position, each game gets its own
+type Shuffled interface {
+	Variant
+	// the setup number needed along with the name to replay a game, -1
+	// when there is only one setup or it hasn't been picked yet
+	Setup() int
+	// the variant to play a new game under
+	Shuffle() Variant
+}
+
+var Diagonal Variant = diagonalVariant{}
+
+// used when a board is made without a variant
+var DefaultVariant = Diagonal
+
+var variants = map[string]Variant{
+	Diagonal.Name(): Diagonal,
+	Standard.Name(): Standard,
+	Chess960.Name(): Chess960,
+}
+
+// Accepts a name or an id from VariantId
+func VariantFromName(name string) (Variant, error) {
+	if name == "" {
+		return DefaultVariant, nil
+	}
+	name, setupStr, hasSetup := strings.Cut(name, ":")
+	variant, found := variants[name]
+	if !found {
+		return nil, fmt.Errorf("unknown variant %q", name)
+	}
+	if !hasSetup {
+		return variant, nil
+	}
+
+	setup, err := strconv.Atoi(setupStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid setup %q: %w", setupStr, err)
+	}
+	if name != Chess960.Name() {
+		return nil, fmt.Errorf("variant %q has no setups", name)
+	}
+	return Chess960Setup(setup)
+}
+
+// The name plus the setup for shuffled variants, e.g. chess960:518
+func VariantId(variant Variant) string {
+	if shuffled, ok := variant.(Shuffled); ok && shuffled.Setup() != -1 {
+		return fmt.Sprintf("%s:%d", variant.Name(), shuffled.Setup())
+	}
+	return variant.Name()
+}
+
+// The kings start in opposite corners with the other pieces packed around
+// them and pawns moving diagonally towards the other corner
+type diagonalVariant struct{}
+
+func (diagonalVariant) Name() string {
+	return "diagonal"
+}
+
+func (diagonalVariant) StartingPosition() [64]Piece {
+	return [64]Piece{
+		WKing, WRook, WBishop, WPawn, WPawn, Clear, Clear, Clear,
+		WRook, WQueen, WKnight, WPawn, Clear, Clear, Clear, Clear,
+		WKnight, WBishop, WPawn, Clear, Clear, Clear, Clear, Clear,
+		WPawn, WPawn, Clear, Clear, Clear, Clear, Clear, BPawn,
+		WPawn, Clear, Clear, Clear, Clear, Clear, BPawn, BPawn,
+		Clear, Clear, Clear, Clear, Clear, BPawn, BBishop, BKnight,
+		Clear, Clear, Clear, Clear, BPawn, BKnight, BQueen, BRook,
+		Clear, Clear, Clear, BPawn, BPawn, BBishop, BRook, BKing,
+	}
+}
+
+var (
+	diagonalWhitePawns = PawnRules{Push: DownRight, Captures: [2]Direction{Down, Right}}
+	diagonalBlackPawns = PawnRules{Push: UpLeft, Captures: [2]Direction{Up, Left}}
+)
+
+func (diagonalVariant) Pawns(colour Colour) PawnRules {
+	if colour == White {
+		return diagonalWhitePawns
+	}
+	return diagonalBlackPawns
+}
+
+func (variant diagonalVariant) IsPawnStart(colour Colour, pos Position) bool {
+	piece := WPawn
+	if colour == Black {
+		piece = BPawn
+	}
+	return variant.StartingPosition()[positionToIndex(pos)] == piece
+}
+
+// distance from the pawn's own corner
+func (diagonalVariant) PawnAdvance(colour Colour, pos Position) int {
+	if colour == White {
+		return int(pos.X+pos.Y) - 3
+	}
+	return 14 - int(pos.X+pos.Y) - 3
+}
+
+// pawns get stuck in the far corner
+func (diagonalVariant) IsPromotionSquare(colour Colour, pos Position) bool {
+	return false
+}
+
+func (variant diagonalVariant) Winner(board *BoardState) WinState {
+	return board.mateOrDraw(variant.MoveRuleLimit())
+}
+
+func (diagonalVariant) MoveRuleLimit() uint16 {
+	return 50
+}
@@ -0,0 +1,148 @@
+package board_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"chess/board"
+)
+
+// pawns move up the board and capture diagonally, everything else is
+// borrowed from the default variant
+type straightPawns struct {
+	board.Variant
+}
+
+func (straightPawns) Name() string {
+	return "straight"
+}
+
+func (straightPawns) Pawns(colour board.Colour) board.PawnRules {
+	if colour == board.White {
+		return board.PawnRules{
+			Push:     board.Down,
+			Captures: [2]board.Direction{board.DownLeft, board.DownRight},
+		}
+	}
+	return board.PawnRules{
+		Push:     board.Up,
+		Captures: [2]board.Direction{board.UpLeft, board.UpRight},
+	}
+}
+
+func (straightPawns) IsPawnStart(colour board.Colour, pos board.Position) bool {
+	if colour == board.White {
+		return pos.Y == 1
+	}
+	return pos.Y == 6
+}
+
+// white wins as soon as black has replied
+type quickWin struct {
+	board.Variant
+}
+
+func (quickWin) Winner(boardState *board.BoardState) board.WinState {
+	if boardState.MoveCounter >= 2 {
+		return board.WhiteWin
+	}
+	return board.NoWin
+}
+
+func Test_variant(test *testing.T) {
+	test.Run("test variant from name", func(test *testing.T) {
+		variant, err := board.VariantFromName("diagonal")
+		assertSuccess(test, err)
+		assertStrEquality(test, board.Diagonal.Name(), variant.Name())
+
+		variant, err = board.VariantFromName("")
+		assertSuccess(test, err)
+		assertStrEquality(test, board.DefaultVariant.Name(), variant.Name())
+
+		_, err = board.VariantFromName("nope")
+		assertFailure(test, err)
+	})
+
+	test.Run("test pawn rules come from the variant", func(test *testing.T) {
+		variant := straightPawns{board.Diagonal}
+		boardState, err := board.ParseVariantFen("k7/3p4/4P3/8/8/8/8/7K w 0", variant)
+		assertSuccess(test, err)
+		err = boardState.Init()
+		assertSuccess(test, err)
+		assertStrEquality(test, "straight", boardState.Variant().Name())
+
+		pawnMoves := make([]string, 0)
+		for _, move := range boardState.LegalMoves {
+			if move.From.CoordsString() == "E2" {
+				pawnMoves = append(pawnMoves, move.Serialise())
+			}
+		}
+		slices.Sort(pawnMoves)
+		expected := []string{"E2:D3", "E2:E3", "E2:E4"}
+		if !slices.Equal(pawnMoves, expected) {
+			test.Fatalf("expected pawn moves %v\nreceived: %v", expected, pawnMoves)
+		}
+
+		black, err := board.StringToPosition("D3")
+		assertSuccess(test, err)
+		assertBoolEq(test, true, boardState.IsSquareAttacked(
+			board.Position{X: 3, Y: 1}, board.Black))
+		assertBoolEq(test, false, boardState.IsSquareAttacked(
+			board.Position{X: 4, Y: 1}, board.Black))
+		assertBoolEq(test, true, slices.Contains(
+			boardState.Attackers(board.Position{X: 3, Y: 1}), black))
+	})
+
+	test.Run("test win conditions come from the variant", func(test *testing.T) {
+		boardState := board.NewVariantBoard(quickWin{board.Diagonal})
+		err := boardState.Init()
+		assertSuccess(test, err)
+
+		for range 2 {
+			assertBoolEq(test, true, boardState.HasWinnerImpl() == board.NoWin)
+			err = boardState.MakeMove(boardState.LegalMoves[0])
+			assertSuccess(test, err)
+		}
+		assertBoolEq(test, true, boardState.HasWinnerImpl() == board.WhiteWin)
+
+		// clones keep the variant
+		assertBoolEq(test, true, boardState.Clone().HasWinnerImpl() == board.WhiteWin)
+	})
+}
+
+func Test_rules(test *testing.T) {
+	test.Run("test rules match the variants", func(test *testing.T) {
+		test.Parallel()
+		names := make([]string, 0)
+		for _, variant := range board.Variants() {
+			names = append(names, variant.Name())
+		}
+		assertStrEquality(test, "chess960 diagonal standard", strings.Join(names, " "))
+
+		diagonal := board.Rules(board.Diagonal)
+		start := board.NewBoard()
+		assertStrEquality(test, start.Fen(), diagonal.StartingFen)
+		assertNumEq(test, 50, int(diagonal.MoveRuleLimit))
+		assertNumEq(test, 0, len(diagonal.SpecialMoves))
+		assertNumEq(test, 0, len(diagonal.WhitePawns.PromotionSquares))
+		// white pushes towards the A file and rank 8
+		assertNumEq(test, 1, int(diagonal.WhitePawns.Push.X))
+		assertNumEq(test, 1, int(diagonal.WhitePawns.Push.Y))
+		assertBoolEq(test, false, diagonal.Shuffled)
+
+		standard := board.Rules(board.Standard)
+		assertNumEq(test, 100, int(standard.MoveRuleLimit))
+		assertStrEquality(test, "kingside castle,queenside castle,en passant",
+			strings.Join(standard.SpecialMoves, ","))
+		assertNumEq(test, 8, len(standard.WhitePawns.PromotionSquares))
+		assertStrEquality(test, "A8", standard.WhitePawns.PromotionSquares[0])
+		assertStrEquality(test, "A1", standard.BlackPawns.PromotionSquares[0])
+		assertStrEquality(test, "queen rook bishop knight",
+			strings.Join(standard.WhitePawns.PromotionPieces, " "))
+		assertNumEq(test, 0, int(standard.WhitePawns.Push.X))
+		assertNumEq(test, 1, int(standard.WhitePawns.Push.Y))
+
+		assertBoolEq(test, true, board.Rules(board.Chess960).Shuffled)
+	})
+}
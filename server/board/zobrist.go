@@ -0,0 +1,61 @@
+package board
+
+import "math/rand/v2"
+
+// zobrist keys are generated from a fixed seed so hashes are stable
+// between runs and can be stored, e.g. in an opening book
+
+var (
+	// indexed by square then (colour - 1) * 6 + piece type
+	zobristPieces    [64][12]uint64
+	zobristBlackMove uint64
+)
+
+func init() {
+	random := rand.New(rand.NewPCG(0x636865737374, 0x68696e67))
+	for square := range zobristPieces {
+		for piece := range zobristPieces[square] {
+			zobristPieces[square][piece] = random.Uint64()
+		}
+	}
+	zobristBlackMove = random.Uint64()
+}
+
+func zobristPieceIndex(piece Piece) int {
+	return (int(piece.Colour())-1)*6 + int(piece.PieceType())
+}
+
+// Hash of the piece placement and side to move, the move counters are not
+// included so the same position reached at different times hashes equally
+func (board *BoardState) Hash() uint64 {
+	var hash uint64
+	for square, piece := range board.State {
+		if piece.IsClear() {
+			continue
+		}
+		hash ^= zobristPieces[square][zobristPieceIndex(piece)]
+	}
+	if board.WhoseMove() == Black {
+		hash ^= zobristBlackMove
+	}
+	return hash
+}
+
+// How many times the current position has come up, including now. Only
+// positions since the last capture or pawn move are kept as none before
+// them can be reached again
+func (board *BoardState) RepetitionCount() int {
+	if len(board.positions) == 0 {
+		return 1
+	}
+	current := board.positions[len(board.positions)-1]
+	count := 0
+	// the side to move is part of the hash so only every other position
+	// can match
+	for i := len(board.positions) - 1; i >= 0; i -= 2 {
+		if board.positions[i] == current {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,165 @@
+package book
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+
+	"chess/board"
+)
+
+// Entries use the polyglot layout, 16 bytes big endian: the position hash,
+// the move, its weight and a learn field which is unused. Keys are the
+// board's zobrist hashes rather than polyglot's so books from other
+// programs won't work
+
+const entrySize = 16
+
+type Entry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+// 3 bits each for to x, to y, from x, from y then the promotion
+func EncodeMove(move board.Move) uint16 {
+	return uint16(move.To.X) |
+		uint16(move.To.Y)<<3 |
+		uint16(move.From.X)<<6 |
+		uint16(move.From.Y)<<9 |
+		uint16(move.Promotion)<<12
+}
+
+func DecodeMove(encoded uint16) board.Move {
+	return board.Move{
+		To:        board.Position{X: int8(encoded & 7), Y: int8(encoded >> 3 & 7)},
+		From:      board.Position{X: int8(encoded >> 6 & 7), Y: int8(encoded >> 9 & 7)},
+		Promotion: board.Promotion(encoded >> 12 & 7),
+	}
+}
+
+// Sorted by key so lookups are a binary search
+type Book struct {
+	entries []Entry
+}
+
+func New(entries []Entry) *Book {
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, compareEntries)
+	return &Book{entries: entries}
+}
+
+// highest weight first within a key
+func compareEntries(a, b Entry) int {
+	if a.Key != b.Key {
+		if a.Key < b.Key {
+			return -1
+		}
+		return 1
+	}
+	return int(b.Weight) - int(a.Weight)
+}
+
+func Read(reader io.Reader) (*Book, error) {
+	entries := make([]Entry, 0)
+	buffered := bufio.NewReader(reader)
+	var bytes [entrySize]byte
+	for {
+		_, err := io.ReadFull(buffered, bytes[:])
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Key:    binary.BigEndian.Uint64(bytes[0:8]),
+			Move:   binary.BigEndian.Uint16(bytes[8:10]),
+			Weight: binary.BigEndian.Uint16(bytes[10:12]),
+			Learn:  binary.BigEndian.Uint32(bytes[12:16]),
+		})
+	}
+	return New(entries), nil
+}
+
+func Load(path string) (*Book, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+func (book *Book) Write(writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	var bytes [entrySize]byte
+	for _, entry := range book.entries {
+		binary.BigEndian.PutUint64(bytes[0:8], entry.Key)
+		binary.BigEndian.PutUint16(bytes[8:10], entry.Move)
+		binary.BigEndian.PutUint16(bytes[10:12], entry.Weight)
+		binary.BigEndian.PutUint32(bytes[12:16], entry.Learn)
+		_, err := buffered.Write(bytes[:])
+		if err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+func (book *Book) Len() int {
+	return len(book.entries)
+}
+
+// Entries for the position, highest weight first
+func (book *Book) Entries(position *board.BoardState) []Entry {
+	key := position.Hash()
+	start, _ := slices.BinarySearchFunc(book.entries, key, func(entry Entry, key uint64) int {
+		if entry.Key < key {
+			return -1
+		} else if entry.Key > key {
+			return 1
+		}
+		return 0
+	})
+	end := start
+	for end < len(book.entries) && book.entries[end].Key == key {
+		end++
+	}
+	return book.entries[start:end]
+}
+
+// Picks a legal book move at random weighted by how good it has been,
+// false when the position is out of book
+func (book *Book) Pick(position *board.BoardState) (board.Move, bool) {
+	moves := make([]board.Move, 0)
+	weights := make([]int, 0)
+	total := 0
+	for _, entry := range book.Entries(position) {
+		move := DecodeMove(entry.Move)
+		// guards against hash collisions
+		if entry.Weight == 0 || !position.IsLegal(move) {
+			continue
+		}
+		moves = append(moves, move)
+		weights = append(weights, int(entry.Weight))
+		total += int(entry.Weight)
+	}
+	if total == 0 {
+		return board.Move{}, false
+	}
+
+	choice := rand.IntN(total)
+	for i, weight := range weights {
+		if choice < weight {
+			return moves[i], true
+		}
+		choice -= weight
+	}
+	return moves[len(moves)-1], true
+}
@@ -0,0 +1,163 @@
+package book
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"chess/board"
+	"chess/model"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newStart(t *testing.T) *board.BoardState {
+	t.Helper()
+	position := board.NewBoard()
+	err := position.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return position
+}
+
+func TestEncodeMove(t *testing.T) {
+	position := newStart(t)
+	for _, move := range position.LegalMoves {
+		if decoded := DecodeMove(EncodeMove(move)); decoded != move {
+			t.Errorf("Expected %s, got %s", move.Serialise(), decoded.Serialise())
+		}
+	}
+	promotion := board.Move{
+		From:      board.Position{X: 1, Y: 6},
+		To:        board.Position{X: 1, Y: 7},
+		Promotion: board.KnightPromotion,
+	}
+	if DecodeMove(EncodeMove(promotion)) != promotion {
+		t.Error("Expected promotion to survive encoding")
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	builder := NewBuilder(2)
+	err := builder.Add([]string{"D1:C2", "E8:F7"}, "1-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = builder.Add([]string{"D1:C2", "E8:F7"}, "1-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the loser's moves are left out
+	err = builder.Add([]string{"E1:D2", "E8:F7"}, "0-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openingBook := builder.Book()
+	start := newStart(t)
+	entries := openingBook.Entries(start)
+	if len(entries) != 1 || entries[0].Weight != 4 {
+		t.Fatalf("Expected one start move with weight 4, got %+v", entries)
+	}
+	move := DecodeMove(entries[0].Move)
+	if move.Serialise() != "D1:C2" {
+		t.Errorf("Expected D1:C2, got %s", move.Serialise())
+	}
+
+	picked, found := openingBook.Pick(start)
+	if !found || picked != move {
+		t.Errorf("Expected to pick D1:C2, got %s", picked.Serialise())
+	}
+
+	next, err := start.Peek(move)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err = next.Peek(next.LegalMoves[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := openingBook.Pick(next); found {
+		t.Error("Expected position past the max ply to be out of book")
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	builder := NewBuilder(0)
+	err := builder.Add([]string{"D1:C2", "E8:F7", "E1:D2"}, "1/2-1/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	openingBook := builder.Book()
+
+	buffer := bytes.Buffer{}
+	err = openingBook.Write(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buffer.Len() != 3*entrySize {
+		t.Fatalf("Expected 3 entries to be written, got %d bytes", buffer.Len())
+	}
+
+	read, err := Read(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(read.entries))
+	}
+	for i, entry := range read.entries {
+		if entry != openingBook.entries[i] {
+			t.Errorf("Expected entry %+v, got %+v", openingBook.entries[i], entry)
+		}
+	}
+}
+
+func TestAddStoredGames(t *testing.T) {
+	ddl, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	_, err = db.Exec(string(ddl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := model.New(db)
+	endedAt := time.Now().UTC().Add(-time.Hour)
+	for _, moves := range []string{"D1:C2 E8:F7", "D1:C2 not-a-move"} {
+		err = queries.CreateGame(context.Background(), model.CreateGameParams{
+			ID:        uuid.New(),
+			WhiteID:   uuid.New(),
+			BlackID:   uuid.New(),
+			Result:    "1-0",
+			Condition: "White win",
+			Moves:     moves,
+			CreatedAt: endedAt,
+			EndedAt:   endedAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	builder := NewBuilder(0)
+	added, err := builder.AddStoredGames(context.Background(), queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Errorf("Expected the broken game to be skipped, added %d", added)
+	}
+}
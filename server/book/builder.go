@@ -0,0 +1,86 @@
+package book
+
+import (
+	"fmt"
+	"math"
+
+	"chess/board"
+)
+
+const DefaultMaxPly = 16
+
+type entryKey struct {
+	key  uint64
+	move uint16
+}
+
+// Builds a book from finished games. Like polyglot's builder a move gets two
+// points each time the side which played it won and one for a draw, so
+// moves which only lost are left out
+type Builder struct {
+	maxPly  int
+	weights map[entryKey]int
+}
+
+func NewBuilder(maxPly int) *Builder {
+	if maxPly <= 0 {
+		maxPly = DefaultMaxPly
+	}
+	return &Builder{maxPly: maxPly, weights: make(map[entryKey]int)}
+}
+
+// moves are in the serialised coords format, result is a pgn result
+func (builder *Builder) Add(moves []string, result string) error {
+	var whitePoints, blackPoints int
+	switch result {
+	case "1-0":
+		whitePoints = 2
+	case "0-1":
+		blackPoints = 2
+	case "1/2-1/2":
+		whitePoints, blackPoints = 1, 1
+	default:
+		return nil
+	}
+
+	position := board.NewBoard()
+	err := position.Init()
+	if err != nil {
+		return err
+	}
+	for ply, str := range moves {
+		if ply >= builder.maxPly {
+			break
+		}
+		move, err := board.DeserialiseMove(str)
+		if err != nil {
+			return err
+		}
+
+		points := whitePoints
+		if position.WhoseMove() == board.Black {
+			points = blackPoints
+		}
+		if points > 0 {
+			builder.weights[entryKey{key: position.Hash(), move: EncodeMove(move)}] += points
+		}
+
+		err = position.MakeMove(move)
+		if err != nil {
+			return fmt.Errorf("move %d %s: %w", ply+1, str, err)
+		}
+	}
+	return nil
+}
+
+func (builder *Builder) Book() *Book {
+	entries := make([]Entry, 0, len(builder.weights))
+	for key, weight := range builder.weights {
+		entries = append(entries, Entry{
+			Key:    key.key,
+			Move:   key.move,
+			Weight: uint16(min(weight, math.MaxUint16)),
+		})
+	}
+	return New(entries)
+}
@@ -0,0 +1,53 @@
+package book
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"chess/board"
+	"chess/model"
+)
+
+const pageSize = 500
+
+// Adds every stored game to the builder, games which can't be replayed are
+// logged and skipped. Returns how many games were added
+func (builder *Builder) AddStoredGames(ctx context.Context, db *model.Queries) (int, error) {
+	params := model.ListGamesEndedBetweenParams{
+		EndedBefore: time.Now().UTC(),
+		Limit:       pageSize,
+	}
+
+	added := 0
+	for {
+		games, err := db.ListGamesEndedBetween(ctx, params)
+		if err != nil {
+			return added, err
+		}
+
+		for _, game := range games {
+			// books are only built for the default variant
+			variant, err := board.VariantFromName(game.Variant)
+			if err != nil || variant != board.DefaultVariant {
+				continue
+			}
+			err = builder.Add(strings.Fields(game.Moves), game.Result)
+			if err != nil {
+				slog.WarnContext(ctx, "skipping game",
+					slog.String("gameId", game.ID.String()),
+					slog.Any("error", err))
+				continue
+			}
+			added++
+		}
+
+		if len(games) < pageSize {
+			return added, nil
+		}
+		last := games[len(games)-1]
+		params.CursorEndedAt = last.EndedAt
+		params.CursorID = last.ID
+	}
+}
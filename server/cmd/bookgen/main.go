@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"chess/book"
+	"chess/model"
+
+	_ "github.com/mattn/go-sqlite3"
+	_ "github.com/tursodatabase/libsql-client-go/libsql"
+)
+
+// builds an opening book from the stored games, reads from the libsql db in
+// LIB_SQL_DB_URL and LIB_SQL_AUTH_TOKEN unless -sqlite is passed
+func main() {
+	log.SetFlags(0)
+
+	maxPly := flag.Int("ply", book.DefaultMaxPly, "how many moves from the start of each game to include")
+	sqlitePath := flag.String("sqlite", "", "read games from a local sqlite file instead")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		log.Fatal("usage: bookgen [-ply n] [-sqlite path] <output file>")
+	}
+
+	var db *sql.DB
+	var err error
+	if *sqlitePath != "" {
+		db, err = sql.Open("sqlite3", *sqlitePath)
+	} else {
+		db, err = sql.Open("libsql", fmt.Sprintf("%s?authToken=%s",
+			os.Getenv("LIB_SQL_DB_URL"), os.Getenv("LIB_SQL_AUTH_TOKEN")))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	builder := book.NewBuilder(*maxPly)
+	added, err := builder.AddStoredGames(context.Background(), model.New(db))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Create(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	openingBook := builder.Book()
+	err = openingBook.Write(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d entries from %d games", openingBook.Len(), added)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"chess/schema"
+)
+
+// writes typescript definitions of the wire messages for the frontend
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		log.Fatal("usage: schemagen <output file>")
+	}
+
+	err := os.WriteFile(os.Args[1], []byte(schema.Messages.TypeScript()), 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
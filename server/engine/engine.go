@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"chess/board"
+	"chess/eval"
+)
+
+const (
+	DefaultMaxDepth  = 4
+	DefaultMoveTime  = time.Second
+	defaultTableSize = 1 << 18
+
+	infinity = eval.MateScore + 1
+	// scores past this are mates, distance to mate is taken off the score
+	mateThreshold = eval.MateScore - 1000
+	// tablebase wins are certain but the distance to mate isn't known so
+	// they score just below any mate
+	tablebaseWinScore = mateThreshold - 1000
+)
+
+var errSearchAborted = errors.New("search aborted")
+
+type Options struct {
+	// zero uses the defaults
+	MaxDepth int
+	MoveTime time.Duration
+	// optional, low piece positions are scored exactly instead of searched
+	Tablebase Tablebase
+}
+
+type Result struct {
+	Move board.Move
+	// centipawns from the perspective of the side to move
+	Score int
+	Depth int
+	Nodes uint64
+}
+
+// Iterative deepening alpha-beta search, not safe for concurrent use
+type Engine struct {
+	options Options
+	table   *transpositionTable
+
+	nodes    uint64
+	deadline time.Time
+	ctx      context.Context
+}
+
+func New(options Options) *Engine {
+	if options.MaxDepth <= 0 {
+		options.MaxDepth = DefaultMaxDepth
+	}
+	if options.MoveTime <= 0 {
+		options.MoveTime = DefaultMoveTime
+	}
+	return &Engine{
+		options: options,
+		table:   newTranspositionTable(defaultTableSize),
+	}
+}
+
+// forget positions from previous searches, e.g. when starting a new game
+func (engine *Engine) Reset() {
+	engine.table.clear()
+}
+
+// Finds the best move for the side to move, the position is not modified.
+// Each depth is searched fully before the next, if time runs out or the
+// context is cancelled the result from the last full depth is returned
+func (engine *Engine) Search(ctx context.Context, position *board.BoardState) (Result, error) {
+	if len(position.LegalMoves) == 0 {
+		return Result{}, errors.New("no legal moves to search")
+	}
+
+	boardState := position.Clone()
+	engine.ctx = ctx
+	engine.deadline = time.Now().Add(engine.options.MoveTime)
+	engine.nodes = 0
+
+	// always have a move to play, even if the first depth doesn't finish
+	best := Result{Move: boardState.LegalMoves[0]}
+	for depth := 1; depth <= engine.options.MaxDepth; depth++ {
+		move, score, err := engine.searchRoot(boardState, depth)
+		if errors.Is(err, errSearchAborted) {
+			break
+		}
+		if err != nil {
+			return Result{}, err
+		}
+
+		best = Result{Move: move, Score: score, Depth: depth}
+		// no point looking deeper once a forced mate is found
+		if score > mateThreshold || score < -mateThreshold {
+			break
+		}
+	}
+
+	best.Nodes = engine.nodes
+	return best, nil
+}
+
+func (engine *Engine) aborted() bool {
+	// checking the clock every node is slow
+	if engine.nodes%1024 != 0 {
+		return false
+	}
+	return time.Now().After(engine.deadline) || engine.ctx.Err() != nil
+}
+
+func (engine *Engine) searchRoot(boardState *board.BoardState, depth int) (board.Move, int, error) {
+	alpha := -infinity
+	var bestMove board.Move
+
+	for _, move := range engine.orderMoves(boardState, boardState.Hash()) {
+		err := boardState.MakeMove(move)
+		if err != nil {
+			return board.Move{}, 0, err
+		}
+		score, err := engine.negamax(boardState, depth-1, 1, -infinity, -alpha)
+		boardState.UnmakeMove()
+		if err != nil {
+			return board.Move{}, 0, err
+		}
+
+		score = -score
+		if score > alpha {
+			alpha = score
+			bestMove = move
+		}
+	}
+
+	engine.table.put(tableEntry{
+		hash:  boardState.Hash(),
+		depth: depth,
+		score: alpha,
+		bound: exactBound,
+		move:  bestMove,
+	})
+	return bestMove, alpha, nil
+}
+
+func (engine *Engine) negamax(
+	boardState *board.BoardState,
+	depth int,
+	ply int,
+	alpha int,
+	beta int,
+) (int, error) {
+	engine.nodes += 1
+	if engine.aborted() {
+		return 0, errSearchAborted
+	}
+
+	switch boardState.HasWinnerImpl() {
+	case board.WhiteWin, board.BlackWin:
+		// the side to move has been mated, quicker mates score higher
+		return -eval.MateScore + ply, nil
+	case board.Stalemate, board.MoveRuleDraw, board.DeadPosition:
+		return eval.DrawScore, nil
+	}
+
+	if wdl, found := ProbeTablebase(engine.options.Tablebase, boardState); found {
+		switch wdl {
+		case Win:
+			return tablebaseWinScore - ply, nil
+		case Loss:
+			return -tablebaseWinScore + ply, nil
+		default:
+			return eval.DrawScore, nil
+		}
+	}
+
+	if depth == 0 {
+		return eval.Relative(boardState), nil
+	}
+
+	hash := boardState.Hash()
+	if entry, found := engine.table.get(hash); found && entry.depth >= depth {
+		score := fromTableScore(entry.score, ply)
+		switch {
+		case entry.bound == exactBound:
+			return score, nil
+		case entry.bound == lowerBound && score >= beta:
+			return score, nil
+		case entry.bound == upperBound && score <= alpha:
+			return score, nil
+		}
+	}
+
+	originalAlpha := alpha
+	best := -infinity
+	var bestMove board.Move
+	for _, move := range engine.orderMoves(boardState, hash) {
+		err := boardState.MakeMove(move)
+		if err != nil {
+			return 0, err
+		}
+		score, err := engine.negamax(boardState, depth-1, ply+1, -beta, -alpha)
+		boardState.UnmakeMove()
+		if err != nil {
+			return 0, err
+		}
+
+		score = -score
+		if score > best {
+			best = score
+			bestMove = move
+		}
+		alpha = max(alpha, score)
+		if alpha >= beta {
+			break
+		}
+	}
+
+	bound := exactBound
+	if best <= originalAlpha {
+		bound = upperBound
+	} else if best >= beta {
+		bound = lowerBound
+	}
+	engine.table.put(tableEntry{
+		hash:  hash,
+		depth: depth,
+		score: toTableScore(best, ply),
+		bound: bound,
+		move:  bestMove,
+	})
+	return best, nil
+}
+
+// mate scores are stored as the distance from the position rather than
+// from the root so they stay correct when found at a different ply
+func toTableScore(score int, ply int) int {
+	if score > mateThreshold {
+		return score + ply
+	}
+	if score < -mateThreshold {
+		return score - ply
+	}
+	return score
+}
+
+func fromTableScore(score int, ply int) int {
+	if score > mateThreshold {
+		return score - ply
+	}
+	if score < -mateThreshold {
+		return score + ply
+	}
+	return score
+}
+
+// the move from the table first then captures of the most valuable piece
+// by the least valuable
+func (engine *Engine) orderMoves(boardState *board.BoardState, hash uint64) []board.Move {
+	moves := slices.Clone(boardState.LegalMoves)
+
+	var tableMove board.Move
+	entry, hasTableMove := engine.table.get(hash)
+	if hasTableMove {
+		tableMove = entry.move
+	}
+
+	priority := func(move board.Move) int {
+		if hasTableMove && move == tableMove {
+			return infinity
+		}
+		captured := boardState.GetSquare(move.To)
+		if captured.IsClear() {
+			return 0
+		}
+		mover := boardState.GetSquare(move.From)
+		return 10*eval.PieceValue(captured.PieceType()) - eval.PieceValue(mover.PieceType())
+	}
+
+	slices.SortStableFunc(moves, func(a, b board.Move) int {
+		return priority(b) - priority(a)
+	})
+	return moves
+}
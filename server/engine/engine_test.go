@@ -0,0 +1,272 @@
+package engine_test
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"chess/board"
+	"chess/engine"
+	"chess/eval"
+)
+
+func isMate(test *testing.T, boardState *board.BoardState, move board.Move) bool {
+	test.Helper()
+	next, err := boardState.Peek(move)
+	if err != nil {
+		test.Fatal(err)
+	}
+	win := next.HasWinnerImpl()
+	return win == board.WhiteWin || win == board.BlackWin
+}
+
+func Test_search(test *testing.T) {
+	test.Run("test finds mate in one", func(test *testing.T) {
+		test.Parallel()
+		searched := 0
+		for searched < 10 {
+			boardState := board.NewBoard()
+			err := boardState.Init()
+			if err != nil {
+				test.Fatal(err)
+			}
+
+			for boardState.HasWinner() == board.NoWin {
+				hasMate := false
+				for _, move := range boardState.LegalMoves {
+					if isMate(test, boardState, move) {
+						hasMate = true
+						break
+					}
+				}
+
+				if hasMate {
+					fen := boardState.Fen()
+					result, err := engine.New(engine.Options{MaxDepth: 3}).
+						Search(context.Background(), boardState)
+					if err != nil {
+						test.Fatal(err)
+					}
+					if !isMate(test, boardState, result.Move) {
+						test.Fatalf("expected mate to be found, played %s\n%s",
+							result.Move.String(), boardState.String())
+					}
+					if result.Score < eval.MateScore-10 {
+						test.Fatalf("expected mate score\nreceived: %d", result.Score)
+					}
+					if boardState.Fen() != fen {
+						test.Fatal("search modified the position")
+					}
+					searched += 1
+					break
+				}
+
+				moves := boardState.LegalMoves
+				err := boardState.MakeMove(moves[rand.IntN(len(moves))])
+				if err != nil {
+					test.Fatal(err)
+				}
+			}
+		}
+	})
+
+	test.Run("test takes a free queen", func(test *testing.T) {
+		test.Parallel()
+		// the black queen on E4 can be taken by the white knight on F2
+		boardState, err := board.ParseFen("k7/2n5/8/3Q4/8/8/8/7K w 0")
+		if err != nil {
+			test.Fatal(err)
+		}
+		err = boardState.Init()
+		if err != nil {
+			test.Fatal(err)
+		}
+
+		result, err := engine.New(engine.Options{MaxDepth: 2}).
+			Search(context.Background(), boardState)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if result.Move.To.CoordsString() != "E4" {
+			test.Fatalf("expected queen capture\nreceived: %s", result.Move.String())
+		}
+	})
+
+	test.Run("test stops at the move time", func(test *testing.T) {
+		test.Parallel()
+		boardState := board.NewBoard()
+		err := boardState.Init()
+		if err != nil {
+			test.Fatal(err)
+		}
+
+		start := time.Now()
+		result, err := engine.New(engine.Options{MaxDepth: 50, MoveTime: 200 * time.Millisecond}).
+			Search(context.Background(), boardState)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			test.Fatalf("expected search to stop after 200ms\nreceived: %s", elapsed)
+		}
+		if !boardState.IsLegal(result.Move) {
+			test.Fatalf("expected a legal move\nreceived: %s", result.Move.String())
+		}
+	})
+}
+
+func Test_tablebase(test *testing.T) {
+	cases := []struct {
+		fen   string
+		found bool
+	}{
+		{"k7/8/8/8/8/8/8/7K w 0", true},
+		{"k7/2n5/8/8/8/8/8/7K w 0", true},
+		{"k7/2n5/8/8/8/8/8/6BK w 0", false},
+		{"k7/8/8/3Q4/8/8/8/7K w 0", false},
+	}
+	for _, testCase := range cases {
+		boardState, err := board.ParseFen(testCase.fen)
+		if err != nil {
+			test.Fatal(err)
+		}
+		wdl, found := engine.ProbeTablebase(engine.InsufficientMaterial{}, boardState)
+		if found != testCase.found || wdl != engine.Draw {
+			test.Errorf("%s: expected found %t\nreceived: %t %d", testCase.fen,
+				testCase.found, found, wdl)
+		}
+	}
+
+	test.Run("test search scores tablebase draws", func(test *testing.T) {
+		boardState, err := board.ParseFen("k7/2n5/8/3Q4/8/8/8/7K w 0")
+		if err != nil {
+			test.Fatal(err)
+		}
+		err = boardState.Init()
+		if err != nil {
+			test.Fatal(err)
+		}
+
+		result, err := engine.New(engine.Options{
+			MaxDepth:  2,
+			Tablebase: engine.InsufficientMaterial{},
+		}).Search(context.Background(), boardState)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if result.Move.To.CoordsString() != "E4" || result.Score != eval.DrawScore {
+			test.Fatalf("expected drawn queen capture\nreceived: %s %d",
+				result.Move.String(), result.Score)
+		}
+	})
+}
+
+func hasMateInOne(test *testing.T, boardState *board.BoardState) bool {
+	test.Helper()
+	for _, move := range boardState.LegalMoves {
+		if isMate(test, boardState, move) {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_forced_mate(test *testing.T) {
+	test.Run("test finds fool's mate", func(test *testing.T) {
+		test.Parallel()
+		boardState := board.NewVariantBoard(board.Standard)
+		if err := boardState.Init(); err != nil {
+			test.Fatal(err)
+		}
+		for _, str := range []string{"F2:F3", "E7:E5", "G2:G4"} {
+			move, err := board.DeserialiseMove(str)
+			if err != nil {
+				test.Fatal(err)
+			}
+			if err = boardState.MakeMove(move); err != nil {
+				test.Fatal(err)
+			}
+		}
+
+		fen := boardState.Fen()
+		mate, found, err := engine.FindForcedMate(boardState, 2)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if !found || mate.MovesToMate != 1 || mate.Move.Serialise() != "D8:H4" {
+			test.Fatalf("expected mate in one with D8:H4\nreceived: %t %+v", found, mate)
+		}
+		if boardState.Fen() != fen {
+			test.Fatal("search modified the position")
+		}
+
+		start := board.NewVariantBoard(board.Standard)
+		if err = start.Init(); err != nil {
+			test.Fatal(err)
+		}
+		_, found, err = engine.FindForcedMate(start, 2)
+		if err != nil || found {
+			test.Fatalf("expected no mate from the start\nreceived: %t %v", found, err)
+		}
+	})
+
+	test.Run("test mates agree with checkmate detection", func(test *testing.T) {
+		test.Parallel()
+		boardState := board.NewBoard()
+		if err := boardState.Init(); err != nil {
+			test.Fatal(err)
+		}
+		records, err := boardState.Playouts(6, 200, 3)
+		if err != nil {
+			test.Fatal(err)
+		}
+
+		mateInTwos := 0
+		for _, record := range records {
+			position := board.NewBoard()
+			if err := position.Init(); err != nil {
+				test.Fatal(err)
+			}
+			// the last few positions are the ones mates are found in
+			start := max(0, len(record.Moves)-6)
+			for i, move := range record.Moves {
+				if i >= start {
+					mate, found, err := engine.FindForcedMate(position, 2)
+					if err != nil {
+						test.Fatal(err)
+					}
+					inOne := hasMateInOne(test, position)
+					if inOne != (found && mate.MovesToMate == 1) {
+						test.Fatalf("expected mate in one %t\nreceived: %t %+v\n%s",
+							inOne, found, mate, position.String())
+					}
+					if found && mate.MovesToMate == 2 {
+						mateInTwos++
+						next, err := position.Peek(mate.Move)
+						if err != nil {
+							test.Fatal(err)
+						}
+						for _, reply := range next.LegalMoves {
+							after, err := next.Peek(reply)
+							if err != nil {
+								test.Fatal(err)
+							}
+							if !hasMateInOne(test, after) {
+								test.Fatalf("expected mate after every reply to %s\n%s",
+									mate.Move.Serialise(), position.String())
+							}
+						}
+					}
+				}
+				if err := position.MakeMove(move); err != nil {
+					test.Fatal(err)
+				}
+			}
+		}
+		// the playouts are seeded so this doesn't change between runs
+		if mateInTwos == 0 {
+			test.Fatal("expected the playouts to reach a mate in two")
+		}
+	})
+}
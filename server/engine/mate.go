@@ -0,0 +1,90 @@
+package engine
+
+import "chess/board"
+
+// Exhaustive search for checkmates the side to move can force, unlike Search
+// there's no evaluation or pruning so a mate found is certain and none found
+// means there isn't one within the depth. Only practical for a few moves
+
+type ForcedMate struct {
+	// the first move of the mate
+	Move board.Move
+	// moves by the mating side, 1 for mate in one
+	MovesToMate int
+}
+
+// Finds the shortest mate in up to maxDepth moves for the side to move, the
+// position is not modified
+func FindForcedMate(position *board.BoardState, maxDepth int) (ForcedMate, bool, error) {
+	boardState := position.Clone()
+	attacker := boardState.WhoseMove()
+	for depth := 1; depth <= maxDepth; depth++ {
+		for _, move := range boardState.LegalMoves {
+			mates, err := matesAfter(boardState, move, attacker, depth)
+			if err != nil {
+				return ForcedMate{}, false, err
+			}
+			if mates {
+				return ForcedMate{Move: move, MovesToMate: depth}, true, nil
+			}
+		}
+	}
+	return ForcedMate{}, false, nil
+}
+
+// Whether the attacker's move mates or leaves every reply mated within
+// depth - 1 more moves
+func matesAfter(
+	boardState *board.BoardState,
+	move board.Move,
+	attacker board.Colour,
+	depth int,
+) (bool, error) {
+	err := boardState.MakeMove(move)
+	if err != nil {
+		return false, err
+	}
+	defer boardState.UnmakeMove()
+
+	win := boardState.HasWinnerImpl()
+	if win != board.NoWin {
+		return board.BoardResult(win).Winner == attacker, nil
+	}
+	if depth == 1 {
+		return false, nil
+	}
+
+	for _, reply := range boardState.LegalMoves {
+		mated, err := matedAfter(boardState, reply, attacker, depth-1)
+		if err != nil || !mated {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Whether the attacker has a mate within depth moves after the defender's
+// reply
+func matedAfter(
+	boardState *board.BoardState,
+	reply board.Move,
+	attacker board.Colour,
+	depth int,
+) (bool, error) {
+	err := boardState.MakeMove(reply)
+	if err != nil {
+		return false, err
+	}
+	defer boardState.UnmakeMove()
+
+	if boardState.HasWinnerImpl() != board.NoWin {
+		return false, nil
+	}
+	for _, move := range boardState.LegalMoves {
+		mates, err := matesAfter(boardState, move, attacker, depth)
+		if err != nil || mates {
+			return mates, err
+		}
+	}
+	return false, nil
+}
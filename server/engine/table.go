@@ -0,0 +1,46 @@
+package engine
+
+import "chess/board"
+
+type boundType uint8
+
+const (
+	exactBound boundType = iota
+	lowerBound
+	upperBound
+)
+
+type tableEntry struct {
+	hash  uint64
+	depth int
+	score int
+	bound boundType
+	move  board.Move
+}
+
+// fixed size and always replaced, good enough for the shallow searches the
+// engine does
+type transpositionTable struct {
+	entries []tableEntry
+}
+
+func newTranspositionTable(size int) *transpositionTable {
+	return &transpositionTable{entries: make([]tableEntry, size)}
+}
+
+func (table *transpositionTable) index(hash uint64) int {
+	return int(hash % uint64(len(table.entries)))
+}
+
+func (table *transpositionTable) get(hash uint64) (tableEntry, bool) {
+	entry := table.entries[table.index(hash)]
+	return entry, entry.hash == hash && entry.depth > 0
+}
+
+func (table *transpositionTable) put(entry tableEntry) {
+	table.entries[table.index(entry.hash)] = entry
+}
+
+func (table *transpositionTable) clear() {
+	clear(table.entries)
+}
@@ -0,0 +1,74 @@
+package engine
+
+import "chess/board"
+
+// There's no Syzygy prober yet, reading the tables needs a decoder for their
+// compressed format and the files themselves, neither of which the server
+// has. Until then InsufficientMaterial covers the endings which are always
+// drawn. A prober can be plugged in through Tablebase when it's added:
+// standard and chess960 positions can use every table, the diagonal
+// variant's pawns move differently so only the pawnless tables apply to it
+
+// result for the side to move
+type WDL int8
+
+const (
+	Loss WDL = iota - 1
+	Draw
+	Win
+)
+
+// Exact results for positions with few pieces. Implementations have to
+// account for the move rule, a win which takes too long is a draw
+type Tablebase interface {
+	// positions with more pieces, kings included, are never probed
+	MaxPieces() int
+	// false when the position isn't in the tablebase
+	Probe(position *board.BoardState) (WDL, bool)
+}
+
+// Bare kings, or a king and a single bishop or knight against a bare king,
+// where mate is impossible
+type InsufficientMaterial struct{}
+
+func (InsufficientMaterial) MaxPieces() int {
+	return 3
+}
+
+func (InsufficientMaterial) Probe(position *board.BoardState) (WDL, bool) {
+	for _, piece := range position.State {
+		if piece.IsClear() || piece.Is(board.King) ||
+			piece.Is(board.Bishop) || piece.Is(board.Knight) {
+			continue
+		}
+		return Draw, false
+	}
+	return Draw, position.PieceCount() <= 3
+}
+
+// Looks the position up if the tablebase covers it
+func ProbeTablebase(tablebase Tablebase, position *board.BoardState) (WDL, bool) {
+	if tablebase == nil || position.PieceCount() > tablebase.MaxPieces() {
+		return Draw, false
+	}
+	return tablebase.Probe(position)
+}
+
+// The result a tablebase decides for the position, NoWin when it isn't
+// covered
+func Adjudicate(tablebase Tablebase, position *board.BoardState) board.WinState {
+	wdl, found := ProbeTablebase(tablebase, position)
+	if !found {
+		return board.NoWin
+	}
+
+	toMove := position.WhoseMove()
+	switch wdl {
+	case Win:
+		return board.ColourToWinState(toMove)
+	case Loss:
+		return board.ColourToWinState(board.OppositeColour(toMove))
+	default:
+		return board.AdjudicatedDraw
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -21,6 +24,43 @@ type Env struct {
 	AppEnv            AppEnv
 	OauthClientId     string
 	OauthClientSecret string
+	// optional, comma separated user ids allowed to use admin endpoints
+	AdminIds []string
+	// optional, records why the server made each rule decision in a game
+	AuditRules bool
+	// optional, daily backups are written here when set
+	BackupDir string
+	// optional, how many backups to keep
+	BackupRetention int
+	// optional, path to an opening book for bots built with cmd/bookgen
+	OpeningBook string
+	// optional, "off", "message" or "context", see protocol.ParseCompressionMode
+	WebsocketCompression string
+	// optional, smallest websocket message in bytes that's compressed
+	WebsocketCompressionThreshold int
+	// optional, how long stale sessions are kept e.g. "30m", see
+	// game_server.SessionTTLs
+	UnconnectedSessionTTL time.Duration
+	IdleSessionTTL        time.Duration
+	EndedSessionTTL       time.Duration
+	// optional, how long games in progress are given to finish on shutdown
+	DrainPeriod time.Duration
+	// optional, games are shared with other nodes through this redis when set
+	RedisUrl string
+	// optional, identifies this node to the others, defaults to the hostname
+	NodeId string
+	// optional, most unfinished games a user can have at once, negative for
+	// no limit, see game_server.GameLimits
+	MaxLiveGames           int
+	MaxCorrespondenceGames int
+	// optional, how often clocks are sent to everyone in a live game,
+	// negative turns it off
+	ClockBroadcastInterval time.Duration
+	// optional, how long disconnected players get to come back, see
+	// game_server.GracePeriods
+	GraceDivisor int
+	GraceMin     time.Duration
+	GraceMax     time.Duration
 }
 
 func GetEnv() (env *Env, err error) {
@@ -63,11 +103,68 @@ func GetEnv() (env *Env, err error) {
 		}
 	}
 
+	adminIds := make([]string, 0)
+	for _, id := range strings.Split(os.Getenv("ADMIN_IDS"), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			adminIds = append(adminIds, id)
+		}
+	}
+
+	// zero falls back to the default retention
+	backupRetention, _ := strconv.Atoi(os.Getenv("BACKUP_RETENTION"))
+	// zero falls back to the default threshold
+	compressionThreshold, _ := strconv.Atoi(os.Getenv("WS_COMPRESSION_THRESHOLD"))
+	// zero falls back to the default ttl
+	unconnectedTTL, _ := time.ParseDuration(os.Getenv("UNCONNECTED_SESSION_TTL"))
+	idleTTL, _ := time.ParseDuration(os.Getenv("IDLE_SESSION_TTL"))
+	endedTTL, _ := time.ParseDuration(os.Getenv("ENDED_SESSION_TTL"))
+	nodeId := os.Getenv("NODE_ID")
+	if nodeId == "" {
+		// stays the same across restarts so the node can take its games back
+		nodeId, _ = os.Hostname()
+	}
+	// zero falls back to the default drain period
+	drainPeriod, _ := time.ParseDuration(os.Getenv("SHUTDOWN_DRAIN_PERIOD"))
+	// zero falls back to the default limits
+	maxLiveGames, _ := strconv.Atoi(os.Getenv("MAX_LIVE_GAMES"))
+	maxCorrespondenceGames, _ := strconv.Atoi(os.Getenv("MAX_CORRESPONDENCE_GAMES"))
+	// zero falls back to the default interval
+	clockBroadcastInterval, _ := time.ParseDuration(os.Getenv("CLOCK_BROADCAST_INTERVAL"))
+	// zero falls back to the default grace periods
+	graceDivisor, _ := strconv.Atoi(os.Getenv("DISCONNECT_GRACE_DIVISOR"))
+	graceMin, _ := time.ParseDuration(os.Getenv("DISCONNECT_GRACE_MIN"))
+	graceMax, _ := time.ParseDuration(os.Getenv("DISCONNECT_GRACE_MAX"))
+
 	return &Env{
 		DbUrl:             dbUrl,
 		DbAuthToken:       dbAuthToken,
 		AppEnv:            appEnv,
 		OauthClientId:     oauthClientId,
 		OauthClientSecret: oauthClientSecret,
+		AdminIds:          adminIds,
+		AuditRules:        os.Getenv("AUDIT_RULES") == "true",
+		BackupDir:         os.Getenv("BACKUP_DIR"),
+		BackupRetention:   backupRetention,
+		OpeningBook:       os.Getenv("OPENING_BOOK"),
+
+		WebsocketCompression:          os.Getenv("WS_COMPRESSION"),
+		WebsocketCompressionThreshold: compressionThreshold,
+
+		UnconnectedSessionTTL: unconnectedTTL,
+		IdleSessionTTL:        idleTTL,
+		EndedSessionTTL:       endedTTL,
+		DrainPeriod:           drainPeriod,
+
+		RedisUrl: os.Getenv("REDIS_URL"),
+		NodeId:   nodeId,
+
+		MaxLiveGames:           maxLiveGames,
+		MaxCorrespondenceGames: maxCorrespondenceGames,
+		ClockBroadcastInterval: clockBroadcastInterval,
+
+		GraceDivisor: graceDivisor,
+		GraceMin:     graceMin,
+		GraceMax:     graceMax,
 	}, nil
 }
@@ -0,0 +1,199 @@
+package eval
+
+import (
+	"chess/board"
+)
+
+// scores are in centipawns
+
+const (
+	MateScore = 100_000
+	DrawScore = 0
+)
+
+// indexed by board.PieceType
+var pieceValues = [...]int{
+	0,   // king
+	900, // queen
+	330, // bishop
+	320, // knight
+	100, // pawn
+	500, // rook
+}
+
+func PieceValue(pieceType board.PieceType) int {
+	return pieceValues[pieceType]
+}
+
+const (
+	mobilityWeight = 4
+	// per step a pawn has made towards the other corner
+	pawnAdvanceWeight = 6
+	// per friendly piece next to the king
+	kingShelterWeight = 12
+	// per square next to the king the opponent attacks
+	kingAttackWeight = 15
+)
+
+type Breakdown struct {
+	Material   int `json:"material"`
+	Mobility   int `json:"mobility"`
+	KingSafety int `json:"kingSafety"`
+	Total      int `json:"total"`
+}
+
+func sign(colour board.Colour) int {
+	if colour == board.White {
+		return 1
+	}
+	return -1
+}
+
+func material(boardState *board.BoardState) int {
+	score := 0
+	for i, piece := range boardState.State {
+		if piece.IsClear() {
+			continue
+		}
+		value := pieceValues[piece.PieceType()]
+		if piece.Is(board.Pawn) {
+			advance := boardState.Variant().PawnAdvance(piece.Colour(),
+				board.IndexToPosition(i))
+			value += max(advance, 0) * pawnAdvanceWeight
+		}
+		score += sign(piece.Colour()) * value
+	}
+	return score
+}
+
+var (
+	diagonals = [...]board.Vector{
+		board.DownRightVec, board.DownLeftVec, board.UpLeftVec, board.UpRightVec,
+	}
+	straights = [...]board.Vector{
+		board.UpVec, board.DownVec, board.LeftVec, board.RightVec,
+	}
+	knightMoves = [...]board.Vector{
+		board.Knight1Vec, board.Knight2Vec, board.Knight3Vec, board.Knight4Vec,
+		board.Knight5Vec, board.Knight6Vec, board.Knight7Vec, board.Knight8Vec,
+	}
+)
+
+// squares reachable ignoring pins and checks, worked out for both sides
+// because the board only keeps legal moves for the side to move
+func pieceMobility(boardState *board.BoardState, piece board.Piece, pos board.Position) int {
+	count := 0
+	step := func(vec board.Vector) {
+		next, inBounds := pos.AddInBounds(vec)
+		if inBounds && boardState.GetSquare(next).Colour() != piece.Colour() {
+			count += 1
+		}
+	}
+	slide := func(vec board.Vector) {
+		next := pos
+		for {
+			var inBounds bool
+			next, inBounds = next.AddInBounds(vec)
+			if !inBounds {
+				return
+			}
+			other := boardState.GetSquare(next)
+			if other.Colour() != piece.Colour() {
+				count += 1
+			}
+			if !other.IsClear() {
+				return
+			}
+		}
+	}
+
+	switch {
+	case piece.Is(board.Knight):
+		for _, vec := range knightMoves {
+			step(vec)
+		}
+	case piece.IsDiagonalAttacker() || piece.IsStraightLongAttacker():
+		if piece.IsDiagonalAttacker() {
+			for _, vec := range diagonals {
+				slide(vec)
+			}
+		}
+		if piece.IsStraightLongAttacker() {
+			for _, vec := range straights {
+				slide(vec)
+			}
+		}
+	}
+	return count
+}
+
+func mobility(boardState *board.BoardState) int {
+	score := 0
+	for i, piece := range boardState.State {
+		if piece.IsClear() || piece.Is(board.King) || piece.Is(board.Pawn) {
+			continue
+		}
+		count := pieceMobility(boardState, piece, board.IndexToPosition(i))
+		score += sign(piece.Colour()) * count * mobilityWeight
+	}
+	return score
+}
+
+func kingSafety(boardState *board.BoardState) int {
+	score := 0
+	for i, piece := range boardState.State {
+		if !piece.Is(board.King) {
+			continue
+		}
+
+		colour := piece.Colour()
+		opponent := board.OppositeColour(colour)
+		pos := board.IndexToPosition(i)
+		safety := 0
+		for _, vecs := range [...][4]board.Vector{diagonals, straights} {
+			for _, vec := range vecs {
+				next, inBounds := pos.AddInBounds(vec)
+				if !inBounds {
+					continue
+				}
+				if boardState.GetSquare(next).Colour() == colour {
+					safety += kingShelterWeight
+				}
+				if boardState.IsSquareAttacked(next, opponent) {
+					safety -= kingAttackWeight
+				}
+			}
+		}
+		score += sign(colour) * safety
+	}
+	return score
+}
+
+func EvaluateBreakdown(boardState *board.BoardState) Breakdown {
+	ret := Breakdown{
+		Material:   material(boardState),
+		Mobility:   mobility(boardState),
+		KingSafety: kingSafety(boardState),
+	}
+	ret.Total = ret.Material + ret.Mobility + ret.KingSafety
+	return ret
+}
+
+// Static score of the position from white's perspective, finished games
+// score as a mate or a draw
+func Evaluate(boardState *board.BoardState) int {
+	switch boardState.HasWinnerImpl() {
+	case board.WhiteWin:
+		return MateScore
+	case board.BlackWin:
+		return -MateScore
+	case board.Stalemate, board.MoveRuleDraw, board.DeadPosition:
+		return DrawScore
+	}
+	return EvaluateBreakdown(boardState).Total
+}
+
+// Score from the perspective of the side to move, for negamax search
+func Relative(boardState *board.BoardState) int {
+	return sign(boardState.WhoseMove()) * Evaluate(boardState)
+}
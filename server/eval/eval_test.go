@@ -0,0 +1,97 @@
+package eval_test
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"unicode"
+
+	"chess/board"
+	"chess/eval"
+)
+
+func newBoard(test *testing.T, fen string) *board.BoardState {
+	test.Helper()
+	boardState, err := board.ParseFen(fen)
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = boardState.Init()
+	if err != nil {
+		test.Fatal(err)
+	}
+	return boardState
+}
+
+// rotates the board half a turn and swaps the colours
+func mirrorFen(fen string) string {
+	placement, rest, _ := strings.Cut(fen, " ")
+	runes := []rune(placement)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	for i, char := range runes {
+		if unicode.IsUpper(char) {
+			runes[i] = unicode.ToLower(char)
+		} else {
+			runes[i] = unicode.ToUpper(char)
+		}
+	}
+	return string(runes) + " " + rest
+}
+
+func Test_evaluate(test *testing.T) {
+	test.Run("test starting position is level", func(test *testing.T) {
+		test.Parallel()
+		boardState := board.NewBoard()
+		err := boardState.Init()
+		if err != nil {
+			test.Fatal(err)
+		}
+		breakdown := eval.EvaluateBreakdown(boardState)
+		if breakdown != (eval.Breakdown{}) {
+			test.Fatalf("expected level position\nreceived: %+v", breakdown)
+		}
+	})
+
+	test.Run("test material advantage", func(test *testing.T) {
+		test.Parallel()
+		// black is missing the queen
+		boardState := newBoard(test, "krbpp3/rqnp4/nbp5/pp5P/p5PP/5PBN/4PN1R/3PPBRK w 0")
+		score := eval.Evaluate(boardState)
+		if score < eval.PieceValue(board.Queen)/2 {
+			test.Fatalf("expected white to be well ahead\nreceived: %d", score)
+		}
+		if eval.Relative(boardState) != score {
+			test.Fatalf("expected relative score to match for white to move")
+		}
+	})
+
+	test.Run("test mirrored positions score the opposite", func(test *testing.T) {
+		test.Parallel()
+		for range 20 {
+			boardState := board.NewBoard()
+			err := boardState.Init()
+			if err != nil {
+				test.Fatal(err)
+			}
+
+			for boardState.HasWinner() == board.NoWin {
+				fen := boardState.Fen()
+				mirrored := newBoard(test, mirrorFen(fen))
+				score := eval.EvaluateBreakdown(boardState)
+				mirroredScore := eval.EvaluateBreakdown(mirrored)
+				if score.Total != -mirroredScore.Total {
+					test.Fatalf("expected %+v to mirror %+v\n%s\n%s",
+						score, mirroredScore, fen, mirrored.Fen())
+				}
+
+				moves := boardState.LegalMoves
+				err := boardState.MakeMove(moves[rand.IntN(len(moves))])
+				if err != nil {
+					test.Fatal(err)
+				}
+			}
+		}
+	})
+}
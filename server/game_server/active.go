@@ -0,0 +1,158 @@
+package game_server
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"chess/board"
+	"chess/pgn"
+
+	"github.com/google/uuid"
+)
+
+const (
+	activeGamesPageSize    = 50
+	maxActiveGamesPageSize = 200
+)
+
+// a game in progress as listed for the watch page
+type ActiveGame struct {
+	Id      string `json:"id"`
+	White   string `json:"white"`
+	Black   string `json:"black"`
+	Variant string `json:"variant"`
+	// e.g. 300+2, see pgn.TimeControl
+	TimeControl string    `json:"timeControl"`
+	GameLength  int32     `json:"gameLength"` // Time in milliseconds
+	Increment   int32     `json:"increment"`  // Time in milliseconds
+	Moves       int       `json:"moves"`      // half moves played so far
+	Spectators  int       `json:"spectators"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ActiveGamesResponse struct {
+	Games []ActiveGame `json:"games"`
+	// pass as ?cursor= for the next page, left out on the last page
+	Cursor *string `json:"cursor,omitempty"`
+}
+
+// Pages are ordered by when the game started so games starting or ending
+// between requests don't shift the pages
+type activeCursor struct {
+	createdAt time.Time
+	id        uuid.UUID
+}
+
+func (cur activeCursor) String() string {
+	str := strconv.FormatInt(cur.createdAt.UnixNano(), 10) + "|" + cur.id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(str))
+}
+
+func (cur activeCursor) before(game ActiveGame) bool {
+	if !cur.createdAt.Equal(game.CreatedAt) {
+		return cur.createdAt.Before(game.CreatedAt)
+	}
+	return cur.id.String() < game.Id
+}
+
+func parseActiveCursor(str string) (activeCursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return activeCursor{}, err
+	}
+	nanosStr, idStr, found := strings.Cut(string(bytes), "|")
+	if !found {
+		return activeCursor{}, errors.New("malformed cursor")
+	}
+	nanos, err := strconv.ParseInt(nanosStr, 10, 64)
+	if err != nil {
+		return activeCursor{}, err
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return activeCursor{}, err
+	}
+	return activeCursor{createdAt: time.Unix(0, nanos), id: id}, nil
+}
+
+func (session *Session) activeGame() (ActiveGame, bool) {
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+	if session.ended {
+		return ActiveGame{}, false
+	}
+
+	return ActiveGame{
+		Id:          session.id.String(),
+		White:       session.players[0].userId.String(),
+		Black:       session.players[1].userId.String(),
+		Variant:     board.VariantId(session.boardState.Variant()),
+		TimeControl: pgn.TimeControl(session.gameLength, session.increment),
+		GameLength:  int32(session.gameLength.Milliseconds()),
+		Increment:   int32(session.increment.Milliseconds()),
+		Moves:       len(session.boardState.MoveHistory),
+		Spectators:  session.spectatorCount(),
+		CreatedAt:   session.createdAt,
+	}, true
+}
+
+// Games still being played oldest first, starting after cursor when there
+// is one
+func (server *GameServer) activeGames(cursor *activeCursor, limit int) ActiveGamesResponse {
+	games := make([]ActiveGame, 0)
+	for _, session := range server.allSessions() {
+		game, active := session.activeGame()
+		if active && (cursor == nil || cursor.before(game)) {
+			games = append(games, game)
+		}
+	}
+	slices.SortFunc(games, func(a, b ActiveGame) int {
+		if compared := a.CreatedAt.Compare(b.CreatedAt); compared != 0 {
+			return compared
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	response := ActiveGamesResponse{Games: games}
+	if len(games) > limit {
+		response.Games = games[:limit]
+		last := response.Games[limit-1]
+		next := activeCursor{createdAt: last.CreatedAt, id: uuid.MustParse(last.Id)}.String()
+		response.Cursor = &next
+	}
+	return response
+}
+
+// ?limit= sets the page size, ?cursor= takes the cursor of the last page
+func (server *GameServer) ActiveGamesHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	query := req.URL.Query()
+
+	limit := activeGamesPageSize
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			http.Error(writer, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxActiveGamesPageSize)
+	}
+
+	var cursor *activeCursor
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		parsed, err := parseActiveCursor(cursorStr)
+		if err != nil {
+			http.Error(writer, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		cursor = &parsed
+	}
+
+	writeJson(ctx, writer, server.activeGames(cursor, limit))
+}
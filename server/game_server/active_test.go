@@ -0,0 +1,71 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestActiveGames(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	cookie, _ := mockUser()
+
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		ids[i] = server.NewSession(uuid.New(), uuid.New(), time.Second, time.Minute)
+		// so the games are listed in the order they were made
+		time.Sleep(time.Millisecond)
+	}
+	first, _ := server.sessions.load(ids[0])
+	ended, _ := server.sessions.load(ids[2])
+	playMoves(t, first, []string{"D1:C2"})
+	first.viewers.Add(NewSubscriber(uuid.New(), first, board.None))
+	ended.handleWin(context.Background(), board.WinResult(board.White, board.TerminationResignation))
+
+	get := func(query string) (int, ActiveGamesResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/active"+query, nil)
+		req.AddCookie(cookie)
+		recorder := httptest.NewRecorder()
+		server.ServeMux.ServeHTTP(recorder, req)
+		response := ActiveGamesResponse{}
+		if recorder.Code == http.StatusOK {
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return recorder.Code, response
+	}
+
+	_, page := get("?limit=1")
+	if len(page.Games) != 1 || page.Cursor == nil {
+		t.Fatalf("Expected one game and a cursor, got %+v", page)
+	}
+	game := page.Games[0]
+	if game.Id != ids[0].String() || game.Moves != 1 || game.Spectators != 1 ||
+		game.TimeControl != "60+1" || game.White != first.players[0].userId.String() {
+		t.Errorf("Unexpected active game %+v", game)
+	}
+
+	_, page = get("?limit=1&cursor=" + *page.Cursor)
+	if len(page.Games) != 1 || page.Games[0].Id != ids[1].String() || page.Cursor != nil {
+		t.Errorf("Expected the second game on the last page, got %+v", page)
+	}
+
+	if code, _ := get("?cursor=nonsense"); code != http.StatusBadRequest {
+		t.Errorf("Expected a bad cursor to be rejected, got %d", code)
+	}
+
+	for _, id := range ids {
+		server.RemoveSession(context.Background(), id)
+	}
+}
@@ -0,0 +1,17 @@
+package game_server
+
+import (
+	"chess/board"
+	"chess/engine"
+)
+
+// games are only ended by the rules until this is called
+func (server *GameServer) SetTablebase(tablebase engine.Tablebase) {
+	server.tablebase = tablebase
+}
+
+// Ends games whose result is already known rather than making the players
+// shuffle until the move rule, boardStateLock should be held
+func (session *Session) adjudicateImpl() board.WinState {
+	return engine.Adjudicate(session.server.tablebase, session.boardState)
+}
@@ -0,0 +1,94 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/engine"
+)
+
+// knows every position is a draw
+type drawTablebase struct{}
+
+func (drawTablebase) MaxPieces() int {
+	return 32
+}
+
+func (drawTablebase) Probe(position *board.BoardState) (engine.WDL, bool) {
+	return engine.Draw, true
+}
+
+func TestTablebaseAdjudication(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	server.SetTablebase(drawTablebase{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	// the white knight taking the queen leaves a drawn ending, which isn't
+	// dead so is only ended by the tablebase
+	boardState, err := board.ParseFen("k7/2n5/8/3Q4/8/8/8/1R5K w 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = boardState.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.boardStateLock.Lock()
+	session.boardState = boardState
+	session.boardStateLock.Unlock()
+
+	playMoves(t, session, []string{"F2:E4"})
+
+	game, found := server.FinishedGame(session.id)
+	if !found || game.Result != "1/2-1/2" || game.Condition != "Adjudicated draw" {
+		t.Errorf("Expected the game to be adjudicated a draw, got %+v", game)
+	}
+	session.cleanup(context.Background())
+}
+
+// handleMove holds the clock lock once the clocks are running, ending the
+// game from there mustn't take it again
+func TestAdjudicationOnTheClock(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	server.SetTablebase(drawTablebase{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	defer session.cleanup(context.Background())
+
+	boardState, err := board.ParseFen("k7/2n5/8/3Q4/8/8/8/1R5K w 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = boardState.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.boardStateLock.Lock()
+	session.boardState = boardState
+	session.boardStateLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		playMoves(t, session, []string{"F2:E4"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the game to end without deadlocking")
+	}
+
+	game, found := server.FinishedGame(session.id)
+	if !found || game.Result != "1/2-1/2" || game.Condition != "Adjudicated draw" {
+		t.Errorf("Expected the game to be adjudicated a draw, got %+v", game)
+	}
+	session.clockLock.Lock()
+	clockStopped := session.clockTimer == nil
+	session.clockLock.Unlock()
+	if !clockStopped {
+		t.Error("Expected the clock to be stopped")
+	}
+}
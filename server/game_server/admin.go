@@ -0,0 +1,241 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"chess/board"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+// Moderation and cleaning up sessions wedged by bugs. Admins can see every
+// session, end a game with whatever result they think is fair and kick
+// someone off a game
+
+var (
+	errAlreadyEnded  = errors.New("game has already ended")
+	errUnknownResult = errors.New(`result should be "white", "black", "draw" or "abort"`)
+	errNotSubscribed = errors.New("user isn't subscribed to the game")
+)
+
+type AdminSession struct {
+	Id      string `json:"id"`
+	White   string `json:"white"`
+	Black   string `json:"black"`
+	Variant string `json:"variant"`
+	Moves   int    `json:"moves"`
+	Ended   bool   `json:"ended"`
+	Aborted bool   `json:"aborted"`
+	// see ConnectionState
+	WhiteConnection string    `json:"whiteConnection"`
+	BlackConnection string    `json:"blackConnection"`
+	Viewers         int       `json:"viewers"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+type AdminSessionsResponse struct {
+	Sessions []AdminSession `json:"sessions"`
+}
+
+type adminResultRequest struct {
+	// "white", "black", "draw" or "abort"
+	Result string `json:"result"`
+}
+
+type adminKickRequest struct {
+	UserId uuid.UUID `json:"userId"`
+}
+
+func (server *GameServer) requireAdmin(writer http.ResponseWriter, req *http.Request) bool {
+	isAdmin, err := server.authServer.IsAdmin(req.Context(), writer, req)
+	if err != nil {
+		return false
+	}
+	if !isAdmin {
+		writer.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (session *Session) adminSession() AdminSession {
+	session.boardStateLock.Lock()
+	ret := AdminSession{
+		Id:        session.id.String(),
+		White:     session.players[0].userId.String(),
+		Black:     session.players[1].userId.String(),
+		Variant:   board.VariantId(session.boardState.Variant()),
+		Moves:     len(session.boardState.MoveHistory),
+		Ended:     session.ended,
+		Aborted:   session.aborted,
+		CreatedAt: session.createdAt,
+		UpdatedAt: session.updatedAt,
+	}
+	session.boardStateLock.Unlock()
+
+	session.subscriberLock.Lock()
+	ret.WhiteConnection = session.players[0].state.String()
+	ret.BlackConnection = session.players[1].state.String()
+	ret.Viewers = session.viewers.Len()
+	session.subscriberLock.Unlock()
+	return ret
+}
+
+// Every session including ones which have ended but haven't been removed,
+// oldest first
+func (server *GameServer) AdminSessionsHandler(writer http.ResponseWriter, req *http.Request) {
+	if !server.requireAdmin(writer, req) {
+		return
+	}
+
+	sessions := make([]AdminSession, 0)
+	for _, session := range server.allSessions() {
+		sessions = append(sessions, session.adminSession())
+	}
+	slices.SortFunc(sessions, func(a, b AdminSession) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	writeJson(req.Context(), writer, AdminSessionsResponse{Sessions: sessions})
+}
+
+// Ends the game as if it had finished that way, the result is saved and
+// everyone is told like any other ending
+func (session *Session) forceResult(ctx context.Context, result string) error {
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+	if session.ended {
+		return errAlreadyEnded
+	}
+
+	switch result {
+	case "white":
+		session.handleWinImpl(ctx, board.WinResult(board.White, board.TerminationAdjudicated))
+	case "black":
+		session.handleWinImpl(ctx, board.WinResult(board.Black, board.TerminationAdjudicated))
+	case "draw":
+		session.handleWinImpl(ctx, board.DrawResult(board.TerminationAdjudicated))
+	case "abort":
+		session.clockLock.Lock()
+		session.abortImpl(ctx, session.boardState.WhoseMove(), "by an admin")
+		session.clockLock.Unlock()
+	default:
+		return errUnknownResult
+	}
+
+	session.logger.InfoContext(ctx, "admin forced a result", slog.String("result", result))
+	return nil
+}
+
+func (server *GameServer) AdminResultHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if !server.requireAdmin(writer, req) {
+		return
+	}
+	session, found := server.adminLoadSession(writer, req)
+	if !found {
+		return
+	}
+
+	body := adminResultRequest{}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = session.forceResult(ctx, body.Result)
+	switch {
+	case errors.Is(err, errUnknownResult):
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errAlreadyEnded):
+		http.Error(writer, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeJson(ctx, writer, session.adminSession())
+}
+
+// Closes every connection the user has to the game. Viewers are removed,
+// players are treated as if their connection dropped, force a result to
+// settle the game instead
+func (session *Session) kick(ctx context.Context, userId uuid.UUID) error {
+	kicked := make([]*subscriber, 0)
+	session.subscriberLock.Lock()
+	for _, player := range session.players {
+		if player.userId == userId && player.Conn != nil && player.state == Connected {
+			kicked = append(kicked, player)
+		}
+	}
+	for viewer := range session.viewers.Keys() {
+		if viewer.userId == userId {
+			kicked = append(kicked, viewer)
+		}
+	}
+	session.subscriberLock.Unlock()
+
+	if len(kicked) == 0 {
+		return errNotSubscribed
+	}
+
+	session.logger.InfoContext(ctx, "admin kicked a subscriber",
+		slog.String("userId", userId.String()))
+
+	for _, sub := range kicked {
+		if sub.colour == board.None {
+			sub.closeNow(ctx, nil)
+			continue
+		}
+		// going away so the read loop treats it like any other dropped
+		// connection
+		sub.Conn.Close(websocket.StatusGoingAway, "kicked by an admin")
+	}
+	return nil
+}
+
+func (server *GameServer) AdminKickHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if !server.requireAdmin(writer, req) {
+		return
+	}
+	session, found := server.adminLoadSession(writer, req)
+	if !found {
+		return
+	}
+
+	body := adminKickRequest{}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = session.kick(ctx, body.UserId)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (server *GameServer) adminLoadSession(writer http.ResponseWriter, req *http.Request) (*Session, bool) {
+	gameId, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return nil, false
+	}
+	session, found := server.sessions.load(gameId)
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	return session, true
+}
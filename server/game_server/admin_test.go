@@ -0,0 +1,102 @@
+package game_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+func TestAdminSessions(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	whiteCookie, white := mockUser()
+	sessionId := server.NewSession(white, uuid.New(), 0, time.Minute)
+	session, _ := server.sessions.load(sessionId)
+	fixture := newSocketFixture(t, server.ServeMux, sessionId)
+
+	post := func(path string, body string) *http.Response {
+		t.Helper()
+		res, err := http.Post(fixture.root+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res
+	}
+	base := "/admin/sessions/" + sessionId.String()
+
+	conn, _ := fixture.dial(whiteCookie)
+	// reads in the background so the close handshake is answered
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			_, _, err := conn.Read(fixture.ctx)
+			if err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+
+	res, err := http.Get(fixture.root + "/admin/sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := AdminSessionsResponse{}
+	err = json.NewDecoder(res.Body).Decode(&listed)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Sessions) != 1 || listed.Sessions[0].WhiteConnection != "connected" ||
+		listed.Sessions[0].BlackConnection != "preConnected" {
+		t.Errorf("Unexpected sessions %+v", listed.Sessions)
+	}
+
+	if res := post(base+"/kick", `{"userId":"`+uuid.NewString()+`"}`); res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected kicking someone who isn't there to fail, got %d", res.StatusCode)
+	}
+	if res := post(base+"/kick", `{"userId":"`+white.String()+`"}`); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected the player to be kicked, got %d", res.StatusCode)
+	}
+	if status := websocket.CloseStatus(<-closed); status != websocket.StatusGoingAway {
+		t.Errorf("Expected the player's socket to be closed, got %s", status)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		session.subscriberLock.Lock()
+		state := session.players[0].state
+		session.subscriberLock.Unlock()
+		if state != Connected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the player to be disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// a fresh game as leaving may have cost the player the first one
+	sessionId = server.NewSession(white, uuid.New(), 0, time.Minute)
+	base = "/admin/sessions/" + sessionId.String()
+	if res := post(base+"/result", `{"result":"resign"}`); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an unknown result to be rejected, got %d", res.StatusCode)
+	}
+	if res := post(base+"/result", `{"result":"draw"}`); res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the result to be forced, got %d", res.StatusCode)
+	}
+	finished, found := server.FinishedGame(sessionId)
+	if !found || finished.GameResult != board.DrawResult(board.TerminationAdjudicated) {
+		t.Errorf("Expected an adjudicated draw, got %+v", finished.GameResult)
+	}
+	if res := post(base+"/result", `{"result":"white"}`); res.StatusCode != http.StatusConflict {
+		t.Errorf("Expected a finished game not to be changed, got %d", res.StatusCode)
+	}
+}
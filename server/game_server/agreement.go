@@ -0,0 +1,112 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+
+	"chess/board"
+)
+
+// Either player can offer a draw, or an abort until the clocks start, and
+// the game ends that way once the opponent accepts. Like takebacks an offer
+// only stands until another move is played. Agreed draws are saved like any
+// other result with their own termination, aborted games aren't saved so
+// they never count towards anyone's record
+
+var (
+	errNoOffer     = errors.New("no offer to accept")
+	errTooLate     = errors.New("games can only be aborted before the clocks start")
+	errOfferViewer = errors.New("only players can make or accept offers")
+)
+
+type offer struct {
+	from board.Colour
+	// moves played when it was made
+	ply int
+}
+
+// The colour whose offer can still be accepted, None when there isn't one.
+// boardStateLock should be held
+func (session *Session) pendingImpl(offer offer) board.Colour {
+	if len(session.boardState.MoveHistory) != offer.ply {
+		return board.None
+	}
+	return offer.from
+}
+
+// boardStateLock should be held
+func (session *Session) abortableImpl() bool {
+	return session.boardState.MoveCounter <= 2
+}
+
+func (sub *subscriber) isPlayer() bool {
+	return sub.colour == board.White || sub.colour == board.Black
+}
+
+func (sub *subscriber) handleOffer(ctx context.Context, eventType eventType) {
+	if !sub.isPlayer() {
+		sub.sendError(ctx, notYourTurn, errOfferViewer)
+		return
+	}
+
+	session := sub.session
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+
+	pending := &session.drawOffer
+	if eventType == abortOffer {
+		if !session.abortableImpl() {
+			sub.sendError(ctx, offerUnavailable, errTooLate)
+			return
+		}
+		pending = &session.abortOffer
+	}
+	switch {
+	case session.ended:
+		sub.sendError(ctx, offerUnavailable, errAlreadyEnded)
+		return
+	case session.pendingImpl(*pending) != board.None:
+		return
+	}
+
+	*pending = offer{from: sub.colour, ply: len(session.boardState.MoveHistory)}
+	colour := serialiseColour(sub.colour)
+	session.publish(ctx, sub, Event{Type: eventType, Colour: &colour})
+}
+
+func (sub *subscriber) handleDrawAccept(ctx context.Context) {
+	if !sub.isPlayer() {
+		sub.sendError(ctx, notYourTurn, errOfferViewer)
+		return
+	}
+
+	session := sub.session
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+
+	if session.ended || session.pendingImpl(session.drawOffer) != board.OppositeColour(sub.colour) {
+		sub.sendError(ctx, offerUnavailable, errNoOffer)
+		return
+	}
+	session.handleWinImpl(ctx, board.DrawResult(board.TerminationAgreement))
+}
+
+func (sub *subscriber) handleAbortAccept(ctx context.Context) {
+	if !sub.isPlayer() {
+		sub.sendError(ctx, notYourTurn, errOfferViewer)
+		return
+	}
+
+	session := sub.session
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+
+	if session.ended || !session.abortableImpl() ||
+		session.pendingImpl(session.abortOffer) != board.OppositeColour(sub.colour) {
+		sub.sendError(ctx, offerUnavailable, errNoOffer)
+		return
+	}
+	session.clockLock.Lock()
+	session.abortImpl(ctx, session.abortOffer.from, "agreed by both players")
+	session.clockLock.Unlock()
+}
@@ -0,0 +1,96 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/model"
+)
+
+func TestDrawAgreement(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeStore{games: make(chan model.CreateGameParams, 1)}
+	server.SetStore(store)
+	session := newTestSession(server, 0, 5*time.Second)
+
+	defer session.cleanup(context.Background())
+	white, black := session.players[0], session.players[1]
+	ctx := context.Background()
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7", "F2:E4"})
+
+	// the offer lapses once another move is played
+	white.handleOffer(ctx, drawOffer)
+	nextEvent(t, black, drawOffer)
+	if err := session.handleMove(ctx, black, session.boardState.LegalMoves[0]); err != nil {
+		t.Fatal(err)
+	}
+	black.handleDrawAccept(ctx)
+	if event := nextEvent(t, black, errorEvent); *event.Code != offerUnavailable {
+		t.Errorf("Expected a lapsed offer not to be accepted, got %+v", event)
+	}
+
+	black.handleOffer(ctx, drawOffer)
+	offer := nextEvent(t, white, drawOffer)
+	if *offer.Colour != "b" {
+		t.Errorf("Unexpected draw offer %+v", offer)
+	}
+	// only the opponent can accept
+	black.handleDrawAccept(ctx)
+	nextEvent(t, black, errorEvent)
+
+	white.handleDrawAccept(ctx)
+	event := nextEvent(t, black, end)
+	if *event.Outcome != "agreement" || *event.Reason != reasonAgreement || *event.Result != "1/2-1/2" {
+		t.Errorf("Unexpected end event %+v", event)
+	}
+	game := <-store.games
+	if game.Termination != string(board.TerminationAgreement) ||
+		game.Condition != board.WinStateToString(board.AgreedDraw) {
+		t.Errorf("Expected the draw to be saved as agreed, got %s %s", game.Termination, game.Condition)
+	}
+}
+
+func TestAbortAgreement(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeStore{games: make(chan model.CreateGameParams, 1)}
+	server.SetStore(store)
+	startedSession := newTestSession(server, 0, 5*time.Second)
+	session := newTestSession(server, 0, 5*time.Second)
+
+	defer startedSession.cleanup(context.Background())
+	defer session.cleanup(context.Background())
+	ctx := context.Background()
+
+	// too late once the clocks are running
+	playMoves(t, startedSession, []string{"D1:C2", "E8:F7", "F2:E4"})
+	startedSession.players[1].handleOffer(ctx, abortOffer)
+	if event := nextEvent(t, startedSession.players[1], errorEvent); *event.Code != offerUnavailable {
+		t.Errorf("Expected an abort offer after the clocks start to fail, got %+v", event)
+	}
+
+	white, black := session.players[0], session.players[1]
+	playMoves(t, session, []string{"D1:C2"})
+	black.handleOffer(ctx, abortOffer)
+	nextEvent(t, white, abortOffer)
+	white.handleAbortAccept(ctx)
+
+	event := nextEvent(t, white, abort)
+	if *event.Outcome != "abort" || *event.Colour != "b" || *event.Reason != reasonAbort {
+		t.Errorf("Unexpected abort event %+v", event)
+	}
+	session.boardStateLock.Lock()
+	aborted := session.aborted
+	session.boardStateLock.Unlock()
+	if !aborted {
+		t.Error("Expected the game to be aborted")
+	}
+	select {
+	case game := <-store.games:
+		t.Errorf("Expected an aborted game not to be saved, saved %+v", game)
+	default:
+	}
+}
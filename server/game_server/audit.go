@@ -0,0 +1,121 @@
+package game_server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"chess/board"
+)
+
+type auditDecision = string
+
+const (
+	moveAccepted  auditDecision = "moveAccepted"
+	moveRejected                = "moveRejected"
+	gameEnded                   = "gameEnded"
+	moveTakenBack               = "moveTakenBack"
+)
+
+// why the server ruled the way it did, kept so disputes about the rules
+// of the variant can be looked into
+type AuditEntry struct {
+	Time     time.Time     `json:"time"`
+	Ply      uint16        `json:"ply"`
+	Decision auditDecision `json:"decision"`
+	Reason   string        `json:"reason"`
+	Move     string        `json:"move,omitempty"`
+	Fen      string        `json:"fen"`
+	Check    string        `json:"check"`
+	// pin on the piece being moved
+	Pin string `json:"pin,omitempty"`
+}
+
+// only created when the server is auditing, a nil audit records nothing
+type ruleAudit struct {
+	lock    sync.Mutex
+	entries []AuditEntry
+}
+
+func (server *GameServer) SetAuditRules(enabled bool) {
+	server.auditRules = enabled
+}
+
+// boardStateLock should be held
+func (session *Session) recordAudit(
+	decision auditDecision,
+	reason string,
+	move *board.Move,
+) {
+	if session.audit == nil {
+		return
+	}
+
+	boardState := session.boardState
+	entry := AuditEntry{
+		Time:     time.Now(),
+		Ply:      boardState.MoveCounter,
+		Decision: decision,
+		Reason:   reason,
+		Fen:      boardState.Fen(),
+		Check:    board.CheckToString(boardState.Check.Check),
+	}
+	if move != nil {
+		entry.Move = move.Serialise()
+		piece := boardState.GetSquare(move.From)
+		if piece.IsPinned() {
+			entry.Pin = board.PinToString(piece.GetPin())
+		}
+	}
+
+	session.audit.lock.Lock()
+	session.audit.entries = append(session.audit.entries, entry)
+	session.audit.lock.Unlock()
+}
+
+func (session *Session) auditEntries() []AuditEntry {
+	session.audit.lock.Lock()
+	defer session.audit.lock.Unlock()
+	return append([]AuditEntry(nil), session.audit.entries...)
+}
+
+func (server *GameServer) AuditHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	ctx := req.Context()
+	isAdmin, err := server.authServer.IsAdmin(ctx, writer, req)
+	if err != nil {
+		return
+	}
+	if !isAdmin {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	gameId, err := getIdWithPrefix(writer, req, "/audit/")
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	session, found := server.sessions.load(gameId)
+
+	if !found || session.audit == nil {
+		writer.WriteHeader(http.StatusNotFound)
+		logError(ctx, errors.New("not found"))
+		return
+	}
+
+	bytes, err := json.Marshal(session.auditEntries())
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Write(bytes)
+}
@@ -0,0 +1,51 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+)
+
+func TestAuditRules(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	server.SetAuditRules(true)
+	session := newTestSession(server, 0, 5*time.Second)
+
+	playMoves(t, session, []string{"D1:C2"})
+	illegal, err := board.DeserialiseMove("D1:D2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = session.handleMove(context.Background(), session.players[1], illegal)
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/"+session.id.String(), nil)
+	recorder := httptest.NewRecorder()
+	server.ServeMux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	entries := []AuditEntry{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(entries))
+	}
+	if entries[0].Decision != moveAccepted || entries[1].Decision != moveRejected {
+		t.Errorf("Unexpected decisions %s and %s", entries[0].Decision, entries[1].Decision)
+	}
+	if entries[1].Move != "D1:D2" {
+		t.Errorf("Expected rejected move to be recorded, got %s", entries[1].Move)
+	}
+
+	session.cleanup(context.Background())
+}
@@ -0,0 +1,101 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"chess/board"
+)
+
+// Berserking halves a player's starting time and they get no increment for
+// the rest of the game, in exchange arenas will give them an extra point
+// for the win. It has to be done before their first move
+
+func (sub *subscriber) handleBerserk(ctx context.Context) {
+	if sub.colour != board.White && sub.colour != board.Black {
+		sub.sendError(ctx, notYourTurn, errors.New("only players can berserk"))
+		return
+	}
+
+	session := sub.session
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+	session.clockLock.Lock()
+	defer session.clockLock.Unlock()
+
+	// white's first move is the first ply and black's the second
+	firstMoveMade := session.boardState.MoveCounter >= uint16(sub.colour)
+	switch {
+	case session.ended:
+		sub.sendError(ctx, gameOver, errors.New("berserk sent after game end"))
+		return
+	case firstMoveMade:
+		sub.sendError(ctx, notAllowed, errors.New("berserk sent after first move"))
+		return
+	case session.berserk[sub.colour-1]:
+		sub.sendError(ctx, notAllowed, errors.New("already berserk"))
+		return
+	}
+
+	session.berserk[sub.colour-1] = true
+	if sub.colour == board.White {
+		session.whiteTime = session.gameLength / 2
+	} else {
+		session.blackTime = session.gameLength / 2
+	}
+
+	colour := serialiseColour(sub.colour)
+	whiteTimeMs := int32(session.whiteTime.Milliseconds())
+	blackTimeMs := int32(session.blackTime.Milliseconds())
+	session.publish(ctx, nil, Event{
+		Type:      berserk,
+		Colour:    &colour,
+		WhiteTime: &whiteTimeMs,
+		BlackTime: &blackTimeMs,
+	})
+}
+
+// The increment the colour gets after each move, clockLock should be held
+func (session *Session) incrementImpl(colour board.Colour) time.Duration {
+	if session.berserk[colour-1] {
+		return 0
+	}
+	return session.increment
+}
+
+// The colours which have berserked, nil when neither has
+func (session *Session) berserkColours() *[]string {
+	session.clockLock.Lock()
+	defer session.clockLock.Unlock()
+
+	var colours []string
+	for index, berserked := range session.berserk {
+		if berserked {
+			colours = append(colours, serialiseColour(board.Colour(index+1)))
+		}
+	}
+	if colours == nil {
+		return nil
+	}
+	return &colours
+}
+
+// e.g. "wb" when both players berserked, how it's kept in the live store
+func parseBerserk(str string) [2]bool {
+	return [2]bool{
+		strings.Contains(str, serialiseColour(board.White)),
+		strings.Contains(str, serialiseColour(board.Black)),
+	}
+}
+
+func serialiseBerserk(berserk [2]bool) string {
+	str := ""
+	for index, berserked := range berserk {
+		if berserked {
+			str += serialiseColour(board.Colour(index + 1))
+		}
+	}
+	return str
+}
@@ -0,0 +1,49 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+)
+
+func TestBerserk(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, time.Second, 5*time.Second)
+
+	white, black := session.players[0], session.players[1]
+	ctx := context.Background()
+
+	black.handleBerserk(ctx)
+	for _, sub := range session.players {
+		event := nextEvent(t, sub, berserk)
+		if *event.Colour != "b" || *event.WhiteTime != 5000 || *event.BlackTime != 2500 {
+			t.Errorf("Unexpected berserk event %+v", event)
+		}
+	}
+	black.handleBerserk(ctx)
+	nextEvent(t, black, errorEvent)
+
+	playMoves(t, session, []string{"D1:C2"})
+	white.handleBerserk(ctx)
+	nextEvent(t, white, errorEvent)
+
+	connectEvent, _ := session.CreateConnectEvent(board.White, Connected)
+	if connectEvent.Berserk == nil || len(*connectEvent.Berserk) != 1 || (*connectEvent.Berserk)[0] != "b" {
+		t.Errorf("Expected the connect event to show black berserked, got %+v", connectEvent.Berserk)
+	}
+
+	playMoves(t, session, []string{"E8:F7", "F2:E4"})
+	playMoves(t, session, []string{session.boardState.LegalMoves[0].Serialise()})
+	whiteTime, blackTime := session.getClockState()
+	if whiteTime <= 5*time.Second {
+		t.Errorf("Expected white to keep their increment, got %v", whiteTime)
+	}
+	if blackTime > 2500*time.Millisecond {
+		t.Errorf("Expected black to get no increment, got %v", blackTime)
+	}
+
+	session.cleanup(ctx)
+}
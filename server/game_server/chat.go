@@ -0,0 +1,129 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chess/board"
+)
+
+const (
+	// longer messages are rejected rather than cut short
+	maxChatLength = 280
+	// messages sent sooner than this after the subscriber's last one are
+	// dropped
+	chatCooldown = time.Second
+	// dropped messages in a row before the connection is closed for flooding
+	maxDroppedChats = 10
+)
+
+// Which conversation a message belongs to, sent with chat events so
+// clients can show them apart
+type chatChannel = string
+
+const (
+	playersChannel    chatChannel = "players"
+	spectatorsChannel             = "spectators"
+)
+
+var (
+	errChatCooldown = errors.New("chat message sent too soon after the last one")
+	errChatFlooding = errors.New("too many chat messages sent during the cooldown")
+	errChatLength   = errors.New("chat message too long")
+)
+
+// Like moveThrottle, only touched from the subscriber's read loop
+type chatThrottle struct {
+	lastChatAt time.Time
+	dropped    int
+}
+
+func (throttle *chatThrottle) allow(now time.Time) (bool, error) {
+	if !throttle.lastChatAt.IsZero() && now.Sub(throttle.lastChatAt) < chatCooldown {
+		throttle.dropped += 1
+		if throttle.dropped > maxDroppedChats {
+			return false, errChatFlooding
+		}
+		return false, nil
+	}
+
+	throttle.lastChatAt = now
+	throttle.dropped = 0
+	return true, nil
+}
+
+func (sub *subscriber) handleChat(ctx context.Context, event Event) {
+	if event.Text == nil {
+		return
+	}
+	text := strings.TrimSpace(*event.Text)
+	if text == "" {
+		return
+	}
+	if utf8.RuneCountInString(text) > maxChatLength {
+		sub.sendError(ctx, chatTooLong, errChatLength)
+		return
+	}
+
+	allowed, err := sub.chat.allow(time.Now())
+	if err != nil {
+		sub.closeNow(ctx, err)
+		return
+	}
+	if !allowed {
+		sub.sendError(ctx, rateLimited, errChatCooldown)
+		return
+	}
+
+	sender := sub.userId.String()
+	colour := serialiseColour(sub.colour)
+	channel := spectatorsChannel
+	if sub.colour == board.White || sub.colour == board.Black {
+		channel = playersChannel
+	}
+	sub.session.publishChat(ctx, sub, Event{
+		Type:    chat,
+		Text:    &text,
+		Sender:  &sender,
+		Colour:  &colour,
+		Channel: &channel,
+	})
+}
+
+// Players talk amongst themselves until the game's over, then viewers can
+// read along too. Viewers only ever chat amongst themselves so they can't
+// pass anything on to the players
+func (session *Session) chatRecipientsImpl(channel chatChannel, ended bool) []*subscriber {
+	recipients := make([]*subscriber, 0, 2+session.viewers.Len())
+	if channel == playersChannel {
+		recipients = append(recipients, session.players[:]...)
+		if !ended {
+			return recipients
+		}
+	}
+	for viewer := range session.viewers.Keys() {
+		recipients = append(recipients, viewer)
+	}
+	return recipients
+}
+
+// The sender isn't sent their own message back
+func (session *Session) publishChat(ctx context.Context, sender *subscriber, event Event) {
+	session.boardStateLock.Lock()
+	ended := session.ended
+	session.boardStateLock.Unlock()
+
+	// publishing can close a slow subscriber which takes the lock itself
+	session.subscriberLock.Lock()
+	recipients := session.chatRecipientsImpl(*event.Channel, ended)
+	session.subscriberLock.Unlock()
+
+	for _, recipient := range recipients {
+		if recipient != sender {
+			session.publishImpl(ctx, event, recipient)
+		}
+	}
+}
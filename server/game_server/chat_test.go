@@ -0,0 +1,211 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestChat(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	viewers := [2]*subscriber{
+		NewSubscriber(uuid.New(), session, board.None),
+		NewSubscriber(uuid.New(), session, board.None),
+	}
+	for _, viewer := range viewers {
+		session.viewers.Add(viewer)
+	}
+	white, black := session.players[0], session.players[1]
+
+	expectChat := func(sub *subscriber, text string, sender *subscriber) {
+		t.Helper()
+		select {
+		case event := <-sub.events:
+			if event.Type != chat || *event.Text != text ||
+				*event.Sender != sender.userId.String() ||
+				*event.Colour != serialiseColour(sender.colour) ||
+				(*event.Channel == playersChannel) != (sender.colour != board.None) {
+				t.Errorf("Unexpected chat event %+v", event)
+			}
+		default:
+			t.Errorf("Expected %q to be sent", text)
+		}
+	}
+	expectNothing := func(sub *subscriber) {
+		t.Helper()
+		select {
+		case event := <-sub.events:
+			t.Errorf("Expected no event, got %+v", event)
+		default:
+		}
+	}
+
+	text := "  good luck "
+	white.handleChat(context.Background(), Event{Type: chat, Text: &text})
+	expectChat(black, "good luck", white)
+	// viewers can't read the players' chat mid game
+	expectNothing(viewers[0])
+	expectNothing(viewers[1])
+	expectNothing(white)
+
+	// viewers can't talk to the players
+	text = "blunder incoming"
+	viewers[0].handleChat(context.Background(), Event{Type: chat, Text: &text})
+	expectChat(viewers[1], text, viewers[0])
+	expectNothing(white)
+	expectNothing(black)
+	expectNothing(viewers[0])
+
+	// too soon after the last message
+	text = "again"
+	white.handleChat(context.Background(), Event{Type: chat, Text: &text})
+	expectNothing(black)
+	if event := <-white.events; event.Type != errorEvent || *event.Text != errChatCooldown.Error() {
+		t.Errorf("Expected cooldown error, got %+v", event)
+	}
+
+	text = strings.Repeat("a", maxChatLength+1)
+	black.handleChat(context.Background(), Event{Type: chat, Text: &text})
+	expectNothing(white)
+	if event := <-black.events; event.Type != errorEvent || *event.Text != errChatLength.Error() {
+		t.Errorf("Expected length error, got %+v", event)
+	}
+
+	// once the game's over everyone can read the players
+	session.boardStateLock.Lock()
+	session.ended = true
+	session.boardStateLock.Unlock()
+	text = "good game"
+	black.handleChat(context.Background(), Event{Type: chat, Text: &text})
+	expectChat(white, text, black)
+	expectChat(viewers[0], text, black)
+	expectChat(viewers[1], text, black)
+
+	// but the viewers still can't reach them
+	text = "well played"
+	viewers[1].handleChat(context.Background(), Event{Type: chat, Text: &text})
+	expectChat(viewers[0], text, viewers[1])
+	expectNothing(white)
+	expectNothing(black)
+
+	session.cleanup(context.Background())
+}
+
+func TestChatCooldown(t *testing.T) {
+	throttle := chatThrottle{}
+	now := time.Now()
+
+	allowed, err := throttle.allow(now)
+	if !allowed || err != nil {
+		t.Fatalf("Expected the first message to be allowed, got %t %v", allowed, err)
+	}
+	for range maxDroppedChats {
+		allowed, err = throttle.allow(now.Add(chatCooldown / 2))
+		if allowed || err != nil {
+			t.Fatalf("Expected messages during the cooldown to be dropped, got %t %v", allowed, err)
+		}
+	}
+	_, err = throttle.allow(now.Add(chatCooldown / 2))
+	if !errors.Is(err, errChatFlooding) {
+		t.Errorf("Expected flooding error, got %v", err)
+	}
+	allowed, err = throttle.allow(now.Add(chatCooldown))
+	if !allowed || err != nil {
+		t.Errorf("Expected a message after the cooldown to be allowed, got %t %v", allowed, err)
+	}
+}
+
+func TestViewerChatOverSocket(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	whiteCookie, white := mockUser()
+	blackCookie, black := mockUser()
+	sessionId := server.NewSession(white, black, 0, time.Minute)
+	fixture := newSocketFixture(t, server.ServeMux, sessionId)
+
+	whiteConn, _ := fixture.dial(whiteCookie)
+	blackConn, _ := fixture.dial(blackCookie)
+	viewerCookie, _ := mockUser()
+	viewerConn, _ := fixture.dial(viewerCookie)
+	otherCookie, _ := mockUser()
+	otherConn, _ := fixture.dial(otherCookie)
+
+	text := "blunder incoming"
+	fixture.send(viewerConn, Event{Type: chat, Text: &text})
+	event := fixture.readUntil(otherConn, chat)
+	if *event.Text != text || *event.Channel != spectatorsChannel {
+		t.Errorf("Unexpected chat event %+v", event)
+	}
+	fixture.expectNone(whiteConn, chat)
+	fixture.expectNone(blackConn, chat)
+
+	text = "good luck"
+	fixture.send(whiteConn, Event{Type: chat, Text: &text})
+	event = fixture.readUntil(blackConn, chat)
+	if *event.Text != text || *event.Channel != playersChannel {
+		t.Errorf("Unexpected chat event %+v", event)
+	}
+	fixture.expectNone(viewerConn, chat)
+
+	// viewers can't play, the connection stays open
+	sent := "D1:C2"
+	fixture.send(viewerConn, Event{Type: sendMove, Move: &sent})
+	event = fixture.readUntil(viewerConn, errorEvent)
+	if *event.Code != notYourTurn {
+		t.Errorf("Expected %s, got %s", notYourTurn, *event.Code)
+	}
+	fixture.expectNone(viewerConn, errorEvent)
+
+	session, _ := server.sessions.load(sessionId)
+	if session.spectatorCount() != 2 {
+		t.Errorf("Expected both viewers to still be watching, got %d", session.spectatorCount())
+	}
+	session.boardStateLock.Lock()
+	moves := len(session.boardState.MoveHistory)
+	session.boardStateLock.Unlock()
+	if moves != 0 {
+		t.Error("Expected the viewer's move to be ignored")
+	}
+}
+
+func TestChatChannelsAfterGameOverSocket(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	whiteCookie, white := mockUser()
+	blackCookie, black := mockUser()
+	sessionId := server.NewSession(white, black, 0, time.Minute)
+	fixture := newSocketFixture(t, server.ServeMux, sessionId)
+
+	whiteConn, _ := fixture.dial(whiteCookie)
+	blackConn, _ := fixture.dial(blackCookie)
+	viewerCookie, _ := mockUser()
+	viewerConn, _ := fixture.dial(viewerCookie)
+
+	fixture.send(blackConn, Event{Type: resign})
+	fixture.readUntil(viewerConn, end)
+
+	// once the game's over viewers can read the players' chat
+	text := "good game"
+	fixture.send(whiteConn, Event{Type: chat, Text: &text})
+	event := fixture.readUntil(viewerConn, chat)
+	if *event.Text != text || *event.Channel != playersChannel {
+		t.Errorf("Unexpected chat event %+v", event)
+	}
+	event = fixture.readUntil(blackConn, chat)
+	if *event.Text != text {
+		t.Errorf("Unexpected chat event %+v", event)
+	}
+
+	// but the players still don't hear from the viewers
+	text = "well played"
+	fixture.send(viewerConn, Event{Type: chat, Text: &text})
+	fixture.expectNone(whiteConn, chat)
+	fixture.expectNone(blackConn, chat)
+}
@@ -0,0 +1,162 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"chess/board"
+)
+
+const (
+	ClockAuditInterval = time.Minute
+	// sessions audited at once
+	clockAuditWorkers = 8
+)
+
+// Counts of clock problems found by the audit, every problem found is
+// repaired so repaired only falls short when a fix couldn't be applied
+type ClockAuditReport struct {
+	Runs    int64 `json:"runs"`
+	Scanned int64 `json:"scanned"`
+	// a player's remaining time had gone below zero
+	NegativeTimes int64 `json:"negativeTimes"`
+	// the timer was running down the time of the player not to move
+	WrongColour int64 `json:"wrongColour"`
+	// the game was over but its timer could still fire
+	ArmedAfterEnd int64 `json:"armedAfterEnd"`
+	Repaired      int64 `json:"repaired"`
+}
+
+func (report *ClockAuditReport) add(other ClockAuditReport) {
+	report.Runs += other.Runs
+	report.Scanned += other.Scanned
+	report.NegativeTimes += other.NegativeTimes
+	report.WrongColour += other.WrongColour
+	report.ArmedAfterEnd += other.ArmedAfterEnd
+	report.Repaired += other.Repaired
+}
+
+// totals since the server started
+type clockAudit struct {
+	lock   sync.Mutex
+	totals ClockAuditReport
+}
+
+func (audit *clockAudit) record(run ClockAuditReport) {
+	audit.lock.Lock()
+	audit.totals.add(run)
+	audit.lock.Unlock()
+}
+
+func (audit *clockAudit) report() ClockAuditReport {
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+	return audit.totals
+}
+
+// A safety net for the clock code, checks every live session and repairs
+// what it can. Sessions are spread over a few workers so one slow lock
+// doesn't hold up the rest
+func (server *GameServer) ClockAuditJob(ctx context.Context) error {
+	sessions := server.sessions.all()
+
+	queue := make(chan *Session)
+	results := make(chan ClockAuditReport, clockAuditWorkers)
+	wg := sync.WaitGroup{}
+	for range min(clockAuditWorkers, len(sessions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found := ClockAuditReport{}
+			for session := range queue {
+				found.add(session.auditClock(ctx))
+			}
+			results <- found
+		}()
+	}
+	for _, session := range sessions {
+		queue <- session
+	}
+	close(queue)
+	wg.Wait()
+	close(results)
+
+	run := ClockAuditReport{Runs: 1}
+	for found := range results {
+		run.add(found)
+	}
+	server.clockAudit.record(run)
+
+	slog.InfoContext(ctx, "clock audit",
+		slog.Int64("scanned", run.Scanned),
+		slog.Int64("negativeTimes", run.NegativeTimes),
+		slog.Int64("wrongColour", run.WrongColour),
+		slog.Int64("armedAfterEnd", run.ArmedAfterEnd),
+		slog.Int64("repaired", run.Repaired))
+	return nil
+}
+
+func (session *Session) auditClock(ctx context.Context) ClockAuditReport {
+	session.boardStateLock.Lock()
+	session.clockLock.Lock()
+	defer session.boardStateLock.Unlock()
+	defer session.clockLock.Unlock()
+
+	found := ClockAuditReport{Scanned: 1}
+	flag := func(problem string) {
+		session.logger.WarnContext(ctx, "clock audit repaired session",
+			slog.String("problem", problem))
+	}
+
+	if session.ended {
+		if session.clockTimer != nil {
+			session.stopClockImpl()
+			found.ArmedAfterEnd += 1
+			found.Repaired += 1
+			flag("timer armed after the game ended")
+		}
+		return found
+	}
+
+	if session.whiteTime < 0 || session.blackTime < 0 {
+		session.whiteTime = max(session.whiteTime, 0)
+		session.blackTime = max(session.blackTime, 0)
+		found.NegativeTimes += 1
+		found.Repaired += 1
+		flag("negative remaining time")
+	}
+
+	toMove := session.boardState.WhoseMove()
+	if session.clockTimer != nil && session.clockColour != toMove {
+		found.WrongColour += 1
+		flag("timer running for " + board.ColourString(session.clockColour))
+		session.stopClockImpl()
+		session.armClockImpl(ctx)
+		found.Repaired += 1
+	}
+	return found
+}
+
+func (server *GameServer) ClockAuditHandler(writer http.ResponseWriter, req *http.Request) {
+	isAdmin, err := server.authServer.IsAdmin(req.Context(), writer, req)
+	if err != nil {
+		return
+	}
+	if !isAdmin {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	bytes, err := json.Marshal(server.clockAudit.report())
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Write(bytes)
+}
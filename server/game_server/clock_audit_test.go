@@ -0,0 +1,51 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+)
+
+func TestClockAudit(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	brokenSession := newTestSession(server, 0, 5*time.Second)
+	finishedSession := newTestSession(server, 0, 5*time.Second)
+
+	playMoves(t, brokenSession, []string{"D1:C2", "E8:F7", "F2:E4"})
+	brokenSession.clockLock.Lock()
+	// black is to move but white's clock is running
+	brokenSession.startClockImpl(context.Background(), board.White)
+	brokenSession.blackTime = -time.Second
+	brokenSession.clockLock.Unlock()
+
+	finishedSession.boardStateLock.Lock()
+	finishedSession.ended = true
+	finishedSession.boardStateLock.Unlock()
+
+	err := server.ClockAuditJob(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := server.clockAudit.report()
+	expected := ClockAuditReport{
+		Runs: 1, Scanned: 2, NegativeTimes: 1, WrongColour: 1, ArmedAfterEnd: 1, Repaired: 3,
+	}
+	if report != expected {
+		t.Errorf("Expected %+v, got %+v", expected, report)
+	}
+
+	brokenSession.clockLock.Lock()
+	colour := brokenSession.clockColour
+	blackTime := brokenSession.blackTime
+	brokenSession.clockLock.Unlock()
+	if colour != board.Black || blackTime != 0 {
+		t.Errorf("Expected black's clock to be running from 0, got %d %v", colour, blackTime)
+	}
+
+	brokenSession.cleanup(context.Background())
+	finishedSession.cleanup(context.Background())
+}
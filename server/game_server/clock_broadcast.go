@@ -0,0 +1,80 @@
+package game_server
+
+import (
+	"context"
+	"time"
+)
+
+// Clients only hear the clocks on moves and connects and count them down
+// themselves in between, so what they show wanders from the server's times.
+// While a live game's clock is running everyone is sent the remaining times
+// every few seconds to pull them back. They aren't numbered or kept for
+// replays, they're stale by the time anyone would ask, and players who
+// aren't connected are skipped so their queues don't fill up while they're
+// away
+
+// zero or negative turns the broadcasts off
+const DefaultClockBroadcastInterval = 5 * time.Second
+
+func (server *GameServer) SetClockBroadcastInterval(interval time.Duration) {
+	server.clockBroadcastInterval = interval
+}
+
+// Called before the session is stored, the broadcasts stop on cleanup
+func (session *Session) startClockBroadcasts() {
+	interval := session.server.clockBroadcastInterval
+	if interval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	session.stopClockBroadcasts = cancel
+	go session.broadcastClocks(ctx, interval)
+}
+
+func (session *Session) broadcastClocks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			session.broadcastClock(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (session *Session) broadcastClock(ctx context.Context) {
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+
+	session.clockLock.Lock()
+	running := session.liveClockRunningImpl()
+	whiteTime, blackTime := session.getClockStateImpl()
+	session.clockLock.Unlock()
+	if !running {
+		return
+	}
+
+	whiteTimeMs := int32(whiteTime.Milliseconds())
+	blackTimeMs := int32(blackTime.Milliseconds())
+	event := Event{Type: clockEvent, WhiteTime: &whiteTimeMs, BlackTime: &blackTimeMs}
+
+	// sent once the lock's let go, a slow viewer is removed under it
+	subs := make([]*subscriber, 0)
+	session.subscriberLock.Lock()
+	for _, player := range session.players {
+		if player.state == Connected {
+			subs = append(subs, player)
+		}
+	}
+	for viewer := range session.viewers.Keys() {
+		subs = append(subs, viewer)
+	}
+	session.subscriberLock.Unlock()
+	for _, sub := range subs {
+		session.publishImpl(ctx, event, sub)
+	}
+	session.fanOut(ctx, event)
+	session.server.lobby.viewerEvent(session.id, event)
+}
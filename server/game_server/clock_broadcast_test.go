@@ -0,0 +1,42 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+)
+
+func TestClockBroadcast(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	server.SetClockBroadcastInterval(10 * time.Millisecond)
+	session := newTestSession(server, 0, time.Minute)
+	defer session.cleanup(context.Background())
+	white, black := session.players[0], session.players[1]
+	white.state = Connected
+
+	// nothing runs until white's second move
+	playMoves(t, session, []string{"D1:C2", "E8:F7", "F2:E4"})
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-white.events:
+			if event.Type != clockEvent {
+				continue
+			}
+			if event.WhiteTime == nil || *event.WhiteTime > int32(time.Minute.Milliseconds()) ||
+				event.BlackTime == nil {
+				t.Errorf("Expected the remaining times, got %+v", event)
+			}
+			for len(black.events) > 0 {
+				if (<-black.events).Type == clockEvent {
+					t.Error("Expected players who aren't connected to be skipped")
+				}
+			}
+			return
+		case <-timeout:
+			t.Fatal("Expected a clock broadcast once the clock was running")
+		}
+	}
+}
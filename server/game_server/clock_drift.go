@@ -0,0 +1,70 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// offsets smaller than this are put down to latency and ignored
+const clockDriftThreshold = 2 * time.Second
+
+type driftState struct {
+	lock       sync.Mutex
+	lastSentAt time.Time
+	warned     bool
+}
+
+func (state *driftState) moveSent() {
+	state.lock.Lock()
+	state.lastSentAt = time.Now()
+	state.lock.Unlock()
+}
+
+// Estimates the offset of the client clock from the server clock assuming
+// the move took half the round trip to arrive. Returns whether a warning
+// should be sent, only once each time the client drifts past the threshold
+func (state *driftState) update(receivedAt time.Time, now time.Time) (time.Duration, bool, error) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	if state.lastSentAt.IsZero() {
+		return 0, false, errors.New("move ack sent before any move was received")
+	}
+
+	roundTrip := now.Sub(state.lastSentAt)
+	drift := receivedAt.Sub(state.lastSentAt.Add(roundTrip / 2))
+
+	if drift.Abs() < clockDriftThreshold {
+		state.warned = false
+		return drift, false, nil
+	}
+	if state.warned {
+		return drift, false, nil
+	}
+	state.warned = true
+	return drift, true, nil
+}
+
+func (sub *subscriber) handleMoveAck(ctx context.Context, event Event) {
+	// the timestamp is optional, acks without one are just ignored
+	if event.ReceivedAt == nil {
+		return
+	}
+
+	drift, warn, err := sub.drift.update(time.UnixMilli(*event.ReceivedAt), time.Now())
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	if !warn {
+		return
+	}
+
+	sub.logger.InfoContext(ctx, "client clock drift", slog.Duration("drift", drift))
+
+	driftMs := drift.Milliseconds()
+	sub.session.publishImpl(ctx, Event{Type: clockDrift, ClockDrift: &driftMs}, sub)
+}
@@ -0,0 +1,37 @@
+package game_server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockDrift(t *testing.T) {
+	state := driftState{}
+	now := time.Now()
+
+	_, _, err := state.update(now, now)
+	if err == nil {
+		t.Fatal("Expected ack before any move to fail")
+	}
+
+	state.lastSentAt = now
+	// 100ms round trip with the client clock in sync
+	drift, warn, err := state.update(now.Add(50*time.Millisecond), now.Add(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warn || drift != 0 {
+		t.Errorf("Expected no drift, got %v", drift)
+	}
+
+	// client clock 5 seconds behind
+	drift, warn, _ = state.update(now.Add(-5*time.Second), now.Add(100*time.Millisecond))
+	if !warn || drift != -5050*time.Millisecond {
+		t.Errorf("Expected drift warning, got %v %t", drift, warn)
+	}
+	// only warned once
+	_, warn, _ = state.update(now.Add(-5*time.Second), now.Add(100*time.Millisecond))
+	if warn {
+		t.Errorf("Expected drift to only be warned about once")
+	}
+}
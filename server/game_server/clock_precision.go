@@ -0,0 +1,80 @@
+package game_server
+
+import (
+	"fmt"
+	"time"
+
+	"chess/board"
+)
+
+// clients can ask for tenth of a second clock updates with
+// ?clockPrecision=tenths
+const clockPrecisionQueryKey = "clockPrecision"
+
+type ClockPrecision uint8
+
+const (
+	// clients count the clock down themselves between moves
+	SecondsPrecision ClockPrecision = iota
+	// clockSync events are sent every tenth of a second once the running
+	// clock is in its final seconds
+	TenthsPrecision
+)
+
+const (
+	precisionThreshold = 10 * time.Second
+	precisionInterval  = 100 * time.Millisecond
+)
+
+func ParseClockPrecision(str string) (ClockPrecision, error) {
+	switch str {
+	case "", "seconds":
+		return SecondsPrecision, nil
+	case "tenths":
+		return TenthsPrecision, nil
+	default:
+		return SecondsPrecision, fmt.Errorf("unknown clock precision: %s", str)
+	}
+}
+
+// nil unless the subscriber asked for tenths, a nil channel never fires
+func (sub *subscriber) clockSyncTicker() (*time.Ticker, <-chan time.Time) {
+	if sub.clockPrecision != TenthsPrecision {
+		return nil, nil
+	}
+	ticker := time.NewTicker(precisionInterval)
+	return ticker, ticker.C
+}
+
+// Whether a live game's clock is counting down, both locks should be held
+func (session *Session) liveClockRunningImpl() bool {
+	// before the second move the timer is the abort timer not the clock
+	return !session.ended && !session.paused && session.clockTimer != nil &&
+		session.boardState.MoveCounter > 1 && !session.isCorrespondence()
+}
+
+// The remaining times rounded down to tenths, only while a clock is running
+// and within the last few seconds
+func (session *Session) clockSyncEvent() (Event, bool) {
+	session.boardStateLock.Lock()
+	session.clockLock.Lock()
+	defer session.boardStateLock.Unlock()
+	defer session.clockLock.Unlock()
+
+	if !session.liveClockRunningImpl() {
+		return Event{}, false
+	}
+
+	whiteTime, blackTime := session.getClockStateImpl()
+	running := whiteTime
+	if session.clockColour == board.Black {
+		running = blackTime
+	}
+	if running > precisionThreshold {
+		return Event{}, false
+	}
+
+	whiteTimeMs := int32(whiteTime.Truncate(precisionInterval).Milliseconds())
+	blackTimeMs := int32(blackTime.Truncate(precisionInterval).Milliseconds())
+	return Event{Type: clockSync, WhiteTime: &whiteTimeMs, BlackTime: &blackTimeMs}, true
+}
@@ -0,0 +1,46 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+)
+
+func TestClockSyncEvent(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	shortSession := newTestSession(server, 0, 5*time.Second)
+	longSession := newTestSession(server, 0, time.Minute)
+
+	// only the abort timer is running before both players have moved
+	if _, send := shortSession.clockSyncEvent(); send {
+		t.Error("Expected no clock sync before the clock starts")
+	}
+
+	// the clock starts with white's second move
+	playMoves(t, shortSession, []string{"D1:C2", "E8:F7", "F2:E4"})
+	playMoves(t, longSession, []string{"D1:C2", "E8:F7", "F2:E4"})
+
+	event, send := shortSession.clockSyncEvent()
+	if !send {
+		t.Fatal("Expected a clock sync in the last seconds")
+	}
+	if event.Type != clockSync || *event.WhiteTime%100 != 0 || *event.BlackTime%100 != 0 {
+		t.Errorf("Expected times rounded to tenths, got %+v", event)
+	}
+	if _, send := longSession.clockSyncEvent(); send {
+		t.Error("Expected no clock sync with plenty of time left")
+	}
+
+	precision, err := ParseClockPrecision("tenths")
+	if err != nil || precision != TenthsPrecision {
+		t.Errorf("Expected tenths precision, got %d %v", precision, err)
+	}
+	if _, err := ParseClockPrecision("minutes"); err == nil {
+		t.Error("Expected an unknown precision to be rejected")
+	}
+
+	shortSession.cleanup(context.Background())
+	longSession.cleanup(context.Background())
+}
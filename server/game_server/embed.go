@@ -0,0 +1,50 @@
+package game_server
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+//go:embed embed.html
+var embedPage string
+
+var embedTemplate = template.Must(template.New("embed").Parse(embedPage))
+
+// A read only board for other sites to put in an iframe, the page is
+// self contained and follows the game through the relay
+func (server *GameServer) EmbedHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	ctx := req.Context()
+	id := req.PathValue("id")
+	gameId, err := uuid.Parse(id)
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logError(ctx, err)
+		return
+	}
+
+	_, found := server.sessions.load(gameId)
+	if _, cached := server.FinishedGame(gameId); !found && !cached {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// the relay is mounted next to the embed, under the same prefix
+	prefix := strings.TrimSuffix(req.URL.Path, "/embed/game/"+id)
+	data := struct{ EventsURL string }{
+		EventsURL: prefix + "/game/" + gameId.String() + "/events",
+	}
+
+	writer.Header().Add("Content-Type", "text/html; charset=utf-8")
+	writer.Header().Add("Content-Security-Policy", "frame-ancestors *")
+	err = embedTemplate.Execute(writer, data)
+	if err != nil {
+		logError(ctx, err)
+	}
+}
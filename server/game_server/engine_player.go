@@ -0,0 +1,181 @@
+package game_server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"chess/board"
+	"chess/engine"
+
+	"github.com/google/uuid"
+)
+
+// user id the engine plays under
+var EngineUserId = uuid.MustParse("00000000-0000-0000-0000-00000000e001")
+
+// On the clock a bot spends this fraction of its remaining time, plus half
+// its increment, on each move. The searcher's own limits still apply
+const (
+	botMovesToGo    = 30
+	minBotThinkTime = 50 * time.Millisecond
+)
+
+// anything which can pick a move, the built in engine or an external one
+type Searcher interface {
+	Search(ctx context.Context, boardState *board.BoardState) (engine.Result, error)
+}
+
+// consulted by bots before searching, see the book package
+type OpeningBook interface {
+	Pick(position *board.BoardState) (board.Move, bool)
+}
+
+// bots search every move until this is called
+func (server *GameServer) SetOpeningBook(book OpeningBook) {
+	server.openingBook = book
+}
+
+// Starts a game between a user and the built in engine
+func (server *GameServer) NewEngineSession(
+	userId uuid.UUID,
+	engineColour board.Colour,
+	increment time.Duration,
+	gameLength time.Duration,
+	options engine.Options,
+) uuid.UUID {
+	return server.NewBotSession(userId, engineColour, increment, gameLength,
+		engine.New(options))
+}
+
+// Starts a game between a user and a searcher, the user connects to the
+// game as usual and the searcher plays from the server
+func (server *GameServer) NewBotSession(
+	userId uuid.UUID,
+	engineColour board.Colour,
+	increment time.Duration,
+	gameLength time.Duration,
+	searcher Searcher,
+) uuid.UUID {
+	return server.NewVariantBotSession(userId, engineColour, increment, gameLength,
+		board.DefaultVariant, searcher)
+}
+
+func (server *GameServer) NewVariantBotSession(
+	userId uuid.UUID,
+	engineColour board.Colour,
+	increment time.Duration,
+	gameLength time.Duration,
+	variant board.Variant,
+	searcher Searcher,
+) uuid.UUID {
+	white, black := userId, EngineUserId
+	if engineColour == board.White {
+		white, black = EngineUserId, userId
+	}
+
+	sessionId := server.NewVariantSession(white, black, increment, gameLength, variant)
+
+	session, _ := server.sessions.load(sessionId)
+
+	sub := session.players[0]
+	if engineColour == board.Black {
+		sub = session.players[1]
+	}
+	sub.state = Connected
+	go sub.runEngine(context.Background(), searcher)
+
+	slog.Info("engine game created",
+		slog.String("gameId", sessionId.String()),
+		slog.String("engineColour", serialiseColour(engineColour)))
+
+	return sessionId
+}
+
+// reads events like a connected player would and replies to every move
+func (sub *subscriber) runEngine(ctx context.Context, searcher Searcher) {
+	sub.engineMove(ctx, searcher)
+
+	for {
+		select {
+		case <-sub.doneChannel:
+			return
+		case event := <-sub.events:
+			switch event.Type {
+			case move:
+				sub.engineMove(ctx, searcher)
+			case end, abort, errorEvent:
+				return
+			}
+		}
+	}
+}
+
+func (sub *subscriber) engineMove(ctx context.Context, searcher Searcher) {
+	session := sub.session
+
+	session.boardStateLock.Lock()
+	if session.ended || session.boardState.WhoseMove() != sub.colour {
+		session.boardStateLock.Unlock()
+		return
+	}
+	position := session.boardState.Clone()
+	budget, onClock := session.botThinkTimeImpl(sub.colour)
+	session.boardStateLock.Unlock()
+
+	if session.server.openingBook != nil {
+		move, found := session.server.openingBook.Pick(position)
+		if found {
+			sub.logger.InfoContext(ctx, "engine book move",
+				slog.String("move", move.Serialise()))
+			err := session.handleMove(ctx, sub, move)
+			if err != nil {
+				sub.logError(ctx, err)
+			}
+			return
+		}
+	}
+
+	searchCtx := ctx
+	if onClock {
+		var cancel context.CancelFunc
+		searchCtx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+	result, err := searcher.Search(searchCtx, position)
+	if err != nil {
+		sub.logError(ctx, err)
+		return
+	}
+
+	sub.logger.InfoContext(ctx, "engine move",
+		slog.String("move", result.Move.Serialise()),
+		slog.Int("score", result.Score),
+		slog.Int("depth", result.Depth),
+		slog.Uint64("nodes", result.Nodes))
+
+	err = session.handleMove(ctx, sub, result.Move)
+	if err != nil {
+		sub.logError(ctx, err)
+	}
+}
+
+// How long the bot can search without losing on time, false while the
+// clock isn't running. boardStateLock should be held
+func (session *Session) botThinkTimeImpl(colour board.Colour) (time.Duration, bool) {
+	session.clockLock.Lock()
+	defer session.clockLock.Unlock()
+	if !session.liveClockRunningImpl() {
+		return 0, false
+	}
+
+	whiteTime, blackTime := session.getClockStateImpl()
+	remaining := whiteTime
+	if colour == board.Black {
+		remaining = blackTime
+	}
+	budget := remaining/botMovesToGo + session.incrementImpl(colour)/2
+	// never plan to use up the whole clock
+	budget = min(budget, remaining/2)
+	return max(budget, minBotThinkTime), true
+}
@@ -0,0 +1,144 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/engine"
+
+	"github.com/google/uuid"
+)
+
+func waitForMoves(t *testing.T, session *Session, count uint16) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		session.boardStateLock.Lock()
+		moves := session.boardState.MoveCounter
+		session.boardStateLock.Unlock()
+		if moves >= count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d moves to be played", count)
+}
+
+func TestEngineSession(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	sessionId := server.NewEngineSession(uuid.New(), board.White, 0, 5*time.Second,
+		engine.Options{MaxDepth: 2, MoveTime: 100 * time.Millisecond})
+
+	session, _ := server.sessions.load(sessionId)
+
+	if session.players[0].userId != EngineUserId {
+		t.Fatal("Expected the engine to play white")
+	}
+
+	waitForMoves(t, session, 1)
+
+	session.boardStateLock.Lock()
+	reply := session.boardState.LegalMoves[0]
+	session.boardStateLock.Unlock()
+	err := session.handleMove(context.Background(), session.players[1], reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitForMoves(t, session, 3)
+	session.cleanup(context.Background())
+}
+
+// plays the first legal move, noting whether the search had a deadline
+type deadlineSearcher struct {
+	deadlines chan bool
+}
+
+func (searcher deadlineSearcher) Search(
+	ctx context.Context, position *board.BoardState,
+) (engine.Result, error) {
+	_, hasDeadline := ctx.Deadline()
+	searcher.deadlines <- hasDeadline
+	return engine.Result{Move: position.LegalMoves[0]}, nil
+}
+
+func TestBotRespectsClock(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	searcher := deadlineSearcher{deadlines: make(chan bool, 4)}
+	sessionId := server.NewBotSession(uuid.New(), board.Black, 0, 5*time.Second, searcher)
+
+	session, _ := server.sessions.load(sessionId)
+	defer session.cleanup(context.Background())
+
+	reply := func() {
+		session.boardStateLock.Lock()
+		move := session.boardState.LegalMoves[0]
+		session.boardStateLock.Unlock()
+		err := session.handleMove(context.Background(), session.players[0], move)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	nextDeadline := func() bool {
+		select {
+		case hasDeadline := <-searcher.deadlines:
+			return hasDeadline
+		case <-time.After(time.Second):
+			t.Fatal("Expected the bot to search")
+			return false
+		}
+	}
+
+	reply()
+	if nextDeadline() {
+		t.Error("Expected no deadline before the clock starts")
+	}
+	waitForMoves(t, session, 2)
+
+	reply()
+	if !nextDeadline() {
+		t.Error("Expected a deadline once the clock is running")
+	}
+	waitForMoves(t, session, 4)
+
+	session.boardStateLock.Lock()
+	budget, onClock := session.botThinkTimeImpl(board.Black)
+	session.boardStateLock.Unlock()
+	if !onClock || budget < minBotThinkTime || budget > 5*time.Second/2 {
+		t.Errorf("Unexpected think time %s", budget)
+	}
+}
+
+type fixedBook struct {
+	move board.Move
+}
+
+func (book fixedBook) Pick(position *board.BoardState) (board.Move, bool) {
+	return book.move, position.MoveCounter == 0
+}
+
+func TestEngineUsesOpeningBook(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	move, err := board.DeserialiseMove("D1:C2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetOpeningBook(fixedBook{move: move})
+	sessionId := server.NewEngineSession(uuid.New(), board.White, 0, 5*time.Second,
+		engine.Options{MaxDepth: 1, MoveTime: 100 * time.Millisecond})
+
+	session, _ := server.sessions.load(sessionId)
+
+	waitForMoves(t, session, 1)
+
+	session.boardStateLock.Lock()
+	played := session.boardState.MoveHistory[0].Move
+	session.boardStateLock.Unlock()
+	if played != move {
+		t.Errorf("Expected book move %s, got %s", move.Serialise(), played.Serialise())
+	}
+	session.cleanup(context.Background())
+}
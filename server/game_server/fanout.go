@@ -0,0 +1,372 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chess/board"
+	"chess/protocol"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+// Sessions live in the memory of the node which created them. With a broker
+// set each node takes a lease on its games and publishes everything viewers
+// are sent, so a game can be watched from any node. A node without the game
+// asks the one holding the lease for the position then forwards the events.
+// Players have to reach the node holding their game, the load balancer
+// should route by game id, other nodes turn them away
+
+const (
+	// how often leases are renewed, they expire after a few missed renewals
+	LeaseInterval     = 10 * time.Second
+	leaseTTL          = 3 * LeaseInterval
+	remoteSyncTimeout = 2 * time.Second
+	fanoutOutboxSize  = 1024
+)
+
+// Redis pub/sub or anything like it, see the redis package
+type Broker interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Messages on channel until ctx is done, the channel is closed then
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+	// Takes the lease if it's free or extends it if node already holds it
+	AcquireLease(ctx context.Context, key string, node string, ttl time.Duration) (bool, error)
+	LeaseHolder(ctx context.Context, key string) (node string, held bool, err error)
+	// Does nothing unless node holds the lease
+	ReleaseLease(ctx context.Context, key string, node string) error
+}
+
+var (
+	errLeaseHeld   = errors.New("game is held by another node")
+	errRemoteGame  = errors.New("game is hosted on another node")
+	errSyncTimeout = errors.New("node holding the game didn't answer")
+)
+
+func leaseKey(gameId uuid.UUID) string {
+	return "game:" + gameId.String() + ":owner"
+}
+
+func eventsChannel(gameId uuid.UUID) string {
+	return "game:" + gameId.String() + ":events"
+}
+
+func syncChannel(node string) string {
+	return "node:" + node + ":sync"
+}
+
+// sent by a node with a new viewer to the node holding the game
+type syncRequest struct {
+	GameId uuid.UUID `json:"gameId"`
+	// channel the response is published on
+	Reply string `json:"reply"`
+}
+
+type syncResponse struct {
+	White uuid.UUID `json:"white"`
+	Black uuid.UUID `json:"black"`
+	// the connect event a viewer on the node holding the game would get
+	Event Event `json:"event"`
+}
+
+type fanout struct {
+	broker Broker
+	node   string
+	// run in order by one goroutine so events are published in the order
+	// they were sent and nothing waits on the broker with a session locked
+	outbox chan func(ctx context.Context) error
+}
+
+func (fanout *fanout) run(ctx context.Context) {
+	for {
+		select {
+		case job := <-fanout.outbox:
+			err := job(ctx)
+			if err != nil {
+				logError(ctx, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Like a slow subscriber, jobs are dropped rather than blocking the sender
+func (fanout *fanout) enqueue(ctx context.Context, job func(ctx context.Context) error) {
+	select {
+	case fanout.outbox <- job:
+	default:
+		logError(ctx, errors.New("fanout outbox full, dropping job"))
+	}
+}
+
+// Called once at startup before ResumeLiveGames, node should stay the same
+// across restarts so the node's own leases can be taken back
+func (server *GameServer) SetBroker(ctx context.Context, broker Broker, node string) error {
+	requests, err := broker.Subscribe(ctx, syncChannel(node))
+	if err != nil {
+		return err
+	}
+	server.fanout = &fanout{
+		broker: broker,
+		node:   node,
+		outbox: make(chan func(ctx context.Context) error, fanoutOutboxSize),
+	}
+	go server.fanout.run(ctx)
+	go server.answerSyncs(ctx, requests)
+	return nil
+}
+
+// Queues taking the lease on a new game
+func (server *GameServer) claimSession(ctx context.Context, gameId uuid.UUID) {
+	if server.fanout == nil {
+		return
+	}
+	fanout := server.fanout
+	fanout.enqueue(ctx, func(ctx context.Context) error {
+		acquired, err := fanout.broker.AcquireLease(ctx, leaseKey(gameId), fanout.node, leaseTTL)
+		if err == nil && !acquired {
+			err = fmt.Errorf("claiming %s: %w", gameId, errLeaseHeld)
+		}
+		return err
+	})
+}
+
+func (server *GameServer) releaseSession(ctx context.Context, gameId uuid.UUID) {
+	if server.fanout == nil {
+		return
+	}
+	fanout := server.fanout
+	fanout.enqueue(ctx, func(ctx context.Context) error {
+		return fanout.broker.ReleaseLease(ctx, leaseKey(gameId), fanout.node)
+	})
+}
+
+// Taken before a saved game is resumed so two nodes starting at once don't
+// both resume it
+func (server *GameServer) acquireResumedSession(ctx context.Context, gameId uuid.UUID) (bool, error) {
+	if server.fanout == nil {
+		return true, nil
+	}
+	return server.fanout.broker.AcquireLease(ctx, leaseKey(gameId), server.fanout.node, leaseTTL)
+}
+
+// Run by the scheduler every LeaseInterval
+func (server *GameServer) RenewLeases(ctx context.Context) error {
+	if server.fanout == nil {
+		return nil
+	}
+	var errs []error
+	for _, session := range server.allSessions() {
+		acquired, err := server.fanout.broker.AcquireLease(ctx,
+			leaseKey(session.id), server.fanout.node, leaseTTL)
+		if err == nil && !acquired {
+			err = errLeaseHeld
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("game %s: %w", session.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Let go of every game on shutdown once they've been saved
+func (server *GameServer) releaseLeases(ctx context.Context) error {
+	if server.fanout == nil {
+		return nil
+	}
+	var errs []error
+	for _, session := range server.allSessions() {
+		err := server.fanout.broker.ReleaseLease(ctx, leaseKey(session.id), server.fanout.node)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("game %s: %w", session.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Publishes what the session's viewers were sent for viewers on other nodes
+func (session *Session) fanOut(ctx context.Context, event Event) {
+	fanout := session.server.fanout
+	if fanout == nil {
+		return
+	}
+	// encoded now, the event's fields could change once it's queued
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	channel := eventsChannel(session.id)
+	fanout.enqueue(ctx, func(ctx context.Context) error {
+		return fanout.broker.Publish(ctx, channel, payload)
+	})
+}
+
+func (server *GameServer) answerSyncs(ctx context.Context, requests <-chan []byte) {
+	for payload := range requests {
+		request := syncRequest{}
+		err := json.Unmarshal(payload, &request)
+		if err != nil {
+			logError(ctx, err)
+			continue
+		}
+
+		session, found := server.sessions.load(request.GameId)
+		if !found {
+			continue
+		}
+
+		session.boardStateLock.Lock()
+		event, _ := session.CreateConnectEvent(board.None, Connected)
+		session.boardStateLock.Unlock()
+		response, err := json.Marshal(syncResponse{
+			White: session.players[0].userId,
+			Black: session.players[1].userId,
+			Event: event,
+		})
+		if err == nil {
+			err = server.fanout.broker.Publish(ctx, request.Reply, response)
+		}
+		if err != nil {
+			logError(ctx, err)
+		}
+	}
+}
+
+// Asks the node holding the game for its position
+func (server *GameServer) requestSync(ctx context.Context, node string, gameId uuid.UUID) (syncResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, remoteSyncTimeout)
+	defer cancel()
+
+	broker := server.fanout.broker
+	reply := "node:" + server.fanout.node + ":reply:" + uuid.NewString()
+	responses, err := broker.Subscribe(ctx, reply)
+	if err != nil {
+		return syncResponse{}, err
+	}
+	request, err := json.Marshal(syncRequest{GameId: gameId, Reply: reply})
+	if err != nil {
+		return syncResponse{}, err
+	}
+	err = broker.Publish(ctx, syncChannel(node), request)
+	if err != nil {
+		return syncResponse{}, err
+	}
+
+	select {
+	case payload, ok := <-responses:
+		if !ok {
+			return syncResponse{}, errSyncTimeout
+		}
+		response := syncResponse{}
+		err = json.Unmarshal(payload, &response)
+		return response, err
+	case <-ctx.Done():
+		return syncResponse{}, errSyncTimeout
+	}
+}
+
+// Serves a viewer of a game held by another node, false when no node holds
+// the game
+func (server *GameServer) serveRemoteViewer(
+	ctx context.Context,
+	writer http.ResponseWriter,
+	req *http.Request,
+	gameId uuid.UUID,
+	userId uuid.UUID,
+	moveFormat board.MoveFormat,
+	version protocol.Version,
+	clockPrecision ClockPrecision,
+) bool {
+	fanout := server.fanout
+	node, held, err := fanout.broker.LeaseHolder(ctx, leaseKey(gameId))
+	if err != nil {
+		logError(ctx, err)
+		return false
+	}
+	if !held || node == fanout.node {
+		return false
+	}
+
+	// subscribed before the position is asked for so no moves are missed
+	relayCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	events, err := fanout.broker.Subscribe(relayCtx, eventsChannel(gameId))
+	if err != nil {
+		cancel()
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+		logError(ctx, err)
+		return true
+	}
+	response, err := server.requestSync(ctx, node, gameId)
+	if err != nil {
+		cancel()
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+		logError(ctx, err)
+		return true
+	}
+	if userId == response.White || userId == response.Black {
+		cancel()
+		http.Error(writer, errRemoteGame.Error(), http.StatusMisdirectedRequest)
+		return true
+	}
+
+	conn, err := websocket.Accept(writer, req,
+		server.compression.AcceptOptions(protocol.Subprotocols...))
+	if err != nil {
+		cancel()
+		logError(ctx, err)
+		return true
+	}
+	sub := NewSubscriber(userId, nil, board.None)
+	sub.init(conn, moveFormat, version, clockPrecision)
+
+	event := response.Event
+	currentVersion := protocol.Current
+	event.Version = &version
+	event.CurrentVersion = &currentVersion
+	err = sub.write(relayCtx, event)
+	if err != nil {
+		cancel()
+		conn.CloseNow()
+		logError(ctx, err)
+		return true
+	}
+
+	go sub.relayRemote(relayCtx, cancel, events)
+	return true
+}
+
+// Forwards the game's events until the viewer leaves or the subscription
+// ends, viewers don't send anything so their reads are only for the close
+func (sub *subscriber) relayRemote(ctx context.Context, cancel context.CancelFunc, events <-chan []byte) {
+	defer cancel()
+	defer sub.Conn.CloseNow()
+	closed := sub.Conn.CloseRead(ctx)
+
+	for {
+		select {
+		case payload, ok := <-events:
+			if !ok {
+				sub.Conn.Close(websocket.StatusGoingAway, "game is no longer being relayed")
+				return
+			}
+			event := Event{}
+			err := json.Unmarshal(payload, &event)
+			if err == nil {
+				err = sub.write(ctx, event)
+			}
+			if err != nil {
+				logError(ctx, err)
+				return
+			}
+		case <-closed.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package game_server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"chess/auth"
+
+	"github.com/google/uuid"
+)
+
+// Pub/sub and leases in memory, shared by servers standing in for nodes
+type fakeBroker struct {
+	lock        sync.Mutex
+	leases      map[string]string
+	subscribers map[string][]chan []byte
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		leases:      make(map[string]string),
+		subscribers: make(map[string][]chan []byte),
+	}
+}
+
+func (broker *fakeBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	for _, subscriber := range broker.subscribers[channel] {
+		select {
+		case subscriber <- payload:
+		default:
+		}
+	}
+	return nil
+}
+
+func (broker *fakeBroker) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	messages := make(chan []byte, 16)
+	broker.lock.Lock()
+	broker.subscribers[channel] = append(broker.subscribers[channel], messages)
+	broker.lock.Unlock()
+	go func() {
+		<-ctx.Done()
+		broker.lock.Lock()
+		defer broker.lock.Unlock()
+		broker.subscribers[channel] = slices.DeleteFunc(broker.subscribers[channel],
+			func(subscriber chan []byte) bool { return subscriber == messages })
+		close(messages)
+	}()
+	return messages, nil
+}
+
+func (broker *fakeBroker) AcquireLease(ctx context.Context, key string, node string, ttl time.Duration) (bool, error) {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	if holder, held := broker.leases[key]; held && holder != node {
+		return false, nil
+	}
+	broker.leases[key] = node
+	return true, nil
+}
+
+func (broker *fakeBroker) LeaseHolder(ctx context.Context, key string) (string, bool, error) {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	holder, held := broker.leases[key]
+	return holder, held, nil
+}
+
+func (broker *fakeBroker) ReleaseLease(ctx context.Context, key string, node string) error {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	if broker.leases[key] == node {
+		delete(broker.leases, key)
+	}
+	return nil
+}
+
+func TestRemoteViewer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker := newFakeBroker()
+	owner := NewGameServer(&auth.MockAuthServer{})
+	other := NewGameServer(&auth.MockAuthServer{})
+	for node, server := range map[string]*GameServer{"owner": owner, "other": other} {
+		err := server.SetBroker(ctx, broker, node)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	whiteCookie, white := mockUser()
+	viewerCookie, _ := mockUser()
+	sessionId := owner.NewSession(white, uuid.New(), 0, time.Minute)
+	session, _ := owner.sessions.load(sessionId)
+	// the lease is taken in the background
+	for {
+		holder, _, _ := broker.LeaseHolder(ctx, leaseKey(sessionId))
+		if holder == "owner" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	fixture := newSocketFixture(t, other.ServeMux, sessionId)
+
+	// players are sent to the node holding their game
+	_, resp, err := fixture.open(fixture.url, whiteCookie, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusMisdirectedRequest {
+		t.Fatalf("Expected the player to be turned away, got %v %v", resp, err)
+	}
+
+	viewer, event := fixture.dial(viewerCookie)
+	if event.Type != connectViewer || event.Fen == nil {
+		t.Fatalf("Expected the position from the owning node, got %+v", event)
+	}
+
+	playMoves(t, session, []string{"D1:C2"})
+	if event := fixture.readUntil(viewer, move); *event.Move != "D1:C2" {
+		t.Errorf("Expected the move to be relayed, got %s", *event.Move)
+	}
+
+	session.cleanup(context.Background())
+}
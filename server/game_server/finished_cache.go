@@ -0,0 +1,85 @@
+package game_server
+
+import (
+	"sync"
+	"time"
+
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+// long enough for the post game screens to load without the db
+const finishedGameTTL = 10 * time.Minute
+
+type FinishedGame struct {
+	Replay     ReplayResponse
+	White      uuid.UUID
+	Black      uuid.UUID
+	Result     string
+	Condition  string
+	GameResult board.GameResult
+	Summary    GameSummary
+	Telemetry  GameTelemetry
+}
+
+type cachedGame struct {
+	game      FinishedGame
+	expiresAt time.Time
+}
+
+// games which just ended, kept after the session is cleaned up
+type finishedCache struct {
+	lock  sync.Mutex
+	ttl   time.Duration
+	games map[uuid.UUID]cachedGame
+}
+
+func newFinishedCache(ttl time.Duration) *finishedCache {
+	return &finishedCache{
+		ttl:   ttl,
+		games: make(map[uuid.UUID]cachedGame),
+	}
+}
+
+func (cache *finishedCache) put(id uuid.UUID, game FinishedGame) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	now := time.Now()
+	// expired games are dropped here rather than on a timer
+	for id, cached := range cache.games {
+		if now.After(cached.expiresAt) {
+			delete(cache.games, id)
+		}
+	}
+	cache.games[id] = cachedGame{game: game, expiresAt: now.Add(cache.ttl)}
+}
+
+func (cache *finishedCache) get(id uuid.UUID) (FinishedGame, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cached, found := cache.games[id]
+	if !found || time.Now().After(cached.expiresAt) {
+		return FinishedGame{}, false
+	}
+	return cached.game, true
+}
+
+func (cache *finishedCache) invalidate(id uuid.UUID) {
+	cache.lock.Lock()
+	delete(cache.games, id)
+	cache.lock.Unlock()
+}
+
+// A game which ended in the last few minutes
+func (server *GameServer) FinishedGame(id uuid.UUID) (FinishedGame, bool) {
+	return server.finished.get(id)
+}
+
+// Should be called whenever the stored game is changed, e.g. by analysis,
+// so stale copies aren't served
+func (server *GameServer) InvalidateFinishedGame(id uuid.UUID) {
+	server.finished.invalidate(id)
+}
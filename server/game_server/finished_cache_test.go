@@ -0,0 +1,43 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestFinishedGameCached(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7"})
+	session.handleWin(context.Background(), board.WinResult(board.Black, board.TerminationCheckmate))
+	session.cleanup(context.Background())
+
+	game, found := server.FinishedGame(session.id)
+	if !found {
+		t.Fatal("Expected finished game to be cached")
+	}
+	if game.Result != "0-1" || len(game.Replay.MoveHistory) != 2 || game.Replay.Outcome == nil {
+		t.Errorf("Unexpected cached game %+v", game)
+	}
+
+	server.InvalidateFinishedGame(session.id)
+	if _, found := server.FinishedGame(session.id); found {
+		t.Error("Expected cached game to be invalidated")
+	}
+}
+
+func TestFinishedCacheExpiry(t *testing.T) {
+	cache := newFinishedCache(-time.Second)
+	id := uuid.New()
+	cache.put(id, FinishedGame{})
+	if _, found := cache.get(id); found {
+		t.Error("Expected expired game to be missing")
+	}
+}
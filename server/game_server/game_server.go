@@ -1,10 +1,12 @@
 package game_server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -13,18 +15,46 @@ import (
 
 	"chess/auth"
 	"chess/board"
+	"chess/engine"
+	"chess/msgpack"
+	"chess/pgn"
+	"chess/protocol"
 	"chess/utility"
 
 	"github.com/coder/websocket"
 	"github.com/google/uuid"
 )
 
-type SessionMap = map[uuid.UUID]*Session
 type GameServer struct {
 	ServeMux     *http.ServeMux
-	sessionsLock sync.Mutex
-	sessions     SessionMap
+	sessions     *sessionMap
 	authServer   auth.AuthStrategy
+	matchmaker   Matchmaker
+	auditRules   bool
+	store        GameStore
+	archive      GameArchive
+	liveStore    LiveGameStore
+	finished     *finishedCache
+	openingBook  OpeningBook
+	tablebase    engine.Tablebase
+	badges       *badgeHub
+	lobby        *lobbyHub
+	vacations    *vacationLedger
+	clockAudit   clockAudit
+	compression  protocol.Compression
+	sessionTTLs  SessionTTLs
+	userGames    *userGames
+	gameLimits   GameLimits
+	drainingLock sync.Mutex
+	// set on shutdown
+	draining bool
+	// nil unless games are shared with other nodes
+	fanout *fanout
+
+	// how often sessions send everyone the clocks, see clock_broadcast.go
+	clockBroadcastInterval time.Duration
+	// how long disconnected players have to come back, see grace.go
+	gracePeriods GracePeriods
 }
 
 type Session struct {
@@ -43,10 +73,44 @@ type Session struct {
 	whiteTime  time.Duration
 	blackTime  time.Duration
 	clockTimer *time.Timer
+	// whose time the timer is running down, None while it isn't armed
+	clockColour board.Colour
+	// the player to move is on vacation so their clock isn't running
+	paused bool
+	// rated games don't allow takebacks, every game is casual for now
+	rated bool
+	// whether each colour gave up half their time and their increment,
+	// guarded by clockLock
+	berserk [2]bool
+	// colour asking to take back the last move and how many moves had been
+	// played when they asked, guarded by boardStateLock
+	takebackFrom board.Colour
+	takebackPly  int
+	// see agreement.go, guarded by boardStateLock
+	drawOffer  offer
+	abortOffer offer
+	// remaining times after each move, guarded by boardStateLock
+	clockHistory []ClockSnapshot
+	// how the game ended, the board only knows about results on the board
+	// so the rest are recorded here. nil until then and for aborted games,
+	// guarded by boardStateLock
+	result *board.GameResult
 
 	server    *GameServer
+	ended     bool
+	aborted   bool
+	audit     *ruleAudit
+	sent      *eventLog
 	updatedAt time.Time
 	createdAt time.Time
+	logger    *slog.Logger
+
+	// nil when clocks aren't being broadcast
+	stopClockBroadcasts context.CancelFunc
+
+	// guarded by boardStateLock, see telemetry.go
+	telemetry     GameTelemetry
+	turnStartedAt time.Time
 }
 
 type ConnectionState int8
@@ -58,6 +122,20 @@ const (
 	Closed
 )
 
+func (state ConnectionState) String() string {
+	switch state {
+	case PreConnected:
+		return "preConnected"
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	case Closed:
+		return "closed"
+	}
+	return "unknown"
+}
+
 type subscriber struct {
 	userId           uuid.UUID
 	events           chan Event
@@ -67,6 +145,24 @@ type subscriber struct {
 	state            ConnectionState
 	session          *Session
 	colour           board.Colour
+	moveFormat       board.MoveFormat
+	version          protocol.Version
+	clockPrecision   ClockPrecision
+	// events are sent as binary msgpack frames rather than json text
+	binary bool
+	// set while a finished player is waiting in the queue for a new game
+	cancelRequeue func()
+	drift         driftState
+	lag           lagState
+	throttle      moveThrottle
+	chat          chatThrottle
+	inbound       inboundLimiter
+	logger        *slog.Logger
+	// reused for each message, only the read loop touches it
+	readBuffer bytes.Buffer
+	// set while the player is disconnected, guarded by subscriberLock
+	graceTimer *time.Timer
+	graceEnds  time.Time
 }
 
 func NewSubscriber(
@@ -77,46 +173,91 @@ func NewSubscriber(
 	return &subscriber{
 		userId:           userId,
 		events:           make(chan Event, 10),
-		doneChannel:      make(chan struct{}),
+		doneChannel:      make(chan struct{}, 1),
 		reconnectChannel: make(chan struct{}),
 		session:          session,
 		colour:           colour,
 		state:            PreConnected,
+		logger:           subscriberLogger(session, userId, colour),
 	}
 }
-func (subscriber *subscriber) init(Conn *websocket.Conn) {
+func (subscriber *subscriber) init(
+	Conn *websocket.Conn,
+	moveFormat board.MoveFormat,
+	version protocol.Version,
+	clockPrecision ClockPrecision,
+) {
 	subscriber.Conn = Conn
 	subscriber.state = Connected
+	subscriber.moveFormat = moveFormat
+	subscriber.version = version
+	subscriber.clockPrecision = clockPrecision
+	subscriber.binary = Conn.Subprotocol() == protocol.MsgpackSubprotocol
 }
 
 func NewGameServer(authServer auth.AuthStrategy) *GameServer {
 	server := &GameServer{
-		ServeMux:     http.NewServeMux(),
-		sessions:     make(SessionMap),
-		sessionsLock: sync.Mutex{},
-		authServer:   authServer,
-	}
+		ServeMux:    http.NewServeMux(),
+		sessions:    newSessionMap(),
+		authServer:  authServer,
+		finished:    newFinishedCache(finishedGameTTL),
+		badges:      newBadgeHub(),
+		lobby:       newLobbyHub(),
+		vacations:   newVacationLedger(),
+		compression: protocol.DefaultCompression,
+		sessionTTLs: DefaultSessionTTLs,
+		userGames:   newUserGames(),
+		gameLimits:  DefaultGameLimits,
+
+		clockBroadcastInterval: DefaultClockBroadcastInterval,
+		gracePeriods:           DefaultGracePeriods,
+	}
+	go server.badges.run(func(userId uuid.UUID) int {
+		return len(server.myTurnGames(userId))
+	})
+	go server.lobby.run(server.pickTopGame)
 
 	server.ServeMux.HandleFunc("/subscribe/", server.SubscribeHandler)
+	server.ServeMux.HandleFunc("/replay/", server.ReplayHandler)
+	server.ServeMux.HandleFunc("/audit/", server.AuditHandler)
+	server.ServeMux.HandleFunc("/my-turn", server.MyTurnHandler)
+	server.ServeMux.HandleFunc("/my-turn/count", server.MyTurnCountHandler)
+	server.ServeMux.HandleFunc("GET /my-games", server.MyGamesHandler)
+	server.ServeMux.HandleFunc("/notifications", server.NotificationsHandler)
+	server.ServeMux.HandleFunc("/vacation", server.VacationHandler)
+	server.ServeMux.HandleFunc("/clock-audit", server.ClockAuditHandler)
+	server.ServeMux.HandleFunc("GET /active", server.ActiveGamesHandler)
+	server.ServeMux.HandleFunc("GET /admin/sessions", server.AdminSessionsHandler)
+	server.ServeMux.HandleFunc("POST /admin/sessions/{id}/result", server.AdminResultHandler)
+	server.ServeMux.HandleFunc("POST /admin/sessions/{id}/kick", server.AdminKickHandler)
+	server.ServeMux.HandleFunc("GET /admin/sessions/{id}/telemetry", server.AdminTelemetryHandler)
+	server.ServeMux.HandleFunc("/{id}", server.GameSnapshotHandler)
 
 	return server
 }
 
+// permessage-deflate settings for the game and notification sockets
+func (server *GameServer) SetCompression(compression protocol.Compression) {
+	server.compression = compression
+}
+
 func newSession(
 	white uuid.UUID,
 	black uuid.UUID,
 	increment time.Duration,
 	gameLength time.Duration,
+	variant board.Variant,
 	server *GameServer,
 ) *Session {
-	boardState := board.NewBoard()
+	boardState := board.NewVariantBoard(variant)
 	err := boardState.Init()
 	if err != nil {
 		panic(err)
 	}
 
+	id := uuid.New()
 	session := &Session{
-		id:             uuid.New(),
+		id:             id,
 		boardState:     boardState,
 		boardStateLock: sync.Mutex{},
 
@@ -132,8 +273,17 @@ func newSession(
 		blackTime: gameLength,
 
 		server:    server,
+		sent:      &eventLog{},
 		createdAt: time.Now(),
 		updatedAt: time.Now(),
+		logger:    sessionLogger(id),
+
+		telemetry:     newGameTelemetry(),
+		turnStartedAt: time.Now(),
+	}
+
+	if server.auditRules {
+		session.audit = &ruleAudit{}
 	}
 
 	session.players[0] = NewSubscriber(white, session, board.White)
@@ -148,20 +298,40 @@ func (server *GameServer) NewSession(
 	increment time.Duration,
 	gameLength time.Duration,
 ) uuid.UUID {
-	server.sessionsLock.Lock()
-	defer server.sessionsLock.Unlock()
-
-	session := newSession(white, black, increment, gameLength, server)
-	server.sessions[session.id] = session
-	return session.id
+	return server.NewVariantSession(white, black, increment, gameLength,
+		board.DefaultVariant)
 }
 
-func (server *GameServer) OnShutdown() {
-	// todo
+func (server *GameServer) NewVariantSession(
+	white uuid.UUID,
+	black uuid.UUID,
+	increment time.Duration,
+	gameLength time.Duration,
+	variant board.Variant,
+) uuid.UUID {
+	session := newSession(white, black, increment, gameLength, variant, server)
+	// games where nobody moves are aborted rather than lingering forever,
+	// armed before anyone else can see the session so no lock is needed
+	session.startAbortClockImpl(context.Background(), board.White)
+	session.startClockBroadcasts()
+
+	server.sessions.store(session)
+	server.userGames.add(session)
+	session.turnChanged()
+	server.claimSession(context.Background(), session.id)
+	if game, active := session.activeGame(); active {
+		server.lobby.gameStarted(game)
+	}
+	return session.id
 }
 
 func (server *GameServer) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
+	// anyone can watch, players are authenticated by SubscribeHandler
+	if strings.HasPrefix(req.URL.Path, "/subscribe/") {
+		server.ServeMux.ServeHTTP(writer, req)
+		return
+	}
 	authenticated, err := server.authServer.IsAuthenticated(ctx, writer, req)
 	if err != nil {
 		return
@@ -180,14 +350,106 @@ func logError(ctx context.Context, err error) {
 type eventType = string
 
 const (
-	connect       eventType = "connect"
-	reconnect               = "reconnect"
-	disconnect              = "disconnect"
-	connectViewer           = "connectViewer"
-	move                    = "move"
-	end                     = "end"
-	errorEvent              = "error"
-	abort                   = "abort"
+	connect           eventType = "connect"
+	reconnect                   = "reconnect"
+	disconnect                  = "disconnect"
+	connectViewer               = "connectViewer"
+	move                        = "move"
+	end                         = "end"
+	errorEvent                  = "error"
+	abort                       = "abort"
+	newGame                     = "newGame"
+	clockDrift                  = "clockDrift"
+	vacation                    = "vacation"
+	vacationEnd                 = "vacationEnd"
+	clockSync                   = "clockSync"
+	spectatorsChanged           = "spectators"
+	latency                     = "latency"
+	// the server is going down, clients should reconnect once it's back
+	shutdown = "shutdown"
+	// the remaining times every few seconds while the clock runs
+	clockEvent = "clock"
+	// sent after the end event, see GameSummary
+	summaryType = "summary"
+	// the disconnected player's new grace time after their opponent chose
+	// to wait longer
+	graceExtended = "graceExtended"
+
+	// inbound
+	sendMove    = "sendMove"
+	newOpponent = "newOpponent"
+	moveAck     = "moveAck"
+	resign      = "resign"
+	// sent on to the opponent, an accept is sent to everyone with the
+	// position after the move is taken back
+	takebackRequest = "takebackRequest"
+	takebackAccept  = "takebackAccept"
+	// sent to everyone with the new clock times
+	berserk = "berserk"
+	// sent back out to the other subscribers
+	chat = "chat"
+	// asks for the events after seq to be sent again
+	replay = "replay"
+	// asks for the position, it's sent back to the subscriber alone
+	resync = "sync"
+	// gives a disconnected opponent longer to come back
+	waitLonger = "waitLonger"
+	// offers are sent on to the opponent, accepting ends the game with the
+	// usual end or abort event
+	drawOffer   = "drawOffer"
+	drawAccept  = "drawAccept"
+	abortOffer  = "abortOffer"
+	abortAccept = "abortAccept"
+)
+
+// Sent with error events answering a message from the client, the
+// connection stays open. Only abuse like flooding closes it
+type errorCode = string
+
+const (
+	badJson errorCode = "badJson"
+	// a type the server doesn't handle
+	unknownEvent = "unknownEvent"
+	// not a legal move in the position or not a move at all
+	illegalMove = "illegalMove"
+	// sent by the player not to move or by a viewer
+	notYourTurn = "notYourTurn"
+	// the events asked for are too old, the client should sync instead
+	replayUnavailable = "replayUnavailable"
+	// waiting longer for an opponent who is still connected
+	opponentConnected = "opponentConnected"
+	// nothing to accept, or the offer can't be made at this point
+	offerUnavailable = "offerUnavailable"
+	// sent after the game has ended, or before for things which wait for
+	// the end
+	gameOver    = "gameOver"
+	gameNotOver = "gameNotOver"
+	// the game's rules don't allow it at this point, e.g. berserking after
+	// the first move, or a signed out viewer sending anything but replay and
+	// sync
+	notAllowed = "notAllowed"
+	// sent too soon after the last one, it was dropped
+	rateLimited = "rateLimited"
+	chatTooLong = "chatTooLong"
+)
+
+// Why the game ended, sent with end and abort events. Unlike termination
+// these don't change wording so clients can switch on them, the result
+// says who won
+type endReason = string
+
+const (
+	reasonCheckmate       endReason = "checkmate"
+	reasonStalemate                 = "stalemate"
+	reasonMoveRule                  = "moveRule"
+	reasonInsufficient              = "insufficientMaterial"
+	reasonTimeout                   = "timeout"
+	reasonResignation               = "resignation"
+	reasonDisconnection             = "disconnection"
+	reasonAgreement                 = "agreement"
+	reasonAdjudication              = "adjudication"
+	reasonRulesInfraction           = "rulesInfraction"
+	reasonAbort                     = "abort"
 )
 
 type Event struct {
@@ -197,11 +459,61 @@ type Event struct {
 	Colour      *string   `json:"colour,omitempty"`
 	Move        *string   `json:"move,omitempty"`
 	LegalMoves  *[]string `json:"legalMoves,omitempty"`
-	Outcome     *string   `json:"outcome,omitempty"`
-	Victor      *string   `json:"victor,omitempty"`
-	Text        *string   `json:"text,omitempty"`
-	WhiteTime   *int32    `json:"whiteTime,omitempty"` // Time in milliseconds
-	BlackTime   *int32    `json:"blackTime,omitempty"` // Time in milliseconds
+	// legalMoves in the compact encoding, replaces it from protocol v2 on
+	CompactLegalMoves *string `json:"compactLegalMoves,omitempty"`
+	Outcome           *string `json:"outcome,omitempty"`
+	Victor            *string `json:"victor,omitempty"`
+	Text              *string `json:"text,omitempty"`
+	WhiteTime         *int32  `json:"whiteTime,omitempty"` // Time in milliseconds
+	BlackTime         *int32  `json:"blackTime,omitempty"` // Time in milliseconds
+	GameId            *string `json:"gameId,omitempty"`
+	// chance of white winning, only sent to viewers
+	WinProbability *float64 `json:"winProbability,omitempty"`
+	// client's unix time in milliseconds when it received the last move
+	ReceivedAt *int64 `json:"receivedAt,omitempty"`
+	// estimated client clock offset in milliseconds, positive when ahead
+	ClockDrift *int64 `json:"clockDrift,omitempty"`
+	// unix time in milliseconds the player's vacation ends
+	VacationUntil *int64 `json:"vacationUntil,omitempty"`
+	// times the position after the move has come up, only sent once it's
+	// been seen before so clients can warn about repeating it
+	Repetitions *int `json:"repetitions,omitempty"`
+	// why the game ended, sent with end events e.g. "checkmate"
+	Termination *string `json:"termination,omitempty"`
+	// see endReason, sent with end and abort events
+	Reason *endReason `json:"reason,omitempty"`
+	// "1-0", "0-1" or "1/2-1/2", sent with end events
+	Result *string `json:"result,omitempty"`
+	// sent with summary events
+	Summary *GameSummary `json:"summary,omitempty"`
+	// user id of whoever sent a chat message, colour is theirs too
+	Sender *string `json:"sender,omitempty"`
+	// see chatChannel, sent with chat events
+	Channel *chatChannel `json:"channel,omitempty"`
+	// viewers currently watching, sent with connect events and when it changes
+	Spectators *int `json:"spectators,omitempty"`
+	// the clocks after the move and how long it took, sent with move events
+	Clock *ClockSnapshot `json:"clock,omitempty"`
+	// every move's clock reading, sent with end events
+	Clocks *[]ClockSnapshot `json:"clocks,omitempty"`
+	// colours which have berserked, sent with connect events
+	Berserk *[]string `json:"berserk,omitempty"`
+	// the event format the subscriber is being sent and the newest the
+	// server has, sent with their own connect event so outdated clients
+	// can tell they're on the way out
+	Version        *protocol.Version `json:"version,omitempty"`
+	CurrentVersion *protocol.Version `json:"currentVersion,omitempty"`
+	// ping round trips in milliseconds, sent with latency events
+	WhiteLatency *int32 `json:"whiteLatency,omitempty"`
+	BlackLatency *int32 `json:"blackLatency,omitempty"`
+	// what was wrong with the message, sent with error events
+	Code *errorCode `json:"code,omitempty"`
+	// number of the event among everything the session has sent, the
+	// latest with connect events, see eventLog
+	Seq *int64 `json:"seq,omitempty"`
+	// milliseconds the disconnected player has left to come back, sent
+	// with disconnect and graceExtended events
+	GraceTime *int32 `json:"graceTime,omitempty"`
 }
 
 func moveList(moves []board.Move) []string {
@@ -224,32 +536,146 @@ func serialiseColour(colour board.Colour) string {
 	return retColour
 }
 
+// clients can ask for moves in uci notation with ?moveFormat=uci
+const moveFormatQueryKey = "moveFormat"
+
+// events are created in the default coords format, this re-serialises
+// the move fields for subscribers which negotiated a different format
+func convertMoveFormat(event Event, format board.MoveFormat) (Event, error) {
+	if format == board.CoordsFormat {
+		return event, nil
+	}
+
+	convert := func(str string) (string, error) {
+		move, err := board.DeserialiseMove(str)
+		if err != nil {
+			return "", err
+		}
+		return move.SerialiseFormat(format), nil
+	}
+	// the lists come from stringListPool, see releaseConverted
+	convertList := func(list []string) (*[]string, error) {
+		ret := getStringList()
+		for _, str := range list {
+			converted, err := convert(str)
+			if err != nil {
+				putStringList(ret)
+				return nil, err
+			}
+			*ret = append(*ret, converted)
+		}
+		return ret, nil
+	}
+
+	if event.Move != nil {
+		move, err := convert(*event.Move)
+		if err != nil {
+			return event, err
+		}
+		event.Move = &move
+	}
+	if event.MoveHistory != nil {
+		history, err := convertList(*event.MoveHistory)
+		if err != nil {
+			return event, err
+		}
+		event.MoveHistory = history
+	}
+	if event.LegalMoves != nil {
+		legalMoves, err := convertList(*event.LegalMoves)
+		if err != nil {
+			putStringList(event.MoveHistory)
+			return event, err
+		}
+		event.LegalMoves = legalMoves
+	}
+	return event, nil
+}
+
+// Swaps the legal move list for its compact encoding, which is the same
+// whatever move format the subscriber asked for
+func compactLegalMoves(event Event) (Event, error) {
+	if event.LegalMoves == nil {
+		return event, nil
+	}
+	moves := make([]board.Move, len(*event.LegalMoves))
+	for i, str := range *event.LegalMoves {
+		var err error
+		moves[i], err = board.DeserialiseMove(str)
+		if err != nil {
+			return event, err
+		}
+	}
+	compact := board.SerialiseMoveListCompact(moves)
+	event.LegalMoves = nil
+	event.CompactLegalMoves = &compact
+	return event, nil
+}
+
+// Hands the lists convertMoveFormat made back to the pool once the
+// converted event has been encoded
+func releaseConverted(original, converted Event) {
+	if converted.MoveHistory != original.MoveHistory {
+		putStringList(converted.MoveHistory)
+	}
+	if converted.LegalMoves != original.LegalMoves {
+		putStringList(converted.LegalMoves)
+	}
+}
+
 func (server *GameServer) SubscribeHandler(
 	writer http.ResponseWriter,
 	req *http.Request,
 ) {
 	ctx := req.Context()
+	if server.rejectWhileDraining(writer) {
+		return
+	}
 	gameId, err := getId(writer, req)
 	if err != nil {
 		logError(ctx, err)
 		return
 	}
 
-	// todo getting back a lot of useless data
-	authSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	moveFormat, err := board.ParseMoveFormat(req.URL.Query().Get(moveFormatQueryKey))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		logError(ctx, err)
+		return
+	}
+
+	version, err := protocol.Parse(req.URL.Query().Get(protocol.QueryKey))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		logError(ctx, err)
+		return
+	}
+
+	clockPrecision, err := ParseClockPrecision(req.URL.Query().Get(clockPrecisionQueryKey))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		logError(ctx, err)
+		return
+	}
+
+	userId, err := server.subscriberId(ctx, writer, req)
 	if err != nil {
 		logError(ctx, err)
 		return
 	}
 
 	slog.InfoContext(ctx, "subscribing user",
-		slog.String("email", authSession.UserEmail),
-		slog.String("gameid", gameId.String()))
+		slog.String("userId", userId.String()),
+		slog.Bool("anonymous", userId == anonymousViewerId),
+		slog.String("gameid", gameId.String()),
+		slog.String("version", version.String()))
 
-	server.sessionsLock.Lock()
-	session, found := server.sessions[gameId]
-	server.sessionsLock.Unlock()
+	session, found := server.sessions.load(gameId)
 
+	if !found && server.fanout != nil && server.serveRemoteViewer(ctx, writer, req,
+		gameId, userId, moveFormat, version, clockPrecision) {
+		return
+	}
 	if !found {
 		// todo accept header
 		writer.WriteHeader(404)
@@ -259,7 +685,7 @@ func (server *GameServer) SubscribeHandler(
 	}
 
 	session.subscriberLock.Lock()
-	sub, colour := session.getSubscriber(ctx, authSession.UserID)
+	sub, colour := session.getSubscriber(ctx, userId)
 	session.subscriberLock.Unlock()
 
 	if colour >= board.White && sub.state == Connected {
@@ -275,17 +701,24 @@ func (server *GameServer) SubscribeHandler(
 	}
 
 	// todo accept header
-	conn, err := websocket.Accept(writer, req, &websocket.AcceptOptions{OriginPatterns: []string{"*"}})
+	conn, err := websocket.Accept(writer, req,
+		server.compression.AcceptOptions(protocol.Subprotocols...))
 	if err != nil {
 		logError(ctx, err)
 		return
 	}
 
-	sub.init(conn)
+	sub.init(conn, moveFormat, version, clockPrecision)
+	if colour != board.None {
+		session.recordClient(sub, req.UserAgent())
+	}
 
 	ctx = context.WithoutCancel(ctx)
 
 	subEvent, eventForOthers := session.CreateConnectEvent(colour, state)
+	currentVersion := protocol.Current
+	subEvent.Version = &version
+	subEvent.CurrentVersion = &currentVersion
 
 	err = sub.write(ctx, subEvent)
 	if err != nil {
@@ -296,9 +729,7 @@ func (server *GameServer) SubscribeHandler(
 
 	session.publish(ctx, sub, eventForOthers)
 
-	if colour != board.None {
-		go sub.initRead(ctx)
-	}
+	go sub.initRead(ctx)
 	go sub.initWrite(ctx)
 }
 
@@ -307,16 +738,15 @@ func (session *Session) getSubscriber(
 	ctx context.Context,
 	userId uuid.UUID,
 ) (*subscriber, board.Colour) {
-	if userId == session.players[0].userId {
-		slog.InfoContext(ctx, "added client to session as white player", slog.String("id", userId.String()))
-		return session.players[0], board.White
-	} else if userId == session.players[1].userId {
-		slog.InfoContext(ctx, "added client to session as black player", slog.String("id", userId.String()))
-		return session.players[1], board.Black
+	for _, player := range session.players {
+		if userId == player.userId {
+			player.logger.InfoContext(ctx, "added client to session as player")
+			return player, player.colour
+		}
 	}
 
-	slog.InfoContext(ctx, "added client to session as viewer", slog.String("id", userId.String()))
 	sub := NewSubscriber(userId, session, board.None)
+	sub.logger.InfoContext(ctx, "added client to session as viewer")
 	session.viewers.Add(sub)
 	return sub, board.None
 }
@@ -329,18 +759,25 @@ func (session *Session) CreateConnectEvent(
 	whiteTime, blackTime := session.getClockState()
 	whiteTimeMs := int32(whiteTime.Milliseconds())
 	blackTimeMs := int32(blackTime.Milliseconds())
+	spectators := session.spectatorCount()
+	berserk := session.berserkColours()
+	seq := session.sent.latest()
 
 	if colour == board.None {
-		list := moveList(session.boardState.MoveHistory)
+		list := moveList(session.boardState.PlayedMoves())
 		subEvent = Event{
 			Type:        connectViewer,
 			Fen:         &fen,
 			MoveHistory: &list,
 			WhiteTime:   &whiteTimeMs,
 			BlackTime:   &blackTimeMs,
+			Spectators:  &spectators,
+			Berserk:     berserk,
+			Seq:         &seq,
 		}
 		otherEvent = Event{
-			Type: connectViewer,
+			Type:       connectViewer,
+			Spectators: &spectators,
 		}
 	} else {
 		var connectionType string
@@ -361,6 +798,9 @@ func (session *Session) CreateConnectEvent(
 			LegalMoves:  &legalMoves,
 			WhiteTime:   &whiteTimeMs,
 			BlackTime:   &blackTimeMs,
+			Spectators:  &spectators,
+			Berserk:     berserk,
+			Seq:         &seq,
 		}
 		otherEvent = Event{
 			Type:   connectionType,
@@ -372,17 +812,22 @@ func (session *Session) CreateConnectEvent(
 
 func (session *Session) DeleteSubscriber(ctx context.Context, sub *subscriber) {
 	if session.players[0] == sub {
-		session.handleWin(ctx, board.BlackWin)
+		session.handleWin(ctx, board.WinResult(board.Black, board.TerminationAbandonment))
 		return
 	} else if sub.session.players[1] == sub {
-		session.handleWin(ctx, board.WhiteWin)
+		session.handleWin(ctx, board.WinResult(board.White, board.TerminationAbandonment))
 		return
 	}
 
 	// TODO concurrent map writes probably because this is being called twice?
 	session.subscriberLock.Lock()
+	watching := session.viewers.Has(sub)
 	session.viewers.Remove(sub)
 	session.subscriberLock.Unlock()
+
+	if watching {
+		session.publishSpectators(ctx, nil)
+	}
 }
 
 func (session *Session) publishImpl(ctx context.Context, event Event, sub *subscriber) {
@@ -398,24 +843,46 @@ func (session *Session) publishImpl(ctx context.Context, event Event, sub *subsc
 }
 
 func (session *Session) publish(ctx context.Context, sub *subscriber, event Event) {
+	session.publishSplit(ctx, sub, event, event)
+}
+
+// viewers can be sent extra info the players shouldn't see mid game
+func (session *Session) publishSplit(
+	ctx context.Context,
+	sub *subscriber,
+	playerEvent Event,
+	viewerEvent Event,
+) {
+	session.sent.record(sub, &playerEvent, &viewerEvent)
+
+	// sent once the lock's let go, a slow viewer is removed under it
+	session.subscriberLock.Lock()
+	viewers := make([]*subscriber, 0, session.viewers.Len())
+	for viewer := range session.viewers.Keys() {
+		viewers = append(viewers, viewer)
+	}
+	session.subscriberLock.Unlock()
+
 	count := 0
 	for _, player := range session.players {
 		if player == sub {
 			continue
 		}
 		count += 1
-		session.publishImpl(ctx, event, player)
+		session.publishImpl(ctx, playerEvent, player)
 	}
-	for viewer := range session.viewers.Keys() {
+	for _, viewer := range viewers {
 		if viewer == sub {
 			continue
 		}
 		count += 1
-		session.publishImpl(ctx, event, viewer)
+		session.publishImpl(ctx, viewerEvent, viewer)
 	}
+	session.fanOut(ctx, viewerEvent)
+	session.server.lobby.viewerEvent(session.id, viewerEvent)
 
-	slog.Info("subscribers were sent an event",
-		slog.Int("count", count), slog.Any("event", event))
+	session.logger.Info("subscribers were sent an event",
+		slog.Int("count", count), slog.Any("event", playerEvent))
 }
 
 func (session *Session) handleError(ctx context.Context, err error) {
@@ -424,11 +891,27 @@ func (session *Session) handleError(ctx context.Context, err error) {
 	for _, player := range session.players {
 		player.closeNow(nil, err)
 	}
+	// closing a viewer removes it under the lock
+	session.subscriberLock.Lock()
+	viewers := make([]*subscriber, 0, session.viewers.Len())
 	for viewer := range session.viewers.Keys() {
+		viewers = append(viewers, viewer)
+	}
+	session.subscriberLock.Unlock()
+	for _, viewer := range viewers {
 		viewer.closeNow(nil, err)
 	}
 }
 
+// Bad moves from a client which are answered with an error event and the
+// game carries on, anything else from the board package means the session
+// can't continue
+func isRejectedMove(err error) bool {
+	return errors.Is(err, board.ErrIllegalMove) ||
+		errors.Is(err, board.ErrInvalidMove) ||
+		errors.Is(err, board.ErrInvalidSquare)
+}
+
 func (session *Session) handleMove(
 	ctx context.Context,
 	sub *subscriber,
@@ -437,6 +920,16 @@ func (session *Session) handleMove(
 	session.boardStateLock.Lock()
 	defer session.boardStateLock.Unlock()
 
+	if session.ended {
+		session.recordAudit(moveRejected, "move sent after game end", &move)
+		return errors.New("move sent after game end")
+	}
+	// rejected before the clock is touched, the game carries on
+	if !session.boardState.IsLegal(move) {
+		session.recordAudit(moveRejected, "not a legal move", &move)
+		return board.ErrIllegalMove
+	}
+
 	moving := session.boardState.WhoseMove()
 
 	whiteTime := session.whiteTime
@@ -444,22 +937,31 @@ func (session *Session) handleMove(
 
 	// clock only starts after both players have made their first move
 	startClock := session.boardState.MoveCounter > 1
+	spent := time.Duration(0)
 
 	if startClock {
+		// not held for the rest of the move, ending the game stops the clock
+		// itself
 		session.clockLock.Lock()
-		defer session.clockLock.Unlock()
 		session.stopClockImpl()
-		session.updateClockImpl()
+		spent = session.updateClockImpl()
 
 		// shouldn't really happen but w/evs
 		whiteTime, blackTime = session.getClockStateImpl()
-		if moving == board.White && whiteTime <= 0 {
-			session.handleTimeLossImpl(ctx, board.White)
-		} else if moving == board.Black && blackTime <= 0 {
-			session.handleTimeLossImpl(ctx, board.Black)
+		outOfTime := (moving == board.White && whiteTime <= 0) ||
+			(moving == board.Black && blackTime <= 0)
+		if outOfTime {
+			session.handleTimeLossImpl(ctx, moving)
+		}
+		session.clockLock.Unlock()
+		// the game's over, the move can't be played after it
+		if outOfTime {
+			session.recordAudit(moveRejected, "move sent after time ran out", &move)
+			return errors.New("move sent after time ran out")
 		}
 	} else {
-
+		// the mover's abort timer, the opponent gets their own below
+		session.stopClock()
 	}
 
 	err := session.boardState.MakeMove(move)
@@ -467,15 +969,35 @@ func (session *Session) handleMove(
 		session.handleError(ctx, err)
 		return err
 	}
+	session.recordAudit(moveAccepted, "legal move", &move)
+	session.recordMoveImpl(sub)
+	session.turnChanged()
+	sub.logger.DebugContext(ctx, "move played", slog.String("move", move.Serialise()))
 
 	serialisedLegalMoves := board.SerialiseMoveList(session.boardState.LegalMoves)
 	moveStr := move.Serialise()
 	fen := session.boardState.Fen()
 	whiteTimeMs := int32(whiteTime.Milliseconds())
 	blackTimeMs := int32(blackTime.Milliseconds())
+	clock := ClockSnapshot{
+		WhiteTime: whiteTimeMs,
+		BlackTime: blackTimeMs,
+		Spent:     int32(spent.Milliseconds()),
+		PlayedAt:  time.Now().UnixMilli(),
+	}
+	session.clockHistory = append(session.clockHistory, clock)
 	event := moveEvent(&moveStr, &fen, &serialisedLegalMoves,
 		&whiteTimeMs, &blackTimeMs)
-	session.publish(ctx, sub, event)
+	event.Clock = &clock
+	if repetitions := session.boardState.RepetitionCount(); repetitions > 1 {
+		event.Repetitions = &repetitions
+	}
+	viewerEvent := event
+	if session.viewers.Len() > 0 {
+		probability := probabilityCache.get(session.boardState)
+		viewerEvent.WinProbability = &probability
+	}
+	session.publishSplit(ctx, sub, event, viewerEvent)
 
 	if session.boardState.WinState > board.NoWin {
 		err = errors.New("move sent after game end")
@@ -483,14 +1005,23 @@ func (session *Session) handleMove(
 		return err
 	}
 
-	win := session.boardState.HasWinner()
-	if win > board.NoWin {
-		session.handleWinImpl(ctx, win)
+	result := board.BoardResult(session.boardState.HasWinner())
+	if !result.IsOver() {
+		if win := session.adjudicateImpl(); win > board.NoWin {
+			result = board.BoardResult(win)
+			result.Reason = board.TerminationAdjudicated
+		}
+	}
+	if result.IsOver() {
+		session.handleWinImpl(ctx, result)
 		return nil
 	}
 
 	if startClock {
-		session.startClockImpl(ctx, board.OppositeColour(moving))
+		session.startClock(ctx, board.OppositeColour(moving))
+	} else if session.boardState.MoveCounter == 1 {
+		// the game can be aborted until both players have made a move
+		session.startAbortClock(ctx, board.Black)
 	}
 	return nil
 }
@@ -512,50 +1043,121 @@ func moveEvent(
 	}
 }
 
-func (session *Session) handleWin(ctx context.Context, win board.WinState) {
+func (session *Session) handleWin(ctx context.Context, result board.GameResult) {
 	session.boardStateLock.Lock()
-	session.handleWinImpl(ctx, win)
+	session.handleWinImpl(ctx, result)
 	session.boardStateLock.Unlock()
 }
-func (session *Session) handleWinImpl(ctx context.Context, win board.WinState) {
-	slog.Info("win",
-		slog.String("condition", board.WinStateToString(win)),
-		slog.String("sessionId", session.id.String()))
+func (session *Session) handleWinImpl(ctx context.Context, result board.GameResult) {
+	if session.ended {
+		return
+	}
+	session.ended = true
+	session.result = &result
+	session.turnChanged()
+	session.server.userGames.remove(session)
+	session.recordAudit(gameEnded, result.String(), nil)
+	summary := session.saveImpl(ctx, result)
+
+	session.logger.InfoContext(ctx, "win",
+		slog.String("condition", board.WinStateToString(result.WinState())),
+		slog.String("termination", string(result.Reason)))
 
 	session.stopClock()
 
+	ended := session.endEventImpl(result)
+	session.publish(ctx, nil, ended)
+	session.server.lobby.gameEnded(session.id, ended)
+	session.publish(ctx, nil, summaryEvent(summary))
+
+	go func() {
+		time.Sleep(5 * time.Second)
+		session.cleanup(ctx)
+	}()
+}
+
+// draws each have their own outcome and no victor, termination says why
+// the game ended e.g. checkmate or time forfeit
+func endEvent(result board.GameResult) Event {
 	var outcome string
-	var victor string
+	var victor *string
+	win := result.WinState()
 	switch win {
-	case board.BlackWin:
-		outcome = "win"
-		victor = "b"
-	case board.WhiteWin:
+	case board.WhiteWin, board.BlackWin:
 		outcome = "win"
-		victor = "w"
+		colour := serialiseColour(board.Colour(win))
+		victor = &colour
 	case board.Stalemate:
 		outcome = "stalemate"
-		victor = "w"
 	case board.MoveRuleDraw:
-		outcome = "stalemate"
-		victor = "w"
-	case board.NoWin:
-		fallthrough
+		outcome = "moveRuleDraw"
+	case board.AgreedDraw:
+		outcome = "agreement"
+	case board.TimeoutDraw:
+		outcome = "timeoutDraw"
+	case board.DeadPosition:
+		outcome = "deadPosition"
 	default:
+		outcome = "draw"
+	}
+	termination := string(result.Reason)
+	reason := endReasonFor(result.Reason)
+	pgnResult := pgn.ResultFromWinState(win)
+	return Event{
+		Type:        end,
+		Outcome:     &outcome,
+		Victor:      victor,
+		Termination: &termination,
+		Reason:      &reason,
+		Result:      &pgnResult,
 	}
-	session.publish(ctx, nil, Event{Type: "end", Outcome: &outcome, Victor: &victor})
+}
 
-	go func() {
-		time.Sleep(5 * time.Second)
-		session.cleanup(ctx)
-	}()
+func endReasonFor(termination board.Termination) endReason {
+	switch termination {
+	case board.TerminationCheckmate:
+		return reasonCheckmate
+	case board.TerminationStalemate:
+		return reasonStalemate
+	case board.TerminationMoveRule:
+		return reasonMoveRule
+	case board.TerminationDeadPosition:
+		return reasonInsufficient
+	case board.TerminationTimeForfeit, board.TerminationTimeoutDraw:
+		return reasonTimeout
+	case board.TerminationResignation:
+		return reasonResignation
+	case board.TerminationAbandonment:
+		return reasonDisconnection
+	case board.TerminationAgreement:
+		return reasonAgreement
+	case board.TerminationRulesInfraction:
+		return reasonRulesInfraction
+	default:
+		return reasonAdjudication
+	}
 }
 
-func writeTimeout(ctx context.Context, timeout time.Duration, wsConn *websocket.Conn, msg []byte) error {
+// The end event with the clock readings of the whole game, boardStateLock
+// should be held
+func (session *Session) endEventImpl(result board.GameResult) Event {
+	event := endEvent(result)
+	clocks := session.clockHistoryImpl()
+	event.Clocks = &clocks
+	return event
+}
+
+func writeTimeout(
+	ctx context.Context,
+	timeout time.Duration,
+	wsConn *websocket.Conn,
+	msgType websocket.MessageType,
+	msg []byte,
+) error {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	return wsConn.Write(ctx, websocket.MessageText, msg)
+	return wsConn.Write(ctx, msgType, msg)
 }
 
 func (sub *subscriber) closeNow(ctx context.Context, err error) {
@@ -568,11 +1170,17 @@ func (sub *subscriber) closeNow(ctx context.Context, err error) {
 		sub.doneChannel <- struct{}{}
 	}
 
-	slog.Info("closing")
+	sub.logger.InfoContext(ctx, "closing")
 	if err != nil {
-		logError(ctx, err)
+		sub.logError(ctx, err)
+	}
+	if sub.Conn != nil {
+		sub.Conn.CloseNow()
+	}
+	if sub.cancelRequeue != nil {
+		sub.cancelRequeue()
+		sub.cancelRequeue = nil
 	}
-	sub.Conn.CloseNow()
 	sub.session.DeleteSubscriber(ctx, sub)
 }
 
@@ -582,7 +1190,7 @@ func (sub *subscriber) closeSlow(ctx context.Context) {
 	}
 	sub.state = Closed
 
-	slog.Info("closing")
+	sub.logger.InfoContext(ctx, "closing slow subscriber")
 	if sub.Conn != nil {
 		err := sub.Conn.Close(websocket.StatusPolicyViolation, "connection too slow to keep up with messages")
 		if err != nil {
@@ -593,69 +1201,164 @@ func (sub *subscriber) closeSlow(ctx context.Context) {
 	sub.session.DeleteSubscriber(ctx, sub)
 }
 
-var buffer = [1000]byte{}
+// Chat messages are the longest thing clients send, a message past this
+// isn't from a real client so the connection is closed
+const maxInboundMessage = 4096
+
+var errMessageTooBig = errors.New("message too big")
 
+// Reads until the connection goes, a reconnecting player gets a new loop
 func (sub *subscriber) initRead(ctx context.Context) {
-	for {
-		sub.initReadImpl(ctx)
+	for sub.initReadImpl(ctx) {
 	}
 }
-func (sub *subscriber) initReadImpl(ctx context.Context) {
+
+// Handles one message, false once the connection has gone
+func (sub *subscriber) initReadImpl(ctx context.Context) bool {
 	msgType, reader, err := sub.Conn.Reader(ctx)
 	if err != nil {
 		closeStatus := websocket.CloseStatus(err)
-		slog.InfoContext(ctx, "close", slog.String("code", closeStatus.String()))
+		sub.logger.InfoContext(ctx, "close", slog.String("code", closeStatus.String()))
 
-		if closeStatus == websocket.StatusGoingAway {
+		// viewers have nothing to come back to
+		if closeStatus == websocket.StatusGoingAway && sub.colour != board.None {
 			sub.Disconnected(ctx, err)
-			return
+			return false
 		}
 
 		sub.closeNow(ctx, err)
-		return
+		return false
 	}
 
-	if msgType != websocket.MessageText {
-		return
+	// msgpack clients may send either
+	if msgType != websocket.MessageText && !(sub.binary && msgType == websocket.MessageBinary) {
+		return true
 	}
 
-	n, err := reader.Read(buffer[:])
+	sub.readBuffer.Reset()
+	n, err := sub.readBuffer.ReadFrom(io.LimitReader(reader, maxInboundMessage+1))
 	if err != nil {
 		sub.closeNow(ctx, err)
-		return
+		return false
+	}
+	if n > maxInboundMessage {
+		sub.closeNow(ctx, errMessageTooBig)
+		return false
+	}
+	if !sub.limitInbound(ctx) {
+		return sub.state != Closed
 	}
 
 	eventBuffer := Event{}
-	err = json.Unmarshal(buffer[:n], &eventBuffer)
+	if msgType == websocket.MessageBinary {
+		err = msgpack.Unmarshal(sub.readBuffer.Bytes(), &eventBuffer)
+	} else {
+		err = json.Unmarshal(sub.readBuffer.Bytes(), &eventBuffer)
+	}
 	if err != nil {
-		sub.closeNow(ctx, err)
-		return
+		sub.sendError(ctx, badJson, err)
+		return true
+	}
+
+	if sub.colour == board.None {
+		sub.handleViewerEvent(ctx, eventBuffer)
+		return true
+	}
+
+	switch eventBuffer.Type {
+	case sendMove:
+		sub.handleSendMove(ctx, eventBuffer)
+	case newOpponent:
+		sub.handleNewOpponent(ctx)
+	case moveAck:
+		sub.handleMoveAck(ctx, eventBuffer)
+	case resign:
+		sub.handleResign(ctx)
+	case takebackRequest:
+		sub.handleTakebackRequest(ctx)
+	case takebackAccept:
+		sub.handleTakebackAccept(ctx)
+	case berserk:
+		sub.handleBerserk(ctx)
+	case chat:
+		sub.handleChat(ctx, eventBuffer)
+	case replay:
+		sub.handleReplay(ctx, eventBuffer)
+	case resync:
+		sub.handleSync(ctx)
+	case waitLonger:
+		sub.handleWaitLonger(ctx)
+	case drawOffer, abortOffer:
+		sub.handleOffer(ctx, eventBuffer.Type)
+	case drawAccept:
+		sub.handleDrawAccept(ctx)
+	case abortAccept:
+		sub.handleAbortAccept(ctx)
+	default:
+		sub.sendError(ctx, unknownEvent, fmt.Errorf("unexpected event type: %s", eventBuffer.Type))
 	}
-	if eventBuffer.Type != "sendMove" {
-		sub.closeNow(ctx, errors.New("event sent is not \"sendMove\""))
+	return true
+}
+
+// Viewers can only talk amongst themselves and catch up on the game
+func (sub *subscriber) handleViewerEvent(ctx context.Context, event Event) {
+	// signed out viewers can only watch
+	if sub.userId == anonymousViewerId && event.Type != replay && event.Type != resync {
+		sub.sendError(ctx, notAllowed, fmt.Errorf("sign in to send %s events", event.Type))
 		return
 	}
 
+	switch event.Type {
+	case chat:
+		sub.handleChat(ctx, event)
+	case replay:
+		sub.handleReplay(ctx, event)
+	case resync:
+		sub.handleSync(ctx)
+	case sendMove, newOpponent, moveAck, resign, takebackRequest, takebackAccept, berserk,
+		waitLonger, drawOffer, drawAccept, abortOffer, abortAccept:
+		sub.sendError(ctx, notYourTurn, fmt.Errorf("viewers can't send %s events", event.Type))
+	default:
+		sub.sendError(ctx, unknownEvent, fmt.Errorf("unexpected event type: %s", event.Type))
+	}
+}
+
+// Answers a bad message from the subscriber, they can carry on
+func (sub *subscriber) sendError(ctx context.Context, code errorCode, err error) {
+	sub.logger.InfoContext(ctx, "message rejected",
+		slog.String("code", code), slog.Any("error", err))
+	text := err.Error()
+	sub.session.publishImpl(ctx, Event{Type: errorEvent, Text: &text, Code: &code}, sub)
+}
+
+func (sub *subscriber) handleSendMove(ctx context.Context, eventBuffer Event) {
 	if sub.colour != board.White && sub.colour != board.Black {
-		sub.closeNow(ctx, errors.New("invalid colour"))
+		sub.sendError(ctx, notYourTurn, errors.New("viewers can't move"))
 		return
 	}
-
-	if sub.colour != sub.session.boardState.WhoseMove() {
-		sub.closeNow(ctx, errors.New("not player to move"))
-		colour := board.OppositeColour(sub.colour)
-		sub.session.handleWin(ctx, board.ColourToWinState(colour))
+	if eventBuffer.Move == nil {
+		sub.sendError(ctx, badJson, errors.New("sendMove without a move"))
+		return
+	}
+	if !sub.throttleMove(ctx) {
 		return
 	}
 
-	move, err := board.DeserialiseMove(*eventBuffer.Move)
-	if err != nil {
-		sub.closeNow(ctx, err)
+	if sub.colour != sub.session.boardState.WhoseMove() {
+		sub.session.recordAudit(moveRejected, "not player to move", nil)
+		sub.sendError(ctx, notYourTurn, board.ErrWrongTurn)
 		return
 	}
-	fmt.Printf("%+v\n", move)
 
-	_ = sub.session.handleMove(ctx, sub, move)
+	// moveFormat is only for what's sent, any format is accepted
+	move, err := board.ParseMove(*eventBuffer.Move)
+	if err == nil {
+		err = sub.session.handleMove(ctx, sub, move)
+	}
+	// anything other than a bad move has already been dealt with by handleMove
+	if isRejectedMove(err) {
+		sub.sendError(ctx, illegalMove, err)
+	}
 }
 
 const (
@@ -664,12 +1367,36 @@ const (
 )
 
 func (sub *subscriber) write(ctx context.Context, event Event) error {
-	resp, err := json.Marshal(event)
+	var err error
+	if sub.version >= protocol.V2 {
+		event, err = compactLegalMoves(event)
+		if err != nil {
+			return err
+		}
+	}
+	converted, err := convertMoveFormat(event, sub.moveFormat)
 	if err != nil {
 		return err
 	}
 
-	err = writeTimeout(ctx, time.Second*5, sub.Conn, resp)
+	if sub.binary {
+		encoded, err := msgpack.Marshal(converted)
+		releaseConverted(event, converted)
+		if err != nil {
+			return err
+		}
+		return writeTimeout(ctx, time.Second*5, sub.Conn, websocket.MessageBinary, encoded)
+	}
+
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+	err = json.NewEncoder(buffer).Encode(converted)
+	releaseConverted(event, converted)
+	if err != nil {
+		return err
+	}
+
+	err = writeTimeout(ctx, time.Second*5, sub.Conn, websocket.MessageText, buffer.Bytes())
 	if err != nil {
 		return err
 	}
@@ -680,6 +1407,10 @@ func (sub *subscriber) write(ctx context.Context, event Event) error {
 func (sub *subscriber) initWrite(ctx context.Context) {
 	pinger := time.NewTicker(pingInterval)
 	defer pinger.Stop()
+	clockTicker, clockSyncs := sub.clockSyncTicker()
+	if clockTicker != nil {
+		defer clockTicker.Stop()
+	}
 
 	for {
 		select {
@@ -692,24 +1423,38 @@ func (sub *subscriber) initWrite(ctx context.Context) {
 				sub.closeNow(ctx, err)
 				return
 			}
+			if event.Type == move {
+				sub.drift.moveSent()
+			}
+		case <-clockSyncs:
+			event, send := sub.session.clockSyncEvent()
+			if !send {
+				continue
+			}
+			err := sub.write(ctx, event)
+			if err != nil {
+				sub.closeNow(ctx, err)
+				return
+			}
 		case <-pinger.C:
-			slog.InfoContext(ctx, "pinging")
+			sub.logger.InfoContext(ctx, "pinging")
 			ctx, cancel := context.WithTimeout(ctx, pongWait)
 			defer cancel()
 
+			pingedAt := time.Now()
 			err := sub.Conn.Ping(ctx)
 
 			if err != nil {
-				slog.Info("ping failed",
-					slog.String("userId", sub.userId.String()),
-					slog.String("gameid", sub.session.id.String()))
+				sub.logger.InfoContext(ctx, "ping failed")
 				sub.Disconnected(ctx, err)
 				return
 			}
+			sub.lag.record(time.Since(pingedAt))
+			if sub.colour != board.None {
+				sub.session.publishLatency(ctx)
+			}
 
-			slog.Info("ping succeeded",
-				slog.String("userId", sub.userId.String()),
-				slog.String("gameid", sub.session.id.String()))
+			sub.logger.InfoContext(ctx, "ping succeeded")
 		case <-ctx.Done():
 			sub.closeNow(ctx, nil)
 			return
@@ -723,31 +1468,31 @@ func (sub *subscriber) Disconnected(ctx context.Context, err error) {
 	}
 	sub.state = Disconnected
 
-	colour := serialiseColour(sub.colour)
-	sub.session.publish(ctx, nil, Event{
-		Type:   disconnect,
-		Colour: &colour,
-	})
-
-	duration := sub.session.gameLength / 10
+	duration := sub.session.gracePeriod()
 	timer := time.NewTimer(duration)
-	defer timer.Stop()
+	sub.session.subscriberLock.Lock()
+	sub.graceTimer = timer
+	sub.graceEnds = time.Now().Add(duration)
+	sub.session.subscriberLock.Unlock()
+	defer func() {
+		sub.session.subscriberLock.Lock()
+		sub.graceTimer = nil
+		sub.session.subscriberLock.Unlock()
+		timer.Stop()
+	}()
+
+	// the connection's gone so it isn't sent its own disconnect
+	sub.session.publish(ctx, sub, graceEvent(disconnect, sub.colour, duration))
 
-	slog.Info("user disconnected",
-		slog.String("userId", sub.userId.String()),
-		slog.String("gameId", sub.session.id.String()),
-		slog.String("waiting", duration.String()),
-	)
+	sub.logger.InfoContext(ctx, "user disconnected",
+		slog.String("waiting", duration.String()))
 
 	select {
 	case <-timer.C:
-		slog.Info("game ended due to timeout",
-			slog.String("userId", sub.userId.String()),
-			slog.String("gameId", sub.session.id.String()),
-		)
+		sub.logger.InfoContext(ctx, "game ended due to timeout")
 
 		colour := board.OppositeColour(sub.colour)
-		sub.session.handleWin(ctx, board.ColourToWinState(colour))
+		sub.session.handleWin(ctx, board.WinResult(colour, board.TerminationAbandonment))
 
 		sub.closeNow(ctx, err)
 	case <-ctx.Done():
@@ -758,7 +1503,15 @@ func (sub *subscriber) Disconnected(ctx context.Context, err error) {
 }
 
 func getId(writer http.ResponseWriter, req *http.Request) (uuid.UUID, error) {
-	id := strings.TrimPrefix(req.URL.Path, "/subscribe/")
+	return getIdWithPrefix(writer, req, "/subscribe/")
+}
+
+func getIdWithPrefix(
+	writer http.ResponseWriter,
+	req *http.Request,
+	prefix string,
+) (uuid.UUID, error) {
+	id := strings.TrimPrefix(req.URL.Path, prefix)
 	if id == "" {
 		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return uuid.UUID{}, errors.New("no campaign id in request")
@@ -802,21 +1555,62 @@ func (session *Session) startClockImpl(
 		remainingTime = session.blackTime
 	}
 
-	session.clockTimer = time.AfterFunc(remainingTime, func() {
+	session.paused = false
+	if session.isCorrespondence() {
+		if session.server.vacations.onVacation(session.players[colour-1].userId) {
+			session.paused = true
+			session.clockTimer = nil
+			session.clockColour = board.None
+			return
+		}
+		session.clockTimer = time.AfterFunc(max(remainingTime-autoVacationMargin, 0), func() {
+			session.handleDeadline(ctx, colour)
+		})
+		session.clockColour = colour
+		return
+	}
+
+	grace := session.players[colour-1].lag.allowance()
+	session.clockTimer = time.AfterFunc(remainingTime+grace, func() {
 		session.handleTimeLoss(ctx, colour)
 	})
+	session.clockColour = colour
+}
+
+func (session *Session) startAbortClock(ctx context.Context, colour board.Colour) {
+	session.clockLock.Lock()
+	session.startAbortClockImpl(ctx, colour)
+	session.clockLock.Unlock()
 }
 
+// A player who doesn't make their first move within a tenth of their time
+// has the game aborted
 func (session *Session) startAbortClockImpl(ctx context.Context, colour board.Colour) {
 	if session.clockTimer != nil {
 		session.clockTimer.Stop()
 	}
 
 	abortTimer := session.whiteTime / 10
+	if colour == board.Black {
+		abortTimer = session.blackTime / 10
+	}
 
 	session.clockTimer = time.AfterFunc(abortTimer, func() {
 		session.handleAbort(ctx, colour)
 	})
+	session.clockColour = colour
+}
+
+// Arms whichever clock handleMove would have left running for the position,
+// for when the position is set some other way. Nothing runs between black's
+// first move and white's second
+func (session *Session) armClockImpl(ctx context.Context) {
+	switch moves := session.boardState.MoveCounter; {
+	case moves < 2:
+		session.startAbortClockImpl(ctx, session.boardState.WhoseMove())
+	case moves > 2:
+		session.startClockImpl(ctx, session.boardState.WhoseMove())
+	}
 }
 
 func (session *Session) stopClock() {
@@ -829,6 +1623,7 @@ func (session *Session) stopClockImpl() {
 		session.clockTimer.Stop()
 		session.clockTimer = nil
 	}
+	session.clockColour = board.None
 }
 
 func (session *Session) updateClock() {
@@ -836,47 +1631,61 @@ func (session *Session) updateClock() {
 	session.updateClockImpl()
 	session.clockLock.Unlock()
 }
-func (session *Session) updateClockImpl() {
+
+// Charges the player to move for the time since the clock was last
+// updated, returns how much was taken off before the increment
+func (session *Session) updateClockImpl() time.Duration {
 	now := time.Now()
 	elapsed := now.Sub(session.updatedAt)
+	if session.paused {
+		elapsed = 0
+	}
+	moving := session.boardState.WhoseMove()
+	elapsed -= session.players[moving-1].lag.compensation(elapsed)
+	increment := session.incrementImpl(moving)
 
-	if session.boardState.WhoseMove() == board.Black {
-		session.whiteTime = session.whiteTime - elapsed + session.increment
+	if moving == board.White {
+		session.whiteTime = session.whiteTime - elapsed + increment
 		if session.whiteTime < 0 {
 			session.whiteTime = 0
 		}
 	} else {
-		session.blackTime = session.blackTime - elapsed + session.increment
+		session.blackTime = session.blackTime - elapsed + increment
 		if session.blackTime < 0 {
 			session.blackTime = 0
 		}
 	}
 
 	session.updatedAt = now
+	return elapsed
 }
 
 func (session *Session) handleTimeLoss(ctx context.Context, losingColour board.Colour) {
+	session.boardStateLock.Lock()
 	session.clockLock.Lock()
 	session.handleTimeLossImpl(ctx, losingColour)
 	session.clockLock.Unlock()
+	session.boardStateLock.Unlock()
 }
-func (session *Session) handleTimeLossImpl(ctx context.Context, losingColour board.Colour) {
-	winningColour := board.OppositeColour(losingColour)
-	winState := board.ColourToWinState(winningColour)
 
-	var outcome string
-	var victor string
-	switch winState {
-	case board.BlackWin:
-		outcome = "win"
-		victor = "b"
-	case board.WhiteWin:
-		outcome = "win"
-		victor = "w"
+func (session *Session) handleTimeLossImpl(ctx context.Context, losingColour board.Colour) {
+	if session.ended {
+		return
 	}
+	session.ended = true
+	session.turnChanged()
+	session.server.userGames.remove(session)
+	session.recordAudit(gameEnded,
+		"time loss for "+serialiseColour(losingColour), nil)
 
-	session.publish(ctx,
-		nil, Event{Type: "end", Outcome: &outcome, Victor: &victor})
+	result := session.timeLossResultImpl(losingColour)
+	session.result = &result
+	summary := session.saveImpl(ctx, result)
+
+	ended := session.endEventImpl(result)
+	session.publish(ctx, nil, ended)
+	session.publish(ctx, nil, summaryEvent(summary))
+	session.server.lobby.gameEnded(session.id, ended)
 
 	go func() {
 		time.Sleep(5 * time.Second)
@@ -885,15 +1694,49 @@ func (session *Session) handleTimeLossImpl(ctx context.Context, losingColour boa
 	}()
 }
 
+// Flagging is only a loss when the opponent could still have mated,
+// boardStateLock should be held
+func (session *Session) timeLossResultImpl(losingColour board.Colour) board.GameResult {
+	winningColour := board.OppositeColour(losingColour)
+	if !session.boardState.HasMatingMaterial(winningColour) {
+		return board.DrawResult(board.TerminationTimeoutDraw)
+	}
+	return board.WinResult(winningColour, board.TerminationTimeForfeit)
+}
+
 func (session *Session) handleAbort(ctx context.Context, colour board.Colour) {
+	session.boardStateLock.Lock()
 	session.clockLock.Lock()
 	session.handleAbortImpl(ctx, colour)
 	session.clockLock.Unlock()
+	session.boardStateLock.Unlock()
 }
+
+// an aborted game has no result, so it must never be counted as a loss
 func (session *Session) handleAbortImpl(ctx context.Context, colour board.Colour) {
+	session.abortImpl(ctx, colour, "no first move from "+serialiseColour(colour))
+}
+
+// colour is the side the game was waiting on, reason is for the audit
+func (session *Session) abortImpl(ctx context.Context, colour board.Colour, reason string) {
+	if session.ended {
+		return
+	}
+	session.ended = true
+	session.turnChanged()
+	session.server.userGames.remove(session)
+	session.aborted = true
+	session.recordAudit(gameEnded, "aborted, "+reason, nil)
+	session.forgetLiveGame(ctx)
+
+	session.logger.InfoContext(ctx, "game aborted", slog.String("reason", reason))
+
 	colourStr := serialiseColour(colour)
-	session.publish(ctx,
-		nil, Event{Type: abort, Colour: &colourStr})
+	outcome := abort
+	abortReason := reasonAbort
+	aborted := Event{Type: abort, Outcome: &outcome, Colour: &colourStr, Reason: &abortReason}
+	session.publish(ctx, nil, aborted)
+	session.server.lobby.gameEnded(session.id, aborted)
 
 	go func() {
 		time.Sleep(5 * time.Second)
@@ -910,6 +1753,9 @@ func (session *Session) getClockState() (whiteTime, blackTime time.Duration) {
 func (session *Session) getClockStateImpl() (whiteTime, blackTime time.Duration) {
 	now := time.Now()
 	elapsed := now.Sub(session.updatedAt)
+	if session.paused {
+		elapsed = 0
+	}
 
 	whiteTime = session.whiteTime
 	blackTime = session.blackTime
@@ -931,32 +1777,62 @@ func (session *Session) getClockStateImpl() (whiteTime, blackTime time.Duration)
 
 func (session *Session) cleanup(ctx context.Context) {
 	session.server.RemoveSession(ctx, session.id)
+	session.server.releaseSession(ctx, session.id)
 
-	session.clockLock.Lock()
-	defer session.clockLock.Unlock()
-
-	if session.clockTimer != nil {
-		session.clockTimer.Stop()
-		session.clockTimer = nil
+	session.stopClock()
+	if session.stopClockBroadcasts != nil {
+		session.stopClockBroadcasts()
 	}
 
 	for _, player := range session.players {
+		// requeued players are closed once they are sent their new game
+		if player.cancelRequeue != nil {
+			continue
+		}
 		player.closeNow(ctx, nil)
 	}
+	// closing a viewer removes it under the lock
+	session.subscriberLock.Lock()
+	viewers := make([]*subscriber, 0, session.viewers.Len())
 	for viewer := range session.viewers.Keys() {
+		viewers = append(viewers, viewer)
+	}
+	session.subscriberLock.Unlock()
+	for _, viewer := range viewers {
 		viewer.closeNow(ctx, nil)
 	}
 
-	slog.Info("session cleaned up",
-		slog.String("sessionId", session.id.String()))
+	session.logger.InfoContext(ctx, "session cleaned up")
 }
 
 func (server *GameServer) RemoveSession(ctx context.Context, sessionId uuid.UUID) {
-	server.sessionsLock.Lock()
-	defer server.sessionsLock.Unlock()
+	session, exists := server.sessions.delete(sessionId)
 
-	if session, exists := server.sessions[sessionId]; exists {
+	if exists {
+		server.userGames.remove(session)
 		session.cleanup(ctx)
-		delete(server.sessions, sessionId)
 	}
 }
+
+// counts for the status page, players are only counted while connected
+func (server *GameServer) Stats() (activeGames int, playersOnline int) {
+	sessions := server.sessions.all()
+
+	for _, session := range sessions {
+		session.boardStateLock.Lock()
+		if !session.ended {
+			activeGames += 1
+		}
+		session.boardStateLock.Unlock()
+
+		session.subscriberLock.Lock()
+		for _, player := range session.players {
+			if player != nil && player.state == Connected {
+				playersOnline += 1
+			}
+		}
+		playersOnline += session.viewers.Len()
+		session.subscriberLock.Unlock()
+	}
+	return activeGames, playersOnline
+}
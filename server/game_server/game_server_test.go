@@ -1,11 +1,23 @@
 package game_server
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"chess/auth"
+	"chess/board"
+	"chess/model"
+	"chess/msgpack"
+	"chess/pgn"
+	"chess/protocol"
 
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
 	"github.com/google/uuid"
 )
 
@@ -21,9 +33,7 @@ func TestGameClock(t *testing.T) {
 
 	sessionId := server.NewSession(white, black, increment, gameLength)
 
-	server.sessionsLock.Lock()
-	session := server.sessions[sessionId]
-	server.sessionsLock.Unlock()
+	session, _ := server.sessions.load(sessionId)
 
 	if session == nil {
 		t.Fatal("Session not found")
@@ -43,9 +53,7 @@ func TestGameClock(t *testing.T) {
 	// Wait longer for cleanup to complete
 	time.Sleep(6 * time.Second)
 
-	server.sessionsLock.Lock()
-	_, exists := server.sessions[sessionId]
-	server.sessionsLock.Unlock()
+	_, exists := server.sessions.load(sessionId)
 
 	if exists {
 		t.Error("Session should have been removed due to time loss")
@@ -64,9 +72,7 @@ func TestGameClockWithMoves(t *testing.T) {
 
 	sessionId := server.NewSession(white, black, increment, gameLength)
 
-	server.sessionsLock.Lock()
-	session := server.sessions[sessionId]
-	server.sessionsLock.Unlock()
+	session, _ := server.sessions.load(sessionId)
 
 	if session == nil {
 		t.Fatal("Session not found")
@@ -97,6 +103,585 @@ func TestGameClockWithMoves(t *testing.T) {
 	}
 
 	// Clean up
-	session.cleanup()
-	server.RemoveSession(sessionId)
+	session.cleanup(context.Background())
+	server.RemoveSession(context.Background(), sessionId)
+}
+
+// A game between two new players who haven't connected yet
+func newTestSession(server *GameServer, increment, gameLength time.Duration) *Session {
+	sessionId := server.NewSession(uuid.New(), uuid.New(), increment, gameLength)
+	session, _ := server.sessions.load(sessionId)
+	return session
+}
+
+func playMoves(t *testing.T, session *Session, moves []string) {
+	t.Helper()
+	for _, str := range moves {
+		move, err := board.DeserialiseMove(str)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub := session.players[0]
+		if session.boardState.WhoseMove() == board.Black {
+			sub = session.players[1]
+		}
+		err = session.handleMove(context.Background(), sub, move)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// the mock auth server derives the user id from the session cookie
+func mockUser() (cookie *http.Cookie, userId uuid.UUID) {
+	sessionId := uuid.New()
+	cookie = &http.Cookie{Name: auth.CookieKeySession, Value: sessionId.String()}
+	return cookie, uuid.NewSHA1(uuid.NameSpaceOID, sessionId[:])
+}
+
+func nextEvent(t *testing.T, sub *subscriber, eventType eventType) Event {
+	t.Helper()
+	for {
+		select {
+		case event := <-sub.events:
+			if event.Type == eventType {
+				return event
+			}
+		default:
+			t.Fatalf("Expected a %s event", eventType)
+		}
+	}
+}
+
+// A server for a game which tests connect to over real websockets,
+// everything is closed when the test ends
+type socketFixture struct {
+	t   *testing.T
+	ctx context.Context
+	// where the server is listening, for routes other than the game's socket
+	root string
+	url  string
+}
+
+// Connects to the url with the cookie, or anonymously without one, and
+// leaves reading to the caller
+func (fixture *socketFixture) open(
+	url string, cookie *http.Cookie, options *websocket.DialOptions,
+) (*websocket.Conn, *http.Response, error) {
+	if options == nil {
+		options = &websocket.DialOptions{}
+	}
+	options.HTTPHeader = http.Header{}
+	if cookie != nil {
+		options.HTTPHeader.Add("Cookie", cookie.String())
+	}
+	conn, resp, err := websocket.Dial(fixture.ctx, url, options)
+	if err == nil {
+		fixture.t.Cleanup(func() { conn.CloseNow() })
+	}
+	return conn, resp, err
+}
+
+// Connects to the game and reads the connect event
+func (fixture *socketFixture) dial(cookie *http.Cookie) (*websocket.Conn, Event) {
+	fixture.t.Helper()
+	conn, _, err := fixture.open(fixture.url, cookie, nil)
+	if err != nil {
+		fixture.t.Fatal(err)
+	}
+	event := Event{}
+	err = wsjson.Read(fixture.ctx, conn, &event)
+	if err != nil {
+		fixture.t.Fatal(err)
+	}
+	return conn, event
+}
+
+func (fixture *socketFixture) send(conn *websocket.Conn, event Event) {
+	fixture.t.Helper()
+	err := wsjson.Write(fixture.ctx, conn, event)
+	if err != nil {
+		fixture.t.Fatal(err)
+	}
+}
+
+// Skips everything else until an event of the type arrives
+func (fixture *socketFixture) readUntil(conn *websocket.Conn, eventType eventType) Event {
+	fixture.t.Helper()
+	for {
+		event := Event{}
+		err := wsjson.Read(fixture.ctx, conn, &event)
+		if err != nil {
+			fixture.t.Fatal(err)
+		}
+		if event.Type == eventType {
+			return event
+		}
+	}
+}
+
+// Asks for a sync and fails if an event of the type arrives before it,
+// anything sent earlier is queued ahead of the answer
+func (fixture *socketFixture) expectNone(conn *websocket.Conn, eventType eventType) {
+	fixture.t.Helper()
+	fixture.send(conn, Event{Type: resync})
+	for {
+		event := Event{}
+		err := wsjson.Read(fixture.ctx, conn, &event)
+		if err != nil {
+			fixture.t.Fatal(err)
+		}
+		switch event.Type {
+		case eventType:
+			fixture.t.Errorf("Expected no %s event, got %+v", eventType, event)
+		case resync:
+			return
+		}
+	}
+}
+
+func newSocketFixture(t *testing.T, handler http.Handler, sessionId uuid.UUID) *socketFixture {
+	t.Helper()
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return &socketFixture{
+		t:    t,
+		ctx:  ctx,
+		root: httpServer.URL,
+		url:  httpServer.URL + "/subscribe/" + sessionId.String(),
+	}
+}
+
+func TestAbortUnplayedGame(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+
+	gameLength := 1 * time.Second
+	session := newTestSession(server, 0, gameLength)
+
+	// abort timer is a tenth of the game length
+	time.Sleep(200 * time.Millisecond)
+
+	session.boardStateLock.Lock()
+	aborted := session.aborted
+	ended := session.ended
+	win := session.boardState.WinState
+	session.boardStateLock.Unlock()
+
+	if !aborted || !ended {
+		t.Fatalf("Expected unplayed game to be aborted, aborted: %t, ended: %t", aborted, ended)
+	}
+	if win != board.NoWin {
+		t.Errorf("Aborted game should not have a winner, got %s", board.WinStateToString(win))
+	}
+
+	session.cleanup(context.Background())
+}
+
+func TestAbortAfterFirstMove(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeStore{games: make(chan model.CreateGameParams, 1)}
+	server.SetStore(store)
+
+	gameLength := 1 * time.Second
+	session := newTestSession(server, 0, gameLength)
+	playedSession := newTestSession(server, 0, gameLength)
+
+	// black never replies so the game is aborted on their abort timer
+	playMoves(t, session, []string{"D1:C2"})
+	playMoves(t, playedSession, []string{"D1:C2", "E8:F7"})
+	time.Sleep(200 * time.Millisecond)
+
+	event := nextEvent(t, session.players[0], abort)
+	if *event.Outcome != "abort" || *event.Colour != "b" || *event.Reason != reasonAbort ||
+		event.Result != nil {
+		t.Errorf("Unexpected abort event %+v", event)
+	}
+	select {
+	case game := <-store.games:
+		t.Errorf("Expected an aborted game not to be saved, saved %+v", game)
+	default:
+	}
+
+	// nothing runs once both players have moved until white's second move
+	playedSession.boardStateLock.Lock()
+	ended := playedSession.ended
+	playedSession.boardStateLock.Unlock()
+	if ended {
+		t.Error("Expected a game with both first moves made not to be aborted")
+	}
+
+	session.cleanup(context.Background())
+	playedSession.cleanup(context.Background())
+}
+
+func TestIllegalMoveRejected(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	move, err := board.DeserialiseMove("D1:D2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fen := session.boardState.Fen()
+	err = session.handleMove(context.Background(), session.players[0], move)
+	if !errors.Is(err, board.ErrIllegalMove) {
+		t.Fatalf("Expected illegal move error, got %v", err)
+	}
+	if session.boardState.Fen() != fen || session.ended {
+		t.Error("Expected illegal move to leave the game untouched")
+	}
+	if !isRejectedMove(err) {
+		t.Error("Expected illegal move to be rejected without ending the game")
+	}
+	_, err = board.DeserialiseMove("D1:D9")
+	if !isRejectedMove(err) {
+		t.Errorf("Expected malformed move to be rejected, got %v", err)
+	}
+	if isRejectedMove(board.ErrInconsistentPosition) {
+		t.Error("Expected inconsistent position not to be treated as a bad move")
+	}
+
+	playMoves(t, session, []string{"D1:C2"})
+	session.cleanup(context.Background())
+}
+
+func TestTimeoutVsInsufficientMaterial(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		result    string
+		condition string
+	}{
+		// black flags against a rook
+		{"loss", "k7/8/8/8/8/8/1r6/7K w 1", "1-0", "White wins"},
+		// black flags with a rook against a lone king
+		{"draw", "k7/8/8/8/8/8/2R5/7K w 1", "1/2-1/2", "Timeout draw"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := NewGameServer(&auth.MockAuthServer{})
+			session := newTestSession(server, 0, 5*time.Second)
+
+			boardState, err := board.ParseFen(test.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = boardState.Init()
+			if err != nil {
+				t.Fatal(err)
+			}
+			session.boardStateLock.Lock()
+			session.boardState = boardState
+			session.boardStateLock.Unlock()
+			session.clockLock.Lock()
+			session.blackTime = 100 * time.Millisecond
+			session.clockLock.Unlock()
+
+			// the clock starts for black once white has moved
+			playMoves(t, session, []string{"H1:G1"})
+			time.Sleep(300 * time.Millisecond)
+
+			game, found := server.FinishedGame(session.id)
+			if !found || game.Result != test.result || game.Condition != test.condition {
+				t.Errorf("Expected %s %s, got %+v", test.condition, test.result, game)
+			}
+			session.cleanup(context.Background())
+		})
+	}
+}
+
+func TestMoveAfterTimeLoss(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+	playMoves(t, session, []string{"D1:C2", "E8:F7"})
+
+	// white's clock ran out before the move reached the server
+	session.clockLock.Lock()
+	session.whiteTime = 0
+	session.clockLock.Unlock()
+
+	move, err := board.DeserialiseMove("F2:E4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.handleMove(context.Background(), session.players[0], move)
+	if err == nil {
+		t.Fatal("Expected a move after a time loss to be rejected")
+	}
+
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+	if !session.ended || session.result == nil || session.result.Reason != board.TerminationTimeForfeit {
+		t.Errorf("Expected the game to end on time, got %+v", session.result)
+	}
+	if session.boardState.MoveCounter != 2 {
+		t.Errorf("Expected the move not to be played, move counter %d", session.boardState.MoveCounter)
+	}
+}
+
+func TestEndEventDraws(t *testing.T) {
+	win := endEvent(board.WinResult(board.White, board.TerminationTimeForfeit))
+	if *win.Outcome != "win" || win.Victor == nil || *win.Victor != "w" ||
+		*win.Termination != "time forfeit" || *win.Reason != reasonTimeout ||
+		*win.Result != pgn.WhiteWinResult {
+		t.Errorf("Unexpected win event %+v", win)
+	}
+	lost := endEvent(board.WinResult(board.Black, board.TerminationAbandonment))
+	if *lost.Reason != reasonDisconnection || *lost.Result != pgn.BlackWinResult {
+		t.Errorf("Expected black to win by disconnection, got %+v", lost)
+	}
+
+	draws := map[board.Termination]struct {
+		outcome string
+		reason  endReason
+	}{
+		board.TerminationStalemate:    {"stalemate", reasonStalemate},
+		board.TerminationMoveRule:     {"moveRuleDraw", reasonMoveRule},
+		board.TerminationAgreement:    {"agreement", reasonAgreement},
+		board.TerminationTimeoutDraw:  {"timeoutDraw", reasonTimeout},
+		board.TerminationDeadPosition: {"deadPosition", reasonInsufficient},
+	}
+	for termination, expected := range draws {
+		event := endEvent(board.DrawResult(termination))
+		if *event.Outcome != expected.outcome || event.Victor != nil ||
+			*event.Termination != string(termination) || *event.Reason != expected.reason ||
+			*event.Result != pgn.DrawResult {
+			t.Errorf("Expected %s draw with no victor, received %+v", expected.outcome, event)
+		}
+	}
+}
+
+func TestCompactLegalMoves(t *testing.T) {
+	boardState := board.NewBoard()
+	if err := boardState.Init(); err != nil {
+		t.Fatal(err)
+	}
+	legalMoves := moveList(boardState.LegalMoves)
+	event := Event{Type: move, LegalMoves: &legalMoves}
+
+	compacted, err := compactLegalMoves(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compacted.LegalMoves != nil || compacted.CompactLegalMoves == nil {
+		t.Fatalf("Expected legal moves to be replaced, got %+v", compacted)
+	}
+	if event.LegalMoves == nil {
+		t.Error("Expected the original event to keep its legal moves")
+	}
+	moves, err := board.DeserialiseMoveListCompact(*compacted.CompactLegalMoves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != len(legalMoves) {
+		t.Errorf("Expected %d legal moves, got %d", len(legalMoves), len(moves))
+	}
+	if len(*compacted.CompactLegalMoves) >= len(strings.Join(legalMoves, `","`)) {
+		t.Errorf("Expected the compact encoding to be smaller: %s", *compacted.CompactLegalMoves)
+	}
+
+	noMoves, err := compactLegalMoves(Event{Type: end})
+	if err != nil || noMoves.CompactLegalMoves != nil {
+		t.Errorf("Expected events without legal moves to be left alone, got %+v %v", noMoves, err)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, time.Minute)
+	ctx := context.Background()
+	white, black := session.players[0], session.players[1]
+
+	illegal := "A1:A8"
+	garbled := "nonsense"
+	legal := "D1:C2"
+	tests := []struct {
+		name  string
+		sub   *subscriber
+		event Event
+		code  errorCode
+	}{
+		{"illegal move", white, Event{Type: sendMove, Move: &illegal}, illegalMove},
+		{"unparseable move", white, Event{Type: sendMove, Move: &garbled}, illegalMove},
+		{"missing move", white, Event{Type: sendMove}, badJson},
+		{"wrong turn", black, Event{Type: sendMove, Move: &legal}, notYourTurn},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.sub.throttle = moveThrottle{}
+			test.sub.handleSendMove(ctx, test.event)
+			event := nextEvent(t, test.sub, errorEvent)
+			if event.Code == nil || *event.Code != test.code {
+				t.Errorf("Expected code %s, got %v", test.code, event.Code)
+			}
+		})
+	}
+
+	long := strings.Repeat("a", maxChatLength+1)
+	hello := "hello"
+	others := []struct {
+		name string
+		sub  *subscriber
+		send func(sub *subscriber)
+		code errorCode
+	}{
+		{"new opponent mid game", black, func(sub *subscriber) { sub.handleNewOpponent(ctx) }, gameNotOver},
+		{"takeback before a move", white, func(sub *subscriber) { sub.handleTakebackRequest(ctx) }, notAllowed},
+		{"takeback nobody asked for", black, func(sub *subscriber) { sub.handleTakebackAccept(ctx) }, offerUnavailable},
+		{"second berserk", white, func(sub *subscriber) {
+			sub.handleBerserk(ctx)
+			nextEvent(t, sub, berserk)
+			sub.handleBerserk(ctx)
+		}, notAllowed},
+		{"long chat", white, func(sub *subscriber) {
+			sub.handleChat(ctx, Event{Type: chat, Text: &long})
+		}, chatTooLong},
+		{"chat too soon", white, func(sub *subscriber) {
+			sub.handleChat(ctx, Event{Type: chat, Text: &hello})
+			sub.handleChat(ctx, Event{Type: chat, Text: &hello})
+		}, rateLimited},
+	}
+	for _, test := range others {
+		t.Run(test.name, func(t *testing.T) {
+			test.send(test.sub)
+			event := nextEvent(t, test.sub, errorEvent)
+			if event.Code == nil || *event.Code != test.code {
+				t.Errorf("Expected code %s, got %v", test.code, event.Code)
+			}
+		})
+	}
+
+	if session.ended {
+		t.Error("Expected the game to carry on after bad messages")
+	}
+	if black.state == Closed {
+		t.Error("Expected black's connection to be left open")
+	}
+}
+
+func TestMsgpackSubprotocol(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	whiteCookie, white := mockUser()
+	viewerCookie, _ := mockUser()
+	sessionId := server.NewSession(white, uuid.New(), 0, time.Minute)
+	fixture := newSocketFixture(t, server.ServeMux, sessionId)
+
+	conn, _, err := fixture.open(fixture.url, whiteCookie, &websocket.DialOptions{
+		Subprotocols: []string{protocol.JSONSubprotocol, protocol.MsgpackSubprotocol},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Subprotocol() != protocol.MsgpackSubprotocol {
+		t.Fatalf("Expected msgpack to be chosen, got %q", conn.Subprotocol())
+	}
+	msgType, bytes, err := conn.Read(fixture.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := Event{}
+	err = msgpack.Unmarshal(bytes, &event)
+	if msgType != websocket.MessageBinary || err != nil || event.Type != connect {
+		t.Fatalf("Expected a binary connect event, got %v %+v %v", msgType, event, err)
+	}
+
+	// older clients which don't offer a subprotocol still get json
+	viewer, event := fixture.dial(viewerCookie)
+	if event.Type != connectViewer {
+		t.Fatalf("Expected a json connect event, got %+v", event)
+	}
+	// no version asked for so they're served the oldest
+	if event.Version == nil || *event.Version != protocol.Oldest ||
+		event.CurrentVersion == nil || *event.CurrentVersion != protocol.Current {
+		t.Errorf("Expected versions %s and %s in the handshake, got %v %v",
+			protocol.Oldest, protocol.Current, event.Version, event.CurrentVersion)
+	}
+
+	sent := "D1:C2"
+	encoded, err := msgpack.Marshal(Event{Type: sendMove, Move: &sent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.Write(fixture.ctx, websocket.MessageBinary, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := fixture.readUntil(viewer, move); *event.Move != sent {
+		t.Errorf("Expected the binary move to be played, got %s", *event.Move)
+	}
+}
+
+func TestCompressionNegotiated(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	fixture := newSocketFixture(t, server.ServeMux, uuid.Nil)
+
+	dial := func(mode websocket.CompressionMode) string {
+		t.Helper()
+		cookie, _ := mockUser()
+		_, resp, err := fixture.open(fixture.root+"/notifications", cookie,
+			&websocket.DialOptions{CompressionMode: mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Header.Get("Sec-WebSocket-Extensions")
+	}
+
+	if extensions := dial(websocket.CompressionNoContextTakeover); !strings.Contains(extensions, "permessage-deflate") {
+		t.Errorf("Expected deflate to be accepted, got %q", extensions)
+	}
+
+	server.SetCompression(protocol.Compression{Mode: websocket.CompressionDisabled})
+	if extensions := dial(websocket.CompressionNoContextTakeover); extensions != "" {
+		t.Errorf("Expected no extensions with compression off, got %q", extensions)
+	}
+}
+
+func TestLargeMessages(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	whiteCookie, white := mockUser()
+	blackCookie, black := mockUser()
+	sessionId := server.NewSession(white, black, 0, time.Minute)
+	fixture := newSocketFixture(t, server.ServeMux, sessionId)
+
+	conn, _ := fixture.dial(whiteCookie)
+	opponent, _ := fixture.dial(blackCookie)
+
+	// padded well past what used to fit in the shared buffer
+	padded := `{"type":"sendMove","move":"D1:C2"` + strings.Repeat(" ", 2000) + "}"
+	err := conn.Write(fixture.ctx, websocket.MessageText, []byte(padded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the mover isn't sent their own move
+	for {
+		event := Event{}
+		err := wsjson.Read(fixture.ctx, opponent, &event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Type == errorEvent {
+			t.Fatalf("Expected the padded move to be played, got %s", *event.Text)
+		}
+		if event.Type == move {
+			break
+		}
+	}
+
+	tooBig := `{"type":"chat","text":"` + strings.Repeat("a", maxInboundMessage) + `"}`
+	err = conn.Write(fixture.ctx, websocket.MessageText, []byte(tooBig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		_, _, err = conn.Read(fixture.ctx)
+		if err != nil {
+			break
+		}
+	}
+	if fixture.ctx.Err() != nil {
+		t.Error("Expected the connection to be closed after an oversized message")
+	}
 }
@@ -0,0 +1,88 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"chess/board"
+)
+
+// A player whose connection drops has a while to come back before the game
+// is given to their opponent. The window grows with the format within
+// limits so a bullet game isn't held up for minutes and a long game doesn't
+// end over a blip. The opponent is told how long is left and can choose to
+// wait longer
+
+type GracePeriods struct {
+	// the window is the game length divided by this plus one increment
+	Divisor int
+	Min     time.Duration
+	Max     time.Duration
+}
+
+var DefaultGracePeriods = GracePeriods{
+	Divisor: 10,
+	Min:     15 * time.Second,
+	Max:     5 * time.Minute,
+}
+
+var errOpponentConnected = errors.New("opponent isn't disconnected")
+
+// Zero values keep the default
+func (server *GameServer) SetGracePeriods(periods GracePeriods) {
+	if periods.Divisor > 0 {
+		server.gracePeriods.Divisor = periods.Divisor
+	}
+	if periods.Min > 0 {
+		server.gracePeriods.Min = periods.Min
+	}
+	if periods.Max > 0 {
+		server.gracePeriods.Max = periods.Max
+	}
+}
+
+func (periods GracePeriods) forFormat(gameLength time.Duration, increment time.Duration) time.Duration {
+	grace := gameLength/time.Duration(periods.Divisor) + increment
+	return min(max(grace, periods.Min), periods.Max)
+}
+
+func (session *Session) gracePeriod() time.Duration {
+	return session.server.gracePeriods.forFormat(session.gameLength, session.increment)
+}
+
+func graceEvent(eventType eventType, colour board.Colour, remaining time.Duration) Event {
+	serialised := serialiseColour(colour)
+	graceTime := int32(remaining.Milliseconds())
+	return Event{Type: eventType, Colour: &serialised, GraceTime: &graceTime}
+}
+
+// Gives the disconnected opponent another grace period on top of what they
+// have left, everyone is told the new time
+func (sub *subscriber) handleWaitLonger(ctx context.Context) {
+	session := sub.session
+	if sub.colour != board.White && sub.colour != board.Black {
+		sub.sendError(ctx, notYourTurn, errors.New("viewers can't wait for a player"))
+		return
+	}
+
+	session.subscriberLock.Lock()
+	opponent := session.players[0]
+	if sub.colour == board.White {
+		opponent = session.players[1]
+	}
+	if opponent.graceTimer == nil {
+		session.subscriberLock.Unlock()
+		sub.sendError(ctx, opponentConnected, errOpponentConnected)
+		return
+	}
+	opponent.graceEnds = opponent.graceEnds.Add(session.gracePeriod())
+	remaining := time.Until(opponent.graceEnds)
+	opponent.graceTimer.Reset(remaining)
+	session.subscriberLock.Unlock()
+
+	sub.logger.InfoContext(ctx, "waiting longer for opponent",
+		slog.String("remaining", remaining.String()))
+	session.publish(ctx, nil, graceEvent(graceExtended, opponent.colour, remaining))
+}
@@ -0,0 +1,74 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+)
+
+func TestGracePeriods(t *testing.T) {
+	periods := DefaultGracePeriods
+	tests := []struct {
+		gameLength time.Duration
+		increment  time.Duration
+		expected   time.Duration
+	}{
+		{time.Minute, 0, periods.Min},
+		{10 * time.Minute, 5 * time.Second, time.Minute + 5*time.Second},
+		{3 * day, 0, periods.Max},
+	}
+	for _, test := range tests {
+		grace := periods.forFormat(test.gameLength, test.increment)
+		if grace != test.expected {
+			t.Errorf("Expected %s+%s to get %s, got %s",
+				test.gameLength, test.increment, test.expected, grace)
+		}
+	}
+}
+
+func TestWaitLonger(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	// a fifth of a second to reconnect
+	server.SetGracePeriods(GracePeriods{Divisor: 300, Min: time.Millisecond, Max: time.Second})
+	session := newTestSession(server, 0, time.Minute)
+
+	defer session.cleanup(context.Background())
+	playMoves(t, session, []string{"D1:C2", "E8:F7"})
+	white, black := session.players[0], session.players[1]
+
+	white.handleWaitLonger(context.Background())
+	event := nextEvent(t, white, errorEvent)
+	if *event.Code != opponentConnected {
+		t.Errorf("Expected waiting for a connected opponent to fail, got %+v", event)
+	}
+
+	go black.Disconnected(context.Background(), nil)
+	time.Sleep(20 * time.Millisecond)
+	event = nextEvent(t, white, disconnect)
+	if *event.Colour != "b" || *event.GraceTime != 200 {
+		t.Errorf("Unexpected disconnect event %+v", event)
+	}
+
+	white.handleWaitLonger(context.Background())
+	event = nextEvent(t, white, graceExtended)
+	if *event.Colour != "b" || *event.GraceTime <= 300 || *event.GraceTime > 400 {
+		t.Errorf("Unexpected grace extended event %+v", event)
+	}
+
+	ended := func() bool {
+		session.boardStateLock.Lock()
+		defer session.boardStateLock.Unlock()
+		return session.ended
+	}
+	// past the first grace period but not the second
+	time.Sleep(280 * time.Millisecond)
+	if ended() {
+		t.Fatal("Expected the game to carry on while white waits longer")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if !ended() {
+		t.Fatal("Expected black to lose once the extended grace ran out")
+	}
+}
@@ -0,0 +1,222 @@
+package game_server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chess/board"
+	"chess/render"
+)
+
+const (
+	// keeps a request from asking for a huge image
+	maxImageSquareSize = 128
+	// gifs have a frame per move so they're drawn smaller by default
+	gifSquareSize = 32
+	gifFrameDelay = 800 * time.Millisecond
+)
+
+var (
+	errGameNotFound = errors.New("game not found")
+	errGameNotOver  = errors.New("game isn't over")
+)
+
+// ?flip draws the board from black's side, ?size= is pixels per square
+func imageOptions(req *http.Request) (render.Options, error) {
+	query := req.URL.Query()
+	opts := render.Options{Flip: query.Has("flip")}
+	if size := query.Get("size"); size != "" {
+		var err error
+		opts.SquareSize, err = strconv.Atoi(size)
+		if err != nil || opts.SquareSize <= 0 || opts.SquareSize > maxImageSquareSize {
+			return opts, errors.New("invalid size")
+		}
+	}
+	return opts, nil
+}
+
+// Renders the current position of a live or recently finished game, this is
+// mounted outside the authenticated routes so link previews can fetch it.
+// Takes ?format=svg|png and ?ply= as well as the imageOptions params
+func (server *GameServer) ImageHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	ctx := req.Context()
+	gameId, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logError(ctx, err)
+		return
+	}
+
+	query := req.URL.Query()
+	opts, err := imageOptions(req)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	replay, found := server.gameReplay(gameId)
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		logError(ctx, errors.New("not found"))
+		return
+	}
+	ply, hasPly, err := parsePly(req, len(replay.MoveHistory))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var state *board.BoardState
+	if hasPly {
+		state, opts.LastMove, err = positionAtPly(replay.Variant, replay.MoveHistory, ply)
+	} else {
+		state, err = board.ParseFen(replay.Fen)
+		if len(replay.MoveHistory) > 0 {
+			last, moveErr := board.DeserialiseMove(replay.MoveHistory[len(replay.MoveHistory)-1])
+			if moveErr == nil {
+				opts.LastMove = &last
+			}
+		}
+	}
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+
+	// finished games and earlier plies don't change so they can be cached
+	// for longer
+	maxAge := 5
+	if replay.Outcome != nil || ply < len(replay.MoveHistory) {
+		maxAge = 3600
+	}
+	writer.Header().Add("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+
+	switch query.Get("format") {
+	case "", "svg":
+		writer.Header().Add("Content-Type", "image/svg+xml")
+		writer.Write(render.SVG(state, opts))
+	case "png":
+		buf := bytes.Buffer{}
+		err = render.PNG(&buf, state, opts)
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			logError(ctx, err)
+			return
+		}
+		writer.Header().Add("Content-Type", "image/png")
+		writer.Write(buf.Bytes())
+	default:
+		http.Error(writer, "unknown format", http.StatusBadRequest)
+	}
+}
+
+// The moves and variant of a finished game, from the cache if it's there
+// otherwise from the archive
+func (server *GameServer) finishedMoves(
+	ctx context.Context,
+	gameId uuid.UUID,
+) (variant string, moves []string, err error) {
+	if finished, cached := server.FinishedGame(gameId); cached {
+		return finished.Replay.Variant, finished.Replay.MoveHistory, nil
+	}
+
+	_, live := server.sessions.load(gameId)
+	if live {
+		return "", nil, errGameNotOver
+	}
+
+	if server.archive == nil {
+		return "", nil, errGameNotFound
+	}
+	game, err := server.archive.GetGameById(ctx, gameId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, errGameNotFound
+	} else if err != nil {
+		return "", nil, err
+	}
+	return game.Variant, strings.Fields(game.Moves), nil
+}
+
+// Animates a finished game for sharing, takes the imageOptions params
+func (server *GameServer) GifHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	ctx := req.Context()
+	gameId, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logError(ctx, err)
+		return
+	}
+
+	opts, err := imageOptions(req)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.SquareSize == 0 {
+		opts.SquareSize = gifSquareSize
+	}
+
+	variantName, moveStrs, err := server.finishedMoves(ctx, gameId)
+	switch {
+	case errors.Is(err, errGameNotFound):
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	case errors.Is(err, errGameNotOver):
+		http.Error(writer, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+
+	variant, err := board.VariantFromName(variantName)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+	start := board.NewVariantBoard(variant)
+	err = start.Init()
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+	moves := make([]board.Move, len(moveStrs))
+	for i, str := range moveStrs {
+		moves[i], err = board.DeserialiseMove(str)
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			logError(ctx, err)
+			return
+		}
+	}
+
+	buf := bytes.Buffer{}
+	err = render.GIF(&buf, start, moves, opts, gifFrameDelay)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+	writer.Header().Add("Cache-Control", "public, max-age=86400")
+	writer.Header().Add("Content-Type", "image/gif")
+	writer.Write(buf.Bytes())
+}
@@ -0,0 +1,111 @@
+package game_server
+
+import (
+	"context"
+	"database/sql"
+	"image/gif"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+func TestImageHandler(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+	playMoves(t, session, []string{"D1:C2"})
+
+	tests := []struct {
+		query       string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, "image/svg+xml"},
+		{"?format=png&size=20&flip", http.StatusOK, "image/png"},
+		{"?format=gif", http.StatusBadRequest, ""},
+		{"?size=1000", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/image"+tt.query, nil)
+		req.SetPathValue("id", session.id.String())
+		recorder := httptest.NewRecorder()
+		server.ImageHandler(recorder, req)
+
+		if recorder.Code != tt.status {
+			t.Fatalf("%q: expected status %d, got %d", tt.query, tt.status, recorder.Code)
+		}
+		if tt.contentType != "" && recorder.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%q: expected %s, got %s",
+				tt.query, tt.contentType, recorder.Header().Get("Content-Type"))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/image", nil)
+	req.SetPathValue("id", uuid.New().String())
+	recorder := httptest.NewRecorder()
+	server.ImageHandler(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown game, got %d", recorder.Code)
+	}
+}
+
+type fakeArchive struct {
+	games map[uuid.UUID]model.Game
+}
+
+func (archive *fakeArchive) GetGameById(ctx context.Context, id uuid.UUID) (model.Game, error) {
+	game, found := archive.games[id]
+	if !found {
+		return model.Game{}, sql.ErrNoRows
+	}
+	return game, nil
+}
+
+func TestGifHandler(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	archived := uuid.New()
+	server.SetArchive(&fakeArchive{games: map[uuid.UUID]model.Game{
+		archived: {ID: archived, Moves: "D1:C2 E8:F7"},
+	}})
+	session := newTestSession(server, 0, 5*time.Second)
+	playMoves(t, session, []string{"D1:C2"})
+
+	getGif := func(id uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/gif", nil)
+		req.SetPathValue("id", id.String())
+		recorder := httptest.NewRecorder()
+		server.GifHandler(recorder, req)
+		return recorder
+	}
+
+	if code := getGif(session.id).Code; code != http.StatusConflict {
+		t.Errorf("Expected 409 while the game is live, got %d", code)
+	}
+	if code := getGif(uuid.New()).Code; code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown game, got %d", code)
+	}
+
+	recorder := getGif(archived)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for an archived game, got %d", recorder.Code)
+	}
+	anim, err := gif.DecodeAll(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 {
+		t.Errorf("Expected a frame for the start and each move, got %d", len(anim.Image))
+	}
+
+	session.handleWin(context.Background(), board.WinResult(board.Black, board.TerminationAbandonment))
+	session.cleanup(context.Background())
+	if code := getGif(session.id).Code; code != http.StatusOK {
+		t.Errorf("Expected 200 once the game is over, got %d", code)
+	}
+}
@@ -0,0 +1,79 @@
+package game_server
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Players on slow connections have their moves arrive later than they were
+// made, so part of the time the server takes off for each move is spent on
+// the network. The round trip of each ping is tracked and half of it is
+// given back when they move, up to a limit so a bad connection can't be
+// used to gain time. Everyone in the game is sent both players' round trips
+// after each of their pings so clients can show how good the connections are
+
+// most a player is credited for a single move
+const maxLagCompensation = 500 * time.Millisecond
+
+type lagState struct {
+	lock sync.Mutex
+	// moving average of the ping round trip, zero until the first pong
+	roundTrip time.Duration
+}
+
+// Weighs the latest round trip at a quarter so a single slow ping doesn't
+// swing the estimate
+func (state *lagState) record(roundTrip time.Duration) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	if state.roundTrip == 0 {
+		state.roundTrip = roundTrip
+		return
+	}
+	state.roundTrip = (3*state.roundTrip + roundTrip) / 4
+}
+
+// false until the first pong
+func (state *lagState) latency() (time.Duration, bool) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	return state.roundTrip, state.roundTrip != 0
+}
+
+// Most that can be given back for the next move, the flag falls this much
+// later so a move already on its way isn't lost on time
+func (state *lagState) allowance() time.Duration {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	return min(state.roundTrip/2, maxLagCompensation)
+}
+
+// Time to give back for a move which took elapsed on the server's clock
+func (state *lagState) compensation(elapsed time.Duration) time.Duration {
+	return min(state.allowance(), max(elapsed, 0))
+}
+
+// in milliseconds, nil until the first pong
+func (state *lagState) latencyMs() *int32 {
+	roundTrip, measured := state.latency()
+	if !measured {
+		return nil
+	}
+	ms := int32(roundTrip.Milliseconds())
+	return &ms
+}
+
+// Round trips of both players, left out for a player who hasn't answered a
+// ping yet
+func (session *Session) latencyEvent() Event {
+	return Event{
+		Type:         latency,
+		WhiteLatency: session.players[0].lag.latencyMs(),
+		BlackLatency: session.players[1].lag.latencyMs(),
+	}
+}
+
+func (session *Session) publishLatency(ctx context.Context) {
+	session.publish(ctx, nil, session.latencyEvent())
+}
@@ -0,0 +1,52 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+)
+
+func TestLagCompensation(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	if event := session.latencyEvent(); event.WhiteLatency != nil || event.BlackLatency != nil {
+		t.Errorf("Expected no latency before any pings, got %+v", event)
+	}
+
+	lag := &session.players[0].lag
+	lag.record(400 * time.Millisecond)
+	lag.record(800 * time.Millisecond)
+	if lag.roundTrip != 500*time.Millisecond || lag.allowance() != 250*time.Millisecond {
+		t.Errorf("Unexpected round trip %v", lag.roundTrip)
+	}
+	event := session.latencyEvent()
+	if event.Type != latency || event.WhiteLatency == nil || *event.WhiteLatency != 500 || event.BlackLatency != nil {
+		t.Errorf("Expected only white's latency to be sent, got %+v", event)
+	}
+
+	session.clockLock.Lock()
+	session.whiteTime = 2 * time.Second
+	session.updatedAt = time.Now().Add(-time.Second)
+	session.clockLock.Unlock()
+	session.updateClock()
+
+	whiteTime, _ := session.getClockState()
+	expectedTime := 2*time.Second - time.Second + 250*time.Millisecond
+	if whiteTime < expectedTime-50*time.Millisecond || whiteTime > expectedTime+50*time.Millisecond {
+		t.Errorf("Expected white time to be around %v, got %v", expectedTime, whiteTime)
+	}
+
+	// a terrible connection is only given so much
+	lag.record(10 * time.Second)
+	if lag.allowance() != maxLagCompensation {
+		t.Errorf("Expected the allowance to be capped, got %v", lag.allowance())
+	}
+	if compensation := lag.compensation(100 * time.Millisecond); compensation != 100*time.Millisecond {
+		t.Errorf("Expected no more than the elapsed time back, got %v", compensation)
+	}
+
+	session.cleanup(context.Background())
+}
@@ -0,0 +1,280 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The homepage shows games as they start and finish and one game, the one
+// with the most spectators, as a live board. Sessions tell the lobby hub
+// about starts, ends and what their viewers are sent without ever waiting
+// on it, one goroutine works out the top game and sends everything on to
+// the feeds
+
+type lobbyEventType = string
+
+const (
+	// sent first, the games in progress when the feed was opened
+	lobbySnapshot lobbyEventType = "lobby"
+	gameStart                    = "gameStart"
+	gameEnd                      = "gameEnd"
+	// a new top game was picked, no game when nothing is being played
+	topGame = "topGame"
+	// something the top game's viewers were sent, moves, clocks, its end
+	topGameEvent = "topGameEvent"
+)
+
+const (
+	lobbyNoticeBuffer = 256
+	// a feed that falls this far behind is closed, the client reconnects
+	// and starts again from a snapshot
+	lobbyFeedBuffer = 64
+)
+
+type LobbyEvent struct {
+	Type   lobbyEventType `json:"type"`
+	GameId *string        `json:"gameId,omitempty"`
+	// games in progress, oldest first, only in the snapshot
+	Games *[]ActiveGame `json:"games,omitempty"`
+	// the game that started or the new top game
+	Game *ActiveGame `json:"game,omitempty"`
+	// the top game's position when it's picked
+	Fen *string `json:"fen,omitempty"`
+	// what viewers of the game were sent
+	Event *Event `json:"event,omitempty"`
+}
+
+type lobbyHub struct {
+	lock  sync.Mutex
+	feeds map[chan LobbyEvent]struct{}
+	// uuid.Nil when there's no top game
+	featured uuid.UUID
+	notices  chan LobbyEvent
+}
+
+func newLobbyHub() *lobbyHub {
+	return &lobbyHub{
+		feeds:   make(map[chan LobbyEvent]struct{}),
+		notices: make(chan LobbyEvent, lobbyNoticeBuffer),
+	}
+}
+
+// never blocks so it's safe to call with session locks held
+func (hub *lobbyHub) notify(event LobbyEvent) {
+	select {
+	case hub.notices <- event:
+	default:
+		slog.Warn("lobby notices full, dropping", slog.String("type", event.Type))
+	}
+}
+
+func (hub *lobbyHub) featuredId() uuid.UUID {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+	return hub.featured
+}
+
+func (hub *lobbyHub) gameStarted(game ActiveGame) {
+	hub.notify(LobbyEvent{Type: gameStart, GameId: &game.Id, Game: &game})
+}
+
+// event is the end or abort event sent to the game's subscribers
+func (hub *lobbyHub) gameEnded(gameId uuid.UUID, event Event) {
+	id := gameId.String()
+	hub.notify(LobbyEvent{Type: gameEnd, GameId: &id, Event: &event})
+}
+
+// Only the top game's events are passed on, spectator counts from any game
+// are too as they can change which game is on top
+func (hub *lobbyHub) viewerEvent(gameId uuid.UUID, event Event) {
+	if event.Type != spectatorsChanged && gameId != hub.featuredId() {
+		return
+	}
+	id := gameId.String()
+	hub.notify(LobbyEvent{Type: topGameEvent, GameId: &id, Event: &event})
+}
+
+func (hub *lobbyHub) subscribe() chan LobbyEvent {
+	feed := make(chan LobbyEvent, lobbyFeedBuffer)
+	hub.lock.Lock()
+	hub.feeds[feed] = struct{}{}
+	hub.lock.Unlock()
+	return feed
+}
+
+func (hub *lobbyHub) unsubscribe(feed chan LobbyEvent) {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+	if _, found := hub.feeds[feed]; found {
+		delete(hub.feeds, feed)
+		close(feed)
+	}
+}
+
+// Doesn't lock
+func (hub *lobbyHub) broadcastImpl(event LobbyEvent) {
+	for feed := range hub.feeds {
+		select {
+		case feed <- event:
+		default:
+			delete(hub.feeds, feed)
+			close(feed)
+		}
+	}
+}
+
+// pick returns the top game or false when nothing is being played
+func (hub *lobbyHub) run(pick func() (LobbyEvent, bool)) {
+	for notice := range hub.notices {
+		hub.lock.Lock()
+		featured := hub.featured
+		hub.lock.Unlock()
+
+		forward := notice.Type != topGameEvent || *notice.GameId == featured.String()
+		if forward {
+			hub.lock.Lock()
+			hub.broadcastImpl(notice)
+			hub.lock.Unlock()
+		}
+
+		// moves don't change the top game
+		if notice.Type == topGameEvent && notice.Event.Type != spectatorsChanged {
+			continue
+		}
+		top, found := pick()
+		next := uuid.Nil
+		if found {
+			next = uuid.MustParse(*top.GameId)
+		}
+		if next == featured {
+			continue
+		}
+
+		hub.lock.Lock()
+		hub.featured = next
+		hub.broadcastImpl(top)
+		hub.lock.Unlock()
+	}
+}
+
+// whether a should be the top game over b
+func moreWatched(a ActiveGame, b ActiveGame) bool {
+	if a.Spectators != b.Spectators {
+		return a.Spectators > b.Spectators
+	}
+	if a.Moves != b.Moves {
+		return a.Moves > b.Moves
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// The game with the most spectators, the longest then the oldest break ties
+// so the top game doesn't flip between equals
+func (server *GameServer) pickTopGame() (LobbyEvent, bool) {
+	var top *Session
+	var best ActiveGame
+	for _, session := range server.allSessions() {
+		game, active := session.activeGame()
+		if active && (top == nil || moreWatched(game, best)) {
+			top, best = session, game
+		}
+	}
+	if top == nil {
+		return LobbyEvent{Type: topGame}, false
+	}
+	return server.topGameEvent(top)
+}
+
+func (server *GameServer) topGameEvent(session *Session) (LobbyEvent, bool) {
+	game, active := session.activeGame()
+	if !active {
+		return LobbyEvent{Type: topGame}, false
+	}
+	session.boardStateLock.Lock()
+	fen := session.boardState.Fen()
+	session.boardStateLock.Unlock()
+	return LobbyEvent{Type: topGame, GameId: &game.Id, Game: &game, Fen: &fen}, true
+}
+
+// Streams games starting and ending and the top game as server sent events.
+// It's public so the homepage can show it before anyone signs in. Events
+// queued before the snapshot was taken can repeat what it shows, clients
+// should go by game id
+func (server *GameServer) LobbyHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	flusher, canFlush := writer.(http.Flusher)
+	if !canFlush {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, errors.New("streaming unsupported"))
+		return
+	}
+
+	// the server's write timeout would cut the stream off
+	err := http.NewResponseController(writer).SetWriteDeadline(time.Time{})
+	if err != nil {
+		logError(ctx, err)
+	}
+	writer.Header().Add("Content-Type", "text/event-stream")
+	writer.Header().Add("Cache-Control", "no-cache")
+
+	feed := server.lobby.subscribe()
+	defer server.lobby.unsubscribe(feed)
+
+	games := server.activeGames(nil, maxActiveGamesPageSize).Games
+	err = writeServerSentData(writer, lobbySnapshot, LobbyEvent{Type: lobbySnapshot, Games: &games})
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	top := LobbyEvent{Type: topGame}
+	if session, found := server.sessions.load(server.lobby.featuredId()); found {
+		top, _ = server.topGameEvent(session)
+	}
+	err = writeServerSentData(writer, topGame, top)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	flusher.Flush()
+
+	streamLobby(ctx, writer, flusher, feed)
+}
+
+func streamLobby(
+	ctx context.Context,
+	writer http.ResponseWriter,
+	flusher http.Flusher,
+	feed chan LobbyEvent,
+) {
+	keepAlive := time.NewTicker(relayKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-feed:
+			if !ok {
+				slog.InfoContext(ctx, "lobby feed fell behind, closing")
+				return
+			}
+			err = writeServerSentData(writer, event.Type, event)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(writer, ": keep-alive\n\n")
+		}
+
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,84 @@
+package game_server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestLobbyFeed(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	firstSession := newTestSession(server, 0, time.Minute)
+	fixture := newSocketFixture(t, http.HandlerFunc(server.LobbyHandler), uuid.Nil)
+
+	req, err := http.NewRequestWithContext(fixture.ctx, http.MethodGet, fixture.root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	reader := bufio.NewReader(res.Body)
+
+	// skips anything else until an event of eventType for the game
+	next := func(eventType lobbyEventType, gameId uuid.UUID) LobbyEvent {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Expected a %s event, got %v", eventType, err)
+			}
+			data, found := strings.CutPrefix(line, "data: ")
+			if !found {
+				continue
+			}
+			event := LobbyEvent{}
+			err = json.Unmarshal([]byte(data), &event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.Type == eventType && (event.GameId == nil || *event.GameId == gameId.String()) {
+				return event
+			}
+		}
+	}
+
+	snapshot := next(lobbySnapshot, uuid.Nil)
+	if snapshot.Games == nil || len(*snapshot.Games) != 1 || (*snapshot.Games)[0].Id != firstSession.id.String() {
+		t.Errorf("Expected the game in progress in the snapshot, got %+v", snapshot.Games)
+	}
+	next(topGame, firstSession.id)
+
+	second := server.NewSession(uuid.New(), uuid.New(), 0, time.Minute)
+	started := next(gameStart, second)
+	if started.Game == nil || started.Game.Id != second.String() {
+		t.Errorf("Expected the new game, got %+v", started.Game)
+	}
+
+	playMoves(t, firstSession, []string{"D1:C2"})
+	moved := next(topGameEvent, firstSession.id)
+	if moved.Event == nil || moved.Event.Type != move || *moved.Event.Move != "D1:C2" {
+		t.Errorf("Expected the top game's move, got %+v", moved.Event)
+	}
+
+	firstSession.handleWin(context.Background(), board.WinResult(board.Black, board.TerminationResignation))
+	ended := next(gameEnd, firstSession.id)
+	if ended.Event == nil || ended.Event.Type != end {
+		t.Errorf("Expected the end event, got %+v", ended.Event)
+	}
+	top := next(topGame, second)
+	if top.Fen == nil || top.Game == nil {
+		t.Errorf("Expected the new top game's position, got %+v", top)
+	}
+}
@@ -0,0 +1,45 @@
+package game_server
+
+import (
+	"context"
+	"log/slog"
+
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+// Lines about a game carry its id, lines about one of its subscribers carry
+// their user id and colour as well, so one game or one player can be pulled
+// out of the logs of every game at once
+
+func sessionLogger(gameId uuid.UUID) *slog.Logger {
+	return slog.Default().With(slog.String("gameId", gameId.String()))
+}
+
+// session can be nil for subscribers relaying a game held by another node
+func subscriberLogger(session *Session, userId uuid.UUID, colour board.Colour) *slog.Logger {
+	logger := slog.Default()
+	if session != nil {
+		logger = session.logger
+	}
+	return logger.With(
+		slog.String("userId", userId.String()),
+		slog.String("colour", serialiseColour(colour)))
+}
+
+// Doesn't lock, for when the session's id changes after it's created
+func (session *Session) resetLoggers() {
+	session.logger = sessionLogger(session.id)
+	for _, player := range session.players {
+		player.logger = subscriberLogger(session, player.userId, player.colour)
+	}
+}
+
+func (session *Session) logError(ctx context.Context, err error) {
+	session.logger.ErrorContext(ctx, "error", slog.Any("error", err))
+}
+
+func (sub *subscriber) logError(ctx context.Context, err error) {
+	sub.logger.ErrorContext(ctx, "error", slog.Any("error", err))
+}
@@ -0,0 +1,43 @@
+package game_server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/auth"
+
+	"github.com/google/uuid"
+)
+
+func TestGameLogger(t *testing.T) {
+	var output bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&output, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	server := NewGameServer(&auth.MockAuthServer{})
+	white := uuid.New()
+	sessionId := server.NewSession(white, uuid.New(), 0, time.Minute)
+	session, _ := server.sessions.load(sessionId)
+
+	illegal := "A1:A8"
+	session.players[0].handleSendMove(context.Background(), Event{Type: sendMove, Move: &illegal})
+
+	for _, line := range strings.Split(output.String(), "\n") {
+		record := map[string]any{}
+		if json.Unmarshal([]byte(line), &record) != nil || record["msg"] != "message rejected" {
+			continue
+		}
+		if record["gameId"] != sessionId.String() || record["userId"] != white.String() ||
+			record["colour"] != "w" {
+			t.Errorf("Expected the game and player on the log line, got %v", record)
+		}
+		return
+	}
+	t.Errorf("Expected the rejected move to be logged, got %s", output.String())
+}
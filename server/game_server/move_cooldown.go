@@ -0,0 +1,56 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// moves sent sooner than this after the player's last move are dropped
+	moveCooldown = 50 * time.Millisecond
+	// dropped moves in a row before the connection is closed for flooding
+	maxDroppedMoves = 20
+)
+
+var (
+	errMoveCooldown = errors.New("move sent too soon after the last one")
+	errMoveFlooding = errors.New("too many moves sent during the cooldown")
+)
+
+// Only touched from the subscriber's read loop so it isn't locked. Every
+// move the server handles counts, illegal ones included, otherwise a client
+// could hold the board lock by spamming bad moves
+type moveThrottle struct {
+	lastMoveAt time.Time
+	dropped    int
+}
+
+// false when the move should be dropped, the error is set once the client
+// has been flooding for long enough to be disconnected
+func (throttle *moveThrottle) allow(now time.Time) (bool, error) {
+	if !throttle.lastMoveAt.IsZero() && now.Sub(throttle.lastMoveAt) < moveCooldown {
+		throttle.dropped += 1
+		if throttle.dropped > maxDroppedMoves {
+			return false, errMoveFlooding
+		}
+		return false, nil
+	}
+
+	throttle.lastMoveAt = now
+	throttle.dropped = 0
+	return true, nil
+}
+
+// Returns whether the move should go on to be handled
+func (sub *subscriber) throttleMove(ctx context.Context) bool {
+	allowed, err := sub.throttle.allow(time.Now())
+	if err != nil {
+		sub.closeNow(ctx, err)
+		return false
+	}
+	if !allowed {
+		sub.sendError(ctx, rateLimited, errMoveCooldown)
+	}
+	return allowed
+}
@@ -0,0 +1,36 @@
+package game_server
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMoveCooldown(t *testing.T) {
+	throttle := moveThrottle{}
+	now := time.Now()
+
+	allowed, err := throttle.allow(now)
+	if !allowed || err != nil {
+		t.Fatalf("Expected the first move to be allowed, got %t %v", allowed, err)
+	}
+	allowed, err = throttle.allow(now.Add(moveCooldown / 2))
+	if allowed || err != nil {
+		t.Fatalf("Expected a move during the cooldown to be dropped, got %t %v", allowed, err)
+	}
+	allowed, err = throttle.allow(now.Add(moveCooldown))
+	if !allowed || err != nil {
+		t.Fatalf("Expected a move after the cooldown to be allowed, got %t %v", allowed, err)
+	}
+
+	for range maxDroppedMoves {
+		_, err = throttle.allow(now.Add(moveCooldown))
+		if err != nil {
+			t.Fatalf("Expected no flooding error yet, got %v", err)
+		}
+	}
+	_, err = throttle.allow(now.Add(moveCooldown))
+	if !errors.Is(err, errMoveFlooding) {
+		t.Errorf("Expected flooding error, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"chess/board"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+type MyTurnGame struct {
+	Id       string    `json:"id"`
+	Opponent string    `json:"opponent"`
+	Colour   string    `json:"colour"`
+	Fen      string    `json:"fen"`
+	Variant  string    `json:"variant"`
+	Deadline time.Time `json:"deadline"` // when the user runs out of time
+}
+
+type MyTurnResponse struct {
+	Games []MyTurnGame `json:"games"`
+}
+
+// sent by /my-turn/count and over the /notifications socket
+type MyTurnCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+const myTurnCountType = "myTurn"
+
+func (server *GameServer) allSessions() []*Session {
+	return server.sessions.all()
+}
+
+// Unfinished games waiting on the user, the closest deadline first
+func (server *GameServer) myTurnGames(userId uuid.UUID) []MyTurnGame {
+	games := make([]MyTurnGame, 0)
+	for _, session := range server.allSessions() {
+		session.boardStateLock.Lock()
+		colour := session.boardState.WhoseMove()
+		player := session.players[colour-1]
+		opponent := session.players[board.OppositeColour(colour)-1]
+		if session.ended || player.userId != userId {
+			session.boardStateLock.Unlock()
+			continue
+		}
+
+		session.clockLock.Lock()
+		remaining := session.whiteTime
+		if colour == board.Black {
+			remaining = session.blackTime
+		}
+		deadline := session.updatedAt.Add(remaining)
+		// the clock starts again once the vacation ends
+		if until := session.server.vacations.status(userId).Until; session.paused && until != nil {
+			deadline = until.Add(remaining)
+		}
+		session.clockLock.Unlock()
+
+		games = append(games, MyTurnGame{
+			Id:       session.id.String(),
+			Opponent: opponent.userId.String(),
+			Colour:   serialiseColour(colour),
+			Fen:      session.boardState.Fen(),
+			Variant:  board.VariantId(session.boardState.Variant()),
+			Deadline: deadline,
+		})
+		session.boardStateLock.Unlock()
+	}
+
+	slices.SortFunc(games, func(a, b MyTurnGame) int {
+		return a.Deadline.Compare(b.Deadline)
+	})
+	return games
+}
+
+func (server *GameServer) MyTurnHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	authSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	writeJson(ctx, writer, MyTurnResponse{Games: server.myTurnGames(authSession.UserID)})
+}
+
+func (server *GameServer) MyTurnCountHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	authSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	count := len(server.myTurnGames(authSession.UserID))
+	writeJson(ctx, writer, MyTurnCount{Type: myTurnCountType, Count: count})
+}
+
+func writeJson(ctx context.Context, writer http.ResponseWriter, value any) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Write(bytes)
+}
+
+// Pushes my turn counts to users with a /notifications socket open. Changes
+// are batched and counted by one goroutine so sockets always end up with
+// the latest count
+type badgeHub struct {
+	lock        sync.Mutex
+	subscribers map[uuid.UUID]map[chan int]struct{}
+	pending     map[uuid.UUID]struct{}
+	wake        chan struct{}
+}
+
+func newBadgeHub() *badgeHub {
+	return &badgeHub{
+		subscribers: make(map[uuid.UUID]map[chan int]struct{}),
+		pending:     make(map[uuid.UUID]struct{}),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+func (hub *badgeHub) subscribe(userId uuid.UUID) chan int {
+	counts := make(chan int, 1)
+	hub.lock.Lock()
+	if hub.subscribers[userId] == nil {
+		hub.subscribers[userId] = make(map[chan int]struct{})
+	}
+	hub.subscribers[userId][counts] = struct{}{}
+	hub.lock.Unlock()
+	return counts
+}
+
+func (hub *badgeHub) unsubscribe(userId uuid.UUID, counts chan int) {
+	hub.lock.Lock()
+	delete(hub.subscribers[userId], counts)
+	if len(hub.subscribers[userId]) == 0 {
+		delete(hub.subscribers, userId)
+	}
+	hub.lock.Unlock()
+}
+
+// never blocks so it's safe to call with session locks held
+func (hub *badgeHub) markDirty(userIds ...uuid.UUID) {
+	hub.lock.Lock()
+	for _, userId := range userIds {
+		if _, found := hub.subscribers[userId]; found {
+			hub.pending[userId] = struct{}{}
+		}
+	}
+	hasPending := len(hub.pending) > 0
+	hub.lock.Unlock()
+
+	if hasPending {
+		select {
+		case hub.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (hub *badgeHub) run(count func(userId uuid.UUID) int) {
+	for range hub.wake {
+		hub.lock.Lock()
+		pending := hub.pending
+		hub.pending = make(map[uuid.UUID]struct{})
+		hub.lock.Unlock()
+
+		for userId := range pending {
+			value := count(userId)
+
+			hub.lock.Lock()
+			for counts := range hub.subscribers[userId] {
+				// replace a count the socket hasn't sent yet
+				select {
+				case <-counts:
+				default:
+				}
+				counts <- value
+			}
+			hub.lock.Unlock()
+		}
+	}
+}
+
+// the players' turn counts may have changed
+func (session *Session) turnChanged() {
+	session.server.badges.markDirty(session.players[0].userId, session.players[1].userId)
+}
+
+// websocket which receives the user's my turn count whenever it changes
+func (server *GameServer) NotificationsHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	authSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	userId := authSession.UserID
+
+	conn, err := websocket.Accept(writer, req, server.compression.AcceptOptions())
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	defer conn.CloseNow()
+
+	counts := server.badges.subscribe(userId)
+	defer server.badges.unsubscribe(userId, counts)
+
+	// nothing is read, this just notices the client going away
+	ctx = conn.CloseRead(context.WithoutCancel(ctx))
+	count := len(server.myTurnGames(userId))
+	for {
+		bytes, err := json.Marshal(MyTurnCount{Type: myTurnCountType, Count: count})
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+		err = writeTimeout(ctx, time.Second*5, conn, websocket.MessageText, bytes)
+		if err != nil {
+			slog.InfoContext(ctx, "notification socket closed", slog.Any("error", err))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case count = <-counts:
+		}
+	}
+}
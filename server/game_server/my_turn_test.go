@@ -0,0 +1,107 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/auth"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+func TestMyTurn(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	cookie, userId := mockUser()
+
+	// white to move in the first, black in the second
+	first := server.NewSession(userId, uuid.New(), 0, 5*time.Minute)
+	second := server.NewSession(userId, uuid.New(), 0, time.Minute)
+	server.NewSession(uuid.New(), userId, 0, time.Minute)
+
+	secondSession, _ := server.sessions.load(second)
+
+	req := httptest.NewRequest(http.MethodGet, "/my-turn", nil)
+	req.AddCookie(cookie)
+	recorder := httptest.NewRecorder()
+	server.ServeMux.ServeHTTP(recorder, req)
+
+	response := MyTurnResponse{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Games) != 2 {
+		t.Fatalf("Expected 2 games, got %d", len(response.Games))
+	}
+	if response.Games[0].Id != second.String() || response.Games[1].Id != first.String() {
+		t.Error("Expected games to be sorted by deadline")
+	}
+
+	playMoves(t, secondSession, []string{"D1:C2"})
+
+	req = httptest.NewRequest(http.MethodGet, "/my-turn/count", nil)
+	req.AddCookie(cookie)
+	recorder = httptest.NewRecorder()
+	server.ServeMux.ServeHTTP(recorder, req)
+
+	count := MyTurnCount{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.Count != 1 {
+		t.Errorf("Expected 1 game after moving, got %d", count.Count)
+	}
+}
+
+func TestNotificationSocket(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	cookie, userId := mockUser()
+	httpServer := httptest.NewServer(server.ServeMux)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	header := http.Header{}
+	header.Add("Cookie", cookie.String())
+	conn, _, err := websocket.Dial(ctx, httpServer.URL+"/notifications",
+		&websocket.DialOptions{HTTPHeader: header})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	readCount := func() int {
+		t.Helper()
+		_, bytes, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := MyTurnCount{}
+		err = json.Unmarshal(bytes, &count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count.Count
+	}
+
+	if count := readCount(); count != 0 {
+		t.Fatalf("Expected no games, got %d", count)
+	}
+
+	sessionId := server.NewSession(userId, uuid.New(), 0, time.Minute)
+	if count := readCount(); count != 1 {
+		t.Fatalf("Expected the new game to be counted, got %d", count)
+	}
+
+	session, _ := server.sessions.load(sessionId)
+	playMoves(t, session, []string{"D1:C2"})
+	if count := readCount(); count != 0 {
+		t.Fatalf("Expected the count to drop after moving, got %d", count)
+	}
+}
@@ -0,0 +1,49 @@
+package game_server
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Every subscriber converts and encodes each event it's sent, the buffers
+// are handed back once the message is written so a busy server isn't
+// allocating new ones for every move
+
+var stringListPool = sync.Pool{
+	New: func() any { return new([]string) },
+}
+
+func getStringList() *[]string {
+	list := stringListPool.Get().(*[]string)
+	*list = (*list)[:0]
+	return list
+}
+
+func putStringList(list *[]string) {
+	if list == nil {
+		return
+	}
+	// the strings themselves can be collected
+	clear(*list)
+	stringListPool.Put(list)
+}
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+// very large buffers aren't worth keeping around
+const maxPooledBufferSize = 64 * 1024
+
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buffer)
+}
@@ -0,0 +1,69 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Every message a client sends is decoded and most take a lock on the
+// session, moves generate the next position's legal moves as well. The
+// per event throttles only see the events they're for so a token bucket
+// limits everything read from the socket before it's decoded
+
+const (
+	// messages a client can send at once before it has to slow down
+	inboundBurst = 20
+	// tokens given back per second
+	inboundRate = 10.0
+	// dropped messages in a row before the connection is closed
+	maxDroppedInbound = 50
+)
+
+var errInboundFlooding = errors.New("too many messages sent")
+
+// Like moveThrottle, only touched from the subscriber's read loop
+type inboundLimiter struct {
+	tokens     float64
+	refilledAt time.Time
+	dropped    int
+}
+
+// false when the message should be dropped, the error is set once the
+// client has been over the limit for long enough to be disconnected
+func (limiter *inboundLimiter) allow(now time.Time) (bool, error) {
+	if limiter.refilledAt.IsZero() {
+		limiter.tokens = inboundBurst
+	} else {
+		elapsed := now.Sub(limiter.refilledAt).Seconds()
+		limiter.tokens = min(inboundBurst, limiter.tokens+elapsed*inboundRate)
+	}
+	limiter.refilledAt = now
+
+	if limiter.tokens < 1 {
+		limiter.dropped += 1
+		if limiter.dropped > maxDroppedInbound {
+			return false, errInboundFlooding
+		}
+		return false, nil
+	}
+
+	limiter.tokens -= 1
+	limiter.dropped = 0
+	return true, nil
+}
+
+// Returns whether the message should go on to be decoded. Dropped messages
+// aren't answered, replying to a flood would only double it
+func (sub *subscriber) limitInbound(ctx context.Context) bool {
+	allowed, err := sub.inbound.allow(time.Now())
+	if err != nil {
+		sub.logger.WarnContext(ctx, "closing flooding client")
+		sub.closeNow(ctx, err)
+		return false
+	}
+	if !allowed && sub.inbound.dropped == 1 {
+		sub.logger.WarnContext(ctx, "dropping messages from client over the rate limit")
+	}
+	return allowed
+}
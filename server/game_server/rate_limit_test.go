@@ -0,0 +1,40 @@
+package game_server
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInboundRateLimit(t *testing.T) {
+	limiter := inboundLimiter{}
+	now := time.Now()
+
+	for range inboundBurst {
+		allowed, err := limiter.allow(now)
+		if !allowed || err != nil {
+			t.Fatalf("Expected the burst to be allowed, got %t %v", allowed, err)
+		}
+	}
+	allowed, err := limiter.allow(now)
+	if allowed || err != nil {
+		t.Fatalf("Expected a message past the burst to be dropped, got %t %v", allowed, err)
+	}
+	// a token comes back every 1/inboundRate seconds
+	now = now.Add(time.Duration(float64(time.Second) / inboundRate))
+	allowed, err = limiter.allow(now)
+	if !allowed || err != nil {
+		t.Fatalf("Expected a message after refilling to be allowed, got %t %v", allowed, err)
+	}
+
+	for range maxDroppedInbound {
+		_, err = limiter.allow(now)
+		if err != nil {
+			t.Fatalf("Expected no flooding error yet, got %v", err)
+		}
+	}
+	_, err = limiter.allow(now)
+	if !errors.Is(err, errInboundFlooding) {
+		t.Errorf("Expected flooding error, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chess/board"
+)
+
+// comments are sent this often so proxies don't drop a quiet stream
+const relayKeepAlive = 15 * time.Second
+
+// Streams a game's viewer events as server sent events. It's read only and
+// public so it can drive embeds without a login or a websocket. Finished
+// games get their final position and result and the stream ends
+func (server *GameServer) RelayHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	ctx := req.Context()
+	gameId, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logError(ctx, err)
+		return
+	}
+
+	flusher, canFlush := writer.(http.Flusher)
+	if !canFlush {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, errors.New("streaming unsupported"))
+		return
+	}
+
+	session, found := server.sessions.load(gameId)
+
+	finished, cached := server.FinishedGame(gameId)
+	if !found && !cached {
+		writer.WriteHeader(http.StatusNotFound)
+		logError(ctx, errors.New("not found"))
+		return
+	}
+
+	// the server's write timeout would cut the stream off
+	err = http.NewResponseController(writer).SetWriteDeadline(time.Time{})
+	if err != nil {
+		logError(ctx, err)
+	}
+	writer.Header().Add("Content-Type", "text/event-stream")
+	writer.Header().Add("Cache-Control", "no-cache")
+
+	if !found {
+		for _, event := range finishedEvents(finished) {
+			err = writeServerSentEvent(writer, event)
+			if err != nil {
+				logError(ctx, err)
+				return
+			}
+		}
+		flusher.Flush()
+		return
+	}
+
+	session.subscriberLock.Lock()
+	sub := NewSubscriber(uuid.Nil, session, board.None)
+	sub.state = Connected
+	session.viewers.Add(sub)
+	session.subscriberLock.Unlock()
+
+	connectEvent, _ := session.CreateConnectEvent(board.None, PreConnected)
+	err = writeServerSentEvent(writer, connectEvent)
+	if err != nil {
+		sub.closeNow(ctx, err)
+		return
+	}
+	flusher.Flush()
+	session.publishSpectators(ctx, sub)
+
+	sub.relay(ctx, writer, flusher)
+}
+
+// Sends the subscriber's events until either side closes the stream
+func (sub *subscriber) relay(
+	ctx context.Context,
+	writer http.ResponseWriter,
+	flusher http.Flusher,
+) {
+	keepAlive := time.NewTicker(relayKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-sub.doneChannel:
+			return
+		case <-ctx.Done():
+			sub.closeNow(context.WithoutCancel(ctx), nil)
+			return
+		case event := <-sub.events:
+			err = writeServerSentEvent(writer, event)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(writer, ": keep-alive\n\n")
+		}
+
+		if err != nil {
+			sub.closeNow(context.WithoutCancel(ctx), err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeServerSentEvent(writer http.ResponseWriter, event Event) error {
+	return writeServerSentData(writer, event.Type, event)
+}
+
+func writeServerSentData(writer http.ResponseWriter, eventType string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", eventType, data)
+	return err
+}
+
+// what a viewer would have been sent for a game that has already ended
+func finishedEvents(finished FinishedGame) []Event {
+	replay := finished.Replay
+	connectEvent := Event{
+		Type:        connectViewer,
+		Fen:         &replay.Fen,
+		MoveHistory: &replay.MoveHistory,
+	}
+	if len(replay.Clocks) > 0 {
+		last := replay.Clocks[len(replay.Clocks)-1]
+		connectEvent.WhiteTime = &last.WhiteTime
+		connectEvent.BlackTime = &last.BlackTime
+	}
+
+	ended := endEvent(finished.GameResult)
+	ended.Clocks = &replay.Clocks
+	return []Event{connectEvent, ended}
+}
@@ -0,0 +1,93 @@
+package game_server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+)
+
+func TestRelay(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /game/{id}/events", server.RelayHandler)
+	mux.HandleFunc("GET /embed/game/{id}", server.EmbedHandler)
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/game/" + session.id.String() + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Unexpected content type %s", resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() Event {
+		t.Helper()
+		event := Event{}
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, found := strings.CutPrefix(line, "data: ")
+			if !found {
+				continue
+			}
+			err = json.Unmarshal([]byte(data), &event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return event
+		}
+	}
+
+	if event := readEvent(); event.Type != connectViewer || event.Fen == nil {
+		t.Fatalf("Expected the position on connecting, got %+v", event)
+	}
+
+	playMoves(t, session, []string{"D1:C2"})
+	if event := readEvent(); event.Type != move || *event.Move != "D1:C2" {
+		t.Fatalf("Expected the move to be relayed, got %+v", event)
+	}
+
+	embed, err := http.Get(httpServer.URL + "/embed/game/" + session.id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := io.ReadAll(embed.Body)
+	embed.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), "/game/"+session.id.String()+"/events") {
+		t.Errorf("Expected the embed to point at the relay")
+	}
+
+	session.handleWin(context.Background(), board.WinResult(board.White, board.TerminationCheckmate))
+	session.cleanup(context.Background())
+
+	finished, err := http.Get(httpServer.URL + "/game/" + session.id.String() + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer finished.Body.Close()
+	reader = bufio.NewReader(finished.Body)
+	readEvent()
+	if event := readEvent(); event.Type != end || event.Victor == nil || *event.Victor != "w" {
+		t.Fatalf("Expected the result of the finished game, got %+v", event)
+	}
+}
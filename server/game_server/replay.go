@@ -0,0 +1,118 @@
+package game_server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"chess/board"
+)
+
+type ClockSnapshot struct {
+	WhiteTime int32 `json:"whiteTime"` // Time in milliseconds
+	BlackTime int32 `json:"blackTime"` // Time in milliseconds
+	// milliseconds taken off the mover's clock for the move before the
+	// increment, nothing until the clocks start after each side's first move
+	Spent int32 `json:"spent"`
+	// unix time in milliseconds the move was played
+	PlayedAt int64 `json:"playedAt"`
+}
+
+type ReplayResponse struct {
+	Id          string   `json:"id"`
+	Variant     string   `json:"variant"`
+	Fen         string   `json:"fen"`
+	MoveHistory []string `json:"moveHistory"`
+	// clocks[i] is the remaining time of both players after moveHistory[i]
+	Clocks     []ClockSnapshot `json:"clocks"`
+	GameLength int32           `json:"gameLength"` // Time in milliseconds
+	Increment  int32           `json:"increment"`  // Time in milliseconds
+	Outcome    *string         `json:"outcome,omitempty"`
+	// set once the game is over, e.g. "checkmate" or "time forfeit"
+	Termination *board.Termination `json:"termination,omitempty"`
+	// only set when asked for with ?ply=, the position after that many half
+	// moves and a png of it for link previews
+	Ply       *int    `json:"ply,omitempty"`
+	PlyFen    *string `json:"plyFen,omitempty"`
+	Thumbnail *string `json:"thumbnail,omitempty"`
+}
+
+func (session *Session) replay() ReplayResponse {
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+	return session.replayImpl()
+}
+
+// A copy of the clock readings so far, boardStateLock should be held
+func (session *Session) clockHistoryImpl() []ClockSnapshot {
+	clocks := make([]ClockSnapshot, len(session.clockHistory))
+	copy(clocks, session.clockHistory)
+	return clocks
+}
+
+func (session *Session) replayImpl() ReplayResponse {
+	clocks := session.clockHistoryImpl()
+
+	response := ReplayResponse{
+		Id:          session.id.String(),
+		Variant:     board.VariantId(session.boardState.Variant()),
+		Fen:         session.boardState.Fen(),
+		MoveHistory: board.SerialiseMoveList(session.boardState.PlayedMoves()),
+		Clocks:      clocks,
+		GameLength:  int32(session.gameLength.Milliseconds()),
+		Increment:   int32(session.increment.Milliseconds()),
+	}
+	if session.result != nil {
+		outcome := board.WinStateToString(session.result.WinState())
+		termination := session.result.Reason
+		response.Outcome = &outcome
+		response.Termination = &termination
+	}
+	return response
+}
+
+func (server *GameServer) ReplayHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	ctx := req.Context()
+	gameId, err := getIdWithPrefix(writer, req, "/replay/")
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	replay, found := server.gameReplay(gameId)
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		logError(ctx, errors.New("not found"))
+		return
+	}
+
+	ply, hasPly, err := parsePly(req, len(replay.MoveHistory))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if hasPly {
+		// the game routes are mounted under a prefix which has been stripped
+		prefix, _, _ := strings.Cut(req.RequestURI, "/replay/")
+		replay, err = withPly(replay, ply, prefix)
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			logError(ctx, err)
+			return
+		}
+	}
+
+	bytes, err := json.Marshal(replay)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Write(bytes)
+}
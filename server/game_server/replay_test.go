@@ -0,0 +1,81 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+)
+
+func TestReplayClocks(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+
+	gameLength := 5 * time.Second
+	session := newTestSession(server, 0, gameLength)
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7"})
+	time.Sleep(50 * time.Millisecond)
+	playMoves(t, session, []string{"F2:E4"})
+
+	req := httptest.NewRequest(http.MethodGet, "/replay/"+session.id.String(), nil)
+	recorder := httptest.NewRecorder()
+	server.ServeMux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	replay := ReplayResponse{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replay.MoveHistory) != 3 || len(replay.Clocks) != 3 {
+		t.Fatalf("Expected 3 moves and clocks, got %d and %d",
+			len(replay.MoveHistory), len(replay.Clocks))
+	}
+	if replay.Variant != board.DefaultVariant.Name() {
+		t.Errorf("Expected the default variant, got %s", replay.Variant)
+	}
+	if replay.Clocks[0].WhiteTime != int32(gameLength.Milliseconds()) {
+		t.Errorf("Expected clock to not start before both players moved, got %d",
+			replay.Clocks[0].WhiteTime)
+	}
+	if replay.Clocks[2].WhiteTime > int32(gameLength.Milliseconds()) {
+		t.Errorf("Expected white clock to have run, got %d", replay.Clocks[2].WhiteTime)
+	}
+	if replay.Outcome != nil {
+		t.Errorf("Expected no outcome while the game's in progress, got %s", *replay.Outcome)
+	}
+	if replay.Clocks[0].Spent != 0 || replay.Clocks[2].Spent < 50 ||
+		replay.Clocks[2].PlayedAt < replay.Clocks[0].PlayedAt+50 {
+		t.Errorf("Unexpected move times %+v", replay.Clocks)
+	}
+
+	// black was sent white's first move too
+	nextEvent(t, session.players[1], move)
+	moveEvent := nextEvent(t, session.players[1], move)
+	if moveEvent.Clock == nil || *moveEvent.Clock != replay.Clocks[2] {
+		t.Errorf("Expected the move event to carry its clock reading, got %+v", moveEvent.Clock)
+	}
+	session.handleWin(context.Background(), board.WinResult(board.White, board.TerminationResignation))
+	endEvent := nextEvent(t, session.players[1], end)
+	if endEvent.Clocks == nil || len(*endEvent.Clocks) != 3 {
+		t.Errorf("Expected the end event to carry every clock reading, got %+v", endEvent.Clocks)
+	}
+
+	// the board knows nothing of the resignation
+	replay = session.replay()
+	if replay.Outcome == nil || *replay.Outcome != board.WinStateToString(board.WhiteWin) ||
+		replay.Termination == nil || *replay.Termination != board.TerminationResignation {
+		t.Errorf("Expected the resignation in the replay, got %v %v", replay.Outcome, replay.Termination)
+	}
+
+	session.cleanup(context.Background())
+}
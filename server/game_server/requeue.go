@@ -0,0 +1,89 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+// Implemented by the matchmaking server, lets a player who just finished
+// a game join the queue for the same format straight from the game socket
+type Matchmaker interface {
+	Requeue(
+		ctx context.Context,
+		userId uuid.UUID,
+		gameLength time.Duration,
+		increment time.Duration,
+		variant board.Variant,
+		notify func(gameId uuid.UUID),
+	) (cancel func(), err error)
+}
+
+func (server *GameServer) SetMatchmaker(matchmaker Matchmaker) {
+	server.matchmaker = matchmaker
+}
+
+// shuffled games go back into the pool for the variant rather than the setup
+// they were played with, so the next game gets a new one
+func requeueVariant(variant board.Variant) board.Variant {
+	if _, ok := variant.(board.Shuffled); !ok {
+		return variant
+	}
+	parent, err := board.VariantFromName(variant.Name())
+	if err != nil {
+		return variant
+	}
+	return parent
+}
+
+func (sub *subscriber) handleNewOpponent(ctx context.Context) {
+	session := sub.session
+	if sub.colour != board.White && sub.colour != board.Black {
+		sub.sendError(ctx, notYourTurn, errors.New("only players can ask for a new opponent"))
+		return
+	}
+
+	session.boardStateLock.Lock()
+	ended := session.ended
+	variant := session.boardState.Variant()
+	session.boardStateLock.Unlock()
+
+	// all games are casual for now, rated games should not allow this
+	if !ended {
+		sub.sendError(ctx, gameNotOver, errors.New("new opponent requested before game end"))
+		return
+	}
+	if session.server.matchmaker == nil || sub.cancelRequeue != nil {
+		return
+	}
+
+	cancel, err := session.server.matchmaker.Requeue(
+		ctx,
+		sub.userId,
+		session.gameLength,
+		session.increment,
+		requeueVariant(variant),
+		func(gameId uuid.UUID) {
+			sub.cancelRequeue = nil
+
+			id := gameId.String()
+			err := sub.write(ctx, Event{Type: newGame, GameId: &id})
+			sub.closeNow(ctx, err)
+		},
+	)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	// nil when an opponent was already waiting and notify has been called
+	if cancel == nil {
+		return
+	}
+	sub.cancelRequeue = cancel
+
+	sub.logger.InfoContext(ctx, "player requeued")
+}
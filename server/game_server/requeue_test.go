@@ -0,0 +1,52 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+type fakeMatchmaker struct {
+	variants chan board.Variant
+}
+
+func (matchmaker *fakeMatchmaker) Requeue(
+	ctx context.Context,
+	userId uuid.UUID,
+	gameLength time.Duration,
+	increment time.Duration,
+	variant board.Variant,
+	notify func(gameId uuid.UUID),
+) (cancel func(), err error) {
+	matchmaker.variants <- variant
+	return func() {}, nil
+}
+
+func TestRequeueVariant(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	matchmaker := &fakeMatchmaker{variants: make(chan board.Variant, 1)}
+	server.SetMatchmaker(matchmaker)
+
+	setup, err := board.Chess960Setup(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := server.NewVariantSession(uuid.New(), uuid.New(), 0, time.Hour, setup)
+	session, _ := server.sessions.load(sessionId)
+	session.handleWin(context.Background(), board.WinResult(board.White, board.TerminationResignation))
+
+	session.players[0].handleNewOpponent(context.Background())
+	select {
+	case variant := <-matchmaker.variants:
+		if variant != board.Chess960 {
+			t.Errorf("Expected to be requeued for chess960, got %s", board.VariantId(variant))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the player to be requeued")
+	}
+}
@@ -0,0 +1,20 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+
+	"chess/board"
+)
+
+// The opponent wins, any time before the game has ended including before
+// the first move. A resignation after the game has ended is ignored
+func (sub *subscriber) handleResign(ctx context.Context) {
+	if sub.colour != board.White && sub.colour != board.Black {
+		sub.sendError(ctx, notYourTurn, errors.New("only players can resign"))
+		return
+	}
+
+	winner := board.OppositeColour(sub.colour)
+	sub.session.handleWin(ctx, board.WinResult(winner, board.TerminationResignation))
+}
@@ -0,0 +1,58 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/model"
+)
+
+func TestResign(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeStore{games: make(chan model.CreateGameParams, 1)}
+	server.SetStore(store)
+	session := newTestSession(server, 0, 5*time.Second)
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7", "F2:E4"})
+	session.players[1].handleResign(context.Background())
+
+	select {
+	case game := <-store.games:
+		if game.Result != "1-0" || game.Termination != "resignation" {
+			t.Errorf("Unexpected saved game %+v", game)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected resigned game to be saved")
+	}
+
+	session.boardStateLock.Lock()
+	ended := session.ended
+	session.boardStateLock.Unlock()
+	if !ended {
+		t.Error("Expected resignation to end the game")
+	}
+	session.clockLock.Lock()
+	clockStopped := session.clockTimer == nil
+	session.clockLock.Unlock()
+	if !clockStopped {
+		t.Error("Expected resignation to stop the clock")
+	}
+
+	// the game is already over so this is ignored
+	session.players[0].handleResign(context.Background())
+	select {
+	case game := <-store.games:
+		t.Errorf("Expected a second resignation to be ignored, saved %+v", game)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	event := endEvent(board.WinResult(board.White, board.TerminationResignation))
+	if *event.Outcome != "win" || *event.Victor != "w" || *event.Termination != "resignation" {
+		t.Errorf("Unexpected end event %+v", event)
+	}
+
+	session.cleanup(context.Background())
+}
@@ -0,0 +1,240 @@
+package game_server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"chess/board"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+// how often games in progress are written to the live store
+const LiveSnapshotInterval = 30 * time.Second
+
+// games in progress are saved through this so they survive a restart, they
+// only live in memory when it isn't set
+type LiveGameStore interface {
+	SaveLiveGame(ctx context.Context, arg model.SaveLiveGameParams) error
+	ListLiveGames(ctx context.Context) ([]model.LiveGame, error)
+	DeleteLiveGame(ctx context.Context, id uuid.UUID) error
+}
+
+func (server *GameServer) SetLiveStore(store LiveGameStore) {
+	server.liveStore = store
+}
+
+// Bot games aren't saved, the searcher playing one side can't be brought
+// back after a restart
+func (session *Session) hasEngine() bool {
+	return session.players[0].userId == EngineUserId ||
+		session.players[1].userId == EngineUserId
+}
+
+// The row for the game as it stands, boardStateLock should be held
+func (session *Session) liveSnapshotImpl() (model.SaveLiveGameParams, error) {
+	data, err := session.boardState.MarshalBinary()
+	if err != nil {
+		return model.SaveLiveGameParams{}, err
+	}
+	clocks, err := json.Marshal(session.clockHistory)
+	if err != nil {
+		return model.SaveLiveGameParams{}, err
+	}
+	session.clockLock.Lock()
+	whiteTime, blackTime := session.getClockStateImpl()
+	berserk := serialiseBerserk(session.berserk)
+	session.clockLock.Unlock()
+
+	return model.SaveLiveGameParams{
+		ID:         session.id,
+		WhiteID:    session.players[0].userId,
+		BlackID:    session.players[1].userId,
+		GameLength: session.gameLength.Milliseconds(),
+		Increment:  session.increment.Milliseconds(),
+		Variant:    board.VariantId(session.boardState.Variant()),
+		Moves:      strings.Join(moveList(session.boardState.PlayedMoves()), " "),
+		Board:      data,
+		WhiteTime:  whiteTime.Milliseconds(),
+		BlackTime:  blackTime.Milliseconds(),
+		Clocks:     string(clocks),
+		CreatedAt:  session.createdAt.UTC(),
+		UpdatedAt:  time.Now().UTC(),
+		Berserk:    berserk,
+	}, nil
+}
+
+// Writes every game in progress to the live store, run on a schedule and
+// once more on shutdown
+func (server *GameServer) SaveLiveGames(ctx context.Context) error {
+	store := server.liveStore
+	if store == nil {
+		return nil
+	}
+
+	sessions := server.sessions.all()
+
+	var errs []error
+	for _, session := range sessions {
+		if session.hasEngine() {
+			continue
+		}
+		session.boardStateLock.Lock()
+		if session.ended {
+			session.boardStateLock.Unlock()
+			continue
+		}
+		params, err := session.liveSnapshotImpl()
+		session.boardStateLock.Unlock()
+
+		if err == nil {
+			err = store.SaveLiveGame(ctx, params)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("game %s: %w", session.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Removes the game from the live store once it's over, the write happens in
+// the background
+func (session *Session) forgetLiveGame(ctx context.Context) {
+	store := session.server.liveStore
+	if store == nil || session.hasEngine() {
+		return
+	}
+
+	go func() {
+		err := store.DeleteLiveGame(context.WithoutCancel(ctx), session.id)
+		if err != nil {
+			session.logger.ErrorContext(ctx, "failed to delete live game",
+				slog.Any("error", err))
+		}
+	}()
+}
+
+// Plays the saved moves again so the history is there for repetitions and
+// replays, the saved board has to match the position they reach
+func restoreLiveBoard(game model.LiveGame) (*board.BoardState, error) {
+	variant, err := board.VariantFromName(game.Variant)
+	if err != nil {
+		return nil, err
+	}
+	boardState := board.NewVariantBoard(variant)
+	err = boardState.Init()
+	if err != nil {
+		return nil, err
+	}
+	for _, str := range strings.Fields(game.Moves) {
+		move, err := board.DeserialiseMove(str)
+		if err != nil {
+			return nil, err
+		}
+		err = boardState.MakeMove(move)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := boardState.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(data, game.Board) {
+		return nil, fmt.Errorf("%w: moves don't reach the saved board",
+			board.ErrInconsistentPosition)
+	}
+	if boardState.HasWinnerImpl() != board.NoWin {
+		return nil, errors.New("saved game is already over")
+	}
+	return boardState, nil
+}
+
+// Rebuilds a session from its saved row. Both players start disconnected
+// and join again through /subscribe as they would after losing their
+// connection
+func (server *GameServer) restoreSession(game model.LiveGame) (*Session, error) {
+	boardState, err := restoreLiveBoard(game)
+	if err != nil {
+		return nil, err
+	}
+	clocks := []ClockSnapshot{}
+	err = json.Unmarshal([]byte(game.Clocks), &clocks)
+	if err != nil {
+		return nil, err
+	}
+
+	session := newSession(game.WhiteID, game.BlackID,
+		time.Duration(game.Increment)*time.Millisecond,
+		time.Duration(game.GameLength)*time.Millisecond,
+		boardState.Variant(), server)
+	session.id = game.ID
+	session.resetLoggers()
+	session.boardState = boardState
+	session.clockHistory = clocks
+	// the time the server was down isn't taken off anyone's clock
+	session.whiteTime = time.Duration(game.WhiteTime) * time.Millisecond
+	session.blackTime = time.Duration(game.BlackTime) * time.Millisecond
+	session.createdAt = game.CreatedAt
+	session.berserk = parseBerserk(game.Berserk)
+	return session, nil
+}
+
+// Loads the games saved in the live store back into the server, called once
+// at startup after SetLiveStore. Games which can't be restored are logged and
+// dropped from the store
+func (server *GameServer) ResumeLiveGames(ctx context.Context) (int, error) {
+	store := server.liveStore
+	if store == nil {
+		return 0, nil
+	}
+	games, err := store.ListLiveGames(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resumed := 0
+	for _, game := range games {
+		// another node has it
+		acquired, err := server.acquireResumedSession(ctx, game.ID)
+		if err != nil {
+			return resumed, err
+		}
+		if !acquired {
+			continue
+		}
+
+		session, err := server.restoreSession(game)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to resume game",
+				slog.String("gameId", game.ID.String()),
+				slog.Any("error", err))
+			err = store.DeleteLiveGame(ctx, game.ID)
+			if err != nil {
+				return resumed, err
+			}
+			continue
+		}
+
+		session.startClockBroadcasts()
+		server.sessions.store(session)
+		server.userGames.add(session)
+
+		session.boardStateLock.Lock()
+		session.turnChanged()
+		session.clockLock.Lock()
+		session.armClockImpl(context.Background())
+		session.clockLock.Unlock()
+		session.boardStateLock.Unlock()
+		resumed++
+	}
+	return resumed, nil
+}
@@ -0,0 +1,113 @@
+package game_server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+type fakeLiveStore struct {
+	lock  sync.Mutex
+	games map[uuid.UUID]model.LiveGame
+}
+
+func (store *fakeLiveStore) SaveLiveGame(ctx context.Context, arg model.SaveLiveGameParams) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	store.games[arg.ID] = model.LiveGame(arg)
+	return nil
+}
+
+func (store *fakeLiveStore) ListLiveGames(ctx context.Context) ([]model.LiveGame, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	games := make([]model.LiveGame, 0, len(store.games))
+	for _, game := range store.games {
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+func (store *fakeLiveStore) DeleteLiveGame(ctx context.Context, id uuid.UUID) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	delete(store.games, id)
+	return nil
+}
+
+func (store *fakeLiveStore) count() int {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return len(store.games)
+}
+
+func TestLiveGameResumed(t *testing.T) {
+	authServer := &auth.MockAuthServer{}
+	store := &fakeLiveStore{games: make(map[uuid.UUID]model.LiveGame)}
+
+	before := NewGameServer(authServer)
+	before.SetLiveStore(store)
+	white, black := uuid.New(), uuid.New()
+	sessionId := before.NewSession(white, black, 0, 5*time.Minute)
+	session, _ := before.sessions.load(sessionId)
+	playMoves(t, session, []string{"D1:C2", "E8:F7", "F2:E4"})
+
+	err := before.SaveLiveGames(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.stopClock()
+	whiteTime, blackTime := session.getClockState()
+
+	// a row which doesn't replay to its board is dropped rather than resumed
+	broken := model.LiveGame{ID: uuid.New(), Moves: "D1:C2", Board: []byte{1}, Clocks: "[]"}
+	store.games[broken.ID] = broken
+
+	after := NewGameServer(authServer)
+	after.SetLiveStore(store)
+	resumed, err := after.ResumeLiveGames(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed != 1 || store.count() != 1 {
+		t.Fatalf("Expected one game resumed and kept, got %d resumed and %d kept",
+			resumed, store.count())
+	}
+
+	restored, found := after.sessions.load(sessionId)
+	if !found {
+		t.Fatal("Expected the game to be resumed under the same id")
+	}
+	if restored.players[0].userId != white || restored.players[1].userId != black {
+		t.Error("Expected the players to keep their colours")
+	}
+	if restored.boardState.Fen() != session.boardState.Fen() ||
+		len(restored.boardState.PlayedMoves()) != 3 || len(restored.clockHistory) != 3 {
+		t.Errorf("Expected the position and history to be restored, got %s", restored.boardState.Fen())
+	}
+	restoredWhite, restoredBlack := restored.getClockState()
+	if restoredWhite.Round(time.Second) != whiteTime.Round(time.Second) ||
+		restoredBlack.Round(time.Second) != blackTime.Round(time.Second) {
+		t.Errorf("Expected clocks %v %v, got %v %v", whiteTime, blackTime, restoredWhite, restoredBlack)
+	}
+
+	playMoves(t, restored, []string{restored.boardState.LegalMoves[0].Serialise()})
+	restored.handleWin(context.Background(), board.WinResult(board.Black, board.TerminationResignation))
+	deadline := time.Now().Add(time.Second)
+	for store.count() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if store.count() != 0 {
+		t.Error("Expected the finished game to be removed from the live store")
+	}
+
+	session.cleanup(context.Background())
+	restored.cleanup(context.Background())
+}
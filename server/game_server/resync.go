@@ -0,0 +1,41 @@
+package game_server
+
+import (
+	"context"
+
+	"chess/board"
+)
+
+// A client which thinks it's out of step, e.g. it's missed more events than
+// can be replayed, can ask for the whole position again without
+// reconnecting. Only the subscriber asking is sent it
+
+// boardStateLock should be held
+func (session *Session) syncEvent(colour board.Colour) Event {
+	fen := session.boardState.Fen()
+	moveHistory := moveList(session.boardState.PlayedMoves())
+	whiteTime, blackTime := session.getClockState()
+	whiteTimeMs := int32(whiteTime.Milliseconds())
+	blackTimeMs := int32(blackTime.Milliseconds())
+	seq := session.sent.latest()
+	event := Event{
+		Type:        resync,
+		Fen:         &fen,
+		MoveHistory: &moveHistory,
+		WhiteTime:   &whiteTimeMs,
+		BlackTime:   &blackTimeMs,
+		Seq:         &seq,
+	}
+	if colour != board.None {
+		legalMoves := board.SerialiseMoveList(session.boardState.LegalMoves)
+		event.LegalMoves = &legalMoves
+	}
+	return event
+}
+
+func (sub *subscriber) handleSync(ctx context.Context) {
+	sub.session.boardStateLock.Lock()
+	event := sub.session.syncEvent(sub.colour)
+	sub.session.boardStateLock.Unlock()
+	sub.session.publishImpl(ctx, event, sub)
+}
@@ -0,0 +1,73 @@
+package game_server
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"chess/auth"
+)
+
+func TestSync(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, time.Minute)
+	ctx := context.Background()
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7"})
+	white, black := session.players[0], session.players[1]
+	for _, player := range session.players {
+		for len(player.events) > 0 {
+			<-player.events
+		}
+	}
+
+	black.handleSync(ctx)
+	event := nextEvent(t, black, resync)
+	if event.Fen == nil || *event.Fen != session.boardState.Fen() {
+		t.Errorf("Expected the current position, got %v", event.Fen)
+	}
+	if event.MoveHistory == nil || !slices.Equal(*event.MoveHistory, []string{"D1:C2", "E8:F7"}) {
+		t.Errorf("Expected both moves in the history, got %v", event.MoveHistory)
+	}
+	if event.LegalMoves == nil || event.WhiteTime == nil || event.Seq == nil ||
+		*event.Seq != session.sent.latest() {
+		t.Errorf("Expected legal moves, clocks and the latest seq, got %+v", event)
+	}
+	if len(white.events) > 0 {
+		t.Error("Expected only the subscriber asking to be sent the position")
+	}
+}
+
+func TestViewerSync(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	whiteCookie, white := mockUser()
+	_, black := mockUser()
+	sessionId := server.NewSession(white, black, 0, time.Minute)
+	fixture := newSocketFixture(t, server.ServeMux, sessionId)
+
+	whiteConn, _ := fixture.dial(whiteCookie)
+	viewerConn, _ := fixture.dial(nil)
+
+	sent := "D1:C2"
+	fixture.send(whiteConn, Event{Type: sendMove, Move: &sent})
+	played := fixture.readUntil(viewerConn, move)
+
+	fixture.send(viewerConn, Event{Type: resync})
+	event := fixture.readUntil(viewerConn, resync)
+	if event.MoveHistory == nil || !slices.Equal(*event.MoveHistory, []string{sent}) {
+		t.Errorf("Expected the move in the history, got %v", event.MoveHistory)
+	}
+	if event.Seq == nil || *event.Seq != *played.Seq {
+		t.Errorf("Expected the latest seq %d, got %v", *played.Seq, event.Seq)
+	}
+	if event.LegalMoves != nil {
+		t.Error("Expected viewers not to be sent legal moves")
+	}
+
+	// the first sync white sees should be its own, with legal moves
+	fixture.send(whiteConn, Event{Type: resync})
+	if event := fixture.readUntil(whiteConn, resync); event.LegalMoves == nil {
+		t.Error("Expected only the viewer asking to be sent its sync")
+	}
+}
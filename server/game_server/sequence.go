@@ -0,0 +1,105 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"chess/board"
+)
+
+// Everything a session sends its subscribers is numbered so a client can
+// tell when it's missed something, usually while reconnecting. The last few
+// events are kept and a client can ask for everything after the last number
+// it saw. Events a subscriber caused, like its own moves, aren't sent back
+// to it so it should expect to skip those numbers, a replay leaves them out
+// too
+
+const eventLogSize = 64
+
+var errReplayUnavailable = errors.New("events are too old to replay, sync instead")
+
+type loggedEvent struct {
+	seq    int64
+	player Event
+	viewer Event
+	// left out when it was sent
+	skip *subscriber
+}
+
+type eventLog struct {
+	lock sync.Mutex
+	// number of the last event, 0 before anything's been sent
+	seq int64
+	// oldest first
+	events []loggedEvent
+}
+
+// Stamps the events with the next number and keeps them for replays
+func (log *eventLog) record(skip *subscriber, player *Event, viewer *Event) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	log.seq++
+	seq := log.seq
+	player.Seq = &seq
+	viewer.Seq = &seq
+
+	if len(log.events) == eventLogSize {
+		log.events = log.events[1:]
+	}
+	log.events = append(log.events, loggedEvent{seq, *player, *viewer, skip})
+}
+
+func (log *eventLog) latest() int64 {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	return log.seq
+}
+
+// Events after seq, false when some of them are no longer kept
+func (log *eventLog) since(seq int64) ([]loggedEvent, bool) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	if seq > log.seq || seq < 0 {
+		return nil, false
+	}
+	if len(log.events) == 0 || seq >= log.events[0].seq-1 {
+		for index, event := range log.events {
+			if event.seq > seq {
+				return append([]loggedEvent(nil), log.events[index:]...), true
+			}
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+// Written straight to the connection rather than queued, a replay can be
+// longer than the subscriber's queue. Live events can arrive in between,
+// clients should go by the numbers
+func (sub *subscriber) handleReplay(ctx context.Context, event Event) {
+	if event.Seq == nil {
+		sub.sendError(ctx, badJson, errors.New("replay without a seq"))
+		return
+	}
+	events, found := sub.session.sent.since(*event.Seq)
+	if !found {
+		sub.sendError(ctx, replayUnavailable, errReplayUnavailable)
+		return
+	}
+
+	for _, logged := range events {
+		if logged.skip == sub {
+			continue
+		}
+		replayed := logged.player
+		if sub.colour == board.None {
+			replayed = logged.viewer
+		}
+		err := sub.write(ctx, replayed)
+		if err != nil {
+			sub.closeNow(ctx, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+)
+
+func TestEventSequence(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, time.Minute)
+	ctx := context.Background()
+	white, black := session.players[0], session.players[1]
+
+	playMoves(t, session, []string{"D1:C2"})
+	first := nextEvent(t, black, move)
+	if first.Seq == nil {
+		t.Fatal("Expected the move to be numbered")
+	}
+	session.publish(ctx, nil, spectatorsEvent(0))
+	second := nextEvent(t, white, spectatorsChanged)
+	if second.Seq == nil || *second.Seq != *first.Seq+1 {
+		t.Errorf("Expected the next event to be %d, got %v", *first.Seq+1, second.Seq)
+	}
+
+	events, found := session.sent.since(*first.Seq - 1)
+	if !found || len(events) != 2 || events[0].skip != white {
+		t.Errorf("Expected both events to be kept with the mover left out, got %v %v",
+			events, found)
+	}
+	events, found = session.sent.since(*second.Seq)
+	if !found || len(events) != 0 {
+		t.Errorf("Expected nothing after the latest event, got %v %v", events, found)
+	}
+
+	for range eventLogSize {
+		session.sent.record(nil, &Event{}, &Event{})
+	}
+	_, found = session.sent.since(*first.Seq)
+	if found {
+		t.Error("Expected events pushed out of the log to be unavailable")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	whiteCookie, white := mockUser()
+	blackCookie, black := mockUser()
+	sessionId := server.NewSession(white, black, 0, time.Minute)
+	fixture := newSocketFixture(t, server.ServeMux, sessionId)
+
+	whiteConn, _ := fixture.dial(whiteCookie)
+	blackConn, _ := fixture.dial(blackCookie)
+
+	sent := "D1:C2"
+	fixture.send(whiteConn, Event{Type: sendMove, Move: &sent})
+	played := fixture.readUntil(blackConn, move)
+
+	from := *played.Seq - 1
+	fixture.send(blackConn, Event{Type: replay, Seq: &from})
+	replayed := fixture.readUntil(blackConn, move)
+	if *replayed.Seq != *played.Seq || *replayed.Move != sent {
+		t.Errorf("Expected move %d to be replayed, got %d %s", *played.Seq,
+			*replayed.Seq, *replayed.Move)
+	}
+
+	ahead := *played.Seq + 10
+	fixture.send(blackConn, Event{Type: replay, Seq: &ahead})
+	unavailable := fixture.readUntil(blackConn, errorEvent)
+	if *unavailable.Code != replayUnavailable {
+		t.Errorf("Expected %s, got %s", replayUnavailable, *unavailable.Code)
+	}
+}
+
+func TestViewerReplay(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	whiteCookie, white := mockUser()
+	_, black := mockUser()
+	sessionId := server.NewSession(white, black, 0, time.Minute)
+	fixture := newSocketFixture(t, server.ServeMux, sessionId)
+
+	whiteConn, _ := fixture.dial(whiteCookie)
+	viewerCookie, _ := mockUser()
+	viewerConn, _ := fixture.dial(viewerCookie)
+
+	sent := "D1:C2"
+	fixture.send(whiteConn, Event{Type: sendMove, Move: &sent})
+	played := fixture.readUntil(viewerConn, move)
+
+	// viewers are replayed what they were sent, including the extras
+	from := *played.Seq - 1
+	fixture.send(viewerConn, Event{Type: replay, Seq: &from})
+	replayed := fixture.readUntil(viewerConn, move)
+	if *replayed.Seq != *played.Seq || *replayed.Move != sent || replayed.WinProbability == nil {
+		t.Errorf("Expected the viewer's move %d to be replayed, got %+v", *played.Seq, replayed)
+	}
+
+	ahead := *played.Seq + 10
+	fixture.send(viewerConn, Event{Type: replay, Seq: &ahead})
+	unavailable := fixture.readUntil(viewerConn, errorEvent)
+	if *unavailable.Code != replayUnavailable {
+		t.Errorf("Expected %s, got %s", replayUnavailable, *unavailable.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package game_server
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Every subscribe, snapshot and relay looks its game up, with one lock
+// around every session they all queue up behind each other. The map is
+// split into shards by game id, each with its own lock, so lookups of
+// different games rarely touch the same lock and reads don't block each
+// other at all
+
+const sessionShards = 32
+
+type sessionShard struct {
+	lock     sync.RWMutex
+	sessions map[uuid.UUID]*Session
+}
+
+type sessionMap struct {
+	shards [sessionShards]sessionShard
+}
+
+func newSessionMap() *sessionMap {
+	sessions := &sessionMap{}
+	for index := range sessions.shards {
+		sessions.shards[index].sessions = make(map[uuid.UUID]*Session)
+	}
+	return sessions
+}
+
+// the last bytes of v4 and v5 ids are random so spread evenly
+func (sessions *sessionMap) shard(id uuid.UUID) *sessionShard {
+	return &sessions.shards[binary.BigEndian.Uint32(id[12:])%sessionShards]
+}
+
+func (sessions *sessionMap) load(id uuid.UUID) (*Session, bool) {
+	shard := sessions.shard(id)
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+	session, found := shard.sessions[id]
+	return session, found
+}
+
+func (sessions *sessionMap) store(session *Session) {
+	shard := sessions.shard(session.id)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	shard.sessions[session.id] = session
+}
+
+func (sessions *sessionMap) delete(id uuid.UUID) (*Session, bool) {
+	shard := sessions.shard(id)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	session, found := shard.sessions[id]
+	delete(shard.sessions, id)
+	return session, found
+}
+
+// Not a consistent snapshot, sessions added or removed while the shards are
+// being read may or may not be included
+func (sessions *sessionMap) all() []*Session {
+	ret := make([]*Session, 0)
+	for index := range sessions.shards {
+		shard := &sessions.shards[index]
+		shard.lock.RLock()
+		for _, session := range shard.sessions {
+			ret = append(ret, session)
+		}
+		shard.lock.RUnlock()
+	}
+	return ret
+}
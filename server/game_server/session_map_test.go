@@ -0,0 +1,96 @@
+package game_server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+// What the session map was before it was sharded, kept to compare against
+type lockedSessionMap struct {
+	lock     sync.Mutex
+	sessions map[uuid.UUID]*Session
+}
+
+func (sessions *lockedSessionMap) load(id uuid.UUID) (*Session, bool) {
+	sessions.lock.Lock()
+	defer sessions.lock.Unlock()
+	session, found := sessions.sessions[id]
+	return session, found
+}
+
+func (sessions *lockedSessionMap) store(session *Session) {
+	sessions.lock.Lock()
+	defer sessions.lock.Unlock()
+	sessions.sessions[session.id] = session
+}
+
+func BenchmarkSessionLookup(b *testing.B) {
+	ids := make([]uuid.UUID, 1024)
+	sharded := newSessionMap()
+	locked := &lockedSessionMap{sessions: make(map[uuid.UUID]*Session)}
+	for index := range ids {
+		ids[index] = uuid.New()
+		session := &Session{id: ids[index]}
+		sharded.store(session)
+		locked.store(session)
+	}
+
+	// every subscribe and move looks its game up while games are created
+	// and removed around it, one in sixteen is a write
+	b.Run("sharded", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for index := 0; pb.Next(); index++ {
+				id := ids[index%len(ids)]
+				if index%16 == 0 {
+					sharded.store(&Session{id: id})
+				} else {
+					sharded.load(id)
+				}
+			}
+		})
+	})
+	b.Run("single lock", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for index := 0; pb.Next(); index++ {
+				id := ids[index%len(ids)]
+				if index%16 == 0 {
+					locked.store(&Session{id: id})
+				} else {
+					locked.load(id)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkConcurrentMoves(b *testing.B) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	ctx := context.Background()
+	move, err := board.DeserialiseMove("D1:C2")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			sessionId := server.NewSession(uuid.New(), uuid.New(), 0, time.Minute)
+			session, found := server.sessions.load(sessionId)
+			if !found {
+				b.Error("Expected the new session to be found")
+				return
+			}
+			err := session.handleMove(ctx, session.players[0], move)
+			if err != nil {
+				b.Error(err)
+			}
+			session.cleanup(ctx)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package game_server
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"chess/board"
+)
+
+// links can point at a position part way through a game with ?ply=, the
+// number of half moves played to reach it
+const plyQueryKey = "ply"
+
+//go:embed share.html
+var sharePage string
+
+var shareTemplate = template.Must(template.New("share").Parse(sharePage))
+
+// The ?ply= param, found is false when the latest position is wanted
+func parsePly(req *http.Request, moveCount int) (ply int, found bool, err error) {
+	str := req.URL.Query().Get(plyQueryKey)
+	if str == "" {
+		return moveCount, false, nil
+	}
+	ply, err = strconv.Atoi(str)
+	if err != nil || ply < 0 || ply > moveCount {
+		return 0, false, fmt.Errorf("ply must be between 0 and %d", moveCount)
+	}
+	return ply, true, nil
+}
+
+// Plays the first ply moves from the start, returns the last of them too
+func positionAtPly(
+	variantName string,
+	moves []string,
+	ply int,
+) (*board.BoardState, *board.Move, error) {
+	variant, err := board.VariantFromName(variantName)
+	if err != nil {
+		return nil, nil, err
+	}
+	state := board.NewVariantBoard(variant)
+	err = state.Init()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var last *board.Move
+	for _, str := range moves[:ply] {
+		move, err := board.DeserialiseMove(str)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = state.MakeMove(move)
+		if err != nil {
+			return nil, nil, err
+		}
+		last = &move
+	}
+	return state, last, nil
+}
+
+// The replay of a live or recently finished game
+func (server *GameServer) gameReplay(gameId uuid.UUID) (ReplayResponse, bool) {
+	session, found := server.sessions.load(gameId)
+
+	if found {
+		return session.replay(), true
+	}
+	if finished, cached := server.FinishedGame(gameId); cached {
+		return finished.Replay, true
+	}
+	return ReplayResponse{}, false
+}
+
+// Adds the position at the ply to a replay, thumbnail is the png of it
+// from the image route under prefix
+func withPly(replay ReplayResponse, ply int, prefix string) (ReplayResponse, error) {
+	state, _, err := positionAtPly(replay.Variant, replay.MoveHistory, ply)
+	if err != nil {
+		return replay, err
+	}
+	fen := state.Fen()
+	thumbnail := fmt.Sprintf("%s/%s/image?format=png&%s=%d", prefix, replay.Id, plyQueryKey, ply)
+	replay.Ply = &ply
+	replay.PlyFen = &fen
+	replay.Thumbnail = &thumbnail
+	return replay, nil
+}
+
+func shareDescription(replay ReplayResponse, ply int) string {
+	if ply == 0 {
+		return "The starting position"
+	}
+	moveNumber := (ply + 1) / 2
+	mover := "white"
+	if ply%2 == 0 {
+		mover = "black"
+	}
+	return fmt.Sprintf("Move %d, %s played %s", moveNumber, mover, replay.MoveHistory[ply-1])
+}
+
+// The scheme and host the request was made to, link previews need the
+// image as an absolute url
+func requestOrigin(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := req.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + req.Host
+}
+
+// A page for sharing a position, it carries the open graph tags link
+// previews read then sends people on to the game at the same ply
+func (server *GameServer) ShareHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	ctx := req.Context()
+	id := req.PathValue("id")
+	gameId, err := uuid.Parse(id)
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logError(ctx, err)
+		return
+	}
+
+	replay, found := server.gameReplay(gameId)
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		logError(ctx, errors.New("not found"))
+		return
+	}
+	ply, _, err := parsePly(req, len(replay.MoveHistory))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the image is mounted next to the share page, under the same prefix
+	prefix := strings.TrimSuffix(req.URL.Path, "/share/game/"+id)
+	gamePath := fmt.Sprintf("/game/%s?%s=%d", gameId, plyQueryKey, ply)
+	data := struct {
+		Title       string
+		Description string
+		ImageURL    string
+		GameURL     string
+	}{
+		Title:       "Chess game " + gameId.String(),
+		Description: shareDescription(replay, ply),
+		ImageURL: fmt.Sprintf("%s%s/game/%s/image?format=png&%s=%d",
+			requestOrigin(req), prefix, gameId, plyQueryKey, ply),
+		GameURL: gamePath,
+	}
+
+	writer.Header().Add("Content-Type", "text/html; charset=utf-8")
+	err = shareTemplate.Execute(writer, data)
+	if err != nil {
+		logError(ctx, err)
+	}
+}
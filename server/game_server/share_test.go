@@ -0,0 +1,93 @@
+package game_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+)
+
+func TestPlyLinks(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+	playMoves(t, session, []string{"D1:C2", "E8:F7", "F2:E4"})
+
+	req := httptest.NewRequest(http.MethodGet, "/replay/"+session.id.String()+"?ply=1", nil)
+	recorder := httptest.NewRecorder()
+	server.ServeMux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	replay := ReplayResponse{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := board.NewBoard()
+	err = expected.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	move, _ := board.DeserialiseMove("D1:C2")
+	err = expected.MakeMove(move)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Ply == nil || *replay.Ply != 1 {
+		t.Fatalf("Expected ply 1, got %v", replay.Ply)
+	}
+	if replay.PlyFen == nil || *replay.PlyFen != expected.Fen() {
+		t.Errorf("Expected fen %s, got %v", expected.Fen(), replay.PlyFen)
+	}
+	if replay.Fen == expected.Fen() {
+		t.Error("Expected the latest fen to still be the current position")
+	}
+	thumbnail := "/" + session.id.String() + "/image?format=png&ply=1"
+	if replay.Thumbnail == nil || *replay.Thumbnail != thumbnail {
+		t.Errorf("Expected thumbnail %s, got %v", thumbnail, replay.Thumbnail)
+	}
+
+	for _, query := range []string{"?ply=4", "?ply=-1", "?ply=a"} {
+		req := httptest.NewRequest(http.MethodGet, "/replay/"+session.id.String()+query, nil)
+		recorder := httptest.NewRecorder()
+		server.ServeMux.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, recorder.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/image?format=png&ply=0", nil)
+	req.SetPathValue("id", session.id.String())
+	recorder = httptest.NewRecorder()
+	server.ImageHandler(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	if !strings.Contains(recorder.Header().Get("Cache-Control"), "max-age=3600") {
+		t.Errorf("Expected earlier plies to be cached, got %s",
+			recorder.Header().Get("Cache-Control"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet,
+		"http://chess.test/api/share/game/"+session.id.String()+"?ply=2", nil)
+	req.SetPathValue("id", session.id.String())
+	recorder = httptest.NewRecorder()
+	server.ShareHandler(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	page := recorder.Body.String()
+	image := "http://chess.test/api/game/" + session.id.String() + "/image?format=png&amp;ply=2"
+	if !strings.Contains(page, `property="og:image" content="`+image+`"`) {
+		t.Errorf("Expected the og:image to be %s\n%s", image, page)
+	}
+	if !strings.Contains(page, "Move 1, black played E8:F7") {
+		t.Errorf("Expected the move to be described\n%s", page)
+	}
+}
@@ -0,0 +1,97 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// On shutdown new subscriptions are turned away and everyone connected is
+// told the server is going down. Games are snapshotted straight away in case
+// the process is killed, then given the drain period to finish before the
+// ones still going are snapshotted again to be resumed on the next start.
+// Connections are left to drop with the process, closing them would count
+// as the players abandoning their games
+
+const (
+	DefaultDrainPeriod = 30 * time.Second
+	drainPollInterval  = 250 * time.Millisecond
+)
+
+var errShuttingDown = errors.New("server is shutting down")
+
+func (server *GameServer) isDraining() bool {
+	server.drainingLock.Lock()
+	defer server.drainingLock.Unlock()
+	return server.draining
+}
+
+// Registered with the http server, runs once however many times it's called
+func (server *GameServer) OnShutdown() {
+	server.drainingLock.Lock()
+	alreadyDraining := server.draining
+	server.draining = true
+	server.drainingLock.Unlock()
+	if alreadyDraining {
+		return
+	}
+
+	ctx := context.Background()
+	text := errShuttingDown.Error()
+	sessions := server.allSessions()
+	for _, session := range sessions {
+		session.publish(ctx, nil, Event{Type: shutdown, Text: &text})
+	}
+	slog.InfoContext(ctx, "draining sessions", slog.Int("sessions", len(sessions)))
+}
+
+func (server *GameServer) gamesInProgress() int {
+	count := 0
+	for _, session := range server.allSessions() {
+		session.boardStateLock.Lock()
+		if !session.ended {
+			count += 1
+		}
+		session.boardStateLock.Unlock()
+	}
+	return count
+}
+
+// Waits for the games in progress to end until ctx is done, the games left
+// are saved to be resumed
+func (server *GameServer) Drain(ctx context.Context) error {
+	server.OnShutdown()
+	err := server.SaveLiveGames(ctx)
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+waiting:
+	for server.gamesInProgress() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			break waiting
+		}
+	}
+
+	remaining := server.gamesInProgress()
+	slog.InfoContext(ctx, "drained sessions", slog.Int("remaining", remaining))
+	if remaining == 0 {
+		return err
+	}
+	// ctx has run out by now
+	ctx = context.WithoutCancel(ctx)
+	err = errors.Join(err, server.SaveLiveGames(ctx))
+	// whichever node starts next can resume them
+	return errors.Join(err, server.releaseLeases(ctx))
+}
+
+func (server *GameServer) rejectWhileDraining(writer http.ResponseWriter) bool {
+	if !server.isDraining() {
+		return false
+	}
+	http.Error(writer, errShuttingDown.Error(), http.StatusServiceUnavailable)
+	return true
+}
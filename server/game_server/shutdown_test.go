@@ -0,0 +1,59 @@
+package game_server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+func TestDrainOnShutdown(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeLiveStore{games: make(map[uuid.UUID]model.LiveGame)}
+	server.SetLiveStore(store)
+
+	finishingSession := newTestSession(server, 0, time.Minute)
+	unfinishedSession := newTestSession(server, 0, time.Minute)
+	playMoves(t, unfinishedSession, []string{"D1:C2", "E8:F7", "F2:E4"})
+
+	server.OnShutdown()
+	nextEvent(t, unfinishedSession.players[0], shutdown)
+
+	req := httptest.NewRequest(http.MethodGet, "/subscribe/"+unfinishedSession.id.String(), nil)
+	recorder := httptest.NewRecorder()
+	server.ServeMux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected new subscriptions to be turned away, got %d", recorder.Code)
+	}
+
+	// one game ends during the drain, the other is still going when it runs out
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		finishingSession.handleWin(context.Background(),
+			board.WinResult(board.White, board.TerminationResignation))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	err := server.Drain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.lock.Lock()
+	_, unfinishedSaved := store.games[unfinishedSession.id]
+	_, finishedSaved := store.games[finishingSession.id]
+	store.lock.Unlock()
+	if !unfinishedSaved || finishedSaved {
+		t.Errorf("Expected only the unfinished game to be kept, unfinished: %t finished: %t",
+			unfinishedSaved, finishedSaved)
+	}
+
+	unfinishedSession.cleanup(context.Background())
+}
@@ -0,0 +1,161 @@
+package game_server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"chess/board"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+// The state of a game as it stands, for pages rendered on the server and
+// bots which poll rather than holding a websocket open
+type GameSnapshot struct {
+	Id          string   `json:"id"`
+	White       string   `json:"white"`
+	Black       string   `json:"black"`
+	Variant     string   `json:"variant"`
+	Fen         string   `json:"fen"`
+	MoveHistory []string `json:"moveHistory"`
+	// colour to move
+	Turn  string `json:"turn"`
+	Ended bool   `json:"ended"`
+	// remaining times in milliseconds, live games are read at the time of
+	// the request. Left out for archived games, their clocks aren't kept
+	WhiteTime *int32 `json:"whiteTime,omitempty"`
+	BlackTime *int32 `json:"blackTime,omitempty"`
+	// set once the game is over, result as in pgn e.g. "1-0"
+	Result      *string            `json:"result,omitempty"`
+	Outcome     *string            `json:"outcome,omitempty"`
+	Termination *board.Termination `json:"termination,omitempty"`
+	// set once the game is over, left out for games saved before summaries
+	// were kept
+	Summary *GameSummary `json:"summary,omitempty"`
+}
+
+// white moves first in every variant
+func turnAfter(moves int) string {
+	if moves%2 == 0 {
+		return serialiseColour(board.White)
+	}
+	return serialiseColour(board.Black)
+}
+
+func (session *Session) snapshot() GameSnapshot {
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+
+	whiteTime, blackTime := session.getClockState()
+	whiteTimeMs := int32(whiteTime.Milliseconds())
+	blackTimeMs := int32(blackTime.Milliseconds())
+	return GameSnapshot{
+		Id:          session.id.String(),
+		White:       session.players[0].userId.String(),
+		Black:       session.players[1].userId.String(),
+		Variant:     board.VariantId(session.boardState.Variant()),
+		Fen:         session.boardState.Fen(),
+		MoveHistory: moveList(session.boardState.PlayedMoves()),
+		Turn:        serialiseColour(session.boardState.WhoseMove()),
+		Ended:       session.ended,
+		WhiteTime:   &whiteTimeMs,
+		BlackTime:   &blackTimeMs,
+	}
+}
+
+func finishedSnapshot(id uuid.UUID, finished FinishedGame) GameSnapshot {
+	replay := finished.Replay
+	snapshot := GameSnapshot{
+		Id:          id.String(),
+		White:       finished.White.String(),
+		Black:       finished.Black.String(),
+		Variant:     replay.Variant,
+		Fen:         replay.Fen,
+		MoveHistory: replay.MoveHistory,
+		Turn:        turnAfter(len(replay.MoveHistory)),
+		Ended:       true,
+		Result:      &finished.Result,
+		Outcome:     &finished.Condition,
+		Termination: &finished.GameResult.Reason,
+		Summary:     &finished.Summary,
+	}
+	if len(replay.Clocks) > 0 {
+		last := replay.Clocks[len(replay.Clocks)-1]
+		snapshot.WhiteTime = &last.WhiteTime
+		snapshot.BlackTime = &last.BlackTime
+	}
+	return snapshot
+}
+
+func archivedSnapshot(game model.Game) GameSnapshot {
+	moves := strings.Fields(game.Moves)
+	termination := board.Termination(game.Termination)
+	return GameSnapshot{
+		Id:          game.ID.String(),
+		White:       game.WhiteID.String(),
+		Black:       game.BlackID.String(),
+		Variant:     game.Variant,
+		Fen:         game.FinalFen,
+		MoveHistory: moves,
+		Turn:        turnAfter(len(moves)),
+		Ended:       true,
+		Result:      &game.Result,
+		Outcome:     &game.Condition,
+		Termination: &termination,
+		Summary:     parseSummary(game.Summary),
+	}
+}
+
+// From the finished cache, then the live session, then the archive. The
+// cache comes first so games which just ended are sent with their result
+func (server *GameServer) gameSnapshot(ctx context.Context, gameId uuid.UUID) (GameSnapshot, error) {
+	if finished, cached := server.FinishedGame(gameId); cached {
+		return finishedSnapshot(gameId, finished), nil
+	}
+	session, live := server.sessions.load(gameId)
+	if live {
+		return session.snapshot(), nil
+	}
+
+	if server.archive == nil {
+		return GameSnapshot{}, errGameNotFound
+	}
+	game, err := server.archive.GetGameById(ctx, gameId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return GameSnapshot{}, errGameNotFound
+	} else if err != nil {
+		return GameSnapshot{}, err
+	}
+	return archivedSnapshot(game), nil
+}
+
+// Registered without a method as the other single segment routes would
+// clash with GET /{id}
+func (server *GameServer) GameSnapshotHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if req.Method != http.MethodGet {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	gameId, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	snapshot, err := server.gameSnapshot(ctx, gameId)
+	switch {
+	case errors.Is(err, errGameNotFound):
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+	writeJson(ctx, writer, snapshot)
+}
@@ -0,0 +1,56 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestGameSnapshot(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	playMoves(t, session, []string{"D1:C2"})
+
+	get := func(id string) (int, GameSnapshot) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		recorder := httptest.NewRecorder()
+		server.ServeMux.ServeHTTP(recorder, req)
+		snapshot := GameSnapshot{}
+		if recorder.Code == http.StatusOK {
+			err := json.Unmarshal(recorder.Body.Bytes(), &snapshot)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return recorder.Code, snapshot
+	}
+
+	code, snapshot := get(session.id.String())
+	if code != http.StatusOK || snapshot.Fen != session.boardState.Fen() ||
+		len(snapshot.MoveHistory) != 1 || snapshot.Turn != "b" || snapshot.Ended ||
+		snapshot.WhiteTime == nil || snapshot.Result != nil {
+		t.Errorf("Unexpected live snapshot %d %+v", code, snapshot)
+	}
+
+	session.handleWin(context.Background(), board.WinResult(board.White, board.TerminationResignation))
+	_, snapshot = get(session.id.String())
+	if !snapshot.Ended || *snapshot.Result != "1-0" || *snapshot.Termination != board.TerminationResignation {
+		t.Errorf("Unexpected finished snapshot %+v", snapshot)
+	}
+
+	if code, _ := get(uuid.NewString()); code != http.StatusNotFound {
+		t.Errorf("Expected an unknown game to be not found, got %d", code)
+	}
+
+	session.cleanup(context.Background())
+}
@@ -0,0 +1,52 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"chess/auth"
+
+	"github.com/google/uuid"
+)
+
+// Players and viewers are told how many viewers are watching when they
+// connect and again whenever a viewer joins or leaves
+
+func (session *Session) spectatorCount() int {
+	session.subscriberLock.Lock()
+	defer session.subscriberLock.Unlock()
+	return session.viewers.Len()
+}
+
+func spectatorsEvent(count int) Event {
+	return Event{Type: spectatorsChanged, Spectators: &count}
+}
+
+// sub is left out, it's already been sent the count some other way
+func (session *Session) publishSpectators(ctx context.Context, sub *subscriber) {
+	session.publish(ctx, sub, spectatorsEvent(session.spectatorCount()))
+}
+
+// Viewers don't need to sign in, anyone without a session cookie watches
+// under this id. It never matches a player so they only get read-only viewer
+// seats
+var anonymousViewerId = uuid.Nil
+
+// Requests with a session cookie are authenticated as usual, a cookie for a
+// session which doesn't exist fails rather than falling back to watching
+func (server *GameServer) subscriberId(
+	ctx context.Context,
+	writer http.ResponseWriter,
+	req *http.Request,
+) (uuid.UUID, error) {
+	_, err := req.Cookie(auth.CookieKeySession)
+	if errors.Is(err, http.ErrNoCookie) {
+		return anonymousViewerId, nil
+	}
+	authSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return authSession.UserID, nil
+}
@@ -0,0 +1,121 @@
+package game_server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestSpectatorCount(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	ctx := context.Background()
+	session.subscriberLock.Lock()
+	viewer, colour := session.getSubscriber(ctx, uuid.New())
+	session.subscriberLock.Unlock()
+	if colour != board.None {
+		t.Fatalf("Expected a viewer, got %s", board.ColourString(colour))
+	}
+
+	viewerEvent, otherEvent := session.CreateConnectEvent(board.None, PreConnected)
+	if viewerEvent.Spectators == nil || *viewerEvent.Spectators != 1 ||
+		otherEvent.Spectators == nil || *otherEvent.Spectators != 1 {
+		t.Errorf("Expected one spectator, got %+v %+v", viewerEvent, otherEvent)
+	}
+	playerEvent, _ := session.CreateConnectEvent(board.White, PreConnected)
+	if playerEvent.Spectators == nil || *playerEvent.Spectators != 1 {
+		t.Errorf("Expected players to be told about the spectator, got %+v", playerEvent)
+	}
+
+	session.DeleteSubscriber(ctx, viewer)
+	for _, player := range session.players {
+		select {
+		case event := <-player.events:
+			if event.Type != spectatorsChanged || *event.Spectators != 0 {
+				t.Errorf("Expected spectator count of 0, got %+v", event)
+			}
+		default:
+			t.Error("Expected players to be told the viewer left")
+		}
+	}
+
+	// already removed so nothing is sent
+	session.DeleteSubscriber(ctx, viewer)
+	select {
+	case event := <-session.players[0].events:
+		t.Errorf("Expected no event, got %+v", event)
+	default:
+	}
+
+	session.cleanup(ctx)
+}
+
+// signed out, every session cookie is rejected by the gate in ServeHTTP
+type signedOutAuth struct {
+	auth.MockAuthServer
+}
+
+func (*signedOutAuth) IsAuthenticated(
+	ctx context.Context,
+	writer http.ResponseWriter,
+	req *http.Request,
+) (bool, error) {
+	return false, nil
+}
+
+func TestAnonymousViewer(t *testing.T) {
+	server := NewGameServer(&signedOutAuth{})
+	whiteCookie, white := mockUser()
+	sessionId := server.NewSession(white, uuid.New(), 0, time.Minute)
+	fixture := newSocketFixture(t, server, sessionId)
+
+	res, err := http.Get(fixture.root + "/my-turn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected other routes to need auth, got %d", res.StatusCode)
+	}
+
+	viewerConn, event := fixture.dial(nil)
+	if event.Type != connectViewer {
+		t.Fatalf("Expected a viewer connect event, got %+v", event)
+	}
+
+	session, _ := server.sessions.load(sessionId)
+	if session.spectatorCount() != 1 {
+		t.Errorf("Expected the anonymous viewer to be counted, got %d", session.spectatorCount())
+	}
+	session.subscriberLock.Lock()
+	whiteState := session.players[0].state
+	session.subscriberLock.Unlock()
+	if whiteState != PreConnected {
+		t.Error("Expected the anonymous viewer not to take a seat")
+	}
+
+	// the player's seat is still theirs
+	whiteConn, event := fixture.dial(whiteCookie)
+	if event.Type != connect || event.Colour == nil ||
+		*event.Colour != serialiseColour(board.White) {
+		t.Errorf("Expected the player to connect as white, got %+v", event)
+	}
+
+	// anonymous viewers can only watch
+	text := "hello"
+	fixture.send(viewerConn, Event{Type: chat, Text: &text})
+	event = fixture.readUntil(viewerConn, errorEvent)
+	if event.Code == nil || *event.Code != notAllowed {
+		t.Errorf("Expected anonymous chat to be rejected, got %+v", event)
+	}
+	fixture.expectNone(whiteConn, chat)
+	fixture.send(viewerConn, Event{Type: resync})
+	fixture.readUntil(viewerConn, resync)
+}
@@ -0,0 +1,97 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"chess/board"
+	"chess/model"
+	"chess/pgn"
+
+	"github.com/google/uuid"
+)
+
+// finished games are saved through this, games are only kept in memory
+// when it isn't set
+type GameStore interface {
+	CreateGame(ctx context.Context, arg model.CreateGameParams) error
+}
+
+func (server *GameServer) SetStore(store GameStore) {
+	server.store = store
+}
+
+// saved games are read back through this once they've left the finished
+// cache, only the cache is used when it isn't set
+type GameArchive interface {
+	GetGameById(ctx context.Context, id uuid.UUID) (model.Game, error)
+}
+
+func (server *GameServer) SetArchive(archive GameArchive) {
+	server.archive = archive
+}
+
+// Caches the finished game and saves it, boardStateLock should be held.
+// The write itself happens in the background, the game's summary is
+// returned to be sent out
+func (session *Session) saveImpl(ctx context.Context, result board.GameResult) GameSummary {
+	win := result.WinState()
+	summary := session.summaryImpl()
+	condition := board.WinStateToString(win)
+	replay := session.replayImpl()
+	session.server.finished.put(session.id, FinishedGame{
+		Replay:     replay,
+		White:      session.players[0].userId,
+		Black:      session.players[1].userId,
+		Result:     pgn.ResultFromWinState(win),
+		Condition:  condition,
+		GameResult: result,
+		Summary:    summary,
+		Telemetry:  session.telemetry.clone(),
+	})
+	session.forgetLiveGame(ctx)
+
+	store := session.server.store
+	if store == nil {
+		return summary
+	}
+	encodedSummary, err := json.Marshal(summary)
+	if err != nil {
+		session.logError(ctx, err)
+	}
+	telemetry, err := json.Marshal(session.telemetry)
+	if err != nil {
+		session.logError(ctx, err)
+	}
+
+	params := model.CreateGameParams{
+		ID:          session.id,
+		WhiteID:     session.players[0].userId,
+		BlackID:     session.players[1].userId,
+		GameLength:  session.gameLength.Milliseconds(),
+		Increment:   session.increment.Milliseconds(),
+		Result:      pgn.ResultFromWinState(win),
+		Condition:   condition,
+		Moves:       strings.Join(moveList(session.boardState.PlayedMoves()), " "),
+		CreatedAt:   session.createdAt.UTC(),
+		EndedAt:     time.Now().UTC(),
+		Variant:     board.VariantId(session.boardState.Variant()),
+		Termination: string(result.Reason),
+		FinalFen:    session.boardState.Fen(),
+		Summary:     string(encodedSummary),
+		Telemetry:   string(telemetry),
+	}
+
+	go func() {
+		err := store.CreateGame(context.WithoutCancel(ctx), params)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to save game",
+				slog.String("gameId", params.ID.String()),
+				slog.Any("error", err))
+		}
+	}()
+	return summary
+}
@@ -0,0 +1,71 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+type fakeStore struct {
+	games chan model.CreateGameParams
+}
+
+func (store *fakeStore) CreateGame(ctx context.Context, arg model.CreateGameParams) error {
+	store.games <- arg
+	return nil
+}
+
+func TestFinishedGameSaved(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeStore{games: make(chan model.CreateGameParams, 1)}
+	server.SetStore(store)
+	session := newTestSession(server, 0, 5*time.Second)
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7"})
+	session.handleWin(context.Background(), board.WinResult(board.Black, board.TerminationAbandonment))
+
+	select {
+	case game := <-store.games:
+		if game.ID != session.id || game.Result != "0-1" || game.Moves != "D1:C2 E8:F7" ||
+			game.Termination != "abandonment" || game.FinalFen != session.boardState.Fen() {
+			t.Errorf("Unexpected saved game %+v", game)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected finished game to be saved")
+	}
+
+	session.cleanup(context.Background())
+}
+
+func TestChess960GameSaved(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeStore{games: make(chan model.CreateGameParams, 1)}
+	server.SetStore(store)
+	variant, err := board.Chess960Setup(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := server.NewVariantSession(uuid.New(), uuid.New(), 0, 5*time.Second, variant)
+
+	session, _ := server.sessions.load(sessionId)
+
+	playMoves(t, session, []string{"E2:E4", "E7:E5"})
+	session.handleWin(context.Background(), board.WinResult(board.Black, board.TerminationCheckmate))
+
+	select {
+	case game := <-store.games:
+		if game.Variant != "chess960:100" || game.Moves != "E2:E4 E7:E5" {
+			t.Errorf("Unexpected saved game %+v", game)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected finished game to be saved")
+	}
+
+	session.cleanup(context.Background())
+}
@@ -0,0 +1,78 @@
+package game_server
+
+import (
+	"encoding/json"
+
+	"chess/board"
+	"chess/eval"
+)
+
+// Sent to everyone once a game ends, after the end event, and saved with
+// the game. Aborted games don't get one
+
+type PlayerSummary struct {
+	// centipawns, the value of the player's pieces left on the board
+	Material int `json:"material"`
+	// milliseconds each of the player's moves took off their clock, zero
+	// until the clocks start
+	MoveTimes []int32 `json:"moveTimes"`
+	TotalTime int32   `json:"totalTime"`
+	// not set until finished games are analysed
+	AverageCentipawnLoss *int `json:"averageCentipawnLoss,omitempty"`
+}
+
+type GameSummary struct {
+	// white's material less black's
+	MaterialBalance int           `json:"materialBalance"`
+	White           PlayerSummary `json:"white"`
+	Black           PlayerSummary `json:"black"`
+}
+
+// boardStateLock should be held
+func (session *Session) summaryImpl() GameSummary {
+	white := PlayerSummary{MoveTimes: make([]int32, 0)}
+	black := PlayerSummary{MoveTimes: make([]int32, 0)}
+
+	for _, piece := range session.boardState.State {
+		if piece.IsClear() {
+			continue
+		}
+		if piece.Colour() == board.White {
+			white.Material += eval.PieceValue(piece.PieceType())
+		} else {
+			black.Material += eval.PieceValue(piece.PieceType())
+		}
+	}
+
+	// white moves first in every variant
+	for index, clock := range session.clockHistory {
+		player := &white
+		if index%2 == 1 {
+			player = &black
+		}
+		player.MoveTimes = append(player.MoveTimes, clock.Spent)
+		player.TotalTime += clock.Spent
+	}
+
+	return GameSummary{
+		MaterialBalance: white.Material - black.Material,
+		White:           white,
+		Black:           black,
+	}
+}
+
+func summaryEvent(summary GameSummary) Event {
+	return Event{Type: summaryType, Summary: &summary}
+}
+
+// Summaries are saved as json, games saved before they were kept have none
+func parseSummary(stored string) *GameSummary {
+	if stored == "" {
+		return nil
+	}
+	summary := GameSummary{}
+	if json.Unmarshal([]byte(stored), &summary) != nil {
+		return nil
+	}
+	return &summary
+}
@@ -0,0 +1,49 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/model"
+)
+
+func TestGameSummary(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeStore{games: make(chan model.CreateGameParams, 1)}
+	server.SetStore(store)
+	session := newTestSession(server, 0, time.Minute)
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7", "F2:E4"})
+	session.handleWin(context.Background(), board.WinResult(board.White, board.TerminationResignation))
+
+	black := session.players[1]
+	nextEvent(t, black, end)
+	event := nextEvent(t, black, summaryType)
+	summary := event.Summary
+	if summary == nil || len(summary.White.MoveTimes) != 2 || len(summary.Black.MoveTimes) != 1 {
+		t.Fatalf("Expected each player's move times, got %+v", summary)
+	}
+	if summary.MaterialBalance != summary.White.Material-summary.Black.Material ||
+		summary.White.Material == 0 {
+		t.Errorf("Unexpected material %+v", summary)
+	}
+
+	select {
+	case game := <-store.games:
+		saved := parseSummary(game.Summary)
+		if saved == nil || saved.MaterialBalance != summary.MaterialBalance {
+			t.Errorf("Expected the summary to be saved with the game, got %q", game.Summary)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected finished game to be saved")
+	}
+	finished, _ := server.FinishedGame(session.id)
+	snapshot := finishedSnapshot(session.id, finished)
+	if snapshot.Summary == nil || len(snapshot.Summary.White.MoveTimes) != 2 {
+		t.Errorf("Expected the summary with the finished game, got %+v", snapshot.Summary)
+	}
+	session.cleanup(context.Background())
+}
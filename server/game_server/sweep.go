@@ -0,0 +1,131 @@
+package game_server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"chess/board"
+)
+
+// Sessions are normally removed a few seconds after they end, but a game
+// nobody connects to or one left without a clock running can sit in the
+// map forever. The sweeper is a backstop for those, games with a timer
+// armed are left alone as they'll end on their own
+
+const SweepInterval = time.Minute
+
+type SessionTTLs struct {
+	// neither player has ever connected
+	Unconnected time.Duration
+	// nobody is connected and there's no clock running, measured from the
+	// last move
+	Idle time.Duration
+	// over but never removed, e.g. viewers still hanging around
+	Ended time.Duration
+}
+
+var DefaultSessionTTLs = SessionTTLs{
+	Unconnected: 10 * time.Minute,
+	Idle:        time.Hour,
+	Ended:       10 * time.Minute,
+}
+
+// Zero durations keep the default
+func (server *GameServer) SetSessionTTLs(ttls SessionTTLs) {
+	if ttls.Unconnected > 0 {
+		server.sessionTTLs.Unconnected = ttls.Unconnected
+	}
+	if ttls.Idle > 0 {
+		server.sessionTTLs.Idle = ttls.Idle
+	}
+	if ttls.Ended > 0 {
+		server.sessionTTLs.Ended = ttls.Ended
+	}
+}
+
+type sweepAction uint8
+
+const (
+	keepSession sweepAction = iota
+	abortSession
+	removeSession
+)
+
+// whether either player has ever connected and whether one is now
+func (session *Session) playerConnections() (everConnected bool, connected bool) {
+	session.subscriberLock.Lock()
+	defer session.subscriberLock.Unlock()
+	for _, player := range session.players {
+		if player == nil {
+			continue
+		}
+		if player.state != PreConnected {
+			everConnected = true
+		}
+		if player.state == Connected {
+			connected = true
+		}
+	}
+	return everConnected, connected
+}
+
+// boardStateLock and clockLock should be held
+func (session *Session) sweepActionImpl(
+	now time.Time,
+	ttls SessionTTLs,
+	everConnected bool,
+	connected bool,
+) (sweepAction, string) {
+	if session.ended {
+		if now.Sub(session.updatedAt) > ttls.Ended {
+			return removeSession, ""
+		}
+		return keepSession, ""
+	}
+	if !everConnected && now.Sub(session.createdAt) > ttls.Unconnected {
+		return abortSession, "neither player connected"
+	}
+	// a paused clock is a vacation, the ledger ends those
+	clockRunning := session.clockColour != board.None || session.paused
+	if !connected && !clockRunning && now.Sub(session.updatedAt) > ttls.Idle {
+		return abortSession, "idle with nobody connected"
+	}
+	return keepSession, ""
+}
+
+func (session *Session) sweep(ctx context.Context, now time.Time, ttls SessionTTLs) sweepAction {
+	everConnected, connected := session.playerConnections()
+
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+	session.clockLock.Lock()
+	defer session.clockLock.Unlock()
+
+	action, reason := session.sweepActionImpl(now, ttls, everConnected, connected)
+	if action == abortSession {
+		session.stopClockImpl()
+		session.abortImpl(ctx, session.boardState.WhoseMove(), reason)
+	}
+	return action
+}
+
+// Run by the scheduler every SweepInterval
+func (server *GameServer) SweepSessions(ctx context.Context) error {
+	now := time.Now()
+	aborted, removed := 0, 0
+	for _, session := range server.allSessions() {
+		switch session.sweep(ctx, now, server.sessionTTLs) {
+		case abortSession:
+			aborted += 1
+		case removeSession:
+			server.RemoveSession(ctx, session.id)
+			removed += 1
+		}
+	}
+	if aborted > 0 || removed > 0 {
+		slog.InfoContext(ctx, "swept stale sessions",
+			slog.Int("aborted", aborted), slog.Int("removed", removed))
+	}
+	return nil
+}
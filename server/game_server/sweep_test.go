@@ -0,0 +1,74 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestSweepSessions(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	ttls := DefaultSessionTTLs
+	getSession := func(sessionId uuid.UUID) *Session {
+		session, _ := server.sessions.load(sessionId)
+		return session
+	}
+	disconnect := func(session *Session) {
+		session.subscriberLock.Lock()
+		for _, player := range session.players {
+			player.state = Disconnected
+		}
+		session.subscriberLock.Unlock()
+	}
+
+	unconnected := newTestSession(server, 0, time.Hour)
+	if action := unconnected.sweep(context.Background(), time.Now(), ttls); action != keepSession {
+		t.Fatalf("Expected a new session to be kept, got %d", action)
+	}
+	later := time.Now().Add(ttls.Unconnected + time.Minute)
+	if action := unconnected.sweep(context.Background(), later, ttls); action != abortSession {
+		t.Fatalf("Expected a session nobody connected to to be aborted, got %d", action)
+	}
+	event := nextEvent(t, unconnected.players[0], abort)
+	if *event.Outcome != "abort" || *event.Colour != "w" {
+		t.Errorf("Unexpected abort event %+v", event)
+	}
+
+	// no clock runs between black's first move and white's second
+	idle := newTestSession(server, 0, time.Hour)
+	playMoves(t, idle, []string{"D1:C2", "E8:F7"})
+	disconnect(idle)
+	// a running clock ends the game by itself
+	running := newTestSession(server, 0, time.Hour)
+	playMoves(t, running, []string{"D1:C2", "E8:F7", "F2:E4"})
+	disconnect(running)
+
+	later = time.Now().Add(ttls.Idle + time.Minute)
+	if action := running.sweep(context.Background(), later, ttls); action != keepSession {
+		t.Errorf("Expected a game with a clock running to be kept, got %d", action)
+	}
+	if action := idle.sweep(context.Background(), later, ttls); action != abortSession {
+		t.Errorf("Expected an idle game to be aborted, got %d", action)
+	}
+
+	ended := newTestSession(server, 0, time.Hour)
+	ended.handleWin(context.Background(), board.WinResult(board.White, board.TerminationResignation))
+	server.SetSessionTTLs(SessionTTLs{Ended: time.Nanosecond})
+	err := server.SweepSessions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getSession(ended.id) != nil {
+		t.Error("Expected the ended session to be removed")
+	}
+	if getSession(running.id) == nil {
+		t.Error("Expected the running session to be kept")
+	}
+
+	running.cleanup(context.Background())
+}
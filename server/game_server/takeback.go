@@ -0,0 +1,117 @@
+package game_server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chess/board"
+)
+
+// Takebacks are for casual games, a player asks to take back the last move
+// and it's undone once the opponent accepts. The request only stands until
+// another move is played
+
+// The colour whose request can still be accepted, None when there isn't
+// one. boardStateLock should be held
+func (session *Session) takebackPendingImpl() board.Colour {
+	if len(session.boardState.MoveHistory) != session.takebackPly {
+		return board.None
+	}
+	return session.takebackFrom
+}
+
+func (sub *subscriber) handleTakebackRequest(ctx context.Context) {
+	if sub.colour != board.White && sub.colour != board.Black {
+		sub.sendError(ctx, notYourTurn, errors.New("only players can ask for a takeback"))
+		return
+	}
+
+	session := sub.session
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+
+	switch {
+	case session.rated:
+		sub.sendError(ctx, notAllowed, errors.New("takebacks aren't allowed in rated games"))
+		return
+	case session.ended:
+		sub.sendError(ctx, gameOver, errors.New("takeback requested after game end"))
+		return
+	case len(session.boardState.MoveHistory) == 0:
+		sub.sendError(ctx, notAllowed, errors.New("no move to take back"))
+		return
+	case session.takebackPendingImpl() != board.None:
+		return
+	}
+
+	session.takebackFrom = sub.colour
+	session.takebackPly = len(session.boardState.MoveHistory)
+	colour := serialiseColour(sub.colour)
+	session.publish(ctx, sub, Event{Type: takebackRequest, Colour: &colour})
+}
+
+func (sub *subscriber) handleTakebackAccept(ctx context.Context) {
+	if sub.colour != board.White && sub.colour != board.Black {
+		sub.sendError(ctx, notYourTurn, errors.New("only players can accept a takeback"))
+		return
+	}
+
+	session := sub.session
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+
+	if session.ended || session.takebackPendingImpl() != board.OppositeColour(sub.colour) {
+		sub.sendError(ctx, offerUnavailable, errors.New("no takeback to accept"))
+		return
+	}
+	session.takebackImpl(ctx)
+}
+
+// Undoes the last move and puts the clocks back to how they were before it,
+// boardStateLock should be held
+func (session *Session) takebackImpl(ctx context.Context) {
+	played := session.boardState.MoveHistory[len(session.boardState.MoveHistory)-1].Move
+	err := session.boardState.UnmakeMove()
+	if err != nil {
+		session.handleError(ctx, err)
+		return
+	}
+	session.takebackFrom = board.None
+	session.recordAudit(moveTakenBack, "takeback accepted", &played)
+	session.turnChanged()
+	// the taken back move's telemetry is kept, the player thinks afresh
+	session.turnStartedAt = time.Now()
+
+	whiteTime, blackTime := session.gameLength, session.gameLength
+	if count := len(session.clockHistory); count > 0 {
+		session.clockHistory = session.clockHistory[:count-1]
+	}
+	if count := len(session.clockHistory); count > 0 {
+		last := session.clockHistory[count-1]
+		whiteTime = time.Duration(last.WhiteTime) * time.Millisecond
+		blackTime = time.Duration(last.BlackTime) * time.Millisecond
+	}
+
+	session.clockLock.Lock()
+	session.stopClockImpl()
+	session.whiteTime = whiteTime
+	session.blackTime = blackTime
+	session.updatedAt = time.Now()
+	session.armClockImpl(ctx)
+	session.clockLock.Unlock()
+
+	fen := session.boardState.Fen()
+	moveHistory := moveList(session.boardState.PlayedMoves())
+	legalMoves := board.SerialiseMoveList(session.boardState.LegalMoves)
+	whiteTimeMs := int32(whiteTime.Milliseconds())
+	blackTimeMs := int32(blackTime.Milliseconds())
+	session.publish(ctx, nil, Event{
+		Type:        takebackAccept,
+		Fen:         &fen,
+		MoveHistory: &moveHistory,
+		LegalMoves:  &legalMoves,
+		WhiteTime:   &whiteTimeMs,
+		BlackTime:   &blackTimeMs,
+	})
+}
@@ -0,0 +1,62 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+)
+
+func TestTakeback(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	white, black := session.players[0], session.players[1]
+	ctx := context.Background()
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7"})
+	fen := session.boardState.Fen()
+	playMoves(t, session, []string{"F2:E4"})
+
+	white.handleTakebackRequest(ctx)
+	request := nextEvent(t, black, takebackRequest)
+	if *request.Colour != "w" {
+		t.Errorf("Unexpected takeback request %+v", request)
+	}
+
+	// only the opponent can accept
+	white.handleTakebackAccept(ctx)
+	nextEvent(t, white, errorEvent)
+
+	black.handleTakebackAccept(ctx)
+	for _, sub := range session.players {
+		event := nextEvent(t, sub, takebackAccept)
+		if *event.Fen != fen || len(*event.MoveHistory) != 2 ||
+			*event.WhiteTime != 5000 || *event.BlackTime != 5000 {
+			t.Errorf("Unexpected takeback event %+v", event)
+		}
+	}
+	session.clockLock.Lock()
+	clockStopped := session.clockTimer == nil
+	session.clockLock.Unlock()
+	if session.boardState.Fen() != fen || len(session.clockHistory) != 2 || !clockStopped {
+		t.Errorf("Expected the last move to be undone, got %s", session.boardState.Fen())
+	}
+
+	black.handleTakebackAccept(ctx)
+	nextEvent(t, black, errorEvent)
+
+	// a request lapses once another move is played
+	black.handleTakebackRequest(ctx)
+	nextEvent(t, white, takebackRequest)
+	playMoves(t, session, []string{"F2:E4"})
+	white.handleTakebackAccept(ctx)
+	nextEvent(t, white, errorEvent)
+
+	session.rated = true
+	white.handleTakebackRequest(ctx)
+	nextEvent(t, white, errorEvent)
+
+	session.cleanup(ctx)
+}
@@ -0,0 +1,161 @@
+package game_server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"chess/board"
+	"chess/protocol"
+
+	"github.com/google/uuid"
+)
+
+// How long each move took, the player's lag at the time and what they
+// connected with, saved with the game so fair-play checks have history to
+// look over once they're written. Admins can read it in the meantime.
+// Games resumed after a restart only have what was collected since
+
+type MoveTelemetry struct {
+	Ply int `json:"ply"`
+	// milliseconds from the player's turn starting to their move arriving,
+	// unlike the clock this counts before the clocks start
+	ThinkTime int64 `json:"thinkTime"`
+	// the player's ping round trip in milliseconds, zero before the first
+	// pong
+	RoundTrip int64 `json:"roundTrip"`
+}
+
+// one for each time the player connects
+type ClientTelemetry struct {
+	ConnectedAt time.Time        `json:"connectedAt"`
+	UserAgent   string           `json:"userAgent"`
+	Version     protocol.Version `json:"version"`
+	Msgpack     bool             `json:"msgpack"`
+	MoveFormat  string           `json:"moveFormat"`
+}
+
+type PlayerTelemetry struct {
+	Moves   []MoveTelemetry   `json:"moves"`
+	Clients []ClientTelemetry `json:"clients"`
+}
+
+type GameTelemetry struct {
+	White PlayerTelemetry `json:"white"`
+	Black PlayerTelemetry `json:"black"`
+}
+
+func newGameTelemetry() GameTelemetry {
+	return GameTelemetry{
+		White: PlayerTelemetry{Moves: make([]MoveTelemetry, 0), Clients: make([]ClientTelemetry, 0)},
+		Black: PlayerTelemetry{Moves: make([]MoveTelemetry, 0), Clients: make([]ClientTelemetry, 0)},
+	}
+}
+
+func (telemetry *GameTelemetry) player(colour board.Colour) *PlayerTelemetry {
+	if colour == board.White {
+		return &telemetry.White
+	}
+	return &telemetry.Black
+}
+
+func (telemetry GameTelemetry) clone() GameTelemetry {
+	telemetry.White.Moves = slices.Clone(telemetry.White.Moves)
+	telemetry.White.Clients = slices.Clone(telemetry.White.Clients)
+	telemetry.Black.Moves = slices.Clone(telemetry.Black.Moves)
+	telemetry.Black.Clients = slices.Clone(telemetry.Black.Clients)
+	return telemetry
+}
+
+// Called once the move has been made, boardStateLock should be held
+func (session *Session) recordMoveImpl(sub *subscriber) {
+	now := time.Now()
+	roundTrip, _ := sub.lag.latency()
+	player := session.telemetry.player(sub.colour)
+	player.Moves = append(player.Moves, MoveTelemetry{
+		Ply:       len(session.boardState.MoveHistory),
+		ThinkTime: now.Sub(session.turnStartedAt).Milliseconds(),
+		RoundTrip: roundTrip.Milliseconds(),
+	})
+	session.turnStartedAt = now
+}
+
+func (session *Session) recordClient(sub *subscriber, userAgent string) {
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+	player := session.telemetry.player(sub.colour)
+	player.Clients = append(player.Clients, ClientTelemetry{
+		ConnectedAt: time.Now(),
+		UserAgent:   userAgent,
+		Version:     sub.version,
+		Msgpack:     sub.binary,
+		MoveFormat:  sub.moveFormat.String(),
+	})
+}
+
+// Telemetry is saved as json, games saved before it was kept have none
+func parseTelemetry(stored string) (GameTelemetry, bool) {
+	if stored == "" {
+		return GameTelemetry{}, false
+	}
+	telemetry := GameTelemetry{}
+	if json.Unmarshal([]byte(stored), &telemetry) != nil {
+		return GameTelemetry{}, false
+	}
+	return telemetry, true
+}
+
+// From the live session, then the finished cache, then the archive
+func (server *GameServer) gameTelemetry(ctx context.Context, gameId uuid.UUID) (GameTelemetry, error) {
+	if session, live := server.sessions.load(gameId); live {
+		session.boardStateLock.Lock()
+		defer session.boardStateLock.Unlock()
+		return session.telemetry.clone(), nil
+	}
+	if finished, cached := server.FinishedGame(gameId); cached {
+		return finished.Telemetry, nil
+	}
+
+	if server.archive == nil {
+		return GameTelemetry{}, errGameNotFound
+	}
+	game, err := server.archive.GetGameById(ctx, gameId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return GameTelemetry{}, errGameNotFound
+	} else if err != nil {
+		return GameTelemetry{}, err
+	}
+	telemetry, found := parseTelemetry(game.Telemetry)
+	if !found {
+		return GameTelemetry{}, errGameNotFound
+	}
+	return telemetry, nil
+}
+
+func (server *GameServer) AdminTelemetryHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if !server.requireAdmin(writer, req) {
+		return
+	}
+	gameId, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	telemetry, err := server.gameTelemetry(ctx, gameId)
+	switch {
+	case errors.Is(err, errGameNotFound):
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(ctx, err)
+		return
+	}
+	writeJson(ctx, writer, telemetry)
+}
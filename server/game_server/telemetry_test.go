@@ -0,0 +1,56 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/model"
+	"chess/protocol"
+)
+
+func TestMoveTelemetry(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	store := &fakeStore{games: make(chan model.CreateGameParams, 1)}
+	server.SetStore(store)
+	session := newTestSession(server, 0, time.Minute)
+	white := session.players[0]
+	white.lag.record(80 * time.Millisecond)
+	white.version = protocol.Current
+	session.recordClient(white, "test-agent")
+
+	playMoves(t, session, []string{"D1:C2", "E8:F7", "F2:E4"})
+	telemetry, err := server.gameTelemetry(context.Background(), session.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(telemetry.White.Moves) != 2 || len(telemetry.Black.Moves) != 1 {
+		t.Fatalf("Expected a record for each move, got %+v", telemetry)
+	}
+	if move := telemetry.White.Moves[1]; move.Ply != 3 || move.RoundTrip != 80 {
+		t.Errorf("Expected white's second move at ply 3 with their lag, got %+v", move)
+	}
+	if len(telemetry.White.Clients) != 1 || telemetry.White.Clients[0].UserAgent != "test-agent" ||
+		telemetry.White.Clients[0].Version != protocol.Current {
+		t.Errorf("Expected white's client to be recorded, got %+v", telemetry.White.Clients)
+	}
+
+	session.handleWin(context.Background(), board.WinResult(board.White, board.TerminationResignation))
+	select {
+	case game := <-store.games:
+		saved, found := parseTelemetry(game.Telemetry)
+		if !found || len(saved.White.Moves) != 2 {
+			t.Errorf("Expected the telemetry to be saved with the game, got %q", game.Telemetry)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected finished game to be saved")
+	}
+	session.cleanup(context.Background())
+
+	finished, err := server.gameTelemetry(context.Background(), session.id)
+	if err != nil || len(finished.Black.Moves) != 1 {
+		t.Errorf("Expected the finished game's telemetry, got %+v %v", finished, err)
+	}
+}
@@ -0,0 +1,171 @@
+package game_server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"chess/board"
+	"chess/pgn"
+	"chess/utility"
+
+	"github.com/google/uuid"
+)
+
+// Users can play several games at once, mostly correspondence games which
+// sit waiting on a move for days. Each user's unfinished games are indexed
+// so matchmaking can check how many they already have without going
+// through every session
+
+// Most unfinished games a user can have of each kind, negative for no limit
+type GameLimits struct {
+	Live           int `json:"live"`
+	Correspondence int `json:"correspondence"`
+}
+
+var DefaultGameLimits = GameLimits{Live: 3, Correspondence: 50}
+
+var errTooManyGames = errors.New("too many games in progress")
+
+func (server *GameServer) SetGameLimits(limits GameLimits) {
+	server.gameLimits = limits
+}
+
+type userGames struct {
+	lock  sync.Mutex
+	games map[uuid.UUID]utility.Set[*Session]
+}
+
+func newUserGames() *userGames {
+	return &userGames{games: make(map[uuid.UUID]utility.Set[*Session])}
+}
+
+func (index *userGames) add(session *Session) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	for _, player := range session.players {
+		games, found := index.games[player.userId]
+		if !found {
+			games = utility.NewSet[*Session]()
+			index.games[player.userId] = games
+		}
+		games.Add(session)
+	}
+}
+
+// never blocks on a session so it's safe to call with session locks held
+func (index *userGames) remove(session *Session) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	for _, player := range session.players {
+		games := index.games[player.userId]
+		games.Remove(session)
+		if games.Len() == 0 {
+			delete(index.games, player.userId)
+		}
+	}
+}
+
+func (index *userGames) get(userId uuid.UUID) []*Session {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	return slices.Collect(index.games[userId].Keys())
+}
+
+// counts the user's live and correspondence games
+func (index *userGames) count(userId uuid.UUID) (live int, correspondence int) {
+	for _, session := range index.get(userId) {
+		if session.isCorrespondence() {
+			correspondence++
+		} else {
+			live++
+		}
+	}
+	return live, correspondence
+}
+
+// Whether the user can start another game of gameLength, called by the
+// matchmaking server before a user joins a queue and again when they're
+// paired
+func (server *GameServer) CanStartGame(userId uuid.UUID, gameLength time.Duration) error {
+	live, correspondence := server.userGames.count(userId)
+	limit, count := server.gameLimits.Live, live
+	if gameLength >= correspondenceMinLength {
+		limit, count = server.gameLimits.Correspondence, correspondence
+	}
+	if limit >= 0 && count >= limit {
+		return errTooManyGames
+	}
+	return nil
+}
+
+type MyGame struct {
+	Id       string `json:"id"`
+	Opponent string `json:"opponent"`
+	Colour   string `json:"colour"`
+	Fen      string `json:"fen"`
+	Variant  string `json:"variant"`
+	// e.g. 300+2, see pgn.TimeControl
+	TimeControl string    `json:"timeControl"`
+	MyTurn      bool      `json:"myTurn"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type MyGamesResponse struct {
+	Games  []MyGame   `json:"games"`
+	Limits GameLimits `json:"limits"`
+}
+
+func (session *Session) myGame(userId uuid.UUID) (MyGame, bool) {
+	session.boardStateLock.Lock()
+	defer session.boardStateLock.Unlock()
+	if session.ended {
+		return MyGame{}, false
+	}
+
+	colour, opponent := board.White, session.players[1]
+	if session.players[1].userId == userId {
+		colour, opponent = board.Black, session.players[0]
+	}
+	return MyGame{
+		Id:          session.id.String(),
+		Opponent:    opponent.userId.String(),
+		Colour:      serialiseColour(colour),
+		Fen:         session.boardState.Fen(),
+		Variant:     board.VariantId(session.boardState.Variant()),
+		TimeControl: pgn.TimeControl(session.gameLength, session.increment),
+		MyTurn:      session.boardState.WhoseMove() == colour,
+		CreatedAt:   session.createdAt,
+	}, true
+}
+
+// The user's unfinished games, oldest first
+func (server *GameServer) myGames(userId uuid.UUID) []MyGame {
+	games := make([]MyGame, 0)
+	for _, session := range server.userGames.get(userId) {
+		game, active := session.myGame(userId)
+		if active {
+			games = append(games, game)
+		}
+	}
+	slices.SortFunc(games, func(a, b MyGame) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return games
+}
+
+func (server *GameServer) MyGamesHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	authSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	writeJson(ctx, writer, MyGamesResponse{
+		Games:  server.myGames(authSession.UserID),
+		Limits: server.gameLimits,
+	})
+}
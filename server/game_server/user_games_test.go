@@ -0,0 +1,72 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestMyGames(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	server.SetGameLimits(GameLimits{Live: 2, Correspondence: -1})
+	cookie, userId := mockUser()
+	ctx := context.Background()
+
+	first := server.NewSession(userId, uuid.New(), 0, 5*time.Minute)
+	server.NewSession(uuid.New(), userId, 0, time.Minute)
+	server.NewSession(userId, uuid.New(), 0, 3*day)
+	server.NewSession(uuid.New(), uuid.New(), 0, time.Minute)
+
+	getGames := func() MyGamesResponse {
+		req := httptest.NewRequest(http.MethodGet, "/my-games", nil)
+		req.AddCookie(cookie)
+		recorder := httptest.NewRecorder()
+		server.ServeMux.ServeHTTP(recorder, req)
+
+		response := MyGamesResponse{}
+		err := json.Unmarshal(recorder.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	response := getGames()
+	if len(response.Games) != 3 {
+		t.Fatalf("Expected 3 games, got %d", len(response.Games))
+	}
+	if response.Games[0].Id != first.String() {
+		t.Error("Expected games to be sorted oldest first")
+	}
+	if !response.Games[0].MyTurn || response.Games[1].MyTurn || response.Games[1].Colour != "b" {
+		t.Errorf("Unexpected games %+v", response.Games)
+	}
+	if response.Limits.Live != 2 {
+		t.Errorf("Expected the limits to be sent, got %+v", response.Limits)
+	}
+
+	if err := server.CanStartGame(userId, time.Minute); err != errTooManyGames {
+		t.Errorf("Expected a third live game to be refused, got %v", err)
+	}
+	if err := server.CanStartGame(userId, 3*day); err != nil {
+		t.Errorf("Expected correspondence games to be unlimited, got %v", err)
+	}
+
+	// finished games stop counting straight away
+	firstSession, _ := server.sessions.load(first)
+	firstSession.handleWin(ctx, board.WinResult(board.Black, board.TerminationResignation))
+	if len(getGames().Games) != 2 {
+		t.Error("Expected the finished game to be dropped")
+	}
+	if err := server.CanStartGame(userId, time.Minute); err != nil {
+		t.Errorf("Expected another live game to be allowed, got %v", err)
+	}
+}
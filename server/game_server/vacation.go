@@ -0,0 +1,355 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+// Correspondence games are long enough to be played over days. Players get
+// vacation time which pauses their clocks in them, it can be started through
+// /vacation or is started automatically when a clock is about to run out
+const (
+	day                     = 24 * time.Hour
+	correspondenceMinLength = day
+
+	startingVacation = 7 * day
+	maxVacation      = 30 * day
+	// a day of vacation for every 12 days, roughly a month a year
+	vacationAccrualRatio = 12
+
+	// how long before a clock runs out vacation is started for the player
+	autoVacationMargin = time.Hour
+	autoVacationLength = day
+	minAutoVacation    = time.Hour
+)
+
+var (
+	errOnVacation         = errors.New("already on vacation")
+	errNotOnVacation      = errors.New("not on vacation")
+	errNotEnoughVacation  = errors.New("not enough vacation left")
+	errInvalidVacationLen = errors.New("vacation length must be positive")
+)
+
+func (session *Session) isCorrespondence() bool {
+	return session.gameLength >= correspondenceMinLength
+}
+
+type vacationAccount struct {
+	balance   time.Duration
+	accruedAt time.Time
+	// zero when not on vacation
+	until time.Time
+	timer *time.Timer
+}
+
+func (account *vacationAccount) accrue(now time.Time) {
+	earned := now.Sub(account.accruedAt) / vacationAccrualRatio
+	account.balance = min(account.balance+earned, maxVacation)
+	account.accruedAt = now
+}
+
+func (account *vacationAccount) onVacation(now time.Time) bool {
+	return account.until.After(now)
+}
+
+type vacationLedger struct {
+	lock     sync.Mutex
+	accounts map[uuid.UUID]*vacationAccount
+}
+
+func newVacationLedger() *vacationLedger {
+	return &vacationLedger{accounts: make(map[uuid.UUID]*vacationAccount)}
+}
+
+// Doesn't lock
+func (ledger *vacationLedger) accountImpl(userId uuid.UUID, now time.Time) *vacationAccount {
+	account, found := ledger.accounts[userId]
+	if !found {
+		account = &vacationAccount{balance: startingVacation, accruedAt: now}
+		ledger.accounts[userId] = account
+	}
+	account.accrue(now)
+	return account
+}
+
+// never blocks on session locks so it's safe to call with them held
+func (ledger *vacationLedger) onVacation(userId uuid.UUID) bool {
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	account, found := ledger.accounts[userId]
+	return found && account.onVacation(time.Now())
+}
+
+// takes the whole length from the balance up front, end refunds what's left
+func (ledger *vacationLedger) start(
+	userId uuid.UUID,
+	length time.Duration,
+	onEnd func(),
+) (time.Time, error) {
+	if length <= 0 {
+		return time.Time{}, errInvalidVacationLen
+	}
+
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	now := time.Now()
+	account := ledger.accountImpl(userId, now)
+	if account.onVacation(now) {
+		return time.Time{}, errOnVacation
+	}
+	if length > account.balance {
+		return time.Time{}, errNotEnoughVacation
+	}
+
+	account.balance -= length
+	account.until = now.Add(length)
+	account.timer = time.AfterFunc(length, onEnd)
+	return account.until, nil
+}
+
+func (ledger *vacationLedger) end(userId uuid.UUID) error {
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	now := time.Now()
+	account := ledger.accountImpl(userId, now)
+	if !account.onVacation(now) {
+		return errNotOnVacation
+	}
+
+	account.timer.Stop()
+	account.balance = min(account.balance+account.until.Sub(now), maxVacation)
+	account.until = time.Time{}
+	return nil
+}
+
+type VacationStatus struct {
+	RemainingDays float64    `json:"remainingDays"`
+	Until         *time.Time `json:"until,omitempty"` // set while on vacation
+}
+
+func (ledger *vacationLedger) status(userId uuid.UUID) VacationStatus {
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	now := time.Now()
+	account := ledger.accountImpl(userId, now)
+	status := VacationStatus{RemainingDays: account.balance.Hours() / 24}
+	if account.onVacation(now) {
+		until := account.until
+		status.Until = &until
+	}
+	return status
+}
+
+func (server *GameServer) StartVacation(
+	ctx context.Context,
+	userId uuid.UUID,
+	length time.Duration,
+) error {
+	ctx = context.WithoutCancel(ctx)
+	until, err := server.vacations.start(userId, length, func() {
+		server.resumeClocks(ctx, userId)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "vacation started",
+		slog.String("userId", userId.String()),
+		slog.Time("until", until))
+	server.pauseClocks(ctx, userId, until)
+	return nil
+}
+
+func (server *GameServer) EndVacation(ctx context.Context, userId uuid.UUID) error {
+	err := server.vacations.end(userId)
+	if err != nil {
+		return err
+	}
+	server.resumeClocks(context.WithoutCancel(ctx), userId)
+	return nil
+}
+
+// the user's correspondence games, with the colour they play
+func (server *GameServer) correspondenceGames(userId uuid.UUID) map[*Session]board.Colour {
+	games := make(map[*Session]board.Colour)
+	for _, session := range server.allSessions() {
+		if !session.isCorrespondence() {
+			continue
+		}
+		for i, player := range session.players {
+			if player.userId == userId {
+				games[session] = board.Colour(i + 1)
+			}
+		}
+	}
+	return games
+}
+
+func (server *GameServer) pauseClocks(ctx context.Context, userId uuid.UUID, until time.Time) {
+	untilMs := until.UnixMilli()
+	for session, colour := range server.correspondenceGames(userId) {
+		session.boardStateLock.Lock()
+		session.clockLock.Lock()
+		if !session.ended {
+			// games where it's the opponent's move are paused once it
+			// becomes the user's, see startClockImpl
+			if session.clockRunningImpl(colour) {
+				session.chargeClockImpl()
+				session.stopClockImpl()
+				session.paused = true
+			}
+			session.publishVacationImpl(ctx, vacation, colour, &untilMs)
+		}
+		session.clockLock.Unlock()
+		session.boardStateLock.Unlock()
+	}
+}
+
+func (server *GameServer) resumeClocks(ctx context.Context, userId uuid.UUID) {
+	slog.InfoContext(ctx, "vacation ended", slog.String("userId", userId.String()))
+	for session, colour := range server.correspondenceGames(userId) {
+		session.boardStateLock.Lock()
+		session.clockLock.Lock()
+		if !session.ended {
+			if session.paused && session.boardState.WhoseMove() == colour {
+				session.updatedAt = time.Now()
+				session.startClockImpl(ctx, colour)
+			}
+			session.publishVacationImpl(ctx, vacationEnd, colour, nil)
+		}
+		session.clockLock.Unlock()
+		session.boardStateLock.Unlock()
+	}
+}
+
+// Doesn't lock, the clock starts after both players have made a move
+func (session *Session) clockRunningImpl(colour board.Colour) bool {
+	return !session.paused &&
+		session.boardState.MoveCounter > 1 &&
+		session.boardState.WhoseMove() == colour
+}
+
+// Doesn't lock, takes the time used so far off the running clock without
+// adding the increment
+func (session *Session) chargeClockImpl() {
+	whiteTime, blackTime := session.getClockStateImpl()
+	session.whiteTime = whiteTime
+	session.blackTime = blackTime
+	session.updatedAt = time.Now()
+}
+
+func (session *Session) publishVacationImpl(
+	ctx context.Context,
+	eventType eventType,
+	colour board.Colour,
+	until *int64,
+) {
+	whiteTime, blackTime := session.getClockStateImpl()
+	whiteTimeMs := int32(whiteTime.Milliseconds())
+	blackTimeMs := int32(blackTime.Milliseconds())
+	colourStr := serialiseColour(colour)
+	session.publish(ctx, nil, Event{
+		Type:          eventType,
+		Colour:        &colourStr,
+		VacationUntil: until,
+		WhiteTime:     &whiteTimeMs,
+		BlackTime:     &blackTimeMs,
+	})
+}
+
+// Called shortly before a correspondence clock runs out, starts vacation for
+// the player if they have enough left and otherwise lets the clock run out
+func (session *Session) handleDeadline(ctx context.Context, colour board.Colour) {
+	userId := session.players[colour-1].userId
+	server := session.server
+
+	length := min(autoVacationLength, server.vacations.status(userId).remaining())
+	if length >= minAutoVacation {
+		err := server.StartVacation(ctx, userId, length)
+		if err == nil {
+			return
+		}
+		slog.WarnContext(ctx, "couldn't start vacation",
+			slog.String("userId", userId.String()),
+			slog.Any("error", err))
+	}
+
+	session.boardStateLock.Lock()
+	session.clockLock.Lock()
+	defer session.boardStateLock.Unlock()
+	defer session.clockLock.Unlock()
+	if session.ended || !session.clockRunningImpl(colour) {
+		return
+	}
+
+	whiteTime, blackTime := session.getClockStateImpl()
+	remaining := whiteTime
+	if colour == board.Black {
+		remaining = blackTime
+	}
+	session.stopClockImpl()
+	session.clockTimer = time.AfterFunc(remaining, func() {
+		session.handleTimeLoss(ctx, colour)
+	})
+	session.clockColour = colour
+}
+
+func (status VacationStatus) remaining() time.Duration {
+	return time.Duration(status.RemainingDays * float64(day))
+}
+
+type vacationRequest struct {
+	Days float64 `json:"days"`
+}
+
+// GET shows the user's vacation, POST {"days": n} starts it and DELETE ends
+// it early, refunding what's left
+func (server *GameServer) VacationHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	authSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	userId := authSession.UserID
+
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		body := vacationRequest{}
+		err = json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = server.StartVacation(ctx, userId, time.Duration(body.Days*float64(day)))
+	case http.MethodDelete:
+		err = server.EndVacation(ctx, userId)
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case errors.Is(err, errInvalidVacationLen), errors.Is(err, errNotEnoughVacation):
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errOnVacation), errors.Is(err, errNotOnVacation):
+		http.Error(writer, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(ctx, writer, server.vacations.status(userId))
+}
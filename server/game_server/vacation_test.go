@@ -0,0 +1,152 @@
+package game_server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+// plays the first legal move until the clock of the side to move is running
+func startCorrespondenceClock(t *testing.T, session *Session) {
+	t.Helper()
+	for session.boardState.MoveCounter < 3 {
+		move := session.boardState.LegalMoves[0]
+		playMoves(t, session, []string{move.Serialise()})
+	}
+}
+
+func vacationRequestTo(t *testing.T, server *GameServer, cookie *http.Cookie, method, body string) (int, VacationStatus) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/vacation", strings.NewReader(body))
+	req.AddCookie(cookie)
+	recorder := httptest.NewRecorder()
+	server.ServeMux.ServeHTTP(recorder, req)
+
+	status := VacationStatus{}
+	if recorder.Code == http.StatusOK {
+		err := json.Unmarshal(recorder.Body.Bytes(), &status)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return recorder.Code, status
+}
+
+func TestVacationPausesClock(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	cookie, userId := mockUser()
+
+	// the user plays black so it's their clock running after three moves
+	sessionId := server.NewSession(uuid.New(), userId, 0, 3*day)
+	session, _ := server.sessions.load(sessionId)
+	startCorrespondenceClock(t, session)
+
+	code, status := vacationRequestTo(t, server, cookie, http.MethodPost, `{"days": 2}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected vacation to start, got %d", code)
+	}
+	if status.Until == nil || status.RemainingDays < 5 || status.RemainingDays > 5.01 {
+		t.Errorf("Unexpected vacation status %+v", status)
+	}
+
+	event := nextEvent(t, session.players[0], vacation)
+	if *event.Colour != "b" || event.VacationUntil == nil {
+		t.Errorf("Unexpected vacation event %+v", event)
+	}
+
+	_, before := session.getClockState()
+	time.Sleep(20 * time.Millisecond)
+	_, after := session.getClockState()
+	if before != after {
+		t.Errorf("Expected the clock to be paused, went from %s to %s", before, after)
+	}
+
+	code, _ = vacationRequestTo(t, server, cookie, http.MethodPost, `{"days": 1}`)
+	if code != http.StatusConflict {
+		t.Errorf("Expected starting vacation twice to conflict, got %d", code)
+	}
+
+	code, status = vacationRequestTo(t, server, cookie, http.MethodDelete, "")
+	if code != http.StatusOK || status.Until != nil || status.RemainingDays < 6.99 {
+		t.Errorf("Expected unused vacation to be refunded, got %d %+v", code, status)
+	}
+	nextEvent(t, session.players[0], vacationEnd)
+
+	session.clockLock.Lock()
+	paused := session.paused
+	running := session.clockTimer != nil
+	session.clockLock.Unlock()
+	if paused || !running {
+		t.Error("Expected the clock to run again after vacation")
+	}
+
+	code, _ = vacationRequestTo(t, server, cookie, http.MethodPost, `{"days": 30}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("Expected too long a vacation to fail, got %d", code)
+	}
+
+	session.cleanup(context.Background())
+}
+
+func TestAutoVacation(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	userId := uuid.New()
+
+	sessionId := server.NewSession(uuid.New(), userId, 0, 3*day)
+	session, _ := server.sessions.load(sessionId)
+	startCorrespondenceClock(t, session)
+
+	session.handleDeadline(context.Background(), board.Black)
+	status := server.vacations.status(userId)
+	if status.Until == nil || status.RemainingDays < 6 || status.RemainingDays > 6.01 {
+		t.Errorf("Expected a day of vacation to be used, got %+v", status)
+	}
+	session.clockLock.Lock()
+	paused := session.paused
+	session.clockLock.Unlock()
+	if !paused {
+		t.Error("Expected the clock to be paused")
+	}
+
+	// with nothing left the clock is left to run out
+	err := server.EndVacation(context.Background(), userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.vacations.lock.Lock()
+	server.vacations.accounts[userId].balance = 0
+	server.vacations.lock.Unlock()
+
+	session.handleDeadline(context.Background(), board.Black)
+	session.clockLock.Lock()
+	paused = session.paused
+	running := session.clockTimer != nil
+	session.clockLock.Unlock()
+	if paused || !running {
+		t.Error("Expected the clock to keep running without vacation left")
+	}
+
+	session.cleanup(context.Background())
+}
+
+func TestVacationAccrual(t *testing.T) {
+	start := time.Now()
+	account := &vacationAccount{balance: 0, accruedAt: start}
+	account.accrue(start.Add(12 * day))
+	if account.balance != day {
+		t.Errorf("Expected a day to accrue, got %s", account.balance)
+	}
+	account.accrue(start.Add(1000 * day))
+	if account.balance != maxVacation {
+		t.Errorf("Expected the balance to be capped, got %s", account.balance)
+	}
+}
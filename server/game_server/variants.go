@@ -0,0 +1,43 @@
+package game_server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"chess/board"
+)
+
+func writeVariantJson(writer http.ResponseWriter, req *http.Request, body any) {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		logError(req.Context(), err)
+		return
+	}
+	// the rules only change with a deploy
+	writer.Header().Add("Cache-Control", "public, max-age=3600")
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Write(bytes)
+}
+
+// The rules of every variant games can be played under
+func VariantsHandler(writer http.ResponseWriter, req *http.Request) {
+	variants := board.Variants()
+	rules := make([]board.VariantRules, len(variants))
+	for i, variant := range variants {
+		rules[i] = board.Rules(variant)
+	}
+	writeVariantJson(writer, req, rules)
+}
+
+// The rules of the variant in the path, by name e.g. /variants/standard
+func VariantHandler(writer http.ResponseWriter, req *http.Request) {
+	name := req.PathValue("name")
+	for _, variant := range board.Variants() {
+		if variant.Name() == name {
+			writeVariantJson(writer, req, board.Rules(variant))
+			return
+		}
+	}
+	http.Error(writer, "unknown variant", http.StatusNotFound)
+}
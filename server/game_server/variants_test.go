@@ -0,0 +1,47 @@
+package game_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chess/board"
+)
+
+func TestVariantsHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	VariantsHandler(recorder, httptest.NewRequest(http.MethodGet, "/variants", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	rules := make([]board.VariantRules, 0)
+	err := json.Unmarshal(recorder.Body.Bytes(), &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != len(board.Variants()) {
+		t.Fatalf("Expected %d variants, got %d", len(board.Variants()), len(rules))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/variants/standard", nil)
+	req.SetPathValue("name", "standard")
+	recorder = httptest.NewRecorder()
+	VariantHandler(recorder, req)
+	standard := board.VariantRules{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &standard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if standard.Name != "standard" || standard.MoveRuleLimit != 100 {
+		t.Errorf("Expected the standard rules, got %+v", standard)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/variants/nope", nil)
+	req.SetPathValue("name", "nope")
+	recorder = httptest.NewRecorder()
+	VariantHandler(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown variant, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package game_server
+
+import (
+	"math"
+	"sync"
+
+	"chess/board"
+	"chess/eval"
+)
+
+// probability white wins, logistic curve with 400cp giving 10:1 odds
+func winProbability(boardState *board.BoardState) float64 {
+	score := float64(eval.Evaluate(boardState))
+	return 1 / (1 + math.Pow(10, -score/400))
+}
+
+const winProbabilityCacheSize = 4096
+
+// shared between sessions so busy games with many viewers and common
+// positions are only evaluated once
+type winProbabilityCache struct {
+	lock  sync.Mutex
+	cache map[uint64]float64
+}
+
+var probabilityCache = winProbabilityCache{
+	cache: make(map[uint64]float64),
+}
+
+// keyed by position hash, the evaluation only looks at the pieces and the
+// side to move which is all the hash covers
+func (cache *winProbabilityCache) get(boardState *board.BoardState) float64 {
+	hash := boardState.Hash()
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if probability, found := cache.cache[hash]; found {
+		return probability
+	}
+	if len(cache.cache) >= winProbabilityCacheSize {
+		clear(cache.cache)
+	}
+	probability := winProbability(boardState)
+	cache.cache[hash] = probability
+	return probability
+}
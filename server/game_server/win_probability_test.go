@@ -0,0 +1,37 @@
+package game_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+func TestWinProbabilityOnlySentToViewers(t *testing.T) {
+	server := NewGameServer(&auth.MockAuthServer{})
+	session := newTestSession(server, 0, 5*time.Second)
+
+	viewer := NewSubscriber(uuid.New(), session, board.None)
+	session.viewers.Add(viewer)
+
+	playMoves(t, session, []string{"D1:C2"})
+
+	playerEvent := <-session.players[1].events
+	if playerEvent.WinProbability != nil {
+		t.Errorf("Expected players to not be sent win probability")
+	}
+	viewerEvent := <-viewer.events
+	if viewerEvent.WinProbability == nil {
+		t.Fatal("Expected viewers to be sent win probability")
+	}
+	// no captures yet so the position is close to level
+	if probability := *viewerEvent.WinProbability; probability < 0.4 || probability > 0.6 {
+		t.Errorf("Expected even position, got %f", probability)
+	}
+
+	session.cleanup(context.Background())
+}
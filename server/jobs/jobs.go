@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type Job func(ctx context.Context) error
+
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	run      Job
+}
+
+// Runs background jobs on a fixed interval until stopped
+type Scheduler struct {
+	lock   sync.Mutex
+	jobs   []scheduledJob
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Jobs added after Start won't run
+func (scheduler *Scheduler) Every(name string, interval time.Duration, job Job) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	scheduler.jobs = append(scheduler.jobs, scheduledJob{
+		name:     name,
+		interval: interval,
+		run:      job,
+	})
+}
+
+func (scheduler *Scheduler) Start(ctx context.Context) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	ctx, scheduler.cancel = context.WithCancel(ctx)
+	for _, job := range scheduler.jobs {
+		scheduler.wg.Add(1)
+		go func() {
+			defer scheduler.wg.Done()
+			job.loop(ctx)
+		}()
+	}
+}
+
+// Cancels running jobs and waits for them to return
+func (scheduler *Scheduler) Stop() {
+	scheduler.lock.Lock()
+	cancel := scheduler.cancel
+	scheduler.lock.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	scheduler.wg.Wait()
+}
+
+func (job scheduledJob) loop(ctx context.Context) {
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			err := job.run(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "job failed",
+					slog.String("job", job.name),
+					slog.Any("error", err))
+			} else {
+				slog.InfoContext(ctx, "job finished",
+					slog.String("job", job.name),
+					slog.Duration("took", time.Since(start)))
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	scheduler := NewScheduler()
+	var runs atomic.Int32
+	scheduler.Every("count", 10*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	scheduler.Start(context.Background())
+	time.Sleep(55 * time.Millisecond)
+	scheduler.Stop()
+
+	count := runs.Load()
+	if count < 2 {
+		t.Fatalf("Expected job to run several times, ran %d", count)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if runs.Load() != count {
+		t.Error("Expected job not to run after stop")
+	}
+}
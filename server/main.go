@@ -15,11 +15,21 @@ import (
 	"strings"
 	"time"
 
+	"chess/admin"
 	"chess/auth"
+	"chess/backup"
+	"chess/book"
+	"chess/engine"
 	"chess/env"
 	"chess/game_server"
+	"chess/jobs"
 	"chess/matchmaking_server"
 	"chess/model"
+	"chess/protocol"
+	"chess/redis"
+	"chess/schema"
+	"chess/status"
+	"chess/verify"
 
 	_ "github.com/mattn/go-sqlite3"
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
@@ -83,6 +93,9 @@ func (server *MiddlewareServer) ServeHTTP(writer http.ResponseWriter, req *http.
 func run() error {
 	ctx := context.Background()
 
+	errorCounter := status.NewErrorCounter(slog.NewTextHandler(os.Stderr, nil))
+	slog.SetDefault(slog.New(errorCounter))
+
 	environment, err := env.GetEnv()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[fatal-error] %s", err)
@@ -103,6 +116,88 @@ func run() error {
 	gameServer := game_server.NewGameServer(authServer)
 	matchmakingServer := matchmaking_server.NewMatchmakingServer(gameServer,
 		queries, authServer)
+	gameServer.SetMatchmaker(matchmakingServer)
+
+	compression := protocol.DefaultCompression
+	compression.Mode, err = protocol.ParseCompressionMode(environment.WebsocketCompression)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[fatal-error] %s", err)
+		os.Exit(1)
+	}
+	if environment.WebsocketCompressionThreshold > 0 {
+		compression.Threshold = environment.WebsocketCompressionThreshold
+	}
+	gameServer.SetCompression(compression)
+	matchmakingServer.SetCompression(compression)
+	gameServer.SetAuditRules(environment.AuditRules)
+	gameServer.SetSessionTTLs(game_server.SessionTTLs{
+		Unconnected: environment.UnconnectedSessionTTL,
+		Idle:        environment.IdleSessionTTL,
+		Ended:       environment.EndedSessionTTL,
+	})
+	gameLimits := game_server.DefaultGameLimits
+	if environment.MaxLiveGames != 0 {
+		gameLimits.Live = environment.MaxLiveGames
+	}
+	if environment.MaxCorrespondenceGames != 0 {
+		gameLimits.Correspondence = environment.MaxCorrespondenceGames
+	}
+	gameServer.SetGameLimits(gameLimits)
+	if environment.ClockBroadcastInterval != 0 {
+		gameServer.SetClockBroadcastInterval(environment.ClockBroadcastInterval)
+	}
+	gameServer.SetGracePeriods(game_server.GracePeriods{
+		Divisor: environment.GraceDivisor,
+		Min:     environment.GraceMin,
+		Max:     environment.GraceMax,
+	})
+	gameServer.SetStore(queries)
+	gameServer.SetArchive(queries)
+	gameServer.SetLiveStore(queries)
+	if environment.RedisUrl != "" {
+		broker, err := redis.Dial(ctx, environment.RedisUrl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[fatal-error] failed to connect to redis: %s", err)
+			os.Exit(1)
+		}
+		defer broker.Close()
+		err = gameServer.SetBroker(ctx, broker, environment.NodeId)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[fatal-error] failed to subscribe to redis: %s", err)
+			os.Exit(1)
+		}
+	}
+	resumed, err := gameServer.ResumeLiveGames(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[fatal-error] failed to resume live games: %s", err)
+		os.Exit(1)
+	}
+	log.Printf("resumed %d live games", resumed)
+	gameServer.SetTablebase(engine.InsufficientMaterial{})
+	if environment.OpeningBook != "" {
+		openingBook, err := book.Load(environment.OpeningBook)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[fatal-error] failed to load opening book %s: %s",
+				environment.OpeningBook, err)
+			os.Exit(1)
+		}
+		gameServer.SetOpeningBook(openingBook)
+	}
+	adminServer := admin.NewAdminServer(queries, authServer)
+	verifyServer := verify.NewVerifyServer(queries, engine.InsufficientMaterial{})
+	statusServer := status.NewStatusServer(gameServer, matchmakingServer, errorCounter)
+
+	scheduler := jobs.NewScheduler()
+	scheduler.Every("clock audit", game_server.ClockAuditInterval, gameServer.ClockAuditJob)
+	scheduler.Every("live games", game_server.LiveSnapshotInterval, gameServer.SaveLiveGames)
+	scheduler.Every("session sweep", game_server.SweepInterval, gameServer.SweepSessions)
+	scheduler.Every("session leases", game_server.LeaseInterval, gameServer.RenewLeases)
+	if environment.BackupDir != "" {
+		dbBackup := backup.New(db, environment.BackupDir, environment.BackupRetention)
+		adminServer.SetBackup(dbBackup)
+		scheduler.Every("backup", backup.Interval, dbBackup.Job)
+	}
+	scheduler.Start(ctx)
 
 	mux := http.NewServeMux()
 
@@ -146,16 +241,44 @@ func run() error {
 		})
 	}
 
-	gamePath := prefix + "/game"
-	matchPath := prefix + "/matchmaking"
-	authPath := prefix + "/auth"
+	// the unversioned routes are kept for clients on the previous version
+	for _, versionPrefix := range []string{"", protocol.APIPrefix} {
+		gamePath := prefix + versionPrefix + "/game"
+		matchPath := prefix + versionPrefix + "/matchmaking"
+		authPath := prefix + versionPrefix + "/auth"
+		adminPath := prefix + versionPrefix + "/admin"
+		verifyPath := prefix + versionPrefix + "/verify"
+		orgPath := prefix + versionPrefix + "/org"
+
+		mux.Handle(gamePath+"/",
+			http.StripPrefix(gamePath, gameServer))
+		// public so link previews can load it
+		mux.HandleFunc("GET "+gamePath+"/{id}/image", gameServer.ImageHandler)
+		mux.HandleFunc("GET "+gamePath+"/{id}/gif", gameServer.GifHandler)
+		mux.HandleFunc("GET "+gamePath+"/{id}/events", gameServer.RelayHandler)
+		mux.HandleFunc("GET "+prefix+versionPrefix+"/embed/game/{id}", gameServer.EmbedHandler)
+		mux.HandleFunc("GET "+prefix+versionPrefix+"/share/game/{id}", gameServer.ShareHandler)
+		mux.HandleFunc("GET "+prefix+versionPrefix+"/lobby", gameServer.LobbyHandler)
+		mux.HandleFunc("GET "+prefix+versionPrefix+"/variants", game_server.VariantsHandler)
+		mux.HandleFunc("GET "+prefix+versionPrefix+"/variants/{name}", game_server.VariantHandler)
+		mux.Handle(matchPath+"/",
+			http.StripPrefix(matchPath, matchmakingServer))
+		mux.Handle(authPath+"/",
+			http.StripPrefix(authPath, authServer))
+		mux.Handle(adminPath+"/",
+			http.StripPrefix(adminPath, adminServer))
+		mux.Handle(verifyPath+"/",
+			http.StripPrefix(verifyPath, verifyServer))
+		// routes for organisations, authenticated by api key rather than session
+		mux.Handle("GET "+orgPath+"/results", authServer.RequireScope(
+			auth.ScopeReadResults, http.HandlerFunc(adminServer.OrganizationExportHandler)))
+	}
 
-	mux.Handle(gamePath+"/",
-		http.StripPrefix(gamePath, gameServer))
-	mux.Handle(matchPath+"/",
-		http.StripPrefix(matchPath, matchmakingServer))
-	mux.Handle(authPath+"/",
-		http.StripPrefix(authPath, authServer))
+	schemaHandler := schema.Messages.Handler()
+	mux.HandleFunc(prefix+"/schema", schemaHandler)
+	mux.HandleFunc(prefix+protocol.APIPrefix+"/schema", schemaHandler)
+	mux.Handle(prefix+"/status", statusServer)
+	mux.Handle(prefix+protocol.APIPrefix+"/status", statusServer)
 
 	middlewareServer := MiddlewareServer{ServeMux: mux}
 
@@ -169,6 +292,7 @@ func run() error {
 
 	httpServer.RegisterOnShutdown(gameServer.OnShutdown)
 	httpServer.RegisterOnShutdown(matchmakingServer.OnShutdown)
+	httpServer.RegisterOnShutdown(scheduler.Stop)
 
 	errc := make(chan error, 1)
 	go func() {
@@ -185,8 +309,21 @@ func run() error {
 		log.Printf("terminating: %v", sig)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	drainPeriod := game_server.DefaultDrainPeriod
+	if environment.DrainPeriod > 0 {
+		drainPeriod = environment.DrainPeriod
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), drainPeriod)
 	defer cancel()
 
-	return httpServer.Shutdown(ctx)
+	err = httpServer.Shutdown(ctx)
+
+	// shutdown hooks aren't waited for and the websockets are hijacked so
+	// Shutdown doesn't wait for them either, the games in progress have to
+	// finish or be written before the process exits
+	drainErr := gameServer.Drain(ctx)
+	if drainErr != nil {
+		log.Printf("failed to save live games: %v", drainErr)
+	}
+	return err
 }
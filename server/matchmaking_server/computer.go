@@ -0,0 +1,79 @@
+package matchmaking_server
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+
+	"chess/board"
+	"chess/engine"
+)
+
+// Playing the computer skips the queue, the game is made straight away with
+// the built in engine in the other seat
+
+const colourQueryKey = "colour"
+
+// the colour the user wants to play, either when it's left out
+func getColour(req *http.Request) (board.Colour, error) {
+	switch req.URL.Query().Get(colourQueryKey) {
+	case "white":
+		return board.White, nil
+	case "black":
+		return board.Black, nil
+	case "", "either":
+		if rand.IntN(2) == 0 {
+			return board.White, nil
+		}
+		return board.Black, nil
+	default:
+		return board.None, errors.New(`colour should be "white", "black" or "either"`)
+	}
+}
+
+func (server *MatchmakingServer) ComputerHandler(
+	writer http.ResponseWriter, req *http.Request,
+) {
+	format, err := getFormat(req)
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	colour, err := getColour(req)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := req.Context()
+	userSession, err := server.authServer.GetUserSession(ctx, writer, req)
+	if err != nil {
+		return
+	}
+	err = server.gameServer.CanStartGame(userSession.UserID, format.GameLength)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if format.Variant == nil {
+		format.Variant = board.DefaultVariant
+	}
+	gameId := server.gameServer.NewVariantBotSession(
+		userSession.UserID,
+		board.OppositeColour(colour),
+		format.Increment,
+		format.GameLength,
+		format.gameVariant(),
+		engine.New(engine.Options{}),
+	)
+
+	slog.Info("computer game created",
+		slog.String("gameId", gameId.String()),
+		slog.String("player", userSession.UserID.String()),
+		slog.String("format", format.String()))
+
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Write(found(gameId.String()))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -12,8 +13,10 @@ import (
 	"time"
 
 	"chess/auth"
+	"chess/board"
 	"chess/game_server"
 	"chess/model"
+	"chess/protocol"
 
 	"github.com/coder/websocket"
 	"github.com/google/uuid"
@@ -23,17 +26,31 @@ import (
 type Format struct {
 	Increment  time.Duration
 	GameLength time.Duration
+	Variant    board.Variant
+}
+
+func (format Format) String() string {
+	str := fmt.Sprintf("%d+%d",
+		int(format.GameLength.Minutes()), int(format.Increment.Minutes()))
+	if format.Variant != nil && format.Variant != board.DefaultVariant {
+		str += ":" + board.VariantId(format.Variant)
+	}
+	return str
 }
 
 type Queue struct {
-	lock  sync.Mutex
-	queue []*Player
+	lock    sync.Mutex
+	queue   []*Player
+	format  Format
+	metrics *MatchmakingMetrics
 }
 
-func newQueue() *Queue {
+func newQueue(format Format, metrics *MatchmakingMetrics) *Queue {
 	return &Queue{
-		lock:  sync.Mutex{},
-		queue: make([]*Player, 0),
+		lock:    sync.Mutex{},
+		queue:   make([]*Player, 0),
+		format:  format,
+		metrics: metrics,
 	}
 }
 
@@ -52,7 +69,7 @@ func (queue *Queue) removePlayer(player *Player) error {
 	if index == -1 {
 		return errors.New("player was not found in queue")
 	}
-	queue.queue = slices.Delete(queue.queue, index, index)
+	queue.queue = slices.Delete(queue.queue, index, index+1)
 	return nil
 }
 
@@ -64,6 +81,9 @@ type MatchmakingServer struct {
 	queues     QueueMap
 	db         *model.Queries
 	authServer *auth.AuthServer
+	metrics    *MatchmakingMetrics
+	// for the queue sockets
+	compression protocol.Compression
 }
 
 type Player struct {
@@ -74,6 +94,10 @@ type Player struct {
 	closed      bool
 	doneChannel chan struct{}
 	queue       *Queue
+	joinedAt    time.Time
+	// set for players requeued from a finished game, they have no
+	// websocket of their own and are told about matches through this
+	notify func(gameId uuid.UUID)
 }
 
 func newPlayer(
@@ -87,6 +111,7 @@ func newPlayer(
 		closed:      false,
 		doneChannel: make(chan struct{}),
 		queue:       queue,
+		joinedAt:    time.Now(),
 	}
 }
 
@@ -97,25 +122,36 @@ func NewMatchmakingServer(
 ) *MatchmakingServer {
 	serveMux := http.NewServeMux()
 	server := &MatchmakingServer{
-		ServeMux:   serveMux,
-		queues:     make(QueueMap),
-		gameServer: gameServer,
-		db:         db,
-		authServer: authServer,
+		ServeMux:    serveMux,
+		queues:      make(QueueMap),
+		gameServer:  gameServer,
+		db:          db,
+		authServer:  authServer,
+		metrics:     newMatchmakingMetrics(),
+		compression: protocol.DefaultCompression,
 	}
 
 	serveMux.HandleFunc("/unranked", server.UnrankedHandler)
 	serveMux.HandleFunc("/unranked/subscribe", server.UnrankedQueueHandler)
+	serveMux.HandleFunc("/computer", server.ComputerHandler)
+	serveMux.HandleFunc("/metrics", server.MetricsHandler)
+
+	go server.metrics.initReporting(context.Background())
 
 	return server
 }
 
+func (server *MatchmakingServer) SetCompression(compression protocol.Compression) {
+	server.compression = compression
+}
+
 func (server *MatchmakingServer) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
 	server.ServeMux.ServeHTTP(writer, req)
 }
 
 func (server *MatchmakingServer) OnShutdown() {
 	// TODO
+	server.metrics.stop()
 }
 
 func logError(ctx context.Context, err error) {
@@ -136,13 +172,19 @@ func (player *Player) write(ctx context.Context, bytes []byte) error {
 
 const formatQueryKey = "format"
 
+// formats look like 5+3, the variant can follow a colon e.g. 5+3:chess960
 func getFormat(req *http.Request) (Format, error) {
 	format := req.URL.Query().Get(formatQueryKey)
 	if format == "" {
 		return Format{}, errors.New("no format found")
 	}
+	format, variantName, _ := strings.Cut(format, ":")
+	variant, err := board.VariantFromName(variantName)
+	if err != nil {
+		return Format{}, err
+	}
 	if format == "custom" {
-		return Format{}, nil
+		return Format{Variant: variant}, nil
 	}
 	before, after, found := strings.Cut(format, "+")
 	if !found {
@@ -160,14 +202,27 @@ func getFormat(req *http.Request) (Format, error) {
 	return Format{
 		GameLength: time.Minute * time.Duration(beforeNum),
 		Increment:  time.Minute * time.Duration(afterNum),
+		Variant:    variant,
 	}, nil
 }
 
+// chess960 games each get their own setup
+func (format Format) gameVariant() board.Variant {
+	if shuffled, ok := format.Variant.(board.Shuffled); ok {
+		return shuffled.Shuffle()
+	}
+	return format.Variant
+}
+
+// formats without a variant are played under the default one
 func (server *MatchmakingServer) getQueue(format *Format) *Queue {
+	if format.Variant == nil {
+		format.Variant = board.DefaultVariant
+	}
 	server.queueLock.Lock()
 	queue, found := server.queues[*format]
 	if !found {
-		queue = newQueue()
+		queue = newQueue(*format, server.metrics)
 		server.queues[*format] = queue
 	}
 	server.queueLock.Unlock()
@@ -199,6 +254,11 @@ func (server *MatchmakingServer) UnrankedHandler(
 	if err != nil {
 		return
 	}
+	err = server.gameServer.CanStartGame(userSession.UserID, format.GameLength)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusConflict)
+		return
+	}
 
 	queue := server.getQueue(&format)
 	queue.lock.Lock()
@@ -223,11 +283,12 @@ func (server *MatchmakingServer) UnrankedHandler(
 	player := queue.pop()
 	queue.lock.Unlock()
 
-	gameId := server.gameServer.NewSession(
+	gameId := server.gameServer.NewVariantSession(
 		player.id,
 		userSession.UserID,
 		format.Increment,
 		format.GameLength,
+		format.gameVariant(),
 	)
 
 	bytes := found(gameId.String())
@@ -236,8 +297,18 @@ func (server *MatchmakingServer) UnrankedHandler(
 		slog.String("queue player", player.id.String()),
 		slog.String("http player", userSession.UserID.String()))
 
-	err = player.write(ctx, bytes)
-	player.closeNow(ctx, err)
+	if player.notify != nil {
+		player.notify(gameId)
+	} else {
+		err = player.write(ctx, bytes)
+		player.closeNow(ctx, err)
+	}
+
+	if err != nil {
+		server.metrics.recordVoidMatch(format)
+	} else {
+		server.metrics.recordPair(format, player, userSession.UserID)
+	}
 
 	println("returning")
 	if err != nil {
@@ -288,10 +359,14 @@ func (server *MatchmakingServer) Subscribe(
 	if err != nil {
 		return err
 	}
+	err = server.gameServer.CanStartGame(userId, format.GameLength)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusConflict)
+		return err
+	}
 
 	// todo accept header
-	conn, err := websocket.Accept(writer, req,
-		&websocket.AcceptOptions{OriginPatterns: []string{"*"}})
+	conn, err := websocket.Accept(writer, req, server.compression.AcceptOptions())
 	if err != nil {
 		return err
 	}
@@ -336,14 +411,17 @@ func (player *Player) closeNow(ctx context.Context, err error) {
 		logError(ctx, err)
 	}
 
-	player.Conn.CloseNow()
+	if player.Conn != nil {
+		player.Conn.CloseNow()
+	}
 	player.queue.lock.Lock()
 	err = player.queue.removePlayer(player)
+	player.queue.lock.Unlock()
 	if err != nil {
 		slog.Error("removing_player", slog.Any("error", err))
 		return
 	}
-	player.queue.lock.Unlock()
+	player.queue.metrics.recordAbandoned(player.queue.format)
 }
 
 const (
@@ -0,0 +1,62 @@
+package matchmaking_server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"chess/board"
+)
+
+func TestGetFormat(t *testing.T) {
+	get := func(format string) (Format, error) {
+		req := httptest.NewRequest("GET", "/unranked?format="+url.QueryEscape(format), nil)
+		return getFormat(req)
+	}
+
+	format, err := get("5+3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.GameLength != 5*time.Minute || format.Increment != 3*time.Minute ||
+		format.Variant != board.DefaultVariant {
+		t.Errorf("Unexpected format %+v", format)
+	}
+
+	format, err = get("5+3:chess960")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.Variant != board.Chess960 || format.String() != "5+3:chess960" {
+		t.Errorf("Unexpected format %s", format)
+	}
+	variant := board.VariantId(format.gameVariant())
+	if variant == "chess960" {
+		t.Errorf("Expected chess960 games to get a setup, got %s", variant)
+	}
+
+	if _, err := get("5+3:checkers"); err == nil {
+		t.Error("Expected an unknown variant to fail")
+	}
+}
+
+func TestGetColour(t *testing.T) {
+	get := func(colour string) (board.Colour, error) {
+		req := httptest.NewRequest("GET", "/computer?format=5%2B3&colour="+colour, nil)
+		return getColour(req)
+	}
+
+	if colour, err := get("white"); err != nil || colour != board.White {
+		t.Errorf("Expected white, got %d %v", colour, err)
+	}
+	if colour, err := get("black"); err != nil || colour != board.Black {
+		t.Errorf("Expected black, got %d %v", colour, err)
+	}
+	if colour, err := get(""); err != nil || colour == board.None {
+		t.Errorf("Expected a colour to be picked, got %d %v", colour, err)
+	}
+	if _, err := get("green"); err == nil {
+		t.Error("Expected an unknown colour to fail")
+	}
+}
@@ -0,0 +1,213 @@
+package matchmaking_server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const metricsReportInterval = time.Minute
+
+// pairing the same players again after this long isn't counted as a rematch
+const rematchWindow = 30 * time.Minute
+
+type poolMetrics struct {
+	Pairs         int64 `json:"pairs"`
+	VoidMatches   int64 `json:"voidMatches"`
+	Abandoned     int64 `json:"abandoned"`
+	Rematches     int64 `json:"rematches"`
+	totalWait     time.Duration
+	AverageWaitMs int64   `json:"averageWaitMs"`
+	VoidMatchRate float64 `json:"voidMatchRate"`
+	RematchRate   float64 `json:"rematchRate"`
+}
+
+func (metrics *poolMetrics) summarise() poolMetrics {
+	ret := *metrics
+	if ret.Pairs > 0 {
+		ret.AverageWaitMs = (ret.totalWait / time.Duration(ret.Pairs)).Milliseconds()
+		ret.RematchRate = float64(ret.Rematches) / float64(ret.Pairs)
+	}
+	attempts := ret.Pairs + ret.VoidMatches
+	if attempts > 0 {
+		ret.VoidMatchRate = float64(ret.VoidMatches) / float64(attempts)
+	}
+	return ret
+}
+
+// collects pairing quality stats per pool to guide tuning of the queue
+type MatchmakingMetrics struct {
+	lock         sync.Mutex
+	pools        map[Format]*poolMetrics
+	lastOpponent map[uuid.UUID]lastPairing
+	doneChannel  chan struct{}
+}
+
+func newMatchmakingMetrics() *MatchmakingMetrics {
+	return &MatchmakingMetrics{
+		lock:         sync.Mutex{},
+		pools:        make(map[Format]*poolMetrics),
+		lastOpponent: make(map[uuid.UUID]lastPairing),
+		doneChannel:  make(chan struct{}),
+	}
+}
+
+// doesn't lock
+func (metrics *MatchmakingMetrics) getPool(format Format) *poolMetrics {
+	pool, found := metrics.pools[format]
+	if !found {
+		pool = &poolMetrics{}
+		metrics.pools[format] = pool
+	}
+	return pool
+}
+
+type lastPairing struct {
+	opponent uuid.UUID
+	at       time.Time
+}
+
+func (metrics *MatchmakingMetrics) recordPair(
+	format Format,
+	queued *Player,
+	other uuid.UUID,
+) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	pool := metrics.getPool(format)
+	pool.Pairs += 1
+	pool.totalWait += time.Since(queued.joinedAt)
+
+	now := time.Now()
+	last, found := metrics.lastOpponent[queued.id]
+	if found && last.opponent == other && now.Sub(last.at) < rematchWindow {
+		pool.Rematches += 1
+	}
+	metrics.lastOpponent[queued.id] = lastPairing{opponent: other, at: now}
+	metrics.lastOpponent[other] = lastPairing{opponent: queued.id, at: now}
+}
+
+// drops pairings too old to count as a rematch so the map doesn't grow with
+// every player who's ever been paired
+func (metrics *MatchmakingMetrics) forgetOpponents(now time.Time) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	for id, last := range metrics.lastOpponent {
+		if now.Sub(last.at) >= rematchWindow {
+			delete(metrics.lastOpponent, id)
+		}
+	}
+}
+
+// a match was found but the queued player could not be told about it
+func (metrics *MatchmakingMetrics) recordVoidMatch(format Format) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	metrics.getPool(format).VoidMatches += 1
+}
+
+// a player left the queue without being paired
+func (metrics *MatchmakingMetrics) recordAbandoned(format Format) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	metrics.getPool(format).Abandoned += 1
+}
+
+type PoolReport struct {
+	Format string `json:"format"`
+	poolMetrics
+}
+
+func (metrics *MatchmakingMetrics) Report() []PoolReport {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	ret := make([]PoolReport, 0, len(metrics.pools))
+	for format, pool := range metrics.pools {
+		ret = append(ret, PoolReport{
+			Format:      format.String(),
+			poolMetrics: pool.summarise(),
+		})
+	}
+	return ret
+}
+
+func (metrics *MatchmakingMetrics) logReport(ctx context.Context) {
+	for _, report := range metrics.Report() {
+		slog.InfoContext(ctx, "matchmaking pool report",
+			slog.String("format", report.Format),
+			slog.Int64("pairs", report.Pairs),
+			slog.Int64("averageWaitMs", report.AverageWaitMs),
+			slog.Float64("voidMatchRate", report.VoidMatchRate),
+			slog.Float64("rematchRate", report.RematchRate),
+			slog.Int64("abandoned", report.Abandoned),
+		)
+	}
+}
+
+func (metrics *MatchmakingMetrics) initReporting(ctx context.Context) {
+	ticker := time.NewTicker(metricsReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			metrics.logReport(ctx)
+			metrics.forgetOpponents(now)
+		case <-metrics.doneChannel:
+			metrics.logReport(ctx)
+			return
+		}
+	}
+}
+
+func (metrics *MatchmakingMetrics) stop() {
+	close(metrics.doneChannel)
+}
+
+// players waiting in each pool
+func (server *MatchmakingServer) QueueSizes() map[string]int {
+	server.queueLock.Lock()
+	queues := make([]*Queue, 0, len(server.queues))
+	for _, queue := range server.queues {
+		queues = append(queues, queue)
+	}
+	server.queueLock.Unlock()
+
+	ret := make(map[string]int, len(queues))
+	for _, queue := range queues {
+		queue.lock.Lock()
+		ret[queue.format.String()] = len(queue.queue)
+		queue.lock.Unlock()
+	}
+	return ret
+}
+
+func (server *MatchmakingServer) MetricsHandler(
+	writer http.ResponseWriter, req *http.Request,
+) {
+	isAdmin, err := server.authServer.IsAdmin(req.Context(), writer, req)
+	if err != nil {
+		return
+	}
+	if !isAdmin {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	bytes, err := json.Marshal(server.metrics.Report())
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Write(bytes)
+}
@@ -0,0 +1,30 @@
+package matchmaking_server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRematchesExpire(t *testing.T) {
+	metrics := newMatchmakingMetrics()
+	format := Format{GameLength: 5 * time.Minute}
+	player := &Player{id: uuid.New(), joinedAt: time.Now()}
+	other := uuid.New()
+
+	metrics.recordPair(format, player, other)
+	metrics.recordPair(format, player, other)
+	if rematches := metrics.pools[format].Rematches; rematches != 1 {
+		t.Fatalf("Expected 1 rematch, got %d", rematches)
+	}
+
+	metrics.forgetOpponents(time.Now().Add(rematchWindow))
+	if len(metrics.lastOpponent) != 0 {
+		t.Fatalf("Expected old pairings to be forgotten, %d left", len(metrics.lastOpponent))
+	}
+	metrics.recordPair(format, player, other)
+	if rematches := metrics.pools[format].Rematches; rematches != 1 {
+		t.Errorf("Expected a pairing after the window not to be a rematch, got %d", rematches)
+	}
+}
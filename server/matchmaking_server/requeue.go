@@ -0,0 +1,114 @@
+package matchmaking_server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"chess/board"
+
+	"github.com/google/uuid"
+)
+
+// Puts a player from a finished game back into the pool for the same format.
+// Shuffled variants are passed before a setup is picked, each game in the
+// pool gets its own. If someone is already waiting they are paired straight
+// away, notify is called before returning and cancel is nil. Otherwise the
+// player waits in the queue until notify is called or cancel is. Players
+// already at their limit of games in progress aren't queued
+func (server *MatchmakingServer) Requeue(
+	ctx context.Context,
+	userId uuid.UUID,
+	gameLength time.Duration,
+	increment time.Duration,
+	variant board.Variant,
+	notify func(gameId uuid.UUID),
+) (cancel func(), err error) {
+	err = server.gameServer.CanStartGame(userId, gameLength)
+	if err != nil {
+		return nil, err
+	}
+
+	format := Format{GameLength: gameLength, Increment: increment, Variant: variant}
+	queue := server.getQueue(&format)
+	queue.lock.Lock()
+
+	if len(queue.queue) == 0 || queue.queue[0].id == userId {
+		player := &Player{
+			id:       userId,
+			queue:    queue,
+			joinedAt: time.Now(),
+			notify:   notify,
+		}
+		queue.push(player)
+		queue.lock.Unlock()
+
+		slog.InfoContext(ctx, "player requeued",
+			slog.String("format", format.String()),
+			slog.String("id", userId.String()))
+
+		return func() { player.leave(ctx) }, nil
+	}
+
+	player := queue.pop()
+	queue.lock.Unlock()
+
+	if !server.pairRequeued(ctx, format, player, userId, notify) {
+		// the waiting player had gone, wait for the next one instead
+		return server.Requeue(ctx, userId, gameLength, increment, variant, notify)
+	}
+	return nil, nil
+}
+
+func (server *MatchmakingServer) pairRequeued(
+	ctx context.Context,
+	format Format,
+	player *Player,
+	userId uuid.UUID,
+	notify func(gameId uuid.UUID),
+) bool {
+
+	gameId := server.gameServer.NewVariantSession(
+		player.id,
+		userId,
+		format.Increment,
+		format.GameLength,
+		format.gameVariant(),
+	)
+
+	slog.InfoContext(ctx, "match found",
+		slog.String("queue player", player.id.String()),
+		slog.String("requeued player", userId.String()))
+
+	var err error
+	if player.notify != nil {
+		player.notify(gameId)
+	} else {
+		err = player.write(ctx, found(gameId.String()))
+		player.closeNow(ctx, err)
+	}
+
+	if err != nil {
+		server.metrics.recordVoidMatch(format)
+		return false
+	}
+	server.metrics.recordPair(format, player, userId)
+
+	notify(gameId)
+	return true
+}
+
+// removes a requeued player who gave up waiting
+func (player *Player) leave(ctx context.Context) {
+	player.queue.lock.Lock()
+	err := player.queue.removePlayer(player)
+	player.queue.lock.Unlock()
+	// already matched
+	if err != nil {
+		return
+	}
+
+	slog.InfoContext(ctx, "requeued player left",
+		slog.String("id", player.id.String()))
+	player.queue.metrics.recordAbandoned(player.queue.format)
+}
@@ -0,0 +1,136 @@
+package matchmaking_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess/auth"
+	"chess/board"
+	"chess/game_server"
+
+	"github.com/google/uuid"
+)
+
+func TestRequeuePairsPlayers(t *testing.T) {
+	gameServer := game_server.NewGameServer(&auth.MockAuthServer{})
+	server := NewMatchmakingServer(gameServer, nil, nil)
+	defer server.OnShutdown()
+
+	ctx := context.Background()
+	first := uuid.New()
+	second := uuid.New()
+
+	var firstGame, secondGame uuid.UUID
+	cancel, err := server.Requeue(ctx, first, 5*time.Minute, 0, board.DefaultVariant,
+		func(gameId uuid.UUID) { firstGame = gameId })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel == nil {
+		t.Fatal("expected first player to wait in the queue")
+	}
+
+	cancel, err = server.Requeue(ctx, second, 5*time.Minute, 0, board.DefaultVariant,
+		func(gameId uuid.UUID) { secondGame = gameId })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel != nil {
+		t.Fatal("expected second player to be paired immediately")
+	}
+	if firstGame == uuid.Nil || firstGame != secondGame {
+		t.Fatalf("expected both players in the same game, got %s and %s",
+			firstGame, secondGame)
+	}
+
+	reports := server.metrics.Report()
+	if len(reports) != 1 || reports[0].Pairs != 1 {
+		t.Fatalf("expected one pair recorded, got %+v", reports)
+	}
+}
+
+func TestRequeueCancel(t *testing.T) {
+	gameServer := game_server.NewGameServer(&auth.MockAuthServer{})
+	server := NewMatchmakingServer(gameServer, nil, nil)
+	defer server.OnShutdown()
+
+	ctx := context.Background()
+	cancel, err := server.Requeue(ctx, uuid.New(), 5*time.Minute, 0, board.DefaultVariant,
+		func(gameId uuid.UUID) { t.Fatal("cancelled player should not be paired") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	// cancelling twice is a no-op
+	cancel()
+
+	queue := server.getQueue(&Format{GameLength: 5 * time.Minute})
+	if len(queue.queue) != 0 {
+		t.Fatalf("expected empty queue, got %d players", len(queue.queue))
+	}
+}
+
+func TestRequeueAtGameLimit(t *testing.T) {
+	gameServer := game_server.NewGameServer(&auth.MockAuthServer{})
+	gameServer.SetGameLimits(game_server.GameLimits{Live: 1, Correspondence: 1})
+	server := NewMatchmakingServer(gameServer, nil, nil)
+	defer server.OnShutdown()
+
+	ctx := context.Background()
+	userId := uuid.New()
+	gameServer.NewSession(userId, uuid.New(), 0, 5*time.Minute)
+
+	_, err := server.Requeue(ctx, userId, 5*time.Minute, 0, board.DefaultVariant,
+		func(gameId uuid.UUID) { t.Fatal("player at their limit should not be paired") })
+	if err == nil {
+		t.Fatal("expected a player at their limit not to be queued")
+	}
+	queue := server.getQueue(&Format{GameLength: 5 * time.Minute})
+	if len(queue.queue) != 0 {
+		t.Fatalf("expected empty queue, got %d players", len(queue.queue))
+	}
+}
+
+func TestRequeueVariantPool(t *testing.T) {
+	gameServer := game_server.NewGameServer(&auth.MockAuthServer{})
+	server := NewMatchmakingServer(gameServer, nil, nil)
+	defer server.OnShutdown()
+
+	ctx := context.Background()
+	cancel, err := server.Requeue(ctx, uuid.New(), 5*time.Minute, 0, board.Chess960,
+		func(gameId uuid.UUID) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel == nil {
+		t.Fatal("expected first player to wait in the queue")
+	}
+
+	// a default variant player shouldn't be paired with the chess960 one
+	cancel, err = server.Requeue(ctx, uuid.New(), 5*time.Minute, 0, board.DefaultVariant,
+		func(gameId uuid.UUID) { t.Fatal("players in different pools should not be paired") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel == nil {
+		t.Fatal("expected the default variant player to wait in their own queue")
+	}
+	cancel()
+
+	var gameId uuid.UUID
+	cancel, err = server.Requeue(ctx, uuid.New(), 5*time.Minute, 0, board.Chess960,
+		func(id uuid.UUID) { gameId = id })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel != nil || gameId == uuid.Nil {
+		t.Fatal("expected the chess960 players to be paired")
+	}
+
+	for _, report := range server.metrics.Report() {
+		if report.Format == "5+0:chess960" && report.Pairs != 1 {
+			t.Errorf("expected one chess960 pair recorded, got %+v", report)
+		}
+	}
+}
@@ -11,6 +11,58 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID           uuid.UUID
+	Organization string
+	KeyHash      string
+	Scopes       string
+	CreatedAt    time.Time
+	RevokedAt    sql.NullTime
+}
+
+type Game struct {
+	ID          uuid.UUID
+	WhiteID     uuid.UUID
+	BlackID     uuid.UUID
+	GameLength  int64
+	Increment   int64
+	Result      string
+	Condition   string
+	Moves       string
+	CreatedAt   time.Time
+	EndedAt     time.Time
+	Variant     string
+	Termination string
+	FinalFen    string
+	Summary     string
+	Telemetry   string
+}
+
+type LiveGame struct {
+	ID         uuid.UUID
+	WhiteID    uuid.UUID
+	BlackID    uuid.UUID
+	GameLength int64
+	Increment  int64
+	Variant    string
+	Moves      string
+	Board      []byte
+	WhiteTime  int64
+	BlackTime  int64
+	Clocks     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Berserk    string
+}
+
+type NotificationPreference struct {
+	UserID    uuid.UUID
+	Event     string
+	Channel   string
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
 type Session struct {
 	ID             uuid.UUID
 	UserID         string
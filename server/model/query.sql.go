@@ -13,6 +13,92 @@ import (
 	"github.com/google/uuid"
 )
 
+const createApiKey = `-- name: CreateApiKey :exec
+INSERT INTO
+  api_keys (id, organization, key_hash, scopes)
+VALUES
+  (?, ?, ?, ?)
+`
+
+type CreateApiKeyParams struct {
+	ID           uuid.UUID
+	Organization string
+	KeyHash      string
+	Scopes       string
+}
+
+func (q *Queries) CreateApiKey(ctx context.Context, arg CreateApiKeyParams) error {
+	_, err := q.db.ExecContext(ctx, createApiKey,
+		arg.ID,
+		arg.Organization,
+		arg.KeyHash,
+		arg.Scopes,
+	)
+	return err
+}
+
+const createGame = `-- name: CreateGame :exec
+INSERT INTO
+  games (
+    id,
+    white_id,
+    black_id,
+    game_length,
+    increment,
+    result,
+    condition,
+    moves,
+    created_at,
+    ended_at,
+    variant,
+    termination,
+    final_fen,
+    summary,
+    telemetry
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateGameParams struct {
+	ID          uuid.UUID
+	WhiteID     uuid.UUID
+	BlackID     uuid.UUID
+	GameLength  int64
+	Increment   int64
+	Result      string
+	Condition   string
+	Moves       string
+	CreatedAt   time.Time
+	EndedAt     time.Time
+	Variant     string
+	Termination string
+	FinalFen    string
+	Summary     string
+	Telemetry   string
+}
+
+func (q *Queries) CreateGame(ctx context.Context, arg CreateGameParams) error {
+	_, err := q.db.ExecContext(ctx, createGame,
+		arg.ID,
+		arg.WhiteID,
+		arg.BlackID,
+		arg.GameLength,
+		arg.Increment,
+		arg.Result,
+		arg.Condition,
+		arg.Moves,
+		arg.CreatedAt,
+		arg.EndedAt,
+		arg.Variant,
+		arg.Termination,
+		arg.FinalFen,
+		arg.Summary,
+		arg.Telemetry,
+	)
+	return err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO
   sessions (
@@ -73,6 +159,17 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 	return i, err
 }
 
+const deleteLiveGame = `-- name: DeleteLiveGame :exec
+DELETE FROM live_games
+WHERE
+  id = ?
+`
+
+func (q *Queries) DeleteLiveGame(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteLiveGame, id)
+	return err
+}
+
 const deleteSessionsById = `-- name: DeleteSessionsById :exec
 DELETE FROM sessions
 WHERE
@@ -95,6 +192,66 @@ func (q *Queries) DeleteSessionsByUserId(ctx context.Context, userID string) err
 	return err
 }
 
+const getApiKeyByHash = `-- name: GetApiKeyByHash :one
+SELECT
+  id, organization, key_hash, scopes, created_at, revoked_at
+FROM
+  api_keys
+WHERE
+  key_hash = ?
+  AND revoked_at IS NULL
+LIMIT
+  1
+`
+
+func (q *Queries) GetApiKeyByHash(ctx context.Context, keyHash string) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, getApiKeyByHash, keyHash)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Organization,
+		&i.KeyHash,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getGameById = `-- name: GetGameById :one
+SELECT
+  id, white_id, black_id, game_length, increment, result, condition, moves, created_at, ended_at, variant, termination, final_fen, summary, telemetry
+FROM
+  games
+WHERE
+  id = ?
+LIMIT
+  1
+`
+
+func (q *Queries) GetGameById(ctx context.Context, id uuid.UUID) (Game, error) {
+	row := q.db.QueryRowContext(ctx, getGameById, id)
+	var i Game
+	err := row.Scan(
+		&i.ID,
+		&i.WhiteID,
+		&i.BlackID,
+		&i.GameLength,
+		&i.Increment,
+		&i.Result,
+		&i.Condition,
+		&i.Moves,
+		&i.CreatedAt,
+		&i.EndedAt,
+		&i.Variant,
+		&i.Termination,
+		&i.FinalFen,
+		&i.Summary,
+		&i.Telemetry,
+	)
+	return i, err
+}
+
 const getSessionById = `-- name: GetSessionById :one
 SELECT
   id, user_id, access_token, refresh_token, expires_at, created_at, last_accessed_at
@@ -245,6 +402,204 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 	return i, err
 }
 
+const listApiKeys = `-- name: ListApiKeys :many
+SELECT
+  id, organization, key_hash, scopes, created_at, revoked_at
+FROM
+  api_keys
+ORDER BY
+  created_at,
+  id
+`
+
+func (q *Queries) ListApiKeys(ctx context.Context) ([]ApiKey, error) {
+	rows, err := q.db.QueryContext(ctx, listApiKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApiKey
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Organization,
+			&i.KeyHash,
+			&i.Scopes,
+			&i.CreatedAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGamesEndedBetween = `-- name: ListGamesEndedBetween :many
+SELECT
+  id, white_id, black_id, game_length, increment, result, condition, moves, created_at, ended_at, variant, termination, final_fen, summary, telemetry
+FROM
+  games
+WHERE
+  ended_at < ?1
+  AND (
+    ended_at > ?2
+    OR (
+      ended_at = ?2
+      AND id > ?3
+    )
+  )
+ORDER BY
+  ended_at,
+  id
+LIMIT
+  ?4
+`
+
+type ListGamesEndedBetweenParams struct {
+	EndedBefore   time.Time
+	CursorEndedAt time.Time
+	CursorID      uuid.UUID
+	Limit         int64
+}
+
+func (q *Queries) ListGamesEndedBetween(ctx context.Context, arg ListGamesEndedBetweenParams) ([]Game, error) {
+	rows, err := q.db.QueryContext(ctx, listGamesEndedBetween,
+		arg.EndedBefore,
+		arg.CursorEndedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Game
+	for rows.Next() {
+		var i Game
+		if err := rows.Scan(
+			&i.ID,
+			&i.WhiteID,
+			&i.BlackID,
+			&i.GameLength,
+			&i.Increment,
+			&i.Result,
+			&i.Condition,
+			&i.Moves,
+			&i.CreatedAt,
+			&i.EndedAt,
+			&i.Variant,
+			&i.Termination,
+			&i.FinalFen,
+			&i.Summary,
+			&i.Telemetry,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLiveGames = `-- name: ListLiveGames :many
+SELECT
+  id, white_id, black_id, game_length, increment, variant, moves, board, white_time, black_time, clocks, created_at, updated_at, berserk
+FROM
+  live_games
+ORDER BY
+  created_at,
+  id
+`
+
+func (q *Queries) ListLiveGames(ctx context.Context) ([]LiveGame, error) {
+	rows, err := q.db.QueryContext(ctx, listLiveGames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LiveGame
+	for rows.Next() {
+		var i LiveGame
+		if err := rows.Scan(
+			&i.ID,
+			&i.WhiteID,
+			&i.BlackID,
+			&i.GameLength,
+			&i.Increment,
+			&i.Variant,
+			&i.Moves,
+			&i.Board,
+			&i.WhiteTime,
+			&i.BlackTime,
+			&i.Clocks,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Berserk,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotificationPreferences = `-- name: ListNotificationPreferences :many
+SELECT
+  user_id, event, channel, enabled, updated_at
+FROM
+  notification_preferences
+WHERE
+  user_id = ?
+`
+
+func (q *Queries) ListNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]NotificationPreference, error) {
+	rows, err := q.db.QueryContext(ctx, listNotificationPreferences, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationPreference
+	for rows.Next() {
+		var i NotificationPreference
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Event,
+			&i.Channel,
+			&i.Enabled,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
 SELECT
   id, username, email, created_at, updated_at
@@ -280,3 +635,116 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 	}
 	return items, nil
 }
+
+const revokeApiKey = `-- name: RevokeApiKey :execrows
+UPDATE api_keys
+SET
+  revoked_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?
+  AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeApiKey(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeApiKey, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const saveLiveGame = `-- name: SaveLiveGame :exec
+INSERT INTO
+  live_games (
+    id,
+    white_id,
+    black_id,
+    game_length,
+    increment,
+    variant,
+    moves,
+    board,
+    white_time,
+    black_time,
+    clocks,
+    created_at,
+    updated_at,
+    berserk
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO
+UPDATE
+SET
+  moves = excluded.moves,
+  board = excluded.board,
+  white_time = excluded.white_time,
+  black_time = excluded.black_time,
+  clocks = excluded.clocks,
+  updated_at = excluded.updated_at,
+  berserk = excluded.berserk
+`
+
+type SaveLiveGameParams struct {
+	ID         uuid.UUID
+	WhiteID    uuid.UUID
+	BlackID    uuid.UUID
+	GameLength int64
+	Increment  int64
+	Variant    string
+	Moves      string
+	Board      []byte
+	WhiteTime  int64
+	BlackTime  int64
+	Clocks     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Berserk    string
+}
+
+func (q *Queries) SaveLiveGame(ctx context.Context, arg SaveLiveGameParams) error {
+	_, err := q.db.ExecContext(ctx, saveLiveGame,
+		arg.ID,
+		arg.WhiteID,
+		arg.BlackID,
+		arg.GameLength,
+		arg.Increment,
+		arg.Variant,
+		arg.Moves,
+		arg.Board,
+		arg.WhiteTime,
+		arg.BlackTime,
+		arg.Clocks,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Berserk,
+	)
+	return err
+}
+
+const setNotificationPreference = `-- name: SetNotificationPreference :exec
+INSERT INTO
+  notification_preferences (user_id, event, channel, enabled)
+VALUES
+  (?, ?, ?, ?) ON CONFLICT (user_id, event, channel) DO
+UPDATE
+SET
+  enabled = excluded.enabled,
+  updated_at = CURRENT_TIMESTAMP
+`
+
+type SetNotificationPreferenceParams struct {
+	UserID  uuid.UUID
+	Event   string
+	Channel string
+	Enabled bool
+}
+
+func (q *Queries) SetNotificationPreference(ctx context.Context, arg SetNotificationPreferenceParams) error {
+	_, err := q.db.ExecContext(ctx, setNotificationPreference,
+		arg.UserID,
+		arg.Event,
+		arg.Channel,
+		arg.Enabled,
+	)
+	return err
+}
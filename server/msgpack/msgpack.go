@@ -0,0 +1,497 @@
+// Package msgpack encodes values in the MessagePack format for clients which
+// would rather not pay for json's field names and punctuation on every
+// event. Structs are encoded as maps keyed by their json names, honouring
+// omitempty, so both encodings carry the same fields
+package msgpack
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var ErrTruncated = errors.New("msgpack: unexpected end of data")
+
+type encoder struct {
+	buf []byte
+}
+
+func Marshal(value any) ([]byte, error) {
+	enc := encoder{buf: make([]byte, 0, 128)}
+	err := enc.encode(reflect.ValueOf(value))
+	if err != nil {
+		return nil, err
+	}
+	return enc.buf, nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonNumberType    = reflect.TypeFor[json.Number]()
+)
+
+func (enc *encoder) encode(value reflect.Value) error {
+	if !value.IsValid() {
+		enc.buf = append(enc.buf, 0xc0)
+		return nil
+	}
+	nilPointer := value.Kind() == reflect.Pointer && value.IsNil()
+	// types with their own json e.g. moves, encoded as what the json decodes to
+	if value.Type().Implements(jsonMarshalerType) && !nilPointer {
+		return enc.encodeJson(value.Interface().(json.Marshaler))
+	}
+	// e.g. uuids, which json also writes as strings
+	if value.Type().Implements(textMarshalerType) && !nilPointer {
+		text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		enc.writeString(string(text))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			enc.buf = append(enc.buf, 0xc0)
+			return nil
+		}
+		return enc.encode(value.Elem())
+	case reflect.Bool:
+		if value.Bool() {
+			enc.buf = append(enc.buf, 0xc3)
+		} else {
+			enc.buf = append(enc.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		enc.writeInt(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		enc.writeUint(value.Uint())
+	case reflect.Float32:
+		enc.buf = append(enc.buf, 0xca)
+		enc.buf = binary.BigEndian.AppendUint32(enc.buf, math.Float32bits(float32(value.Float())))
+	case reflect.Float64:
+		enc.buf = append(enc.buf, 0xcb)
+		enc.buf = binary.BigEndian.AppendUint64(enc.buf, math.Float64bits(value.Float()))
+	case reflect.String:
+		if value.Type() == jsonNumberType {
+			return enc.writeNumber(json.Number(value.String()))
+		}
+		enc.writeString(value.String())
+	case reflect.Slice:
+		if value.IsNil() {
+			enc.buf = append(enc.buf, 0xc0)
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			enc.writeBinary(value.Bytes())
+			return nil
+		}
+		return enc.encodeArray(value)
+	case reflect.Array:
+		return enc.encodeArray(value)
+	case reflect.Map:
+		return enc.encodeMap(value)
+	case reflect.Struct:
+		return enc.encodeStruct(value)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+func (enc *encoder) encodeJson(marshaler json.Marshaler) error {
+	bytes, err := marshaler.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(bytes)))
+	decoder.UseNumber()
+	var decoded any
+	err = decoder.Decode(&decoded)
+	if err != nil {
+		return err
+	}
+	return enc.encode(reflect.ValueOf(decoded))
+}
+
+// integers stay integers rather than going through float64
+func (enc *encoder) writeNumber(num json.Number) error {
+	if integer, err := num.Int64(); err == nil {
+		enc.writeInt(integer)
+		return nil
+	}
+	float, err := num.Float64()
+	if err != nil {
+		return err
+	}
+	enc.buf = append(enc.buf, 0xcb)
+	enc.buf = binary.BigEndian.AppendUint64(enc.buf, math.Float64bits(float))
+	return nil
+}
+
+func (enc *encoder) writeInt(num int64) {
+	switch {
+	case num >= 0:
+		enc.writeUint(uint64(num))
+	case num >= -32:
+		enc.buf = append(enc.buf, byte(num))
+	case num >= math.MinInt8:
+		enc.buf = append(enc.buf, 0xd0, byte(num))
+	case num >= math.MinInt16:
+		enc.buf = append(enc.buf, 0xd1)
+		enc.buf = binary.BigEndian.AppendUint16(enc.buf, uint16(num))
+	case num >= math.MinInt32:
+		enc.buf = append(enc.buf, 0xd2)
+		enc.buf = binary.BigEndian.AppendUint32(enc.buf, uint32(num))
+	default:
+		enc.buf = append(enc.buf, 0xd3)
+		enc.buf = binary.BigEndian.AppendUint64(enc.buf, uint64(num))
+	}
+}
+
+func (enc *encoder) writeUint(num uint64) {
+	switch {
+	case num <= 0x7f:
+		enc.buf = append(enc.buf, byte(num))
+	case num <= math.MaxUint8:
+		enc.buf = append(enc.buf, 0xcc, byte(num))
+	case num <= math.MaxUint16:
+		enc.buf = append(enc.buf, 0xcd)
+		enc.buf = binary.BigEndian.AppendUint16(enc.buf, uint16(num))
+	case num <= math.MaxUint32:
+		enc.buf = append(enc.buf, 0xce)
+		enc.buf = binary.BigEndian.AppendUint32(enc.buf, uint32(num))
+	default:
+		enc.buf = append(enc.buf, 0xcf)
+		enc.buf = binary.BigEndian.AppendUint64(enc.buf, num)
+	}
+}
+
+// fix is the format for lengths under fixLimit, the others hold 8, 16 and 32
+// bit lengths. A zero format means the size has no 8 bit form
+func (enc *encoder) writeLength(length int, fix byte, fixLimit int, formats [3]byte) {
+	switch {
+	case length < fixLimit:
+		enc.buf = append(enc.buf, fix|byte(length))
+	case formats[0] != 0 && length <= math.MaxUint8:
+		enc.buf = append(enc.buf, formats[0], byte(length))
+	case length <= math.MaxUint16:
+		enc.buf = append(enc.buf, formats[1])
+		enc.buf = binary.BigEndian.AppendUint16(enc.buf, uint16(length))
+	default:
+		enc.buf = append(enc.buf, formats[2])
+		enc.buf = binary.BigEndian.AppendUint32(enc.buf, uint32(length))
+	}
+}
+
+func (enc *encoder) writeString(str string) {
+	enc.writeLength(len(str), 0xa0, 32, [3]byte{0xd9, 0xda, 0xdb})
+	enc.buf = append(enc.buf, str...)
+}
+
+func (enc *encoder) writeBinary(data []byte) {
+	// binary has no fixed size form
+	enc.writeLength(len(data), 0xc4, 0, [3]byte{0xc4, 0xc5, 0xc6})
+	enc.buf = append(enc.buf, data...)
+}
+
+func (enc *encoder) encodeArray(value reflect.Value) error {
+	enc.writeLength(value.Len(), 0x90, 16, [3]byte{0, 0xdc, 0xdd})
+	for i := range value.Len() {
+		err := enc.encode(value.Index(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (enc *encoder) encodeMap(value reflect.Value) error {
+	if value.IsNil() {
+		enc.buf = append(enc.buf, 0xc0)
+		return nil
+	}
+	if value.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("msgpack: unsupported map key type %s", value.Type().Key())
+	}
+
+	// sorted so the same map always encodes the same way
+	keys := value.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	enc.writeLength(len(keys), 0x80, 16, [3]byte{0, 0xde, 0xdf})
+	for _, key := range keys {
+		enc.writeString(key.String())
+		err := enc.encode(value.MapIndex(key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// The fields json would write, in declaration order
+func structFields(structType reflect.Type) []structField {
+	fields := make([]structField, 0, structType.NumField())
+	for i := range structType.NumField() {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{
+			index:     i,
+			name:      name,
+			omitEmpty: slices.Contains(strings.Split(options, ","), "omitempty"),
+		})
+	}
+	return fields
+}
+
+// what json counts as empty for omitempty
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	case reflect.Struct:
+		return false
+	}
+	return value.IsZero()
+}
+
+func (enc *encoder) encodeStruct(value reflect.Value) error {
+	fields := structFields(value.Type())
+	written := fields[:0:0]
+	for _, field := range fields {
+		if !field.omitEmpty || !isEmpty(value.Field(field.index)) {
+			written = append(written, field)
+		}
+	}
+
+	enc.writeLength(len(written), 0x80, 16, [3]byte{0, 0xde, 0xdf})
+	for _, field := range written {
+		enc.writeString(field.name)
+		err := enc.encode(value.Field(field.index))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// Decodes into the types encoding/json would, maps with string keys
+// become map[string]any, arrays []any and all numbers float64
+func Decode(data []byte) (any, error) {
+	dec := decoder{data: data}
+	value, err := dec.decode()
+	if err != nil {
+		return nil, err
+	}
+	if dec.pos != len(data) {
+		return nil, errors.New("msgpack: trailing data")
+	}
+	return value, nil
+}
+
+// Decodes into value the way json.Unmarshal would, by way of json. Only
+// meant for the small messages clients send
+func Unmarshal(data []byte, value any) error {
+	decoded, err := Decode(data)
+	if err != nil {
+		return err
+	}
+	asJson, err := json.Marshal(decoded)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(asJson, value)
+}
+
+func (dec *decoder) take(count int) ([]byte, error) {
+	if count < 0 || len(dec.data)-dec.pos < count {
+		return nil, ErrTruncated
+	}
+	bytes := dec.data[dec.pos : dec.pos+count]
+	dec.pos += count
+	return bytes, nil
+}
+
+// reads a big endian length of size bytes
+func (dec *decoder) length(size int) (int, error) {
+	bytes, err := dec.take(size)
+	if err != nil {
+		return 0, err
+	}
+	length := 0
+	for _, b := range bytes {
+		length = length<<8 | int(b)
+	}
+	return length, nil
+}
+
+func (dec *decoder) decode() (any, error) {
+	head, err := dec.take(1)
+	if err != nil {
+		return nil, err
+	}
+	format := head[0]
+
+	switch {
+	case format <= 0x7f:
+		return float64(format), nil
+	case format >= 0xe0:
+		return float64(int8(format)), nil
+	case format&0xf0 == 0x80:
+		return dec.decodeMap(int(format & 0x0f))
+	case format&0xf0 == 0x90:
+		return dec.decodeArray(int(format & 0x0f))
+	case format&0xe0 == 0xa0:
+		return dec.decodeString(int(format & 0x1f))
+	}
+
+	switch format {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		length, err := dec.length(1 << (format - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := dec.take(length)
+		return slices.Clone(bytes), err
+	case 0xca:
+		bytes, err := dec.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(bytes))), nil
+	case 0xcb:
+		bytes, err := dec.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(bytes)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		bytes, err := dec.take(1 << (format - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		num := uint64(0)
+		for _, b := range bytes {
+			num = num<<8 | uint64(b)
+		}
+		return float64(num), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (format - 0xd0)
+		bytes, err := dec.take(size)
+		if err != nil {
+			return nil, err
+		}
+		num := uint64(0)
+		for _, b := range bytes {
+			num = num<<8 | uint64(b)
+		}
+		// sign extend from the top bit of the value
+		shift := 64 - 8*size
+		return float64(int64(num<<shift) >> shift), nil
+	case 0xd9, 0xda, 0xdb:
+		length, err := dec.length(1 << (format - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeString(length)
+	case 0xdc, 0xdd:
+		length, err := dec.length(2 << (format - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeArray(length)
+	case 0xde, 0xdf:
+		length, err := dec.length(2 << (format - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeMap(length)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported format 0x%02x", format)
+}
+
+func (dec *decoder) decodeString(length int) (any, error) {
+	bytes, err := dec.take(length)
+	if err != nil {
+		return nil, err
+	}
+	return string(bytes), nil
+}
+
+func (dec *decoder) decodeArray(length int) (any, error) {
+	// every element takes at least a byte, so a bad length can't allocate
+	// more than the message
+	if length > len(dec.data)-dec.pos {
+		return nil, ErrTruncated
+	}
+	array := make([]any, length)
+	for i := range array {
+		var err error
+		array[i], err = dec.decode()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return array, nil
+}
+
+func (dec *decoder) decodeMap(length int) (any, error) {
+	if length > len(dec.data)-dec.pos {
+		return nil, ErrTruncated
+	}
+	ret := make(map[string]any, length)
+	for range length {
+		key, err := dec.decode()
+		if err != nil {
+			return nil, err
+		}
+		keyStr, ok := key.(string)
+		if !ok {
+			return nil, errors.New("msgpack: map keys must be strings")
+		}
+		ret[keyStr], err = dec.decode()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
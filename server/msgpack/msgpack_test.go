@@ -0,0 +1,135 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"chess/board"
+	"chess/msgpack"
+)
+
+type testEvent struct {
+	Type       string       `json:"type"`
+	Move       *string      `json:"move,omitempty"`
+	WhiteTime  *int32       `json:"whiteTime,omitempty"`
+	Moves      []string     `json:"moves"`
+	Played     []board.Move `json:"played,omitempty"`
+	Ratio      float64      `json:"ratio"`
+	Hidden     string       `json:"-"`
+	unexported int
+}
+
+func Test_msgpack(test *testing.T) {
+	test.Run("encodes the smallest formats", func(test *testing.T) {
+		test.Parallel()
+		cases := []struct {
+			value    any
+			expected []byte
+		}{
+			{nil, []byte{0xc0}},
+			{true, []byte{0xc3}},
+			{5, []byte{0x05}},
+			{-3, []byte{0xfd}},
+			{200, []byte{0xcc, 200}},
+			{-100, []byte{0xd0, 0x9c}},
+			{70000, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+			{"ab", []byte{0xa2, 'a', 'b'}},
+			{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+			{map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+		}
+		for _, testCase := range cases {
+			encoded, err := msgpack.Marshal(testCase.value)
+			if err != nil {
+				test.Fatal(err)
+			}
+			if !bytes.Equal(encoded, testCase.expected) {
+				test.Errorf("%v encoded as % x, expected % x", testCase.value, encoded, testCase.expected)
+			}
+		}
+	})
+
+	test.Run("decodes to what json would", func(test *testing.T) {
+		test.Parallel()
+		move := "D1:C2"
+		whiteTime := int32(-1500)
+		played, err := board.DeserialiseMove(move)
+		if err != nil {
+			test.Fatal(err)
+		}
+		event := testEvent{
+			Type:      "move",
+			Move:      &move,
+			WhiteTime: &whiteTime,
+			Moves:     []string{strings.Repeat("long", 20), "E8:F7"},
+			Played:    []board.Move{played},
+			Ratio:     0.25,
+			Hidden:    "not sent",
+		}
+
+		encoded, err := msgpack.Marshal(event)
+		if err != nil {
+			test.Fatal(err)
+		}
+		decoded, err := msgpack.Decode(encoded)
+		if err != nil {
+			test.Fatal(err)
+		}
+
+		asJson, err := json.Marshal(event)
+		if err != nil {
+			test.Fatal(err)
+		}
+		var expected any
+		err = json.Unmarshal(asJson, &expected)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, expected) {
+			test.Fatalf("decoded %v, expected %v", decoded, expected)
+		}
+
+		if len(encoded) >= len(asJson) {
+			test.Errorf("msgpack took %d bytes, json took %d", len(encoded), len(asJson))
+		}
+	})
+
+	test.Run("round trips large and negative numbers", func(test *testing.T) {
+		test.Parallel()
+		values := []int64{math.MaxInt32 + 1, math.MinInt16 - 1, math.MinInt32, -33, 0}
+		for _, value := range values {
+			encoded, err := msgpack.Marshal(value)
+			if err != nil {
+				test.Fatal(err)
+			}
+			var decoded int64
+			err = msgpack.Unmarshal(encoded, &decoded)
+			if err != nil {
+				test.Fatal(err)
+			}
+			if decoded != value {
+				test.Errorf("%d decoded as %d", value, decoded)
+			}
+		}
+	})
+
+	test.Run("rejects truncated data", func(test *testing.T) {
+		test.Parallel()
+		encoded, err := msgpack.Marshal(map[string]string{"type": "sendMove"})
+		if err != nil {
+			test.Fatal(err)
+		}
+		_, err = msgpack.Decode(encoded[:len(encoded)-1])
+		if err != msgpack.ErrTruncated {
+			test.Fatalf("expected truncated error, got %v", err)
+		}
+		// claims far more elements than there is data for
+		_, err = msgpack.Decode([]byte{0xdd, 0xff, 0xff, 0xff, 0xff})
+		if err != msgpack.ErrTruncated {
+			test.Fatalf("expected truncated error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	Event Event
+	Title string
+	Body  string
+	// optional, e.g. the game to open
+	Url string
+}
+
+// delivers notifications over one channel
+type Sender interface {
+	Send(ctx context.Context, userId uuid.UUID, notification Notification) error
+}
+
+// Sends notifications over every channel the user has enabled for the event
+type Dispatcher struct {
+	store       Store
+	sendersLock sync.RWMutex
+	senders     map[Channel]Sender
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		store:   store,
+		senders: make(map[Channel]Sender),
+	}
+}
+
+// channels without a sender are skipped
+func (dispatcher *Dispatcher) SetSender(channel Channel, sender Sender) {
+	dispatcher.sendersLock.Lock()
+	dispatcher.senders[channel] = sender
+	dispatcher.sendersLock.Unlock()
+}
+
+// Returns the channels the notification was sent over, a channel failing
+// doesn't stop the others
+func (dispatcher *Dispatcher) Dispatch(
+	ctx context.Context,
+	userId uuid.UUID,
+	notification Notification,
+) ([]Channel, error) {
+	preferences, err := Load(ctx, dispatcher.store, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatcher.sendersLock.RLock()
+	defer dispatcher.sendersLock.RUnlock()
+
+	sent := make([]Channel, 0, len(Channels))
+	errs := make([]error, 0)
+	for _, channel := range Channels {
+		sender, found := dispatcher.senders[channel]
+		if !found || !preferences.Enabled(notification.Event, channel) {
+			continue
+		}
+		err = sender.Send(ctx, userId, notification)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+			continue
+		}
+		sent = append(sent, channel)
+	}
+	return sent, errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"chess/model"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDb(t *testing.T) *model.Queries {
+	t.Helper()
+	ddl, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(string(ddl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.New(db)
+}
+
+func TestPreferences(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDb(t)
+	userId := uuid.New()
+
+	preferences, err := Load(ctx, db, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preferences.Enabled(Challenge, Push) || preferences.Enabled(GameResult, Email) {
+		t.Errorf("Expected the defaults, got %v", preferences)
+	}
+
+	preferences, err = Update(ctx, db, userId, Preferences{
+		Challenge:  {Push: false},
+		GameResult: {Email: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preferences.Enabled(Challenge, Push) || !preferences.Enabled(GameResult, Email) {
+		t.Errorf("Expected the patch to be applied, got %v", preferences)
+	}
+	if !preferences.Enabled(Challenge, Websocket) {
+		t.Error("Expected choices missing from the patch to be kept")
+	}
+
+	// updating the same choice again replaces it
+	preferences, err = Update(ctx, db, userId, Preferences{Challenge: {Push: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preferences.Enabled(Challenge, Push) {
+		t.Error("Expected challenge push to be enabled again")
+	}
+
+	_, err = Update(ctx, db, userId, Preferences{"unknown": {Push: true}})
+	if err == nil {
+		t.Error("Expected unknown event to be rejected")
+	}
+	_, err = Update(ctx, db, userId, Preferences{Challenge: {"pigeon": true}})
+	if err == nil {
+		t.Error("Expected unknown channel to be rejected")
+	}
+}
+
+type fakeSender struct {
+	sent []Notification
+	err  error
+}
+
+func (sender *fakeSender) Send(ctx context.Context, userId uuid.UUID, notification Notification) error {
+	if sender.err != nil {
+		return sender.err
+	}
+	sender.sent = append(sender.sent, notification)
+	return nil
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDb(t)
+	userId := uuid.New()
+	_, err := Update(ctx, db, userId, Preferences{GameResult: {Websocket: false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := NewDispatcher(db)
+	websocket := &fakeSender{}
+	push := &fakeSender{err: errors.New("push failed")}
+	dispatcher.SetSender(Websocket, websocket)
+	dispatcher.SetSender(Push, push)
+
+	sent, err := dispatcher.Dispatch(ctx, userId, Notification{Event: Challenge})
+	if err == nil {
+		t.Error("Expected the push failure to be returned")
+	}
+	if !slices.Equal(sent, []Channel{Websocket}) || len(websocket.sent) != 1 {
+		t.Errorf("Expected challenge to be sent over websocket, sent %v", sent)
+	}
+
+	sent, err = dispatcher.Dispatch(ctx, userId, Notification{Event: GameResult})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("Expected disabled game results not to be sent, sent %v", sent)
+	}
+}
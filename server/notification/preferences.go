@@ -0,0 +1,117 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	"chess/model"
+
+	"github.com/google/uuid"
+)
+
+type Event string
+
+const (
+	Challenge          Event = "challenge"
+	TournamentReminder Event = "tournamentReminder"
+	GameResult         Event = "gameResult"
+	CorrespondenceMove Event = "correspondenceMove"
+)
+
+var Events = []Event{Challenge, TournamentReminder, GameResult, CorrespondenceMove}
+
+type Channel string
+
+const (
+	Websocket Channel = "websocket"
+	Email     Channel = "email"
+	Push      Channel = "push"
+)
+
+var Channels = []Channel{Websocket, Email, Push}
+
+// which channels each event is sent over
+type Preferences map[Event]map[Channel]bool
+
+// websocket notifications are always on, email is kept for things a user
+// might miss and push for anything waiting on them
+func Defaults() Preferences {
+	preferences := make(Preferences, len(Events))
+	for _, event := range Events {
+		preferences[event] = map[Channel]bool{Websocket: true, Email: false, Push: false}
+	}
+	preferences[Challenge][Push] = true
+	preferences[TournamentReminder][Email] = true
+	preferences[TournamentReminder][Push] = true
+	preferences[CorrespondenceMove][Email] = true
+	preferences[CorrespondenceMove][Push] = true
+	return preferences
+}
+
+func (preferences Preferences) Enabled(event Event, channel Channel) bool {
+	return preferences[event][channel]
+}
+
+// Checks every event and channel is known, a patch doesn't have to
+// include all of them
+func (preferences Preferences) Validate() error {
+	defaults := Defaults()
+	for event, channels := range preferences {
+		if _, found := defaults[event]; !found {
+			return fmt.Errorf("unknown event %q", event)
+		}
+		for channel := range channels {
+			if _, found := defaults[event][channel]; !found {
+				return fmt.Errorf("unknown channel %q", channel)
+			}
+		}
+	}
+	return nil
+}
+
+type Store interface {
+	ListNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]model.NotificationPreference, error)
+	SetNotificationPreference(ctx context.Context, arg model.SetNotificationPreferenceParams) error
+}
+
+// The defaults with the user's stored choices applied
+func Load(ctx context.Context, store Store, userId uuid.UUID) (Preferences, error) {
+	preferences := Defaults()
+	rows, err := store.ListNotificationPreferences(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		channels, found := preferences[Event(row.Event)]
+		if !found {
+			// left over from an event which has since been removed
+			continue
+		}
+		if _, found := channels[Channel(row.Channel)]; found {
+			channels[Channel(row.Channel)] = row.Enabled
+		}
+	}
+	return preferences, nil
+}
+
+// Stores the choices in the patch and returns the updated preferences
+func Update(ctx context.Context, store Store, userId uuid.UUID, patch Preferences) (Preferences, error) {
+	err := patch.Validate()
+	if err != nil {
+		return nil, err
+	}
+	for event, channels := range patch {
+		for channel, enabled := range channels {
+			err = store.SetNotificationPreference(ctx, model.SetNotificationPreferenceParams{
+				UserID:  userId,
+				Event:   string(event),
+				Channel: string(channel),
+				Enabled: enabled,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return Load(ctx, store, userId)
+}
@@ -0,0 +1,233 @@
+package pgn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"chess/board"
+)
+
+type Game struct {
+	Headers Headers
+	// every tag found including the ones mapped into Headers
+	Tags  map[string]string
+	Moves []board.Move
+	Board *board.BoardState
+}
+
+type tokenType uint8
+
+const (
+	symbolToken tokenType = iota
+	tagToken
+)
+
+type token struct {
+	kind  tokenType
+	value string
+	name  string
+}
+
+func parseTag(str string) (name string, value string, err error) {
+	str = strings.TrimSpace(str)
+	name, rest, found := strings.Cut(str, " ")
+	if !found {
+		return "", "", fmt.Errorf("malformed tag: [%s]", str)
+	}
+
+	rest = strings.TrimSpace(rest)
+	if len(rest) < 2 || rest[0] != '"' || rest[len(rest)-1] != '"' {
+		return "", "", fmt.Errorf("tag value should be quoted: [%s]", str)
+	}
+	rest = rest[1 : len(rest)-1]
+	rest = strings.ReplaceAll(rest, `\"`, `"`)
+	rest = strings.ReplaceAll(rest, `\\`, `\`)
+	return name, rest, nil
+}
+
+func tokenise(str string) ([]token, error) {
+	tokens := make([]token, 0)
+	variationDepth := 0
+
+	for i := 0; i < len(str); i++ {
+		char := str[i]
+		switch {
+		case unicode.IsSpace(rune(char)):
+			continue
+		case char == '{':
+			end := strings.IndexByte(str[i:], '}')
+			if end == -1 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += end
+		case char == ';':
+			end := strings.IndexByte(str[i:], '\n')
+			if end == -1 {
+				return tokens, nil
+			}
+			i += end
+		case char == '(':
+			variationDepth += 1
+		case char == ')':
+			variationDepth -= 1
+			if variationDepth < 0 {
+				return nil, errors.New("unexpected ) found")
+			}
+		case char == '[':
+			if variationDepth > 0 {
+				return nil, errors.New("tag found inside a variation")
+			}
+			end := strings.IndexByte(str[i:], ']')
+			if end == -1 {
+				return nil, errors.New("unterminated tag")
+			}
+			name, value, err := parseTag(str[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tagToken, name: name, value: value})
+			i += end
+		default:
+			start := i
+			for i < len(str) && !unicode.IsSpace(rune(str[i])) &&
+				!strings.ContainsRune("{}();[]", rune(str[i])) {
+				i += 1
+			}
+			// variations are skipped, only the main line is replayed
+			if variationDepth == 0 {
+				tokens = append(tokens, token{kind: symbolToken, value: str[start:i]})
+			}
+			i -= 1
+		}
+	}
+
+	if variationDepth != 0 {
+		return nil, errors.New("unterminated variation")
+	}
+	return tokens, nil
+}
+
+func isResult(str string) bool {
+	return str == WhiteWinResult || str == BlackWinResult ||
+		str == DrawResult || str == OngoingResult
+}
+
+// strips move numbers like 12. or 12... from the front of a symbol
+func stripMoveNumber(str string) string {
+	index := 0
+	for index < len(str) && str[index] >= '0' && str[index] <= '9' {
+		index += 1
+	}
+	if index == 0 || index == len(str) || str[index] != '.' {
+		return str
+	}
+	return strings.TrimLeft(str[index:], ".")
+}
+
+func (game *Game) applyTag(name, value string) {
+	game.Tags[name] = value
+	switch name {
+	case "Event":
+		game.Headers.Event = value
+	case "Site":
+		game.Headers.Site = value
+	case "White":
+		game.Headers.White = value
+	case "Black":
+		game.Headers.Black = value
+	case "Result":
+		game.Headers.Result = value
+	case "TimeControl":
+		game.Headers.TimeControl = value
+	case "Date":
+		date, err := time.Parse("2006.01.02", value)
+		if err == nil {
+			game.Headers.Date = date
+		}
+	}
+}
+
+// the Variant tag picks the rules, the FEN tag the position if the game
+// didn't start from the variant's own
+func newStartingBoard(tags map[string]string) (*board.BoardState, error) {
+	variant, err := board.VariantFromName(tags["Variant"])
+	if err != nil {
+		return nil, err
+	}
+
+	var boardState *board.BoardState
+	if fen, found := tags["FEN"]; found {
+		boardState, err = board.ParseVariantFen(fen, variant)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		boardState = board.NewVariantBoard(variant)
+	}
+
+	err = boardState.Init()
+	if err != nil {
+		return nil, err
+	}
+	return boardState, nil
+}
+
+// Parses a single game, replaying the main line and validating each move
+func Parse(str string) (*Game, error) {
+	tokens, err := tokenise(str)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &Game{
+		Tags:  make(map[string]string),
+		Moves: make([]board.Move, 0),
+	}
+
+	index := 0
+	for ; index < len(tokens) && tokens[index].kind == tagToken; index++ {
+		game.applyTag(tokens[index].name, tokens[index].value)
+	}
+
+	boardState, err := newStartingBoard(game.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if _, found := game.Tags["Variant"]; found {
+		game.Headers.Variant = boardState.Variant()
+	}
+
+	for ; index < len(tokens); index++ {
+		tok := tokens[index]
+		if tok.kind == tagToken {
+			return nil, fmt.Errorf("tag %s found inside movetext", tok.name)
+		}
+
+		symbol := stripMoveNumber(tok.value)
+		if symbol == "" || symbol[0] == '$' {
+			continue
+		}
+		if isResult(symbol) {
+			if game.Headers.Result == "" {
+				game.Headers.Result = symbol
+			}
+			break
+		}
+
+		move, err := boardState.SanToMove(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("ply %d: %w", len(game.Moves)+1, err)
+		}
+		err = boardState.MakeMove(move)
+		if err != nil {
+			return nil, fmt.Errorf("ply %d: %w", len(game.Moves)+1, err)
+		}
+		game.Moves = append(game.Moves, move)
+	}
+
+	game.Board = boardState
+	return game, nil
+}
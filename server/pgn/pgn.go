@@ -0,0 +1,184 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"chess/board"
+)
+
+const (
+	WhiteWinResult = "1-0"
+	BlackWinResult = "0-1"
+	DrawResult     = "1/2-1/2"
+	OngoingResult  = "*"
+)
+
+func ResultFromWinState(winState board.WinState) string {
+	switch winState {
+	case board.WhiteWin:
+		return WhiteWinResult
+	case board.BlackWin:
+		return BlackWinResult
+	case board.NoWin:
+		return OngoingResult
+	default:
+		return DrawResult
+	}
+}
+
+// The pgn spec only has a few termination tags, the details are lost
+func TerminationTag(termination board.Termination) string {
+	switch termination {
+	case board.TerminationNone:
+		return ""
+	case board.TerminationAdjudicated:
+		return "adjudication"
+	case board.TerminationTimeForfeit, board.TerminationTimeoutDraw:
+		return "time forfeit"
+	case board.TerminationAbandonment:
+		return "abandoned"
+	case board.TerminationRulesInfraction:
+		return "rules infraction"
+	default:
+		return "normal"
+	}
+}
+
+type Headers struct {
+	Event       string
+	Site        string
+	Date        time.Time
+	White       string
+	Black       string
+	Result      string
+	TimeControl string
+	// left out when empty
+	Termination board.Termination
+	// nil for the default variant, shuffled variants should have their
+	// setup picked so the starting position can be written
+	Variant board.Variant
+}
+
+func (headers *Headers) variant() board.Variant {
+	if headers.Variant == nil {
+		return board.DefaultVariant
+	}
+	return headers.Variant
+}
+
+// time control as described in the pgn spec, e.g. 300+5
+func TimeControl(gameLength, increment time.Duration) string {
+	return fmt.Sprintf("%d+%d", int(gameLength.Seconds()), int(increment.Seconds()))
+}
+
+func orUnknown(str string) string {
+	if str == "" {
+		return "?"
+	}
+	return str
+}
+
+func pgnDate(date time.Time) string {
+	if date.IsZero() {
+		return "????.??.??"
+	}
+	return date.Format("2006.01.02")
+}
+
+func escape(str string) string {
+	str = strings.ReplaceAll(str, `\`, `\\`)
+	return strings.ReplaceAll(str, `"`, `\"`)
+}
+
+func (headers *Headers) write(builder *strings.Builder) {
+	result := headers.Result
+	if result == "" {
+		result = OngoingResult
+	}
+
+	tags := [][2]string{
+		{"Event", orUnknown(headers.Event)},
+		{"Site", orUnknown(headers.Site)},
+		{"Date", pgnDate(headers.Date)},
+		{"Round", "-"},
+		{"White", orUnknown(headers.White)},
+		{"Black", orUnknown(headers.Black)},
+		{"Result", result},
+		{"TimeControl", orUnknown(headers.TimeControl)},
+	}
+	if tag := TerminationTag(headers.Termination); tag != "" {
+		tags = append(tags, [2]string{"Termination", tag})
+	}
+	variant := headers.variant()
+	if variant.Name() != board.DefaultVariant.Name() {
+		tags = append(tags, [2]string{"Variant", board.VariantId(variant)})
+	}
+	// readers which don't know the setup numbers can still replay the game
+	if shuffled, ok := variant.(board.Shuffled); ok && shuffled.Setup() != -1 {
+		tags = append(tags,
+			[2]string{"SetUp", "1"},
+			[2]string{"FEN", board.NewVariantBoard(variant).Fen()},
+		)
+	}
+	for _, tag := range tags {
+		fmt.Fprintf(builder, "[%s \"%s\"]\n", tag[0], escape(tag[1]))
+	}
+	builder.WriteString("\n")
+}
+
+const maxLineLength = 79
+
+func writeMovetext(builder *strings.Builder, sanMoves []string, result string) {
+	lineLength := 0
+	writeToken := func(token string) {
+		if lineLength > 0 && lineLength+1+len(token) > maxLineLength {
+			builder.WriteString("\n")
+			lineLength = 0
+		} else if lineLength > 0 {
+			builder.WriteString(" ")
+			lineLength += 1
+		}
+		builder.WriteString(token)
+		lineLength += len(token)
+	}
+
+	for i, san := range sanMoves {
+		if i%2 == 0 {
+			writeToken(fmt.Sprintf("%d.", i/2+1))
+		}
+		writeToken(san)
+	}
+	writeToken(result)
+	builder.WriteString("\n")
+}
+
+// Writes a game played from the variant's starting position as pgn
+func Write(writer io.Writer, headers Headers, moves []board.Move) error {
+	str, err := Export(headers, moves)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, str)
+	return err
+}
+
+func Export(headers Headers, moves []board.Move) (string, error) {
+	sanMoves, err := board.MoveHistoryToSan(headers.variant(), moves)
+	if err != nil {
+		return "", err
+	}
+
+	builder := strings.Builder{}
+	headers.write(&builder)
+
+	result := headers.Result
+	if result == "" {
+		result = OngoingResult
+	}
+	writeMovetext(&builder, sanMoves, result)
+
+	return builder.String(), nil
+}
@@ -0,0 +1,211 @@
+package pgn_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"chess/board"
+	"chess/pgn"
+)
+
+func getMoves(test *testing.T, moves []string) []board.Move {
+	test.Helper()
+	ret := make([]board.Move, len(moves))
+	for i, str := range moves {
+		move, err := board.DeserialiseMove(str)
+		if err != nil {
+			test.Fatal(err)
+		}
+		ret[i] = move
+	}
+	return ret
+}
+
+func Test_export(test *testing.T) {
+	test.Run("test pgn export", func(test *testing.T) {
+		test.Parallel()
+		moves := getMoves(test, []string{"D1:C2", "E8:F7", "F2:E4"})
+		headers := pgn.Headers{
+			White:       "white player",
+			Black:       "black \"player\"",
+			Date:        time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			Result:      pgn.OngoingResult,
+			TimeControl: pgn.TimeControl(5*time.Minute, 3*time.Second),
+		}
+
+		str, err := pgn.Export(headers, moves)
+		if err != nil {
+			test.Fatal(err)
+		}
+
+		expectedParts := []string{
+			"[White \"white player\"]\n",
+			"[Black \"black \\\"player\\\"\"]\n",
+			"[Date \"2025.03.01\"]\n",
+			"[TimeControl \"300+3\"]\n",
+			"\n1. c2 f7 2. Ne4 *\n",
+		}
+		for _, part := range expectedParts {
+			if !strings.Contains(str, part) {
+				test.Fatalf("expected pgn to contain:\n%s\nreceived:\n%s", part, str)
+			}
+		}
+	})
+
+	test.Run("test termination tag", func(test *testing.T) {
+		test.Parallel()
+		moves := getMoves(test, []string{"D1:C2"})
+		str, err := pgn.Export(pgn.Headers{
+			Result:      pgn.ResultFromWinState(board.TimeoutDraw),
+			Termination: board.TerminationTimeoutDraw,
+		}, moves)
+		if err != nil {
+			test.Fatal(err)
+		}
+		for _, part := range []string{"[Result \"1/2-1/2\"]\n", "[Termination \"time forfeit\"]\n"} {
+			if !strings.Contains(str, part) {
+				test.Fatalf("expected pgn to contain:\n%s\nreceived:\n%s", part, str)
+			}
+		}
+
+		str, err = pgn.Export(pgn.Headers{}, moves)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if strings.Contains(str, "Termination") {
+			test.Fatalf("expected no termination tag\nreceived:\n%s", str)
+		}
+	})
+
+	test.Run("test variant tags", func(test *testing.T) {
+		test.Parallel()
+		variant, err := board.Chess960Setup(518)
+		if err != nil {
+			test.Fatal(err)
+		}
+		moves := getMoves(test, []string{"E2:E4", "E7:E5"})
+		str, err := pgn.Export(pgn.Headers{Variant: variant}, moves)
+		if err != nil {
+			test.Fatal(err)
+		}
+		expectedParts := []string{
+			"[Variant \"chess960:518\"]\n",
+			"[SetUp \"1\"]\n",
+			"[FEN \"" + board.NewVariantBoard(variant).Fen() + "\"]\n",
+			"\n1. e4 e5 *\n",
+		}
+		for _, part := range expectedParts {
+			if !strings.Contains(str, part) {
+				test.Fatalf("expected pgn to contain:\n%s\nreceived:\n%s", part, str)
+			}
+		}
+
+		str, err = pgn.Export(pgn.Headers{}, getMoves(test, []string{"D1:C2"}))
+		if err != nil {
+			test.Fatal(err)
+		}
+		if strings.Contains(str, "Variant") || strings.Contains(str, "FEN") {
+			test.Fatalf("expected no variant tags\nreceived:\n%s", str)
+		}
+	})
+
+	test.Run("test illegal moves fail", func(test *testing.T) {
+		test.Parallel()
+		moves := getMoves(test, []string{"D1:C2", "D1:C2"})
+		_, err := pgn.Export(pgn.Headers{}, moves)
+		if err == nil {
+			test.Fatal("no error found")
+		}
+	})
+}
+
+func Test_parse(test *testing.T) {
+	test.Run("test export then parse round trip", func(test *testing.T) {
+		test.Parallel()
+		moves := getMoves(test, []string{"D1:C2", "E8:F7", "F2:E4", "C7:E6"})
+		str, err := pgn.Export(pgn.Headers{White: "a", Black: "b"}, moves)
+		if err != nil {
+			test.Fatal(err)
+		}
+
+		game, err := pgn.Parse(str)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if len(game.Moves) != len(moves) {
+			test.Fatalf("expected %d moves\nreceived: %d", len(moves), len(game.Moves))
+		}
+		for i, move := range moves {
+			if game.Moves[i] != move {
+				test.Fatalf("expected %s\nreceived: %s", move.String(), game.Moves[i].String())
+			}
+		}
+		if game.Headers.White != "a" || game.Headers.Black != "b" {
+			test.Fatalf("headers not parsed: %+v", game.Headers)
+		}
+		if game.Board.MoveCounter != uint16(len(moves)) {
+			test.Fatalf("expected board after %d moves\nreceived: %d",
+				len(moves), game.Board.MoveCounter)
+		}
+	})
+
+	test.Run("test comments and variations are skipped", func(test *testing.T) {
+		test.Parallel()
+		game, err := pgn.Parse(
+			"[Event \"test\"]\n\n1. c2 {a comment} (1. e2 f7) f7 ; line comment\n2. Ne4 $1 *")
+		if err != nil {
+			test.Fatal(err)
+		}
+		if len(game.Moves) != 3 {
+			test.Fatalf("expected 3 moves\nreceived: %d", len(game.Moves))
+		}
+		if game.Headers.Result != pgn.OngoingResult {
+			test.Fatalf("expected result *\nreceived: %s", game.Headers.Result)
+		}
+	})
+
+	test.Run("test variant games", func(test *testing.T) {
+		test.Parallel()
+		variant, err := board.Chess960Setup(518)
+		if err != nil {
+			test.Fatal(err)
+		}
+		moves := getMoves(test, []string{"E2:E4", "E7:E5", "G1:F3"})
+		str, err := pgn.Export(pgn.Headers{Variant: variant}, moves)
+		if err != nil {
+			test.Fatal(err)
+		}
+
+		game, err := pgn.Parse(str)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if len(game.Moves) != len(moves) {
+			test.Fatalf("expected %d moves\nreceived: %d", len(moves), len(game.Moves))
+		}
+		if id := board.VariantId(game.Headers.Variant); id != "chess960:518" {
+			test.Fatalf("expected variant chess960:518\nreceived: %s", id)
+		}
+		if id := board.VariantId(game.Board.Variant()); id != "chess960:518" {
+			test.Fatalf("expected the board to use chess960:518\nreceived: %s", id)
+		}
+
+		_, err = pgn.Parse("[Variant \"checkers\"]\n\n*")
+		if err == nil {
+			test.Fatal("expected an unknown variant to fail")
+		}
+	})
+
+	test.Run("test illegal moves fail", func(test *testing.T) {
+		test.Parallel()
+		_, err := pgn.Parse("1. c2 c2 *")
+		if err == nil {
+			test.Fatal("no error found")
+		}
+		_, err = pgn.Parse("1. Qa8 *")
+		if err == nil {
+			test.Fatal("no error found")
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"fmt"
+
+	"github.com/coder/websocket"
+)
+
+// permessage-deflate for the game and matchmaking sockets. Move events with
+// the full list of legal moves are mostly repeated squares so they shrink
+// a lot, small events aren't worth the cpu and are sent as they are.
+// Clients which don't support the extension e.g. safari aren't affected
+type Compression struct {
+	Mode websocket.CompressionMode
+	// messages smaller than this in bytes aren't compressed, zero uses the
+	// library's default for the mode
+	Threshold int
+}
+
+// Without context takeover each message is compressed on its own, it
+// compresses less but doesn't hold a window open for every connection
+var DefaultCompression = Compression{
+	Mode:      websocket.CompressionNoContextTakeover,
+	Threshold: 256,
+}
+
+// "off", "message" for no context takeover or "context" to keep the window
+// between messages. Empty gives the default mode
+func ParseCompressionMode(str string) (websocket.CompressionMode, error) {
+	switch str {
+	case "":
+		return DefaultCompression.Mode, nil
+	case "off":
+		return websocket.CompressionDisabled, nil
+	case "message":
+		return websocket.CompressionNoContextTakeover, nil
+	case "context":
+		return websocket.CompressionContextTakeover, nil
+	}
+	return 0, fmt.Errorf("invalid compression mode: %s", str)
+}
+
+func (compression Compression) AcceptOptions(subprotocols ...string) *websocket.AcceptOptions {
+	return &websocket.AcceptOptions{
+		OriginPatterns:       []string{"*"},
+		Subprotocols:         subprotocols,
+		CompressionMode:      compression.Mode,
+		CompressionThreshold: compression.Threshold,
+	}
+}
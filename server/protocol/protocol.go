@@ -0,0 +1,71 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Version of the websocket event format. Clients which don't ask for a
+// version are assumed to be on the oldest one still being served
+type Version uint16
+
+const (
+	V0 Version = iota
+	V1
+	// legal moves are sent as one compactLegalMoves string grouped by the
+	// square they're from instead of the legalMoves array
+	V2
+)
+
+const (
+	Current = V2
+	// the server keeps serving one version behind current so deployed
+	// clients keep working while they update
+	Oldest = Current - 1
+)
+
+// rest routes are served both with and without this prefix, the
+// unprefixed routes being the previous version
+const APIPrefix = "/v1"
+
+const QueryKey = "v"
+
+// Websocket subprotocols a client can ask for. Events are json unless the
+// client offers the msgpack subprotocol, it's versioned separately from
+// the event format so the encoding can change without a new Version
+const (
+	JSONSubprotocol    = "chess.json.v1"
+	MsgpackSubprotocol = "chess.msgpack.v1"
+)
+
+// In order of preference when a client offers more than one
+var Subprotocols = []string{MsgpackSubprotocol, JSONSubprotocol}
+
+func (version Version) Supported() bool {
+	return version >= Oldest && version <= Current
+}
+
+func (version Version) String() string {
+	return fmt.Sprintf("v%d", version)
+}
+
+func Parse(str string) (Version, error) {
+	if str == "" {
+		return Oldest, nil
+	}
+
+	if str[0] == 'v' {
+		str = str[1:]
+	}
+	num, err := strconv.ParseUint(str, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid protocol version: %s", str)
+	}
+
+	version := Version(num)
+	if !version.Supported() {
+		return 0, fmt.Errorf("unsupported protocol version %s, supported versions are %s to %s",
+			version.String(), Oldest.String(), Current.String())
+	}
+	return version, nil
+}
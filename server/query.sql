@@ -95,3 +95,151 @@ WHERE
 DELETE FROM sessions
 WHERE
   id = ?;
+
+-- name: CreateGame :exec
+INSERT INTO
+  games (
+    id,
+    white_id,
+    black_id,
+    game_length,
+    increment,
+    result,
+    condition,
+    moves,
+    created_at,
+    ended_at,
+    variant,
+    termination,
+    final_fen,
+    summary,
+    telemetry
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetGameById :one
+SELECT
+  *
+FROM
+  games
+WHERE
+  id = ?
+LIMIT
+  1;
+
+-- name: ListGamesEndedBetween :many
+SELECT
+  *
+FROM
+  games
+WHERE
+  ended_at < sqlc.arg(ended_before)
+  AND (
+    ended_at > sqlc.arg(cursor_ended_at)
+    OR (
+      ended_at = sqlc.arg(cursor_ended_at)
+      AND id > sqlc.arg(cursor_id)
+    )
+  )
+ORDER BY
+  ended_at,
+  id
+LIMIT
+  sqlc.arg(limit);
+
+-- name: ListNotificationPreferences :many
+SELECT
+  *
+FROM
+  notification_preferences
+WHERE
+  user_id = ?;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO
+  notification_preferences (user_id, event, channel, enabled)
+VALUES
+  (?, ?, ?, ?) ON CONFLICT (user_id, event, channel) DO
+UPDATE
+SET
+  enabled = excluded.enabled,
+  updated_at = CURRENT_TIMESTAMP;
+
+-- name: CreateApiKey :exec
+INSERT INTO
+  api_keys (id, organization, key_hash, scopes)
+VALUES
+  (?, ?, ?, ?);
+
+-- name: GetApiKeyByHash :one
+SELECT
+  *
+FROM
+  api_keys
+WHERE
+  key_hash = ?
+  AND revoked_at IS NULL
+LIMIT
+  1;
+
+-- name: ListApiKeys :many
+SELECT
+  *
+FROM
+  api_keys
+ORDER BY
+  created_at,
+  id;
+
+-- name: RevokeApiKey :execrows
+UPDATE api_keys
+SET
+  revoked_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?
+  AND revoked_at IS NULL;
+
+-- name: SaveLiveGame :exec
+INSERT INTO
+  live_games (
+    id,
+    white_id,
+    black_id,
+    game_length,
+    increment,
+    variant,
+    moves,
+    board,
+    white_time,
+    black_time,
+    clocks,
+    created_at,
+    updated_at,
+    berserk
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO
+UPDATE
+SET
+  moves = excluded.moves,
+  board = excluded.board,
+  white_time = excluded.white_time,
+  black_time = excluded.black_time,
+  clocks = excluded.clocks,
+  updated_at = excluded.updated_at,
+  berserk = excluded.berserk;
+
+-- name: ListLiveGames :many
+SELECT
+  *
+FROM
+  live_games
+ORDER BY
+  created_at,
+  id;
+
+-- name: DeleteLiveGame :exec
+DELETE FROM live_games
+WHERE
+  id = ?;
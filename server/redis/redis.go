@@ -0,0 +1,261 @@
+// Package redis is a small client for the few redis commands the game
+// server needs to share games between nodes, pub/sub and leases kept as
+// keys with an expiry. It speaks RESP2 so works with redis and its forks
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dialTimeout = 5 * time.Second
+
+// An error reply from the server
+type Error string
+
+func (err Error) Error() string {
+	return "redis: " + string(err)
+}
+
+var errUnexpectedReply = errors.New("redis: unexpected reply")
+
+// Takes the lease when it's free, extends it when node already holds it
+const acquireScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0`
+
+// Only deletes the lease if node still holds it
+const releaseScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+type options struct {
+	addr     string
+	password string
+	db       int
+}
+
+// e.g. redis://:password@localhost:6379/0, a bare host:port works too
+func parseUrl(str string) (options, error) {
+	if !strings.Contains(str, "://") {
+		return options{addr: str}, nil
+	}
+	parsed, err := url.Parse(str)
+	if err != nil {
+		return options{}, err
+	}
+	if parsed.Scheme != "redis" {
+		return options{}, fmt.Errorf("redis: unsupported scheme %s", parsed.Scheme)
+	}
+
+	opts := options{addr: parsed.Host}
+	if parsed.Port() == "" {
+		opts.addr = net.JoinHostPort(parsed.Hostname(), "6379")
+	}
+	if parsed.User != nil {
+		opts.password, _ = parsed.User.Password()
+	}
+	if db := strings.TrimPrefix(parsed.Path, "/"); db != "" {
+		opts.db, err = strconv.Atoi(db)
+		if err != nil {
+			return options{}, fmt.Errorf("redis: invalid db %s", db)
+		}
+	}
+	return opts, nil
+}
+
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+}
+
+func (conn *conn) do(args ...string) (any, error) {
+	err := writeCommand(conn.netConn, args)
+	if err != nil {
+		return nil, err
+	}
+	return readReply(conn.reader)
+}
+
+// Commands share one connection, subscriptions get their own as a
+// subscribed connection can't be used for anything else
+type Client struct {
+	opts options
+	lock sync.Mutex
+	// nil after an error until the next command redials
+	conn *conn
+}
+
+func Dial(ctx context.Context, redisUrl string) (*Client, error) {
+	opts, err := parseUrl(redisUrl)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{opts: opts}
+	client.conn, err = client.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (client *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", client.opts.addr)
+	if err != nil {
+		return nil, err
+	}
+	ret := &conn{netConn: netConn, reader: bufio.NewReader(netConn)}
+
+	if client.opts.password != "" {
+		_, err = ret.do("AUTH", client.opts.password)
+	}
+	if err == nil && client.opts.db != 0 {
+		_, err = ret.do("SELECT", strconv.Itoa(client.opts.db))
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (client *Client) Close() error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if client.conn == nil {
+		return nil
+	}
+	err := client.conn.netConn.Close()
+	client.conn = nil
+	return err
+}
+
+func (client *Client) do(ctx context.Context, args ...string) (any, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if client.conn == nil {
+		var err error
+		client.conn, err = client.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		client.conn.netConn.SetDeadline(deadline)
+	} else {
+		client.conn.netConn.SetDeadline(time.Time{})
+	}
+
+	reply, err := client.conn.do(args...)
+	// error replies leave the connection usable, anything else doesn't
+	var replyErr Error
+	if err != nil && !errors.As(err, &replyErr) {
+		client.conn.netConn.Close()
+		client.conn = nil
+	}
+	return reply, err
+}
+
+func (client *Client) Publish(ctx context.Context, channel string, payload []byte) error {
+	_, err := client.do(ctx, "PUBLISH", channel, string(payload))
+	return err
+}
+
+// Messages sent on channel until ctx is done or the connection drops, the
+// returned channel is closed then
+func (client *Client) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	conn, err := client.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do("SUBSCRIBE", channel)
+	if err == nil && !isPush(reply, "subscribe") {
+		err = errUnexpectedReply
+	}
+	if err != nil {
+		conn.netConn.Close()
+		return nil, err
+	}
+
+	messages := make(chan []byte, 16)
+	go func() {
+		<-ctx.Done()
+		conn.netConn.Close()
+	}()
+	go func() {
+		defer close(messages)
+		for {
+			reply, err := readReply(conn.reader)
+			if err != nil {
+				return
+			}
+			// ["message", channel, payload]
+			if !isPush(reply, "message") {
+				continue
+			}
+			payload, _ := reply.([]any)[2].([]byte)
+			select {
+			case messages <- payload:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
+
+func isPush(reply any, kind string) bool {
+	array, ok := reply.([]any)
+	if !ok || len(array) != 3 {
+		return false
+	}
+	str, ok := array[0].([]byte)
+	return ok && string(str) == kind
+}
+
+func (client *Client) AcquireLease(ctx context.Context, key string, node string, ttl time.Duration) (bool, error) {
+	reply, err := client.do(ctx, "EVAL", acquireScript, "1", key, node,
+		strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	acquired, ok := reply.(int64)
+	if !ok {
+		return false, errUnexpectedReply
+	}
+	return acquired == 1, nil
+}
+
+func (client *Client) LeaseHolder(ctx context.Context, key string) (string, bool, error) {
+	reply, err := client.do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return "", false, err
+	}
+	node, ok := reply.([]byte)
+	if !ok {
+		return "", false, errUnexpectedReply
+	}
+	return string(node), true, nil
+}
+
+func (client *Client) ReleaseLease(ctx context.Context, key string, node string) error {
+	_, err := client.do(ctx, "EVAL", releaseScript, "1", key, node)
+	return err
+}
@@ -0,0 +1,221 @@
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Just enough of a redis server for the client's commands, the lease
+// scripts are recognised rather than run
+type fakeServer struct {
+	listener    net.Listener
+	lock        sync.Mutex
+	keys        map[string]string
+	subscribers map[string][]net.Conn
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeServer{
+		listener:    listener,
+		keys:        make(map[string]string),
+		subscribers: make(map[string][]net.Conn),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func bulk(str string) string {
+	return "$" + strconv.Itoa(len(str)) + "\r\n" + str + "\r\n"
+}
+
+func (server *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		command, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		args := make([]string, 0)
+		for _, arg := range command.([]any) {
+			args = append(args, string(arg.([]byte)))
+		}
+		conn.Write([]byte(server.handle(conn, args)))
+	}
+}
+
+func (server *fakeServer) handle(conn net.Conn, args []string) string {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	switch args[0] {
+	case "GET":
+		value, found := server.keys[args[1]]
+		if !found {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "SUBSCRIBE":
+		server.subscribers[args[1]] = append(server.subscribers[args[1]], conn)
+		return "*3\r\n" + bulk("subscribe") + bulk(args[1]) + ":1\r\n"
+	case "PUBLISH":
+		subscribers := server.subscribers[args[1]]
+		for _, subscriber := range subscribers {
+			subscriber.Write([]byte("*3\r\n" + bulk("message") + bulk(args[1]) + bulk(args[2])))
+		}
+		return ":" + strconv.Itoa(len(subscribers)) + "\r\n"
+	case "EVAL":
+		key, node := args[3], args[4]
+		holder, held := server.keys[key]
+		switch args[1] {
+		case acquireScript:
+			if held && holder != node {
+				return ":0\r\n"
+			}
+			server.keys[key] = node
+			return ":1\r\n"
+		case releaseScript:
+			if holder != node {
+				return ":0\r\n"
+			}
+			delete(server.keys, key)
+			return ":1\r\n"
+		}
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func TestReadReply(t *testing.T) {
+	input := "+OK\r\n-ERR bad\r\n:42\r\n$5\r\nhe\r\no\r\n$-1\r\n*2\r\n:1\r\n$1\r\nx\r\n"
+	reader := bufio.NewReader(bytes.NewBufferString(input))
+
+	reply, err := readReply(reader)
+	if err != nil || reply != "OK" {
+		t.Errorf("Expected OK, got %v %v", reply, err)
+	}
+	_, err = readReply(reader)
+	if err != Error("ERR bad") {
+		t.Errorf("Expected an error reply, got %v", err)
+	}
+	reply, err = readReply(reader)
+	if err != nil || reply != int64(42) {
+		t.Errorf("Expected 42, got %v %v", reply, err)
+	}
+	// bulk strings can hold line breaks
+	reply, err = readReply(reader)
+	if err != nil || string(reply.([]byte)) != "he\r\no" {
+		t.Errorf("Expected a bulk string, got %v %v", reply, err)
+	}
+	reply, err = readReply(reader)
+	if err != nil || reply != nil {
+		t.Errorf("Expected null, got %v %v", reply, err)
+	}
+	reply, err = readReply(reader)
+	array, ok := reply.([]any)
+	if err != nil || !ok || len(array) != 2 || array[0] != int64(1) || string(array[1].([]byte)) != "x" {
+		t.Errorf("Expected an array, got %v %v", reply, err)
+	}
+}
+
+func TestParseUrl(t *testing.T) {
+	opts, err := parseUrl("redis://:secret@cache:6380/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.addr != "cache:6380" || opts.password != "secret" || opts.db != 2 {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+	opts, err = parseUrl("redis://cache")
+	if err != nil || opts.addr != "cache:6379" {
+		t.Errorf("Expected the default port, got %+v %v", opts, err)
+	}
+	_, err = parseUrl("http://cache")
+	if err == nil {
+		t.Error("Expected other schemes to be rejected")
+	}
+}
+
+func TestPubSubAndLeases(t *testing.T) {
+	server := newFakeServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := Dial(ctx, server.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	messages, err := client.Subscribe(subCtx, "game:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Publish(ctx, "game:1", []byte(`{"type":"move"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message := <-messages; string(message) != `{"type":"move"}` {
+		t.Errorf("Unexpected message %s", message)
+	}
+	unsubscribe()
+	for range messages {
+	}
+
+	acquired, err := client.AcquireLease(ctx, "lease", "a", time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("Expected the free lease to be taken, got %t %v", acquired, err)
+	}
+	acquired, err = client.AcquireLease(ctx, "lease", "b", time.Second)
+	if err != nil || acquired {
+		t.Fatalf("Expected a held lease not to be taken, got %t %v", acquired, err)
+	}
+	holder, held, err := client.LeaseHolder(ctx, "lease")
+	if err != nil || !held || holder != "a" {
+		t.Errorf("Expected a to hold the lease, got %s %t %v", holder, held, err)
+	}
+
+	// only the holder can release it
+	err = client.ReleaseLease(ctx, "lease", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.ReleaseLease(ctx, "lease", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, held, err = client.LeaseHolder(ctx, "lease")
+	if err != nil || held {
+		t.Errorf("Expected the lease to be released, got %t %v", held, err)
+	}
+
+	_, err = client.do(ctx, "NOPE")
+	if _, isError := err.(Error); !isError {
+		t.Errorf("Expected an error reply, got %v", err)
+	}
+	// the connection is still usable after an error reply
+	_, _, err = client.LeaseHolder(ctx, "lease")
+	if err != nil {
+		t.Error(err)
+	}
+}
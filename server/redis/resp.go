@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// RESP2, commands are sent as arrays of bulk strings and replies are one of
+// simple strings, errors, integers, bulk strings or arrays of those
+
+// Limits what a bad reply can make the client allocate
+const maxBulkLength = 512 * 1024 * 1024
+
+func writeCommand(writer io.Writer, args []string) error {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, "\r\n"...)
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	_, err := writer.Write(buf)
+	return err
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// Simple strings are returned as strings, bulk strings as []byte, integers
+// as int64, arrays as []any and nulls as nil. Error replies are returned as
+// an Error
+func readReply(reader *bufio.Reader) (any, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errUnexpectedReply
+	}
+
+	kind, rest := line[0], line[1:]
+	switch kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, Error(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		if length > maxBulkLength {
+			return nil, fmt.Errorf("redis: bulk string of %d bytes", length)
+		}
+		// the data is followed by \r\n
+		data := make([]byte, length+2)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return nil, err
+		}
+		return data[:length], nil
+	case '*':
+		length, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		array := make([]any, 0, min(length, 64))
+		for range length {
+			element, err := readReply(reader)
+			// an error in an array is a value, e.g. from EXEC
+			if _, isError := err.(Error); err != nil && !isError {
+				return nil, err
+			}
+			if err != nil {
+				element = err
+			}
+			array = append(array, element)
+		}
+		return array, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
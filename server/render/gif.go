@@ -0,0 +1,62 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+
+	"chess/board"
+)
+
+// every colour the renderer draws with, so frames can be paletted exactly
+var palette = color.Palette{
+	lightSquare,
+	darkSquare,
+	lightLastMove,
+	darkLastMove,
+	whitePiece,
+	blackPiece,
+}
+
+// the final position is held for longer so the result can be seen before
+// the animation loops
+const finalFrameHold = 4
+
+// Animates the game from start, one frame per move with the move highlighted.
+// start isn't changed
+func GIF(
+	writer io.Writer,
+	start *board.BoardState,
+	moves []board.Move,
+	opts Options,
+	frameDelay time.Duration,
+) error {
+	state := start.Clone()
+	// gif delays are in hundredths of a second
+	delay := max(int(frameDelay/(10*time.Millisecond)), 1)
+
+	anim := gif.GIF{}
+	addFrame := func(lastMove *board.Move) {
+		opts.LastMove = lastMove
+		frame := Image(state, opts)
+		paletted := image.NewPaletted(frame.Bounds(), palette)
+		draw.Draw(paletted, paletted.Bounds(), frame, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	addFrame(nil)
+	for i := range moves {
+		err := state.MakeMove(moves[i])
+		if err != nil {
+			return err
+		}
+		addFrame(&moves[i])
+	}
+	anim.Delay[len(anim.Delay)-1] = delay * finalFrameHold
+
+	return gif.EncodeAll(writer, &anim)
+}
@@ -0,0 +1,202 @@
+// Draws positions as images for link previews and spectator thumbnails
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"chess/board"
+)
+
+const DefaultSquareSize = 45
+
+var (
+	lightSquare   = color.RGBA{0xf0, 0xd9, 0xb5, 0xff}
+	darkSquare    = color.RGBA{0xb5, 0x88, 0x63, 0xff}
+	lightLastMove = color.RGBA{0xcd, 0xd2, 0x6a, 0xff}
+	darkLastMove  = color.RGBA{0xaa, 0xa2, 0x3a, 0xff}
+	whitePiece    = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	blackPiece    = color.RGBA{0x22, 0x22, 0x22, 0xff}
+)
+
+type Options struct {
+	// pixels, DefaultSquareSize when zero
+	SquareSize int
+	// rotates the board so rank 8 is at the top, for black's point of view
+	Flip bool
+	// the move highlighted on the board, usually the last one played
+	LastMove *board.Move
+}
+
+func (opts Options) squareSize() int {
+	if opts.SquareSize <= 0 {
+		return DefaultSquareSize
+	}
+	return opts.SquareSize
+}
+
+// The board is laid out as the FEN is, with H1 in the top left, unless
+// flipped to white's side
+func (opts Options) orientation() board.Orientation {
+	if opts.Flip {
+		return board.WhiteOrientation
+	}
+	return board.BlackOrientation
+}
+
+// The square drawn at column col and row row of the image
+func (opts Options) squareAt(col, row int) board.Position {
+	return opts.orientation().Square(col, row)
+}
+
+func (opts Options) highlighted(pos board.Position) bool {
+	return opts.LastMove != nil && (pos == opts.LastMove.From || pos == opts.LastMove.To)
+}
+
+func squareColour(pos board.Position, highlighted bool) color.RGBA {
+	light := (pos.X+pos.Y)%2 == 0
+	switch {
+	case light && highlighted:
+		return lightLastMove
+	case highlighted:
+		return darkLastMove
+	case light:
+		return lightSquare
+	default:
+		return darkSquare
+	}
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Pieces are drawn with the unicode chess glyphs so how they look depends on
+// the fonts of whatever displays the image
+func SVG(state *board.BoardState, opts Options) []byte {
+	size := opts.squareSize()
+
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		size*8, size*8, size*8, size*8)
+	for row := range 8 {
+		for col := range 8 {
+			pos := opts.squareAt(col, row)
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`,
+				col*size, row*size, size, size, hex(squareColour(pos, opts.highlighted(pos))))
+		}
+	}
+
+	fmt.Fprintf(&buf,
+		`<g font-family="sans-serif" font-size="%d" text-anchor="middle" dominant-baseline="central">`,
+		size*4/5)
+	for row := range 8 {
+		for col := range 8 {
+			piece := state.GetSquare(opts.squareAt(col, row))
+			if piece.IsClear() {
+				continue
+			}
+			// the filled glyphs read better at small sizes, the colour tells
+			// the sides apart
+			fill, stroke := whitePiece, blackPiece
+			if piece.IsBlack() {
+				fill, stroke = blackPiece, whitePiece
+			}
+			filled := board.Piece(piece.PieceType())<<board.PieceShift | board.Piece(board.Black)
+			fmt.Fprintf(&buf,
+				`<text x="%d" y="%d" fill="%s" stroke="%s" stroke-width="1">%c</text>`,
+				col*size+size/2, row*size+size/2, hex(fill), hex(stroke), filled.Rune())
+		}
+	}
+	buf.WriteString(`</g></svg>`)
+	return buf.Bytes()
+}
+
+func FenSVG(fen string, opts Options) ([]byte, error) {
+	state, err := board.ParseFen(fen)
+	if err != nil {
+		return nil, err
+	}
+	return SVG(state, opts), nil
+}
+
+// 5x7 letters drawn on the pieces, there's no font rendering in the standard
+// library
+var pieceLetters = [...][7]uint8{
+	board.King:   {0b10001, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010, 0b10001},
+	board.Queen:  {0b01110, 0b10001, 0b10001, 0b10001, 0b10101, 0b10010, 0b01101},
+	board.Bishop: {0b11110, 0b10001, 0b10001, 0b11110, 0b10001, 0b10001, 0b11110},
+	board.Knight: {0b10001, 0b11001, 0b10101, 0b10101, 0b10011, 0b10001, 0b10001},
+	board.Pawn:   {0b11110, 0b10001, 0b10001, 0b11110, 0b10000, 0b10000, 0b10000},
+	board.Rook:   {0b11110, 0b10001, 0b10001, 0b11110, 0b10100, 0b10010, 0b10001},
+}
+
+// Pieces are discs in the colour of their side with the piece's letter on top
+func Image(state *board.BoardState, opts Options) image.Image {
+	size := opts.squareSize()
+	img := image.NewRGBA(image.Rect(0, 0, size*8, size*8))
+
+	for row := range 8 {
+		for col := range 8 {
+			pos := opts.squareAt(col, row)
+			fillRect(img, col*size, row*size, size, size, squareColour(pos, opts.highlighted(pos)))
+
+			piece := state.GetSquare(pos)
+			if !piece.IsClear() {
+				drawPiece(img, piece, col*size, row*size, size)
+			}
+		}
+	}
+	return img
+}
+
+func PNG(writer io.Writer, state *board.BoardState, opts Options) error {
+	return png.Encode(writer, Image(state, opts))
+}
+
+func fillRect(img *image.RGBA, x, y, width, height int, c color.RGBA) {
+	for dy := range height {
+		for dx := range width {
+			img.SetRGBA(x+dx, y+dy, c)
+		}
+	}
+}
+
+func drawPiece(img *image.RGBA, piece board.Piece, x, y, size int) {
+	fill, ink := whitePiece, blackPiece
+	if piece.IsBlack() {
+		fill, ink = blackPiece, whitePiece
+	}
+
+	centre := size / 2
+	outer := size * 2 / 5
+	inner := outer - max(size/20, 1)
+	for dy := range size {
+		for dx := range size {
+			distX, distY := dx-centre, dy-centre
+			dist := distX*distX + distY*distY
+			if dist <= inner*inner {
+				img.SetRGBA(x+dx, y+dy, fill)
+			} else if dist <= outer*outer {
+				img.SetRGBA(x+dx, y+dy, ink)
+			}
+		}
+	}
+
+	letter := pieceLetters[piece.PieceType()]
+	scale := max(size/16, 1)
+	left := x + centre - 5*scale/2
+	top := y + centre - 7*scale/2
+	for row, bits := range letter {
+		for col := range 5 {
+			if bits&(1<<(4-col)) != 0 {
+				fillRect(img, left+col*scale, top+row*scale, scale, scale, ink)
+			}
+		}
+	}
+}
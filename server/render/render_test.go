@@ -0,0 +1,132 @@
+package render_test
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/board"
+	"chess/render"
+)
+
+func Test_svg(test *testing.T) {
+	test.Run("starting position", func(test *testing.T) {
+		test.Parallel()
+		state := board.NewBoard()
+		pieces := 0
+		for _, piece := range state.State {
+			if !piece.IsClear() {
+				pieces += 1
+			}
+		}
+
+		svg := string(render.SVG(state, render.Options{}))
+		if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+			test.Fatalf("not an svg document: %s", svg)
+		}
+		if count := strings.Count(svg, "<rect"); count != 64 {
+			test.Fatalf("expected 64 squares, received %d", count)
+		}
+		if count := strings.Count(svg, "<text"); count != pieces {
+			test.Fatalf("expected %d pieces, received %d", pieces, count)
+		}
+	})
+
+	test.Run("fen", func(test *testing.T) {
+		test.Parallel()
+		svg, err := render.FenSVG("k7/8/8/8/8/8/8/7K w 0", render.Options{SquareSize: 10})
+		if err != nil {
+			test.Fatal(err)
+		}
+		if !strings.Contains(string(svg), `width="80"`) {
+			test.Fatalf("expected an 80px wide image: %s", svg)
+		}
+		if count := strings.Count(string(svg), "<text"); count != 2 {
+			test.Fatalf("expected 2 pieces, received %d", count)
+		}
+
+		_, err = render.FenSVG("not a fen", render.Options{})
+		if err == nil {
+			test.Fatal("expected an error for an invalid fen")
+		}
+	})
+}
+
+func Test_png(test *testing.T) {
+	test.Parallel()
+	move, err := board.DeserialiseMove("D1:C2")
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	buf := bytes.Buffer{}
+	err = render.PNG(&buf, board.NewBoard(), render.Options{SquareSize: 12, LastMove: &move})
+	if err != nil {
+		test.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if img.Bounds().Dx() != 96 || img.Bounds().Dy() != 96 {
+		test.Fatalf("expected a 96x96 image, received %v", img.Bounds())
+	}
+
+	plain := render.Image(board.NewBoard(), render.Options{SquareSize: 12})
+	// the corner of the square isn't covered by the piece
+	x, y := int(move.To.X)*12, int(move.To.Y)*12
+	if img.At(x, y) == plain.At(x, y) {
+		test.Fatal("last move wasn't highlighted")
+	}
+}
+
+func Test_gif(test *testing.T) {
+	test.Parallel()
+	start := board.NewBoard()
+	err := start.Init()
+	if err != nil {
+		test.Fatal(err)
+	}
+	moves := []board.Move{}
+	for _, str := range []string{"D1:C2", "E8:F7", "F2:E4"} {
+		move, err := board.DeserialiseMove(str)
+		if err != nil {
+			test.Fatal(err)
+		}
+		moves = append(moves, move)
+	}
+
+	buf := bytes.Buffer{}
+	err = render.GIF(&buf, start, moves, render.Options{SquareSize: 8}, 500*time.Millisecond)
+	if err != nil {
+		test.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		test.Fatal(err)
+	}
+	assertNumEq(test, len(moves)+1, len(anim.Image))
+	assertNumEq(test, 50, anim.Delay[0])
+	if len(start.MoveHistory) != 0 {
+		test.Fatal("the starting board was changed")
+	}
+
+	bad, err := board.DeserialiseMove("A1:A8")
+	if err != nil {
+		test.Fatal(err)
+	}
+	err = render.GIF(&bytes.Buffer{}, start, []board.Move{bad}, render.Options{}, time.Second)
+	if err == nil {
+		test.Fatal("expected an error for an illegal move")
+	}
+}
+
+func assertNumEq(test *testing.T, expected, received int) {
+	test.Helper()
+	if expected != received {
+		test.Fatalf("expected %d, received %d", expected, received)
+	}
+}
@@ -23,6 +23,82 @@ CREATE INDEX idx_sessions_user_id ON sessions (user_id);
 
 CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
 
+CREATE TABLE IF NOT EXISTS games (
+  id TEXT PRIMARY KEY NOT NULL,
+  white_id TEXT NOT NULL,
+  black_id TEXT NOT NULL,
+  -- milliseconds
+  game_length INTEGER NOT NULL,
+  increment INTEGER NOT NULL,
+  result TEXT NOT NULL,
+  condition TEXT NOT NULL,
+  -- space separated moves in coords format
+  moves TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  ended_at TIMESTAMP NOT NULL,
+  -- variant name, followed by the setup for chess960 e.g. chess960:518
+  variant TEXT NOT NULL DEFAULT 'diagonal',
+  -- why the game ended e.g. checkmate, time forfeit or agreement
+  termination TEXT NOT NULL DEFAULT '',
+  -- position the game ended in, empty for games stored before it was kept
+  final_fen TEXT NOT NULL DEFAULT '',
+  -- json post-game summary, empty for games stored before it was kept
+  summary TEXT NOT NULL DEFAULT '',
+  -- json move times and client details for fair-play checks, empty for
+  -- games stored before it was kept
+  telemetry TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_games_ended_at ON games (ended_at, id);
+
+-- games still being played, rewritten every so often and on shutdown so
+-- they can carry on after a restart. Removed once the game ends
+CREATE TABLE IF NOT EXISTS live_games (
+  id TEXT PRIMARY KEY NOT NULL,
+  white_id TEXT NOT NULL,
+  black_id TEXT NOT NULL,
+  -- milliseconds
+  game_length INTEGER NOT NULL,
+  increment INTEGER NOT NULL,
+  variant TEXT NOT NULL,
+  -- space separated moves in coords format
+  moves TEXT NOT NULL,
+  -- the position after the moves in the board package's binary format
+  board BLOB NOT NULL,
+  -- milliseconds left when the snapshot was taken
+  white_time INTEGER NOT NULL,
+  black_time INTEGER NOT NULL,
+  -- json list of the times left after each move
+  clocks TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  -- colours which berserked e.g. wb for both
+  berserk TEXT NOT NULL DEFAULT ''
+);
+
+-- only choices which differ from the defaults need a row
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  user_id TEXT NOT NULL,
+  event TEXT NOT NULL,
+  channel TEXT NOT NULL,
+  enabled BOOLEAN NOT NULL,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  PRIMARY KEY (user_id, event, channel)
+);
+
+-- keys for organisations e.g. clubs and tournament organisers, separate
+-- from user sessions
+CREATE TABLE IF NOT EXISTS api_keys (
+  id TEXT PRIMARY KEY NOT NULL,
+  organization TEXT NOT NULL,
+  -- sha256 of the key, the key itself is only shown when it's created
+  key_hash TEXT NOT NULL UNIQUE,
+  -- space separated e.g. results:read
+  scopes TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP
+);
+
 -- CREATE TABLE oauth_tokens (
 --   token_id INTEGER PRIMARY KEY AUTOINCREMENT,
 --   user_id INTEGER NOT NULL,
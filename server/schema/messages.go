@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"chess/admin"
+	"chess/board"
+	"chess/game_server"
+	"chess/matchmaking_server"
+	"chess/notification"
+	"chess/status"
+	"chess/verify"
+)
+
+//go:generate go run ../cmd/schemagen ../../web/src/library/schema.gen.ts
+
+var Messages = Registry{
+	"ActiveGames":             game_server.ActiveGamesResponse{},
+	"AdminSessions":           game_server.AdminSessionsResponse{},
+	"ApiKey":                  admin.ApiKeyResponse{},
+	"ClockAudit":              game_server.ClockAuditReport{},
+	"GameEvent":               game_server.Event{},
+	"GameSnapshot":            game_server.GameSnapshot{},
+	"GameTelemetry":           game_server.GameTelemetry{},
+	"LobbyEvent":              game_server.LobbyEvent{},
+	"MyGames":                 game_server.MyGamesResponse{},
+	"MyTurn":                  game_server.MyTurnResponse{},
+	"MyTurnCount":             game_server.MyTurnCount{},
+	"NotificationPreferences": notification.Preferences{},
+	"QueueResponse":           matchmaking_server.QueueResponse{},
+	"Replay":                  game_server.ReplayResponse{},
+	"Status":                  status.Status{},
+	"Vacation":                game_server.VacationStatus{},
+	"VariantRules":            board.VariantRules{},
+	"Verification":            verify.Report{},
+}
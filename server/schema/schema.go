@@ -0,0 +1,181 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Registry maps message names to an example value of the go type that is
+// serialised onto the wire, it's used to generate json schema for /schema
+// and typescript definitions for the frontend so neither side can drift
+type Registry map[string]any
+
+type Schema = map[string]any
+
+type field struct {
+	name     string
+	optional bool
+	typ      reflect.Type
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func jsonFields(typ reflect.Type) []field {
+	fields := make([]field, 0, typ.NumField())
+	for i := range typ.NumField() {
+		structField := typ.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		tag := structField.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = structField.Name
+		}
+
+		fieldType := structField.Type
+		optional := strings.Contains(options, "omitempty")
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		// embedded structs are flattened like encoding/json does
+		if structField.Anonymous && tag == "" && fieldType.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(fieldType)...)
+			continue
+		}
+
+		fields = append(fields, field{name, optional, fieldType})
+	}
+	return fields
+}
+
+func JsonSchema(typ reflect.Type) Schema {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == timeType {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": JsonSchema(typ.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": JsonSchema(typ.Elem())}
+	case reflect.Struct:
+		properties := Schema{}
+		required := make([]string, 0)
+		for _, field := range jsonFields(typ) {
+			properties[field.name] = JsonSchema(field.typ)
+			if !field.optional {
+				required = append(required, field.name)
+			}
+		}
+		return Schema{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	default:
+		return Schema{}
+	}
+}
+
+func typescriptType(typ reflect.Type) string {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == timeType {
+		return "string"
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return typescriptType(typ.Elem()) + "[]"
+	case reflect.Map:
+		return fmt.Sprintf("Record<string, %s>", typescriptType(typ.Elem()))
+	case reflect.Struct:
+		builder := strings.Builder{}
+		builder.WriteString("{\n")
+		for _, field := range jsonFields(typ) {
+			optional := ""
+			if field.optional {
+				optional = "?"
+			}
+			fmt.Fprintf(&builder, "  %s%s: %s\n", field.name, optional, typescriptType(field.typ))
+		}
+		builder.WriteString("}")
+		return builder.String()
+	default:
+		return "unknown"
+	}
+}
+
+func (registry Registry) names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (registry Registry) JsonSchema() Schema {
+	definitions := Schema{}
+	for name, value := range registry {
+		definitions[name] = JsonSchema(reflect.TypeOf(value))
+	}
+	return Schema{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"definitions": definitions,
+	}
+}
+
+func (registry Registry) TypeScript() string {
+	builder := strings.Builder{}
+	builder.WriteString("// Code generated by cmd/schemagen. DO NOT EDIT.\n")
+	for _, name := range registry.names() {
+		fmt.Fprintf(&builder, "\nexport type %s = %s\n",
+			name, typescriptType(reflect.TypeOf(registry[name])))
+	}
+	return builder.String()
+}
+
+func (registry Registry) Handler() http.HandlerFunc {
+	bytes, err := json.Marshal(registry.JsonSchema())
+	if err != nil {
+		panic(err)
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		writer.Header().Add("Content-Type", "application/schema+json")
+		writer.Write(bytes)
+	}
+}
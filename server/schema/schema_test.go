@@ -0,0 +1,31 @@
+package schema_test
+
+import (
+	"os"
+	"testing"
+
+	"chess/schema"
+)
+
+func Test_generated_typescript(test *testing.T) {
+	test.Run("test frontend definitions are up to date", func(test *testing.T) {
+		bytes, err := os.ReadFile("../../web/src/library/schema.gen.ts")
+		if err != nil {
+			test.Fatal(err)
+		}
+		if string(bytes) != schema.Messages.TypeScript() {
+			test.Fatal("web/src/library/schema.gen.ts is out of date, run go generate ./schema")
+		}
+	})
+
+	test.Run("test event schema has fields", func(test *testing.T) {
+		definitions := schema.Messages.JsonSchema()["definitions"].(schema.Schema)
+		event := definitions["GameEvent"].(schema.Schema)
+		properties := event["properties"].(schema.Schema)
+		for _, name := range []string{"type", "fen", "moveHistory", "legalMoves"} {
+			if _, found := properties[name]; !found {
+				test.Fatalf("expected GameEvent to have property %s", name)
+			}
+		}
+	})
+}
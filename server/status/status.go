@@ -0,0 +1,149 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	cacheDuration   = 10 * time.Second
+	errorRateWindow = 5 * time.Minute
+)
+
+type GameStats interface {
+	// active games and connected players and viewers
+	Stats() (activeGames int, playersOnline int)
+}
+
+type QueueStats interface {
+	// players waiting in each pool keyed by format
+	QueueSizes() map[string]int
+}
+
+type Status struct {
+	UptimeSeconds int64          `json:"uptimeSeconds"`
+	ActiveGames   int            `json:"activeGames"`
+	PlayersOnline int            `json:"playersOnline"`
+	QueueSizes    map[string]int `json:"queueSizes"`
+	// errors logged per minute over the last few minutes
+	ErrorRate float64 `json:"errorRate"`
+}
+
+// public health data for the status page, cached so the widget being
+// polled by lots of clients doesn't lock the game and queue maps each time
+type StatusServer struct {
+	startedAt time.Time
+	games     GameStats
+	queues    QueueStats
+	errors    *ErrorCounter
+
+	lock     sync.Mutex
+	cached   []byte
+	cachedAt time.Time
+}
+
+func NewStatusServer(
+	games GameStats,
+	queues QueueStats,
+	errors *ErrorCounter,
+) *StatusServer {
+	return &StatusServer{
+		startedAt: time.Now(),
+		games:     games,
+		queues:    queues,
+		errors:    errors,
+	}
+}
+
+func (server *StatusServer) Status() Status {
+	activeGames, playersOnline := server.games.Stats()
+	return Status{
+		UptimeSeconds: int64(time.Since(server.startedAt).Seconds()),
+		ActiveGames:   activeGames,
+		PlayersOnline: playersOnline,
+		QueueSizes:    server.queues.QueueSizes(),
+		ErrorRate:     server.errors.Rate(errorRateWindow),
+	}
+}
+
+func (server *StatusServer) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	server.lock.Lock()
+	if server.cached == nil || time.Since(server.cachedAt) > cacheDuration {
+		bytes, err := json.Marshal(server.Status())
+		if err != nil {
+			server.lock.Unlock()
+			slog.ErrorContext(req.Context(), "error", slog.Any("error", err))
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		server.cached = bytes
+		server.cachedAt = time.Now()
+	}
+	bytes := server.cached
+	server.lock.Unlock()
+
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Header().Add("Cache-Control", "public, max-age=10")
+	writer.Write(bytes)
+}
+
+// slog handler which counts error records before passing them on
+type ErrorCounter struct {
+	slog.Handler
+	lock   *sync.Mutex
+	errors *[]time.Time
+}
+
+func NewErrorCounter(handler slog.Handler) *ErrorCounter {
+	return &ErrorCounter{
+		Handler: handler,
+		lock:    &sync.Mutex{},
+		errors:  &[]time.Time{},
+	}
+}
+
+func (counter *ErrorCounter) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError {
+		counter.lock.Lock()
+		now := time.Now()
+		*counter.errors = append(trimBefore(*counter.errors, now.Add(-errorRateWindow)), now)
+		counter.lock.Unlock()
+	}
+	return counter.Handler.Handle(ctx, record)
+}
+
+// the counts are shared with the derived handlers
+func (counter *ErrorCounter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ErrorCounter{counter.Handler.WithAttrs(attrs), counter.lock, counter.errors}
+}
+
+func (counter *ErrorCounter) WithGroup(name string) slog.Handler {
+	return &ErrorCounter{counter.Handler.WithGroup(name), counter.lock, counter.errors}
+}
+
+func trimBefore(times []time.Time, cutoff time.Time) []time.Time {
+	index := 0
+	for index < len(times) && times[index].Before(cutoff) {
+		index += 1
+	}
+	return times[index:]
+}
+
+// errors per minute over the window
+func (counter *ErrorCounter) Rate(window time.Duration) float64 {
+	counter.lock.Lock()
+	defer counter.lock.Unlock()
+
+	*counter.errors = trimBefore(*counter.errors, time.Now().Add(-errorRateWindow))
+	count := len(trimBefore(*counter.errors, time.Now().Add(-window)))
+	return float64(count) / window.Minutes()
+}
@@ -0,0 +1,64 @@
+package status_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess/status"
+)
+
+type mockStats struct {
+	calls int
+}
+
+func (stats *mockStats) Stats() (int, int) {
+	stats.calls += 1
+	return 2, 3
+}
+
+func (stats *mockStats) QueueSizes() map[string]int {
+	return map[string]int{"5+0": 1}
+}
+
+func TestStatus(t *testing.T) {
+	counter := status.NewErrorCounter(slog.NewTextHandler(io.Discard, nil))
+	logger := slog.New(counter).With(slog.String("attr", "value"))
+	logger.Error("first")
+	logger.Error("second")
+	logger.InfoContext(context.Background(), "not counted")
+
+	stats := &mockStats{}
+	server := status.NewStatusServer(stats, stats, counter)
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", recorder.Code)
+		}
+
+		resp := status.Status{}
+		err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.ActiveGames != 2 || resp.PlayersOnline != 3 || resp.QueueSizes["5+0"] != 1 {
+			t.Errorf("Unexpected status %+v", resp)
+		}
+		if resp.ErrorRate != 2/(5*time.Minute).Minutes() {
+			t.Errorf("Expected 2 errors to be counted, got rate %f", resp.ErrorRate)
+		}
+	}
+
+	if stats.calls != 1 {
+		t.Errorf("Expected status to be cached, stats were read %d times", stats.calls)
+	}
+}
@@ -0,0 +1,52 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"chess/board"
+)
+
+// Fen in the standard layout uci engines expect: ranks from 8 down to 1,
+// files from a to h and white pieces in upper case. The square names match
+// the ones used by board.Move.UciString. There is no castling or en passant
+// in the variant so those fields are always empty
+func StandardFen(boardState *board.BoardState) string {
+	builder := strings.Builder{}
+	for rank := int8(7); rank >= 0; rank-- {
+		empty := 0
+		for file := int8(0); file < 8; file++ {
+			piece := boardState.GetSquare(board.PositionFromFileRank(file, rank))
+			if piece.IsClear() {
+				empty += 1
+				continue
+			}
+			if empty > 0 {
+				fmt.Fprintf(&builder, "%d", empty)
+				empty = 0
+			}
+			char := rune(piece.FenByte())
+			if piece.IsWhite() {
+				char = unicode.ToUpper(char)
+			} else {
+				char = unicode.ToLower(char)
+			}
+			builder.WriteRune(char)
+		}
+		if empty > 0 {
+			fmt.Fprintf(&builder, "%d", empty)
+		}
+		if rank > 0 {
+			builder.WriteByte('/')
+		}
+	}
+
+	side := "w"
+	if boardState.WhoseMove() == board.Black {
+		side = "b"
+	}
+	fmt.Fprintf(&builder, " %s - - %d %d", side,
+		boardState.CaptureMoveCounter, boardState.MoveCounter/2+1)
+	return builder.String()
+}
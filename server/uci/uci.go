@@ -0,0 +1,331 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chess/board"
+	"chess/engine"
+	"chess/eval"
+)
+
+const (
+	handshakeTimeout = 5 * time.Second
+	quitTimeout      = time.Second
+)
+
+var errClosed = errors.New("uci engine closed")
+
+// An external engine run as a subprocess and spoken to over uci. One
+// search runs at a time, concurrent calls wait for each other
+type Engine struct {
+	lock   sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan string
+	name   string
+	closed bool
+}
+
+type Limits struct {
+	// zero values are left out of the go command
+	Depth    int
+	MoveTime time.Duration
+}
+
+type Analysis struct {
+	BestMove board.Move
+	// centipawns from the perspective of the side to move
+	Score int
+	// moves until mate, negative when the side to move is getting mated
+	// and zero when no mate was found
+	Mate  int
+	Depth int
+	// principal variation
+	Pv []board.Move
+}
+
+// Launches the engine and waits for it to be ready
+func Start(ctx context.Context, path string, args ...string) (*Engine, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	uciEngine := &Engine{
+		cmd:   cmd,
+		stdin: stdin,
+		lines: make(chan string, 64),
+	}
+	go uciEngine.read(stdout)
+
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+
+	err = uciEngine.send("uci")
+	if err == nil {
+		err = uciEngine.waitFor(ctx, "uciok", func(line string) {
+			if name, found := strings.CutPrefix(line, "id name "); found {
+				uciEngine.name = name
+			}
+		})
+	}
+	if err == nil {
+		err = uciEngine.ready(ctx)
+	}
+	if err != nil {
+		uciEngine.kill()
+		return nil, fmt.Errorf("uci handshake with %s failed: %w", path, err)
+	}
+
+	slog.Info("uci engine started", slog.String("name", uciEngine.name))
+	return uciEngine, nil
+}
+
+func (uciEngine *Engine) Name() string {
+	return uciEngine.name
+}
+
+func (uciEngine *Engine) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		uciEngine.lines <- scanner.Text()
+	}
+	close(uciEngine.lines)
+}
+
+func (uciEngine *Engine) send(command string) error {
+	_, err := io.WriteString(uciEngine.stdin, command+"\n")
+	return err
+}
+
+// reads lines until one starting with prefix, the others are passed to
+// onLine if it isn't nil
+func (uciEngine *Engine) waitFor(ctx context.Context, prefix string, onLine func(string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-uciEngine.lines:
+			if !ok {
+				return errClosed
+			}
+			if strings.HasPrefix(line, prefix) {
+				if onLine != nil && line != prefix {
+					onLine(line)
+				}
+				return nil
+			}
+			if onLine != nil {
+				onLine(line)
+			}
+		}
+	}
+}
+
+func (uciEngine *Engine) ready(ctx context.Context) error {
+	err := uciEngine.send("isready")
+	if err != nil {
+		return err
+	}
+	return uciEngine.waitFor(ctx, "readyok", nil)
+}
+
+func (uciEngine *Engine) SetOption(ctx context.Context, name, value string) error {
+	uciEngine.lock.Lock()
+	defer uciEngine.lock.Unlock()
+
+	if uciEngine.closed {
+		return errClosed
+	}
+	err := uciEngine.send(fmt.Sprintf("setoption name %s value %s", name, value))
+	if err != nil {
+		return err
+	}
+	return uciEngine.ready(ctx)
+}
+
+// Runs a search on the position, if the context is cancelled the engine
+// is told to stop and its best move so far is still returned
+func (uciEngine *Engine) Analyse(
+	ctx context.Context,
+	boardState *board.BoardState,
+	limits Limits,
+) (Analysis, error) {
+	uciEngine.lock.Lock()
+	defer uciEngine.lock.Unlock()
+
+	if uciEngine.closed {
+		return Analysis{}, errClosed
+	}
+	if len(boardState.LegalMoves) == 0 {
+		return Analysis{}, errors.New("no legal moves to analyse")
+	}
+
+	err := uciEngine.send("position fen " + StandardFen(boardState))
+	if err != nil {
+		return Analysis{}, err
+	}
+
+	if limits.Depth <= 0 && limits.MoveTime <= 0 {
+		limits.MoveTime = engine.DefaultMoveTime
+	}
+	command := "go"
+	if limits.Depth > 0 {
+		command += " depth " + strconv.Itoa(limits.Depth)
+	}
+	if limits.MoveTime > 0 {
+		command += " movetime " + strconv.FormatInt(limits.MoveTime.Milliseconds(), 10)
+	}
+	err = uciEngine.send(command)
+	if err != nil {
+		return Analysis{}, err
+	}
+
+	analysis := Analysis{}
+	var bestMove string
+	stopped := false
+	for bestMove == "" {
+		select {
+		case <-ctx.Done():
+			if !stopped {
+				stopped = true
+				err = uciEngine.send("stop")
+				if err != nil {
+					return Analysis{}, err
+				}
+			}
+			ctx = context.Background()
+		case line, ok := <-uciEngine.lines:
+			if !ok {
+				return Analysis{}, errClosed
+			}
+			if rest, found := strings.CutPrefix(line, "bestmove "); found {
+				bestMove, _, _ = strings.Cut(rest, " ")
+			} else if rest, found := strings.CutPrefix(line, "info "); found {
+				parseInfo(rest, &analysis)
+			}
+		}
+	}
+
+	move, err := board.DeserialiseUciMove(bestMove)
+	if err != nil {
+		return Analysis{}, fmt.Errorf("engine sent bad best move %s: %w", bestMove, err)
+	}
+	if !boardState.IsLegal(move) {
+		return Analysis{}, fmt.Errorf("engine sent illegal best move %s", bestMove)
+	}
+	analysis.BestMove = move
+	return analysis, nil
+}
+
+// fills in the fields found in an info line, e.g.
+// depth 12 seldepth 20 score cp 35 nodes 1000 pv e2e4 e7e5
+func parseInfo(info string, analysis *Analysis) {
+	fields := strings.Fields(info)
+	// lines for other pv lines or plain strings are ignored
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "string":
+			return
+		case "multipv":
+			if i+1 < len(fields) && fields[i+1] != "1" {
+				return
+			}
+			i += 1
+		case "depth":
+			if i+1 < len(fields) {
+				analysis.Depth, _ = strconv.Atoi(fields[i+1])
+				i += 1
+			}
+		case "score":
+			if i+2 < len(fields) {
+				value, _ := strconv.Atoi(fields[i+2])
+				switch fields[i+1] {
+				case "cp":
+					analysis.Score = value
+					analysis.Mate = 0
+				case "mate":
+					analysis.Mate = value
+					if value > 0 {
+						analysis.Score = eval.MateScore - value
+					} else {
+						analysis.Score = -eval.MateScore - value
+					}
+				}
+				i += 2
+			}
+		case "pv":
+			pv := make([]board.Move, 0, len(fields)-i-1)
+			for _, str := range fields[i+1:] {
+				move, err := board.DeserialiseUciMove(str)
+				if err != nil {
+					break
+				}
+				pv = append(pv, move)
+			}
+			analysis.Pv = pv
+			return
+		}
+	}
+}
+
+// Lets the engine be used anywhere the built in engine is
+func (uciEngine *Engine) Search(ctx context.Context, boardState *board.BoardState) (engine.Result, error) {
+	analysis, err := uciEngine.Analyse(ctx, boardState, Limits{})
+	if err != nil {
+		return engine.Result{}, err
+	}
+	return engine.Result{
+		Move:  analysis.BestMove,
+		Score: analysis.Score,
+		Depth: analysis.Depth,
+	}, nil
+}
+
+func (uciEngine *Engine) kill() {
+	uciEngine.cmd.Process.Kill()
+	uciEngine.cmd.Wait()
+}
+
+// Asks the engine to quit, killing it if it doesn't in time
+func (uciEngine *Engine) Close() error {
+	uciEngine.lock.Lock()
+	defer uciEngine.lock.Unlock()
+
+	if uciEngine.closed {
+		return nil
+	}
+	uciEngine.closed = true
+
+	uciEngine.send("quit")
+	uciEngine.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- uciEngine.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(quitTimeout):
+		uciEngine.cmd.Process.Kill()
+		return <-done
+	}
+}
@@ -0,0 +1,140 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"chess/board"
+)
+
+// the test binary runs itself as a fake engine when this is set
+const fakeEngineEnv = "UCI_FAKE_ENGINE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeEngineEnv) == "1" {
+		runFakeEngine()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeEngine() {
+	scanner := bufio.NewScanner(os.Stdin)
+	fen := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "uci":
+			fmt.Println("id name fake engine")
+			fmt.Println("uciok")
+		case line == "isready":
+			fmt.Println("readyok")
+		case strings.HasPrefix(line, "position fen "):
+			fen = strings.TrimPrefix(line, "position fen ")
+		case strings.HasPrefix(line, "go"):
+			fmt.Println("info string " + fen)
+			fmt.Println("info depth 1 score cp 10 pv e8f7")
+			fmt.Println("info depth 3 seldepth 5 score cp 42 nodes 100 pv d1c2 e8f7")
+			fmt.Println("bestmove d1c2 ponder e8f7")
+		case line == "quit":
+			return
+		}
+	}
+}
+
+func startFakeEngine(t *testing.T) *Engine {
+	t.Helper()
+	t.Setenv(fakeEngineEnv, "1")
+	uciEngine, err := Start(context.Background(), os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { uciEngine.Close() })
+	return uciEngine
+}
+
+func TestStandardFen(t *testing.T) {
+	boardState := board.NewBoard()
+	err := boardState.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the start position is symmetric under the half turn
+	expected := "krbpp3/rqnp4/nbp5/pp5P/p5PP/5PBN/4PNQR/3PPBRK w - - 0 1"
+	if fen := StandardFen(boardState); fen != expected {
+		t.Fatalf("Expected %s, got %s", expected, fen)
+	}
+
+	// white knight on f2 and black queen on e4
+	boardState, err = board.ParseFen("k7/2n5/8/3Q4/8/8/8/7K w 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "k7/8/8/8/4q3/8/5N2/7K w - - 0 1"
+	if fen := StandardFen(boardState); fen != expected {
+		t.Fatalf("Expected %s, got %s", expected, fen)
+	}
+}
+
+func TestAnalyse(t *testing.T) {
+	uciEngine := startFakeEngine(t)
+	if uciEngine.Name() != "fake engine" {
+		t.Errorf("Expected engine name to be read, got %s", uciEngine.Name())
+	}
+
+	boardState := board.NewBoard()
+	err := boardState.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	analysis, err := uciEngine.Analyse(ctx, boardState, Limits{Depth: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if analysis.BestMove.Serialise() != "D1:C2" {
+		t.Errorf("Expected best move D1:C2, got %s", analysis.BestMove.Serialise())
+	}
+	if analysis.Score != 42 || analysis.Depth != 3 || len(analysis.Pv) != 2 {
+		t.Errorf("Unexpected analysis %+v", analysis)
+	}
+
+	result, err := uciEngine.Search(ctx, boardState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Move != analysis.BestMove {
+		t.Errorf("Expected search to return the best move")
+	}
+
+	err = uciEngine.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = uciEngine.Analyse(ctx, boardState, Limits{})
+	if err == nil {
+		t.Fatal("Expected analysing after close to fail")
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	analysis := Analysis{}
+	parseInfo("depth 8 score mate -3 pv h5h6", &analysis)
+	if analysis.Mate != -3 || analysis.Score >= 0 || analysis.Depth != 8 {
+		t.Errorf("Unexpected analysis %+v", analysis)
+	}
+
+	parseInfo("multipv 2 depth 9 score cp 100", &analysis)
+	if analysis.Depth != 8 {
+		t.Errorf("Expected secondary lines to be ignored, got %+v", analysis)
+	}
+}
@@ -0,0 +1,243 @@
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"chess/board"
+	"chess/engine"
+	"chess/model"
+	"chess/pgn"
+
+	"github.com/google/uuid"
+)
+
+// one move of the replay, the fen is the position after it
+type MoveCheck struct {
+	Ply   int    `json:"ply"`
+	Move  string `json:"move"`
+	Legal bool   `json:"legal"`
+	Fen   string `json:"fen,omitempty"`
+}
+
+// Proof that a stored game is playable from the start and its result
+// follows from the moves
+type Report struct {
+	GameId   string `json:"gameId"`
+	Variant  string `json:"variant"`
+	Verified bool   `json:"verified"`
+	// stops after the first illegal move
+	Moves            []MoveCheck `json:"moves"`
+	MovesLegal       bool        `json:"movesLegal"`
+	Result           string      `json:"result"`
+	Condition        string      `json:"condition"`
+	Termination      string      `json:"termination"`
+	ResultConsistent bool        `json:"resultConsistent"`
+	Problems         []string    `json:"problems,omitempty"`
+}
+
+// Replays the game and checks the stored result against the final position,
+// the tablebase is used to check adjudicated draws
+func Game(game model.Game, tablebase engine.Tablebase) Report {
+	report := Report{
+		GameId:      game.ID.String(),
+		Variant:     game.Variant,
+		Moves:       make([]MoveCheck, 0),
+		Result:      game.Result,
+		Condition:   game.Condition,
+		Termination: game.Termination,
+	}
+	problem := func(format string, args ...any) {
+		report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+	}
+
+	variant, err := board.VariantFromName(game.Variant)
+	if err != nil {
+		problem("%s", err)
+		return report
+	}
+	boardState := board.NewVariantBoard(variant)
+	err = boardState.Init()
+	if err != nil {
+		problem("%s", err)
+		return report
+	}
+
+	report.MovesLegal = true
+	for i, str := range strings.Fields(game.Moves) {
+		check := MoveCheck{Ply: i + 1, Move: str}
+		move, err := board.DeserialiseMove(str)
+		if err == nil && boardState.WinState == board.NoWin {
+			err = boardState.MakeMove(move)
+		} else if err == nil {
+			err = errors.New("game was already over")
+		}
+		if err != nil {
+			report.Moves = append(report.Moves, check)
+			report.MovesLegal = false
+			problem("move %d (%s) is illegal: %s", i+1, str, err)
+			return report
+		}
+
+		check.Legal = true
+		check.Fen = boardState.Fen()
+		report.Moves = append(report.Moves, check)
+		boardState.HasWinner()
+	}
+
+	// older games were stored without it
+	if game.FinalFen != "" && game.FinalFen != boardState.Fen() {
+		problem("stored final position %q doesn't match the moves, which end in %q",
+			game.FinalFen, boardState.Fen())
+	}
+
+	err = checkResult(boardState, game.Result, game.Condition,
+		board.Termination(game.Termination), tablebase)
+	if err != nil {
+		problem("%s", err)
+	} else {
+		report.ResultConsistent = true
+	}
+	report.Verified = report.MovesLegal && report.ResultConsistent && len(report.Problems) == 0
+	return report
+}
+
+// the conditions games which didn't end on the board can be stored with
+var conditions = map[string]board.WinState{
+	board.WinStateToString(board.WhiteWin):        board.WhiteWin,
+	board.WinStateToString(board.BlackWin):        board.BlackWin,
+	board.WinStateToString(board.AdjudicatedDraw): board.AdjudicatedDraw,
+	board.WinStateToString(board.AgreedDraw):      board.AgreedDraw,
+	board.WinStateToString(board.TimeoutDraw):     board.TimeoutDraw,
+}
+
+func checkResult(
+	final *board.BoardState,
+	result, condition string,
+	termination board.Termination,
+	tablebase engine.Tablebase,
+) error {
+	// games which ended on the board have to be stored as they ended
+	win := final.HasWinner()
+	if win != board.NoWin {
+		expected := board.WinStateToString(win)
+		if condition != expected {
+			return fmt.Errorf("game ended by %q but was stored as %q", expected, condition)
+		}
+		if termination != board.BoardTermination(win) {
+			return fmt.Errorf("%q stored with termination %q", condition, termination)
+		}
+		if result != pgn.ResultFromWinState(win) {
+			return fmt.Errorf("%q should be %s, stored as %s",
+				condition, pgn.ResultFromWinState(win), result)
+		}
+		return nil
+	}
+
+	stored, found := conditions[condition]
+	if !found {
+		return fmt.Errorf("unfinished game stored as %q", condition)
+	}
+	if result != pgn.ResultFromWinState(stored) {
+		return fmt.Errorf("%q stored as %s", condition, result)
+	}
+
+	decisive := stored == board.WhiteWin || stored == board.BlackWin
+	switch termination {
+	case board.TerminationAdjudicated:
+		if engine.Adjudicate(tablebase, final) != stored {
+			return fmt.Errorf("%q adjudication doesn't match the tablebase", condition)
+		}
+	case board.TerminationTimeForfeit:
+		if !decisive {
+			return fmt.Errorf("time forfeit stored as %q", condition)
+		}
+		if !final.HasMatingMaterial(board.Colour(stored)) {
+			return errors.New("time forfeit won without mating material")
+		}
+	case board.TerminationAbandonment, board.TerminationRulesInfraction:
+		if !decisive {
+			return fmt.Errorf("%s stored as %q", termination, condition)
+		}
+	case board.TerminationAgreement:
+		if stored != board.AgreedDraw {
+			return fmt.Errorf("agreement stored as %q", condition)
+		}
+	case board.TerminationTimeoutDraw:
+		if stored != board.TimeoutDraw {
+			return fmt.Errorf("timeout draw stored as %q", condition)
+		}
+		if final.HasMatingMaterial(board.White) && final.HasMatingMaterial(board.Black) {
+			return errors.New("timeout draw with mating material on both sides")
+		}
+	default:
+		return fmt.Errorf("%q termination but the game didn't end on the board", termination)
+	}
+	return nil
+}
+
+type GameGetter interface {
+	GetGameById(ctx context.Context, id uuid.UUID) (model.Game, error)
+}
+
+// serves /{gameId} with the game's report, public so the frontend can
+// show a verified badge
+type VerifyServer struct {
+	ServeMux  *http.ServeMux
+	db        GameGetter
+	tablebase engine.Tablebase
+}
+
+func NewVerifyServer(db GameGetter, tablebase engine.Tablebase) *VerifyServer {
+	server := &VerifyServer{
+		ServeMux:  http.NewServeMux(),
+		db:        db,
+		tablebase: tablebase,
+	}
+	server.ServeMux.HandleFunc("/", server.VerifyHandler)
+	return server
+}
+
+func (server *VerifyServer) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	server.ServeMux.ServeHTTP(writer, req)
+}
+
+func (server *VerifyServer) VerifyHandler(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if req.Method != http.MethodGet {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := uuid.Parse(strings.TrimPrefix(req.URL.Path, "/"))
+	if err != nil {
+		http.Error(writer, "invalid game id", http.StatusBadRequest)
+		return
+	}
+
+	game, err := server.db.GetGameById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		slog.ErrorContext(ctx, "error", slog.Any("error", err))
+		return
+	}
+
+	bytes, err := json.Marshal(Game(game, server.tablebase))
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		slog.ErrorContext(ctx, "error", slog.Any("error", err))
+		return
+	}
+	writer.Header().Add("Content-Type", "application/json")
+	writer.Write(bytes)
+}
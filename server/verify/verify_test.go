@@ -0,0 +1,126 @@
+package verify_test
+
+import (
+	"testing"
+
+	"chess/board"
+	"chess/engine"
+	"chess/model"
+	"chess/pgn"
+	"chess/verify"
+
+	"github.com/google/uuid"
+)
+
+// fool's mate
+const foolsMate = "F2:F3 E7:E5 G2:G4 D8:H4"
+
+func newGame(moves, result string, win board.WinState, termination board.Termination) model.Game {
+	return model.Game{
+		ID:          uuid.New(),
+		Moves:       moves,
+		Result:      result,
+		Condition:   board.WinStateToString(win),
+		Termination: string(termination),
+		Variant:     board.Standard.Name(),
+	}
+}
+
+func Test_verify(test *testing.T) {
+	test.Run("test checkmate verifies", func(test *testing.T) {
+		test.Parallel()
+		game := newGame(foolsMate, pgn.BlackWinResult,
+			board.BlackWin, board.TerminationCheckmate)
+		report := verify.Game(game, engine.InsufficientMaterial{})
+		if !report.Verified {
+			test.Fatalf("expected game to verify, problems: %v", report.Problems)
+		}
+		if len(report.Moves) != 4 {
+			test.Fatalf("expected 4 checked moves, received %d", len(report.Moves))
+		}
+	})
+
+	test.Run("test illegal move stops the replay", func(test *testing.T) {
+		test.Parallel()
+		game := newGame("F2:F3 E7:E5 G2:G5 D8:H4", pgn.BlackWinResult,
+			board.BlackWin, board.TerminationCheckmate)
+		report := verify.Game(game, engine.InsufficientMaterial{})
+		if report.Verified || report.MovesLegal {
+			test.Fatal("expected illegal move to fail verification")
+		}
+		if len(report.Moves) != 3 || report.Moves[2].Legal {
+			test.Fatalf("expected replay to stop at ply 3, received %v", report.Moves)
+		}
+	})
+
+	test.Run("test wrong result is inconsistent", func(test *testing.T) {
+		test.Parallel()
+		game := newGame(foolsMate, pgn.WhiteWinResult,
+			board.BlackWin, board.TerminationCheckmate)
+		report := verify.Game(game, engine.InsufficientMaterial{})
+		if report.Verified || report.ResultConsistent {
+			test.Fatal("expected swapped result to fail verification")
+		}
+		if !report.MovesLegal {
+			test.Fatal("expected moves to be legal")
+		}
+	})
+
+	test.Run("test time loss verifies", func(test *testing.T) {
+		test.Parallel()
+		game := newGame("E2:E4", pgn.WhiteWinResult,
+			board.WhiteWin, board.TerminationTimeForfeit)
+		report := verify.Game(game, engine.InsufficientMaterial{})
+		if !report.Verified {
+			test.Fatalf("expected game to verify, problems: %v", report.Problems)
+		}
+	})
+
+	test.Run("test agreed draw verifies", func(test *testing.T) {
+		test.Parallel()
+		game := newGame("E2:E4", pgn.DrawResult,
+			board.AgreedDraw, board.TerminationAgreement)
+		report := verify.Game(game, engine.InsufficientMaterial{})
+		if !report.Verified {
+			test.Fatalf("expected game to verify, problems: %v", report.Problems)
+		}
+	})
+
+	test.Run("test checkmate stored as abandonment is inconsistent", func(test *testing.T) {
+		test.Parallel()
+		game := newGame(foolsMate, pgn.BlackWinResult,
+			board.BlackWin, board.TerminationAbandonment)
+		report := verify.Game(game, engine.InsufficientMaterial{})
+		if report.ResultConsistent {
+			test.Fatal("expected wrong termination to fail verification")
+		}
+	})
+
+	test.Run("test final position has to match the moves", func(test *testing.T) {
+		test.Parallel()
+		game := newGame(foolsMate, pgn.BlackWinResult,
+			board.BlackWin, board.TerminationCheckmate)
+		game.FinalFen = verify.Game(game, nil).Moves[3].Fen
+		report := verify.Game(game, engine.InsufficientMaterial{})
+		if !report.Verified {
+			test.Fatalf("expected game to verify, problems: %v", report.Problems)
+		}
+
+		game.FinalFen = board.NewVariantBoard(board.Standard).Fen()
+		report = verify.Game(game, engine.InsufficientMaterial{})
+		if report.Verified || len(report.Problems) != 1 {
+			test.Fatalf("expected wrong final position to fail verification, problems: %v",
+				report.Problems)
+		}
+	})
+
+	test.Run("test unfinished game is inconsistent", func(test *testing.T) {
+		test.Parallel()
+		game := newGame("E2:E4", pgn.DrawResult,
+			board.Stalemate, board.TerminationStalemate)
+		report := verify.Game(game, engine.InsufficientMaterial{})
+		if report.ResultConsistent {
+			test.Fatal("expected stalemate claim to fail verification")
+		}
+	})
+}
@@ -6,6 +6,16 @@ export type ConnectEvent = {
   moveHistory?: string[]
   colour: "w" | "b"
   legalMoves?: string[]
+  compactLegalMoves?: string
+  // viewers watching the game
+  spectators?: number
+  // colours which have berserked
+  berserk?: ("w" | "b")[]
+  // protocol version being served and the newest the server has
+  version?: number
+  currentVersion?: number
+  // number of the last event the game sent, see Sequenced
+  seq?: number
 }
 export type ConnectOtherEvent = {
   type: "connect"
@@ -15,53 +25,281 @@ export type ConnectViewerEvent = {
   type: "connectViewer"
   fen: string
   moveHistory?: string[]
+  spectators?: number
+  berserk?: ("w" | "b")[]
+  version?: number
+  currentVersion?: number
+  seq?: number
 }
 export type ConnectOtherViewerEvent = {
   type: "connectViewer"
+  spectators?: number
+}
+// sent when a viewer leaves, or joins over the relay
+export type SpectatorsEvent = {
+  type: "spectators"
+  spectators: number
+}
+// a player's connection dropped, they lose if they aren't back within
+// graceTime milliseconds
+export type DisconnectEvent = {
+  type: "disconnect"
+  colour: "w" | "b"
+  graceTime: number
+}
+// sent to give a disconnected opponent another grace period
+export type WaitLongerEvent = {
+  type: "waitLonger"
+}
+// the disconnected player's grace time after their opponent waited longer
+export type GraceExtendedEvent = {
+  type: "graceExtended"
+  colour: "w" | "b"
+  graceTime: number
+}
+// the server is restarting, games in progress are resumed once it's back
+export type ShutdownEvent = {
+  type: "shutdown"
+  text: string
+}
+// both players' ping round trips in milliseconds, sent after each ping
+export type LatencyEvent = {
+  type: "latency"
+  whiteLatency?: number
+  blackLatency?: number
 }
 export type MoveEvent = {
   type: "move"
   move: string
   fen: string
   legalMoves?: string[]
+  // sent instead of legalMoves from protocol v2, moves grouped by the square
+  // they're from e.g. "E2E3E4 G1F3H3"
+  compactLegalMoves?: string
+  // set once the position has been seen before
+  repetitions?: number
+  // the clocks after the move and how long it took
+  clock?: ClockReading
 }
 export type SendMoveEvent = {
   type: "sendMove"
   move: string
 }
+// the opponent wins by resignation, ignored once the game is over
+export type ResignEvent = {
+  type: "resign"
+}
+// casual games only, the opponent is sent the request with the colour
+// asking and can accept until another move is played
+export type TakebackRequestEvent = {
+  type: "takebackRequest"
+  colour?: "w" | "b"
+}
+// sent to everyone with the position after the last move is taken back
+export type TakebackAcceptEvent = {
+  type: "takebackAccept"
+  fen?: string
+  moveHistory?: string[]
+  legalMoves?: string[]
+  compactLegalMoves?: string
+  whiteTime?: number
+  blackTime?: number
+}
+// halves the sender's time and drops their increment, only before their
+// first move. Sent to everyone with the new times
+// offers are sent on to the opponent and only stand until another move is
+// played, accepting ends the game with the usual end or abort event. Aborts
+// can only be offered before the clocks start
+export type DrawOfferEvent = {
+  type: "drawOffer"
+  colour?: "w" | "b"
+}
+export type DrawAcceptEvent = {
+  type: "drawAccept"
+}
+export type AbortOfferEvent = {
+  type: "abortOffer"
+  colour?: "w" | "b"
+}
+export type AbortAcceptEvent = {
+  type: "abortAccept"
+}
+export type BerserkEvent = {
+  type: "berserk"
+  colour?: "w" | "b"
+  whiteTime?: number
+  blackTime?: number
+}
 export type DuplicateSessionEvent = {
   type: "connect"
   fen: string
   moveHistory?: string[]
   colour: "w" | "b"
   legalMoves?: string[]
+  compactLegalMoves?: string
+}
+// times are in milliseconds, spent is what the move took off the mover's
+// clock and playedAt is a unix time
+export type ClockReading = {
+  whiteTime: number
+  blackTime: number
+  spent: number
+  playedAt: number
 }
+// why the game ended, termination is the same for people to read
+export type EndReason =
+  | "checkmate"
+  | "stalemate"
+  | "moveRule"
+  | "insufficientMaterial"
+  | "timeout"
+  | "resignation"
+  | "disconnection"
+  | "agreement"
+  | "adjudication"
+  | "rulesInfraction"
 export type WinEvent = {
   type: "end"
   outcome: "win"
   victor: "w" | "b"
+  // why the game ended e.g. "checkmate" or "time forfeit"
+  termination?: string
+  reason?: EndReason
+  result?: "1-0" | "0-1"
+  // every move's clock reading
+  clocks?: ClockReading[]
 }
 export type DrawEvent = {
   type: "end"
-  outcome: "moveRuleDraw" | "stalemate" | "draw"
+  outcome: "moveRuleDraw" | "stalemate" | "agreement" | "timeoutDraw" | "deadPosition" | "draw"
+  termination?: string
+  reason?: EndReason
+  result?: "1/2-1/2"
+  clocks?: ClockReading[]
+}
+export type PlayerSummary = {
+  // centipawns of material left on the board
+  material: number
+  // milliseconds each move took off the player's clock
+  moveTimes: number[]
+  totalTime: number
+  averageCentipawnLoss?: number
 }
+// sent after the end event
+export type SummaryEvent = {
+  type: "summary"
+  summary: {
+    // white's material less black's
+    materialBalance: number
+    white: PlayerSummary
+    black: PlayerSummary
+  }
+}
+// the game ended before both players made a move, colour is the player who
+// didn't. It has no result and isn't counted as a loss
+export type AbortEvent = {
+  type: "abort"
+  outcome: "abort"
+  colour: "w" | "b"
+  reason?: "abort"
+}
+// only sent to clients which connected with ?clockPrecision=tenths, every
+// tenth of a second during the last seconds of the running clock
+export type ClockSyncEvent = {
+  type: "clockSync"
+  whiteTime: number
+  blackTime: number
+}
+// the remaining times every few seconds while the clock runs
+export type ClockEvent = {
+  type: "clock"
+  whiteTime: number
+  blackTime: number
+}
+// players' messages only go to the other player until the game's over,
+// viewers' only to the other viewers
 export type ChatEvent = {
   type: "chat"
   text: string
+  // user id of the sender, not set on messages being sent
+  sender?: string
+  colour?: "w" | "b" | "v"
+  channel?: "players" | "spectators"
 }
 export type ErrorEvent = {
   type: "error"
   text: string
+  // set when the error answers a message the client sent
+  code?:
+    | "badJson"
+    | "unknownEvent"
+    | "illegalMove"
+    | "notYourTurn"
+    | "replayUnavailable"
+    | "opponentConnected"
+    | "offerUnavailable"
+    | "gameOver"
+    | "gameNotOver"
+    | "notAllowed"
+    | "rateLimited"
+    | "chatTooLong"
+}
+// asks for the events numbered after seq to be sent again
+export type ReplayEvent = {
+  type: "replay"
+  seq: number
 }
-export type GameEvent =
+// events sent to everyone in the game are numbered in order, a jump means
+// one was missed. The client's own moves and requests aren't sent back to
+// it so their numbers are skipped
+export type Sequenced = { seq?: number }
+// sent to ask for the position again, e.g. after a replayUnavailable error,
+// the answer only has the fields filled in
+export type SyncEvent = {
+  type: "sync"
+  fen?: string
+  moveHistory?: string[]
+  // only sent to players
+  legalMoves?: string[]
+  compactLegalMoves?: string
+  whiteTime?: number
+  blackTime?: number
+}
+export type GameEvent = Sequenced & (
   | ConnectEvent
   | ConnectViewerEvent
   | MoveEvent
   | SendMoveEvent
+  | ResignEvent
+  | TakebackRequestEvent
+  | TakebackAcceptEvent
+  | DrawOfferEvent
+  | DrawAcceptEvent
+  | AbortOfferEvent
+  | AbortAcceptEvent
+  | BerserkEvent
   | WinEvent
   | DrawEvent
+  | AbortEvent
+  | SummaryEvent
+  | ClockSyncEvent
+  | ClockEvent
+  | SpectatorsEvent
+  | DisconnectEvent
+  | WaitLongerEvent
+  | GraceExtendedEvent
+  | LatencyEvent
+  | ShutdownEvent
   | ChatEvent
   | ErrorEvent
+  | ReplayEvent
+  | SyncEvent
+)
+
+// websocket subprotocols, events are json unless msgpack is offered in
+// which case they're binary msgpack frames with the same fields
+export const jsonSubprotocol = "chess.json.v1"
+export const msgpackSubprotocol = "chess.msgpack.v1"
 
 export function parseBoardState(event: ConnectEvent): Board {
   const board = parseFen(event.fen)
@@ -73,3 +311,51 @@ export function parseBoardState(event: ConnectEvent): Board {
 export function sendMove(from: Position, to: Position): SendMoveEvent {
   return { type: "sendMove", move: serialiseMove(from, to) }
 }
+
+export function sendChat(text: string): ChatEvent {
+  return { type: "chat", text }
+}
+
+export function replay(seq: number): ReplayEvent {
+  return { type: "replay", seq }
+}
+
+export function requestSync(): SyncEvent {
+  return { type: "sync" }
+}
+
+export function waitLonger(): WaitLongerEvent {
+  return { type: "waitLonger" }
+}
+
+export function resign(): ResignEvent {
+  return { type: "resign" }
+}
+
+export function requestTakeback(): TakebackRequestEvent {
+  return { type: "takebackRequest" }
+}
+
+export function acceptTakeback(): TakebackAcceptEvent {
+  return { type: "takebackAccept" }
+}
+
+export function offerDraw(): DrawOfferEvent {
+  return { type: "drawOffer" }
+}
+
+export function acceptDraw(): DrawAcceptEvent {
+  return { type: "drawAccept" }
+}
+
+export function offerAbort(): AbortOfferEvent {
+  return { type: "abortOffer" }
+}
+
+export function acceptAbort(): AbortAcceptEvent {
+  return { type: "abortAccept" }
+}
+
+export function goBerserk(): BerserkEvent {
+  return { type: "berserk" }
+}
@@ -0,0 +1,394 @@
+// Code generated by cmd/schemagen. DO NOT EDIT.
+
+export type ActiveGames = {
+  games: {
+  id: string
+  white: string
+  black: string
+  variant: string
+  timeControl: string
+  gameLength: number
+  increment: number
+  moves: number
+  spectators: number
+  createdAt: string
+}[]
+  cursor?: string
+}
+
+export type AdminSessions = {
+  sessions: {
+  id: string
+  white: string
+  black: string
+  variant: string
+  moves: number
+  ended: boolean
+  aborted: boolean
+  whiteConnection: string
+  blackConnection: string
+  viewers: number
+  createdAt: string
+  updatedAt: string
+}[]
+}
+
+export type ApiKey = {
+  id: string
+  organization: string
+  scopes: string[]
+  createdAt: string
+  revokedAt?: string
+  key?: string
+}
+
+export type ClockAudit = {
+  runs: number
+  scanned: number
+  negativeTimes: number
+  wrongColour: number
+  armedAfterEnd: number
+  repaired: number
+}
+
+export type GameEvent = {
+  type: string
+  fen?: string
+  moveHistory?: string[]
+  colour?: string
+  move?: string
+  legalMoves?: string[]
+  compactLegalMoves?: string
+  outcome?: string
+  victor?: string
+  text?: string
+  whiteTime?: number
+  blackTime?: number
+  gameId?: string
+  winProbability?: number
+  receivedAt?: number
+  clockDrift?: number
+  vacationUntil?: number
+  repetitions?: number
+  termination?: string
+  reason?: string
+  result?: string
+  summary?: {
+  materialBalance: number
+  white: {
+  material: number
+  moveTimes: number[]
+  totalTime: number
+  averageCentipawnLoss?: number
+}
+  black: {
+  material: number
+  moveTimes: number[]
+  totalTime: number
+  averageCentipawnLoss?: number
+}
+}
+  sender?: string
+  channel?: string
+  spectators?: number
+  clock?: {
+  whiteTime: number
+  blackTime: number
+  spent: number
+  playedAt: number
+}
+  clocks?: {
+  whiteTime: number
+  blackTime: number
+  spent: number
+  playedAt: number
+}[]
+  berserk?: string[]
+  version?: number
+  currentVersion?: number
+  whiteLatency?: number
+  blackLatency?: number
+  code?: string
+  seq?: number
+  graceTime?: number
+}
+
+export type GameSnapshot = {
+  id: string
+  white: string
+  black: string
+  variant: string
+  fen: string
+  moveHistory: string[]
+  turn: string
+  ended: boolean
+  whiteTime?: number
+  blackTime?: number
+  result?: string
+  outcome?: string
+  termination?: string
+  summary?: {
+  materialBalance: number
+  white: {
+  material: number
+  moveTimes: number[]
+  totalTime: number
+  averageCentipawnLoss?: number
+}
+  black: {
+  material: number
+  moveTimes: number[]
+  totalTime: number
+  averageCentipawnLoss?: number
+}
+}
+}
+
+export type GameTelemetry = {
+  white: {
+  moves: {
+  ply: number
+  thinkTime: number
+  roundTrip: number
+}[]
+  clients: {
+  connectedAt: string
+  userAgent: string
+  version: number
+  msgpack: boolean
+  moveFormat: string
+}[]
+}
+  black: {
+  moves: {
+  ply: number
+  thinkTime: number
+  roundTrip: number
+}[]
+  clients: {
+  connectedAt: string
+  userAgent: string
+  version: number
+  msgpack: boolean
+  moveFormat: string
+}[]
+}
+}
+
+export type LobbyEvent = {
+  type: string
+  gameId?: string
+  games?: {
+  id: string
+  white: string
+  black: string
+  variant: string
+  timeControl: string
+  gameLength: number
+  increment: number
+  moves: number
+  spectators: number
+  createdAt: string
+}[]
+  game?: {
+  id: string
+  white: string
+  black: string
+  variant: string
+  timeControl: string
+  gameLength: number
+  increment: number
+  moves: number
+  spectators: number
+  createdAt: string
+}
+  fen?: string
+  event?: {
+  type: string
+  fen?: string
+  moveHistory?: string[]
+  colour?: string
+  move?: string
+  legalMoves?: string[]
+  compactLegalMoves?: string
+  outcome?: string
+  victor?: string
+  text?: string
+  whiteTime?: number
+  blackTime?: number
+  gameId?: string
+  winProbability?: number
+  receivedAt?: number
+  clockDrift?: number
+  vacationUntil?: number
+  repetitions?: number
+  termination?: string
+  reason?: string
+  result?: string
+  summary?: {
+  materialBalance: number
+  white: {
+  material: number
+  moveTimes: number[]
+  totalTime: number
+  averageCentipawnLoss?: number
+}
+  black: {
+  material: number
+  moveTimes: number[]
+  totalTime: number
+  averageCentipawnLoss?: number
+}
+}
+  sender?: string
+  channel?: string
+  spectators?: number
+  clock?: {
+  whiteTime: number
+  blackTime: number
+  spent: number
+  playedAt: number
+}
+  clocks?: {
+  whiteTime: number
+  blackTime: number
+  spent: number
+  playedAt: number
+}[]
+  berserk?: string[]
+  version?: number
+  currentVersion?: number
+  whiteLatency?: number
+  blackLatency?: number
+  code?: string
+  seq?: number
+  graceTime?: number
+}
+}
+
+export type MyGames = {
+  games: {
+  id: string
+  opponent: string
+  colour: string
+  fen: string
+  variant: string
+  timeControl: string
+  myTurn: boolean
+  createdAt: string
+}[]
+  limits: {
+  live: number
+  correspondence: number
+}
+}
+
+export type MyTurn = {
+  games: {
+  id: string
+  opponent: string
+  colour: string
+  fen: string
+  variant: string
+  deadline: string
+}[]
+}
+
+export type MyTurnCount = {
+  type: string
+  count: number
+}
+
+export type NotificationPreferences = Record<string, Record<string, boolean>>
+
+export type QueueResponse = {
+  found: boolean
+  gameId?: string
+}
+
+export type Replay = {
+  id: string
+  variant: string
+  fen: string
+  moveHistory: string[]
+  clocks: {
+  whiteTime: number
+  blackTime: number
+  spent: number
+  playedAt: number
+}[]
+  gameLength: number
+  increment: number
+  outcome?: string
+  termination?: string
+  ply?: number
+  plyFen?: string
+  thumbnail?: string
+}
+
+export type Status = {
+  uptimeSeconds: number
+  activeGames: number
+  playersOnline: number
+  queueSizes: Record<string, number>
+  errorRate: number
+}
+
+export type Vacation = {
+  remainingDays: number
+  until?: string
+}
+
+export type VariantRules = {
+  name: string
+  startingFen: string
+  whitePawns: {
+  push: {
+  x: number
+  y: number
+}
+  doublePush: boolean
+  captures: {
+  x: number
+  y: number
+}[]
+  promotionSquares: string[]
+  promotionPieces: string[]
+}
+  blackPawns: {
+  push: {
+  x: number
+  y: number
+}
+  doublePush: boolean
+  captures: {
+  x: number
+  y: number
+}[]
+  promotionSquares: string[]
+  promotionPieces: string[]
+}
+  specialMoves: string[]
+  winConditions: string[]
+  drawRules: string[]
+  moveRuleLimit: number
+  shuffled: boolean
+}
+
+export type Verification = {
+  gameId: string
+  variant: string
+  verified: boolean
+  moves: {
+  ply: number
+  move: string
+  legal: boolean
+  fen?: string
+}[]
+  movesLegal: boolean
+  result: string
+  condition: string
+  termination: string
+  resultConsistent: boolean
+  problems?: string[]
+}